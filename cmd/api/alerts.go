@@ -0,0 +1,337 @@
+// File: cmd/api/alerts.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+const (
+	// alertTimeout bounds a single run of the saved search matcher and the digests after it.
+	alertTimeout = 5 * time.Minute
+	// matchPageSize is how many listings the matcher reads at a time.
+	matchPageSize = 100
+	// matchOverlap is how far each match window reaches back into the one before it, but never
+	// before the search was created. A listing is stamped with the time its transaction started, so
+	// one that committed after the previous window closed can carry a time inside it; matching a
+	// listing twice is a no-op.
+	matchOverlap = time.Minute
+	// maxDigestListings caps the listings written out in one digest; the rest are only counted.
+	maxDigestListings = 20
+	// alertLockKey is the advisory lock that elects the one API instance running the alerts.
+	// It must differ from the migration and scheduler lock keys.
+	alertLockKey int64 = 0x636173685f616c72 // "cash_alr"
+)
+
+// digestListing is a matched listing as the digest template shows it.
+type digestListing struct {
+	Title string
+	URL   string
+}
+
+// watchEvent is a notification of a watched listing as the watchlist template shows it.
+type watchEvent struct {
+	Title   string
+	URL     string
+	Message string
+}
+
+// alert matches the listings changed since the last run against every saved search with alerts
+// on, then emails the digests that are due and the notifications queued for watchers. Only one API
+// instance runs it at a time; the others skip the run. A saved search or watcher that fails is
+// logged and skipped, so that it cannot hold up everyone else's alerts.
+func (app *application) alert(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, alertTimeout)
+	defer cancel()
+
+	ran, err := app.models.Exclusive(ctx, alertLockKey, func() error { return app.runAlert(ctx) })
+	if err != nil {
+		return err
+	}
+	if !ran {
+		app.logger.Debug("skipped alerts", "reason", "another instance holds the lock")
+	}
+	return nil
+}
+
+// runAlert is a single run of the alerts, once the lock is held.
+func (app *application) runAlert(ctx context.Context) error {
+	searches, err := app.models.SavedSearches.GetAlerting(ctx)
+	if err != nil {
+		return err
+	}
+	matched := 0
+	for _, s := range searches {
+		n, err := app.matchSavedSearch(ctx, s)
+		if err != nil {
+			app.logger.Error(err.Error(), "saved_search_id", s.ID)
+			continue
+		}
+		matched += n
+	}
+
+	now := time.Now()
+	due, err := app.models.SavedSearches.GetDue(ctx, now)
+	if err != nil {
+		return err
+	}
+	sent := 0
+	for _, s := range due {
+		if err := app.sendDigest(ctx, s, now); err != nil {
+			app.logger.Error(err.Error(), "saved_search_id", s.ID)
+			continue
+		}
+		sent++
+	}
+
+	notified, err := app.notifyWatchers(ctx)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("ran alerts", "searches", len(searches), "matches", matched, "digests", sent, "watchers_notified", notified)
+	return nil
+}
+
+// matchSavedSearch records the active listings created or edited since the search was last checked
+// that it matches, leaving out the user's own listings. It returns the number of matches.
+func (app *application) matchSavedSearch(ctx context.Context, s *savedsearches.SavedSearch) (int, error) {
+	checkedAt, err := app.models.Now(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	filter, err := app.savedSearchFilter(s)
+	if err != nil {
+		return 0, err
+	}
+	active := true
+	filter.IsActive = &active
+	since := s.CheckedAt.Add(-matchOverlap)
+	if since.Before(s.CreatedAt) {
+		since = s.CreatedAt
+	}
+	filter.UpdatedSince = &since
+	filter.Default = filters.Filters{Page: 1, PageSize: matchPageSize, Sort: "id", SortSafelist: []string{"id"}}
+
+	ids := []int64{}
+	for {
+		list, metadata, err := app.models.Listings.GetAll(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		for _, l := range list {
+			if l.UserID != s.UserID {
+				ids = append(ids, l.ID)
+			}
+		}
+		if len(list) == 0 || metadata.CurrentPage >= metadata.LastPage {
+			break
+		}
+		filter.Default.Page++
+	}
+
+	if err := app.models.SavedSearches.RecordMatches(ctx, s.ID, ids, checkedAt); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// sendDigest emails the owner of a saved search the listings it has matched since the last digest.
+// Listings withdrawn in the meantime are left out, and a digest left with none is not sent.
+func (app *application) sendDigest(ctx context.Context, s *savedsearches.SavedSearch, now time.Time) error {
+	pending, err := app.models.SavedSearches.GetPending(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+
+	// The digest is queued in the same transaction that claims its matches, and only holds the
+	// matches claimed there, so each match goes out once
+	return app.models.WithTx(ctx, func(tx data.Models) error {
+		claimed, err := tx.SavedSearches.MarkSent(ctx, s.ID, pending, now)
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+		digest, err := app.digestEmail(ctx, tx, s, claimed)
+		if err != nil || digest == nil {
+			return err
+		}
+		return tx.Emails.Insert(ctx, digest)
+	})
+}
+
+// digestEmail builds the digest of the given matches of a saved search, or returns nil when none
+// of the listings is still on the market.
+func (app *application) digestEmail(ctx context.Context, tx data.Models, s *savedsearches.SavedSearch, listingIDs []int64) (*outbox.Email, error) {
+	var matches []digestListing
+	for _, id := range listingIDs {
+		l, err := tx.Listings.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, internalErrors.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if l.IsDeleted() || (l.IsActive != nil && !*l.IsActive) {
+			continue
+		}
+		matches = append(matches, digestListing{Title: l.Title, URL: fmt.Sprintf("%s/listings/%d", app.config.webURL, l.ID)})
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	user, err := tx.Users.GetByID(ctx, s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return outbox.New(user.Email, "saved_search_digest.tmpl", map[string]any{
+		"firstName":      user.FirstName,
+		"searchName":     s.Name,
+		"listings":       matches[:min(len(matches), maxDigestListings)],
+		"more":           max(len(matches)-maxDigestListings, 0),
+		"searchURL":      app.config.webURL + "/listings?" + s.Query,
+		"unsubscribeURL": app.config.webURL + "/saved-searches/unsubscribe?token=" + url.QueryEscape(s.UnsubscribeToken),
+	})
+}
+
+// notifyWatchers emails each watcher the notifications queued for them, together in one email,
+// and returns the number of watchers emailed.
+func (app *application) notifyWatchers(ctx context.Context) (int, error) {
+	pending, err := app.models.Watches.GetPendingNotifications(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for start := 0; start < len(pending); {
+		end := start + 1
+		for end < len(pending) && pending[end].UserID == pending[start].UserID {
+			end++
+		}
+		if err := app.sendWatchNotifications(ctx, pending[start:end]); err != nil {
+			app.logger.Error(err.Error(), "user_id", pending[start].UserID)
+		} else {
+			sent++
+		}
+		start = end
+	}
+	return sent, nil
+}
+
+// sendWatchNotifications emails one watcher the notifications queued for them. Those of listings
+// deleted in the meantime are left out, and an email left with none is not sent.
+func (app *application) sendWatchNotifications(ctx context.Context, notifications []*watchlists.Notification) error {
+	ids := make([]int64, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+
+	// The email is queued in the same transaction that claims its notifications, and only holds the
+	// notifications claimed there, so each goes out once
+	return app.models.WithTx(ctx, func(tx data.Models) error {
+		claimed, err := tx.Watches.MarkNotificationsSent(ctx, ids, time.Now())
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+		mine := []*watchlists.Notification{}
+		for _, n := range notifications {
+			if _, found := slices.BinarySearch(claimed, n.ID); found {
+				mine = append(mine, n)
+			}
+		}
+		email, err := app.watchEmail(ctx, tx, mine)
+		if err != nil || email == nil {
+			return err
+		}
+		return tx.Emails.Insert(ctx, email)
+	})
+}
+
+// watchEmail builds the email of the given notifications of one watcher, or returns nil when all
+// of their listings have been deleted.
+func (app *application) watchEmail(ctx context.Context, tx data.Models, notifications []*watchlists.Notification) (*outbox.Email, error) {
+	var events []watchEvent
+	for _, n := range notifications {
+		l, err := tx.Listings.GetByID(ctx, n.ListingID)
+		if err != nil {
+			if errors.Is(err, internalErrors.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if l.IsDeleted() {
+			continue
+		}
+
+		event := watchEvent{Title: l.Title, URL: fmt.Sprintf("%s/listings/%d", app.config.webURL, l.ID)}
+		switch n.Event {
+		case watchlists.EventPriceChanged:
+			event.Message = "Price changed: " + n.Detail
+		case watchlists.EventSold:
+			event.Message = "Sold"
+		case watchlists.EventExpiring:
+			event.Message = "Expires " + n.Detail
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	user, err := tx.Users.GetByID(ctx, notifications[0].UserID)
+	if err != nil {
+		return nil, err
+	}
+	return outbox.New(user.Email, "watchlist_notification.tmpl", map[string]any{
+		"firstName":    user.FirstName,
+		"events":       events,
+		"watchlistURL": app.config.webURL + "/watchlist",
+	})
+}
+
+// savedSearchFilter reads the listing search stored in a saved search.
+func (app *application) savedSearchFilter(s *savedsearches.SavedSearch) (*listings.ListingFilter, error) {
+	qs, err := url.ParseQuery(s.Query)
+	if err != nil {
+		return nil, fmt.Errorf("saved search %d: %w", s.ID, err)
+	}
+
+	v := validator.New()
+	filter := app.readListingFilter(qs, v)
+	if listings.ValidateListingFilter(v, filter); !v.Valid() {
+		return nil, fmt.Errorf("saved search %d: invalid listing search: %v", s.ID, v.Errors)
+	}
+	return filter, nil
+}
+
+// runAlerts runs the saved search alerts on every tick of the alert interval until ctx is
+// cancelled. A failed run is logged and retried on the next tick.
+func (app *application) runAlerts(ctx context.Context) {
+	ticker := time.NewTicker(app.config.alerts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.alert(ctx); err != nil && ctx.Err() == nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	}
+}
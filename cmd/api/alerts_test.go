@@ -0,0 +1,99 @@
+// File: cmd/api/alerts_test.go
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+// queued returns the emails in the outbox.
+func queued(t *testing.T, app *application) []*outbox.Email {
+	t.Helper()
+	emails, _, err := app.models.Emails.GetAll(t.Context(), &outbox.EmailFilter{Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return emails
+}
+
+func TestAlertSendsDigestOnce(t *testing.T) {
+	app := newTestApplication(t)
+	f := testdb.NewFactory(t, app.models)
+	ctx := t.Context()
+
+	s := f.SavedSearch(func(s *savedsearches.SavedSearch) { s.Frequency = savedsearches.FrequencyImmediate })
+	f.Listing()
+
+	// While another instance holds the alert lock, this one skips the run
+	ran, err := app.models.Exclusive(ctx, alertLockKey, func() error { return app.alert(ctx) })
+	if err != nil || !ran {
+		t.Fatalf("Exclusive = %v, %v; want it to run", ran, err)
+	}
+	if emails := queued(t, app); len(emails) != 0 {
+		t.Fatalf("queued %d emails while locked out, want none", len(emails))
+	}
+
+	if err := app.alert(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if emails := queued(t, app); len(emails) != 1 {
+		t.Fatalf("queued %d emails, want one digest", len(emails))
+	}
+
+	// A second digest of the same matches claims none of them and sends nothing
+	if err := app.sendDigest(ctx, s, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if emails := queued(t, app); len(emails) != 1 {
+		t.Errorf("queued %d emails after a second digest, want one", len(emails))
+	}
+}
+
+func TestAlertMatchesListingsCommittedLate(t *testing.T) {
+	app := newTestApplication(t)
+	f := testdb.NewFactory(t, app.models)
+	ctx := t.Context()
+
+	s := f.SavedSearch(func(s *savedsearches.SavedSearch) { s.Frequency = savedsearches.FrequencyImmediate })
+	f.Listing()
+	// A run whose window closed after the listing was stamped, but before it was committed
+	if err := app.models.SavedSearches.RecordMatches(ctx, s.ID, []int64{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.alert(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if emails := queued(t, app); len(emails) != 1 {
+		t.Errorf("queued %d emails, want a digest of the late listing", len(emails))
+	}
+}
+
+func TestNotifyWatchersOnce(t *testing.T) {
+	app := newTestApplication(t)
+	f := testdb.NewFactory(t, app.models)
+	ctx := t.Context()
+
+	w := f.Watch()
+	if err := app.models.Listings.MarkSold(ctx, *w.ListingID); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := app.models.Watches.GetPendingNotifications(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two runs racing to the same notifications queue one email between them
+	for range 2 {
+		if err := app.sendWatchNotifications(ctx, pending); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if emails := queued(t, app); len(emails) != 1 {
+		t.Errorf("queued %d emails, want one", len(emails))
+	}
+}
@@ -0,0 +1,184 @@
+// File: cmd/api/areas.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// listAreasHandler lists areas, optionally within a radius of a point ("near=lat,lng&radius_km=50")
+// or inside a bounding box ("bbox=min_lng,min_lat,max_lng,max_lat"). Proximity results are sorted nearest first.
+func (app *application) listAreasHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filter := app.readAreaFilter(r.URL.Query(), v)
+	fields := app.readFields(r.URL.Query(), locations.Area{}, v)
+	if filters.ValidateFilters(v, filter.Default); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	areas, metadata, err := app.models.Areas.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"areas": fields.project(areas), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// patchAreaHandler applies a partial update to an area, changing only the fields sent. Moving the area
+// re-derives its region from the new coordinates where a boundary covers them.
+func (app *application) patchAreaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	area, err := app.models.Areas.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if area.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, area.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        *string                `json:"name"`
+		RegionID    *int                   `json:"region_id"`
+		AreaType    *locations.AreaType    `json:"area_type"`
+		Coordinates *locations.Coordinates `json:"coordinates"`
+		IsActive    *bool                  `json:"is_active"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var changed changes
+	patchField(&changed, "name", &area.Name, input.Name)
+	patchField(&changed, "area_type", &area.AreaType, input.AreaType)
+	patchField(&changed, "coordinates", &area.Coordinates, input.Coordinates)
+	patchNullable(&changed, "is_active", &area.IsActive, input.IsActive)
+
+	if input.RegionID == nil && input.Coordinates != nil {
+		// The area follows its new coordinates into the region covering them, if any.
+		regionID, err := app.models.Regions.ResolveRegion(r.Context(), 0, area.Coordinates)
+		switch {
+		case err == nil:
+			input.RegionID = &regionID
+		case !errors.Is(err, internalErrors.ErrRegionUnknown):
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	patchField(&changed, "region_id", &area.RegionID, input.RegionID)
+
+	v := validator.New()
+	if locations.ValidateArea(v, area); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		err = app.models.Areas.Update(r.Context(), area)
+		if err != nil {
+			switch {
+			case isDuplicate(err, "name"):
+				v.AddError("name", "an area with this name already exists in the region")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrInvalidRegionID):
+				v.AddError("region_id", "must refer to an existing region")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrRegionMismatch):
+				v.AddError("region_id", "does not match the region covering the coordinates")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.writePatched(w, r, "area", area, area.Version, changed)
+}
+
+// deleteAreaHandler soft deletes an area. Listings already placed in it keep their area.
+func (app *application) deleteAreaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Areas.SoftDelete(r.Context(), int(id), app.contextGetUser(r).ID)
+	app.writeDeletion(w, r, err, "area successfully deleted")
+}
+
+// restoreAreaHandler restores a soft deleted area.
+func (app *application) restoreAreaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Areas.Restore(r.Context(), int(id))
+	app.writeDeletion(w, r, err, "area successfully restored")
+}
+
+// readAreaFilter reads the area search parameters shared by the JSON and GeoJSON endpoints.
+func (app *application) readAreaFilter(qs url.Values, v *validator.Validator) *locations.AreaFilter {
+	var filter locations.AreaFilter
+	filter.Name = app.readString(qs, "name", "")
+	if regionID := app.readOptionalInt64(qs, "region_id", v); regionID != nil {
+		id := int(*regionID)
+		filter.RegionID = &id
+	}
+	if areaType := app.readString(qs, "area_type", ""); areaType != "" {
+		t := locations.AreaType(areaType)
+		v.Check(t == locations.AreaTypeCity || t == locations.AreaTypeTown || t == locations.AreaTypeVillage, "area_type", "must be a valid area type")
+		filter.AreaType = &t
+	}
+	filter.IsActive = app.readOptionalBool(qs, "is_active", v)
+	filter.Near = app.readProximity(qs, v)
+	filter.Box = app.readBoundingBox(qs, v)
+
+	defaultSort := "name"
+	if filter.Near != nil {
+		defaultSort = "distance_km"
+	}
+
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", defaultSort)
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "name", "area_type", "created_at", "-id", "-name", "-area_type", "-created_at"}
+	if filter.Near != nil {
+		filter.Default.SortSafelist = append(filter.Default.SortSafelist, "distance_km", "-distance_km")
+	}
+	return &filter
+}
@@ -0,0 +1,54 @@
+// File: cmd/api/audit.go
+package main
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// auditLogHandler lists the audit trail, newest first. It can be narrowed to one actor, one record
+// ("entity=cattle&entity_id=7"), one action, one request or a time window ("since" and "until" as
+// RFC 3339 timestamps).
+func (app *application) auditLogHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filter := app.readEntryFilter(r.URL.Query(), v)
+	fields := app.readFields(r.URL.Query(), audit.Entry{}, v)
+	if audit.ValidateEntryFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entries, metadata, err := app.models.Audit.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit_log": fields.project(entries), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readEntryFilter reads the audit trail filters from the query string.
+func (app *application) readEntryFilter(qs url.Values, v *validator.Validator) *audit.EntryFilter {
+	var filter audit.EntryFilter
+	filter.ActorID = app.readOptionalInt64(qs, "actor_id", v)
+	filter.Entity = app.readString(qs, "entity", "")
+	filter.EntityID = app.readOptionalInt64(qs, "entity_id", v)
+	filter.Action = app.readString(qs, "action", "")
+	filter.RequestID = app.readString(qs, "request_id", "")
+	filter.Since = app.readOptionalTime(qs, "since", v)
+	filter.Until = app.readOptionalTime(qs, "until", v)
+
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", "-created_at")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+	return &filter
+}
@@ -0,0 +1,102 @@
+// File: cmd/api/breeds.go
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// patchBreedHandler applies a partial update to a breed, changing only the fields sent.
+func (app *application) patchBreedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	b, err := app.models.Breeds.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if b.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, b.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		IsActive    *bool   `json:"is_active"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var changed changes
+	patchField(&changed, "name", &b.Name, input.Name)
+	patchField(&changed, "description", &b.Description, input.Description)
+	patchNullable(&changed, "is_active", &b.IsActive, input.IsActive)
+
+	v := validator.New()
+	if cattle.ValidateBreed(v, b); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		err = app.models.Breeds.Update(r.Context(), b)
+		if err != nil {
+			switch {
+			case isDuplicate(err, "name"):
+				v.AddError("name", "a breed with this name already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.writePatched(w, r, "breed", b, b.Version, changed)
+}
+
+// deleteBreedHandler soft deletes a breed. Animals already recorded against it keep their composition.
+func (app *application) deleteBreedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Breeds.SoftDelete(r.Context(), int(id), app.contextGetUser(r).ID)
+	app.writeDeletion(w, r, err, "breed successfully deleted")
+}
+
+// restoreBreedHandler restores a soft deleted breed.
+func (app *application) restoreBreedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Breeds.Restore(r.Context(), int(id))
+	app.writeDeletion(w, r, err, "breed successfully restored")
+}
@@ -0,0 +1,182 @@
+// File: cmd/api/cattle.go
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// patchCattleHandler applies a partial update to an animal. Only the fields sent are changed; a new
+// breed_id records the animal as purebred, while a composition replaces its breed make-up and derives
+// breed_id. The owner can edit their animals, and holders of write:cattle can edit anyone's.
+func (app *application) patchCattleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	c, err := app.models.Cattle.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canEdit(r, int64(c.OwnerID), "write:cattle")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	if c.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, c.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		TagNumber      *string            `json:"tag_number"`
+		BreedID        *int               `json:"breed_id"`
+		Sex            *cattle.Sex        `json:"sex"`
+		AgeMonths      *int               `json:"age_months"`
+		WeightKg       *int               `json:"weight_kg"`
+		Vaccinations   *string            `json:"vaccinations"`
+		MedicalHistory *string            `json:"medical_history"`
+		IsPregnant     *bool              `json:"is_pregnant"`
+		IsCastrated    *bool              `json:"is_castrated"`
+		IsActive       *bool              `json:"is_active"`
+		Composition    cattle.Composition `json:"composition"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.BreedID == nil || input.Composition == nil, "breed_id", "must not be sent together with composition")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var changed changes
+	patchField(&changed, "tag_number", &c.TagNumber, input.TagNumber)
+	patchField(&changed, "sex", &c.Sex, input.Sex)
+	patchField(&changed, "age_months", &c.AgeMonths, input.AgeMonths)
+	patchField(&changed, "weight_kg", &c.WeightKg, input.WeightKg)
+	patchField(&changed, "vaccinations", &c.Vaccinations, input.Vaccinations)
+	patchField(&changed, "medical_history", &c.MedicalHistory, input.MedicalHistory)
+	patchNullable(&changed, "is_pregnant", &c.IsPregnant, input.IsPregnant)
+	patchNullable(&changed, "is_castrated", &c.IsCastrated, input.IsCastrated)
+	patchNullable(&changed, "is_active", &c.IsActive, input.IsActive)
+
+	composition := input.Composition
+	if input.BreedID != nil {
+		composition = cattle.Purebred(*input.BreedID)
+	}
+	if composition != nil && !composition.Equal(c.Composition) {
+		c.Composition = composition
+		changed = append(changed, "composition")
+		if primary := composition.PrimaryBreed(); primary != c.BreedID {
+			c.BreedID = primary
+			changed = append(changed, "breed_id")
+		}
+	}
+
+	if cattle.ValidateCattle(v, c); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		err = app.models.Cattle.Update(r.Context(), c)
+		if err != nil {
+			switch {
+			case isDuplicate(err, "tag_number"):
+				v.AddError("tag_number", "an animal with this tag number already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrForeignKeyViolation):
+				v.AddError("composition", "must only name existing breeds")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrInvalidComposition):
+				v.AddError("composition", "percentages must add up to 100")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.writePatched(w, r, "cattle", c, c.Version, changed)
+}
+
+// deleteCattleHandler soft deletes an animal. The owner can delete their animals, and holders of
+// write:cattle can delete anyone's; the animal's media and listing entries are kept until it is purged.
+func (app *application) deleteCattleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	animal, err := app.models.Cattle.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.authorizeEdit(w, r, int64(animal.OwnerID), "write:cattle") {
+		return
+	}
+
+	err = app.models.Cattle.SoftDelete(r.Context(), animal.ID, app.contextGetUser(r).ID)
+	app.writeDeletion(w, r, err, "animal successfully deleted")
+}
+
+// restoreCattleHandler restores a soft deleted animal for the same users who may delete it.
+func (app *application) restoreCattleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	animal, err := app.models.Cattle.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.authorizeEdit(w, r, int64(animal.OwnerID), "write:cattle") {
+		return
+	}
+
+	err = app.models.Cattle.Restore(r.Context(), animal.ID)
+	app.writeDeletion(w, r, err, "animal successfully restored")
+}
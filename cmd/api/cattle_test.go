@@ -0,0 +1,95 @@
+// File: cmd/api/cattle_test.go
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestPatchCattleComposition(t *testing.T) {
+	app := newTestApplication(t)
+	f := testdb.NewFactory(t, app.models)
+
+	owner := f.User()
+	brahman, nelore := f.Breed(), f.Breed()
+	c := f.Cattle(func(c *cattle.Cattle) {
+		c.OwnerID = int(owner.ID)
+		c.Composition = cattle.Composition{{BreedID: brahman.ID, Percentage: 62.5}, {BreedID: nelore.ID, Percentage: 37.5}}
+	})
+	read, err := app.models.Cattle.GetByID(t.Context(), c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := login(t, app, owner)
+	path := fmt.Sprintf("/v1/cattle/%d", c.ID)
+
+	type response struct {
+		Cattle  cattle.Cattle `json:"cattle"`
+		Changed []string      `json:"changed"`
+	}
+	tests := []struct {
+		name        string
+		body        map[string]any
+		wantChanged []string
+	}{
+		{
+			// The composition as GET returns it, names and all, is not a change.
+			name:        "as read",
+			body:        map[string]any{"composition": read.Composition},
+			wantChanged: []string{},
+		},
+		{
+			name: "reordered without names",
+			body: map[string]any{"composition": []map[string]any{
+				{"breed_id": nelore.ID, "percentage": 37.5},
+				{"breed_id": brahman.ID, "percentage": 62.5},
+			}},
+			wantChanged: []string{},
+		},
+		{
+			name: "new shares",
+			body: map[string]any{"composition": []map[string]any{
+				{"breed_id": brahman.ID, "percentage": 25},
+				{"breed_id": nelore.ID, "percentage": 75},
+			}},
+			wantChanged: []string{"composition", "breed_id"},
+		},
+		{
+			name:        "purebred",
+			body:        map[string]any{"breed_id": nelore.ID},
+			wantChanged: []string{"composition"},
+		},
+		{
+			name:        "same purebred",
+			body:        map[string]any{"breed_id": nelore.ID},
+			wantChanged: []string{},
+		},
+	}
+	for _, tt := range tests {
+		before, err := app.models.Cattle.GetByID(t.Context(), c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res response
+		if code := send(t, app, http.MethodPatch, path, token, tt.body, &res); code != http.StatusOK {
+			t.Fatalf("%s: PATCH status = %d, want 200", tt.name, code)
+		}
+		if !slices.Equal(res.Changed, tt.wantChanged) {
+			t.Errorf("%s: changed = %v, want %v", tt.name, res.Changed, tt.wantChanged)
+		}
+
+		after, err := app.models.Cattle.GetByID(t.Context(), c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bumped := after.Version != before.Version; bumped != (len(tt.wantChanged) > 0) {
+			t.Errorf("%s: version %d -> %d, want it bumped only by a change", tt.name, before.Version, after.Version)
+		}
+	}
+}
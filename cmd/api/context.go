@@ -0,0 +1,36 @@
+// File: cmd/api/context.go
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+)
+
+// contextKey is a private type for request context keys.
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+// contextSetUser returns a copy of the request with the user stored in its context. The user also
+// becomes the actor that the request's writes are recorded against in the audit trail.
+func (app *application) contextSetUser(r *http.Request, user *users.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+
+	actor := audit.ActorFrom(ctx)
+	actor.UserID = user.ID
+	ctx = audit.WithActor(ctx, actor)
+
+	return r.WithContext(ctx)
+}
+
+// contextGetUser retrieves the user stored by the authenticate middleware.
+func (app *application) contextGetUser(r *http.Request) *users.User {
+	user, ok := r.Context().Value(userContextKey).(*users.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
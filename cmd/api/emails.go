@@ -0,0 +1,151 @@
+// File: cmd/api/emails.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+/****************************************************************************************
+ *										Handlers										*
+ ***************************************************************************************/
+
+// deliverEmail runs a deliver_email job, sending an email from the outbox and recording the
+// attempt on it. Emails already sent, or purged since, are skipped, so that a job run twice after
+// a lost lease does not send its email twice.
+func (app *application) deliverEmail(ctx context.Context, d outbox.Delivery) error {
+	email, err := app.models.Emails.GetByID(ctx, d.EmailID)
+	if err != nil {
+		if errors.Is(err, internalErrors.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if email.Status == outbox.StatusSent {
+		return nil
+	}
+
+	var templateData map[string]any
+	if err := json.Unmarshal(email.Data, &templateData); err != nil {
+		return err
+	}
+	sendErr := app.mailer.Send(email.Recipient, email.Template, templateData)
+
+	// The attempt is recorded even when shutting down, as the email may already be on its way
+	ctx = context.WithoutCancel(ctx)
+	if sendErr != nil {
+		return errors.Join(sendErr, app.models.Emails.MarkFailed(ctx, email.ID, sendErr.Error()))
+	}
+	return app.models.Emails.MarkSent(ctx, email.ID, time.Now())
+}
+
+/****************************************************************************************
+ *										Endpoints										*
+ ***************************************************************************************/
+
+// listEmailsHandler lists the outbox, newest first, optionally only the emails of one status
+// ("status=failed") or to one recipient ("recipient=ana@example.com").
+func (app *application) listEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filter := app.readEmailFilter(r.URL.Query(), v)
+	fields := app.readFields(r.URL.Query(), outbox.Email{}, v)
+	if outbox.ValidateEmailFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	emails, metadata, err := app.models.Emails.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"emails": fields.project(emails), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showEmailHandler shows an email, including its delivery attempts and the error of the last one
+// that failed.
+func (app *application) showEmailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	email, err := app.models.Emails.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"email": email}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resendEmailHandler queues an email to be delivered again, e.g. one that failed for good or that
+// the recipient never received. Emails whose delivery job is still pending or running, retries
+// included, cannot be resent.
+func (app *application) resendEmailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Emails.Resend(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, internalErrors.ErrEmailQueued):
+			app.emailQueuedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	email, err := app.models.Emails.GetByID(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"email": email}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readEmailFilter reads the outbox filters from the query string.
+func (app *application) readEmailFilter(qs url.Values, v *validator.Validator) *outbox.EmailFilter {
+	var filter outbox.EmailFilter
+	filter.Status = app.readString(qs, "status", "")
+	filter.Recipient = app.readString(qs, "recipient", "")
+
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", "-created_at")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "created_at", "updated_at", "-id", "-created_at", "-updated_at"}
+	return &filter
+}
@@ -0,0 +1,125 @@
+// File: cmd/api/errors.go
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+)
+
+// logError logs an error along with the request method, URI and ID.
+func (app *application) logError(r *http.Request, err error) {
+	requestID := audit.ActorFrom(r.Context()).RequestID
+	app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestID)
+}
+
+// errorResponse sends a JSON-formatted error message with the given status code.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
+	env := envelope{"error": message}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// serverErrorResponse logs the error and sends a 500 Internal Server Error response.
+func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, err)
+
+	message := "the server encountered a problem and could not process your request"
+	app.errorResponse(w, r, http.StatusInternalServerError, message)
+}
+
+// notFoundResponse sends a 404 Not Found response.
+func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested resource could not be found"
+	app.errorResponse(w, r, http.StatusNotFound, message)
+}
+
+// methodNotAllowedResponse sends a 405 Method Not Allowed response.
+func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
+}
+
+// badRequestResponse sends a 400 Bad Request response with the error message.
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+}
+
+// failedValidationResponse sends a 422 Unprocessable Entity response with the validation errors.
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
+// editConflictResponse sends a 409 Conflict response.
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// notDeletedResponse sends a 409 Conflict response when restoring a record that is not deleted.
+func (app *application) notDeletedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record is not deleted, so there is nothing to restore"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// notDeadResponse sends a 409 Conflict response when retrying a job that is not dead.
+func (app *application) notDeadResponse(w http.ResponseWriter, r *http.Request) {
+	message := "only dead jobs can be retried"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// emailQueuedResponse sends a 409 Conflict response when resending an email whose delivery job is
+// still pending or running.
+func (app *application) emailQueuedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the email is already queued to be sent"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// alreadySoldResponse sends a 409 Conflict response when marking a listing sold a second time, or
+// renewing a sold listing.
+func (app *application) alreadySoldResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the listing has already been sold"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// preconditionFailedResponse sends a 412 Precondition Failed response when If-Match names a stale version.
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record has been changed since you fetched it, please fetch it again and retry"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+// requestEntityTooLargeResponse sends a 413 Request Entity Too Large response.
+func (app *application) requestEntityTooLargeResponse(w http.ResponseWriter, r *http.Request, message string) {
+	app.errorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
+// invalidAuthenticationTokenResponse sends a 401 Unauthorized response for a bad token.
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// authenticationRequiredResponse sends a 401 Unauthorized response for anonymous users.
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// inactiveAccountResponse sends a 403 Forbidden response for users that are not activated.
+func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account must be activated to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// notPermittedResponse sends a 403 Forbidden response.
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
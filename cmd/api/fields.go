@@ -0,0 +1,124 @@
+// File: cmd/api/fields.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// fieldset lists the JSON fields a client asked for with "fields=id,title", in the order they
+// appear in the full record. A nil fieldset keeps every field.
+type fieldset []string
+
+// projection is a list of records cut down to a fieldset.
+type projection struct {
+	fields  fieldset
+	records any
+}
+
+// readFields reads the "fields" parameter from the query string, checking each name against the
+// JSON fields of record, a value of the listed type.
+func (app *application) readFields(qs url.Values, record any, v *validator.Validator) fieldset {
+	s := qs.Get("fields")
+	if s == "" {
+		return nil
+	}
+
+	var requested []string
+	for _, name := range strings.Split(s, ",") {
+		requested = append(requested, strings.TrimSpace(name))
+	}
+
+	known := jsonFields(reflect.TypeOf(record))
+	for _, name := range requested {
+		v.Check(slices.Contains(known, name), "fields", "must only name fields of the record")
+	}
+
+	fields := fieldset{}
+	for _, name := range known {
+		if slices.Contains(requested, name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// jsonFields returns the names a struct type encodes its fields under, including those of
+// embedded structs, in the order encoding/json writes them.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFields(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// project returns records, a slice, with only the fields in the set when the set is not nil.
+func (fs fieldset) project(records any) any {
+	if fs == nil {
+		return records
+	}
+	return projection{fields: fs, records: records}
+}
+
+// MarshalJSON encodes each record as usual and keeps only the fields of the set. Fields the record
+// omits, such as empty omitempty fields, stay omitted.
+func (p projection) MarshalJSON() ([]byte, error) {
+	js, err := json.Marshal(p.records)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(js, &rows); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		first := true
+		for _, name := range p.fields {
+			value, ok := row[name]
+			if !ok {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+
+			key, _ := json.Marshal(name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+// File: cmd/api/fields_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// record exercises every way a struct field can be named in JSON.
+type record struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Untagged string
+	Secret   string `json:"-"`
+	hidden   string
+	database.Deletion
+	Position locations.Coordinates `json:"position"`
+}
+
+func TestReadFields(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		fields string
+		want   fieldset
+		valid  bool
+	}{
+		{"", nil, true},
+		{"id", fieldset{"id"}, true},
+		{"position, name,id", fieldset{"id", "name", "position"}, true},
+		{"id,id", fieldset{"id"}, true},
+		{"Untagged,deleted_at", fieldset{"Untagged", "deleted_at"}, true},
+		{"colour", fieldset{}, false},
+		{"id,,name", fieldset{"id", "name"}, false},
+		{"Secret", fieldset{}, false},
+		{"hidden", fieldset{}, false},
+		{"ID", fieldset{}, false},
+		{"Deletion", fieldset{}, false},
+		{"position.latitude", fieldset{}, false},
+		{"latitude", fieldset{}, false},
+	}
+	for _, tt := range tests {
+		v := validator.New()
+		got := app.readFields(url.Values{"fields": {tt.fields}}, record{}, v)
+		if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+			t.Errorf("readFields(%q) = %#v, want %#v", tt.fields, got, tt.want)
+		}
+		if v.Valid() != tt.valid {
+			t.Errorf("readFields(%q) errors = %v, want valid %v", tt.fields, v.Errors, tt.valid)
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	deletedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []record{
+		{ID: 1, Name: "Daisy", Position: locations.Coordinates{Latitude: 17.25, Longitude: -88.75}},
+		{ID: 2, Deletion: database.Deletion{DeletedAt: &deletedAt}},
+	}
+
+	tests := []struct {
+		fields fieldset
+		want   string
+	}{
+		{fieldset{"id", "name"}, `[{"id":1,"name":"Daisy"},{"id":2}]`},
+		{fieldset{"id", "deleted_at"}, `[{"id":1},{"id":2,"deleted_at":"2026-01-02T03:04:05Z"}]`},
+		{fieldset{"position"}, `[{"position":{"latitude":17.25,"longitude":-88.75}},{"position":{"latitude":0,"longitude":0}}]`},
+		{fieldset{}, `[{},{}]`},
+	}
+	for _, tt := range tests {
+		js, err := json.Marshal(tt.fields.project(records))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(js) != tt.want {
+			t.Errorf("project(%v) = %s, want %s", tt.fields, js, tt.want)
+		}
+	}
+
+	var none fieldset
+	if got := none.project(records); !slices.Equal(got.([]record), records) {
+		t.Errorf("project without fields = %v, want the records as they are", got)
+	}
+	if js, err := json.Marshal(fieldset{"id"}.project([]record{})); err != nil || string(js) != "[]" {
+		t.Errorf("project of no records = %s, %v, want []", js, err)
+	}
+}
+
+func TestListingFields(t *testing.T) {
+	app := newTestApplication(t)
+
+	v := validator.New()
+	fields := app.readFields(url.Values{"fields": {"title,coordinates,deleted_at,distance_km,id"}}, listings.Listing{}, v)
+	if !v.Valid() {
+		t.Fatalf("readFields errors = %v", v.Errors)
+	}
+	if want := (fieldset{"id", "title", "coordinates", "deleted_at", "distance_km"}); !slices.Equal(fields, want) {
+		t.Errorf("listing fields = %v, want %v in record order", fields, want)
+	}
+
+	for _, name := range []string{"reminded_at", "RemindedAt", "coordinates.latitude"} {
+		v := validator.New()
+		app.readFields(url.Values{"fields": {name}}, listings.Listing{}, v)
+		if v.Valid() {
+			t.Errorf("readFields(%q) of a listing succeeded, want it rejected", name)
+		}
+	}
+}
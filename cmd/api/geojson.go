@@ -0,0 +1,102 @@
+// File: cmd/api/geojson.go
+package main
+
+import (
+	"net/http"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// The GeoJSON endpoints accept the same query parameters as their JSON counterparts but default
+// to larger pages and only return rows that can be drawn on a map.
+const geoJSONPageSize = 100
+
+// areasGeoJSONHandler serves areas as a FeatureCollection of points.
+func (app *application) areasGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := app.readAreaFilter(qs, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", geoJSONPageSize, v)
+	filter.HasCoordinates = true
+
+	if filters.ValidateFilters(v, filter.Default); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	areas, metadata, err := app.models.Areas.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeGeoJSON(w, http.StatusOK, areas.FeatureCollection(metadata))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// regionsGeoJSONHandler serves regions as a FeatureCollection of boundary polygons.
+func (app *application) regionsGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	var filter locations.RegionFilter
+	filter.Name = app.readString(qs, "name", "")
+	filter.Code = app.readString(qs, "code", "")
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", geoJSONPageSize, v)
+	filter.Default.Sort = app.readString(qs, "sort", "name")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "name", "code", "-id", "-name", "-code"}
+	filter.WithBoundary = true
+
+	if filters.ValidateFilters(v, filter.Default); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	regions, metadata, err := app.models.Regions.GetAll(r.Context(), &filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeGeoJSON(w, http.StatusOK, regions.FeatureCollection(metadata))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listingsGeoJSONHandler serves active listings as a FeatureCollection of points.
+func (app *application) listingsGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := app.readListingFilter(qs, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", geoJSONPageSize, v)
+	filter.HasCoordinates = true
+
+	active := true
+	filter.IsActive = &active
+
+	if listings.ValidateListingFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	list, metadata, err := app.models.Listings.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeGeoJSON(w, http.StatusOK, list.FeatureCollection(metadata))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
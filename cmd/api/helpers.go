@@ -0,0 +1,403 @@
+// File: cmd/api/helpers.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// envelope wraps JSON responses in a top-level key.
+type envelope map[string]any
+
+// readIDParam reads the "id" URL parameter from the request context.
+func (app *application) readIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid id parameter")
+	}
+	return id, nil
+}
+
+// writeJSON encodes data as JSON and writes it with the given status and headers.
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+	return nil
+}
+
+// writeGeoJSON encodes a GeoJSON object without an envelope, as map libraries expect it at the top level.
+func (app *application) writeGeoJSON(w http.ResponseWriter, status int, data any) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(status)
+	w.Write(js)
+	return nil
+}
+
+// readJSON decodes a single JSON value from the request body into dst, translating decoder errors into client friendly messages.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	maxBytes := 1_048_576
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+			}
+			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("body contains unknown key %s", fieldName)
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		case errors.As(err, &invalidUnmarshalError):
+			panic(err)
+		default:
+			return err
+		}
+	}
+
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single JSON value")
+	}
+	return nil
+}
+
+// readString returns a string value from the query string, or the default value if none is provided.
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+// readInt returns an int value from the query string, or the default value if none is provided.
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+	return i
+}
+
+// readOptionalInt64 returns a pointer to an int64 from the query string, or nil if none is provided.
+func (app *application) readOptionalInt64(qs url.Values, key string, v *validator.Validator) *int64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return nil
+	}
+	return &i
+}
+
+// readOptionalBool returns a pointer to a bool from the query string, or nil if none is provided.
+func (app *application) readOptionalBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+	return &b
+}
+
+// readOptionalTime returns a pointer to an RFC 3339 timestamp from the query string, or nil if none is provided.
+func (app *application) readOptionalTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp")
+		return nil
+	}
+	return &t
+}
+
+// readFloats parses a comma-separated list of exactly n numbers from the query string, or returns nil if none is provided.
+func (app *application) readFloats(qs url.Values, key string, n int, v *validator.Validator) []float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		v.AddError(key, fmt.Sprintf("must contain %d comma-separated numbers", n))
+		return nil
+	}
+
+	values := make([]float64, n)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			v.AddError(key, fmt.Sprintf("must contain %d comma-separated numbers", n))
+			return nil
+		}
+		values[i] = f
+	}
+	return values
+}
+
+// readList returns the comma-separated values of a query string parameter, or nil if none is provided.
+func (app *application) readList(qs url.Values, key string) []string {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	values := strings.Split(s, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// readInt64List returns a comma-separated list of integers from the query string, or nil if none is provided.
+func (app *application) readInt64List(qs url.Values, key string, v *validator.Validator) []int64 {
+	var values []int64
+	for _, s := range app.readList(qs, key) {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			v.AddError(key, "must be a comma-separated list of integers")
+			return nil
+		}
+		values = append(values, i)
+	}
+	return values
+}
+
+// readProximity reads a "near=lat,lng" search and its "radius_km" from the query string, or returns nil if none is provided.
+func (app *application) readProximity(qs url.Values, v *validator.Validator) *locations.Proximity {
+	center := app.readFloats(qs, "near", 2, v)
+	if center == nil {
+		return nil
+	}
+
+	radius, err := strconv.ParseFloat(app.readString(qs, "radius_km", "25"), 64)
+	if err != nil {
+		v.AddError("radius_km", "must be a number")
+		return nil
+	}
+
+	p := &locations.Proximity{
+		Center:   locations.Coordinates{Latitude: center[0], Longitude: center[1]},
+		RadiusKm: radius,
+	}
+	locations.ValidateProximity(v, p)
+	return p
+}
+
+// readBoundingBox reads a "bbox=min_lng,min_lat,max_lng,max_lat" box (the order used by GeoJSON and Leaflet)
+// from the query string, or returns nil if none is provided.
+func (app *application) readBoundingBox(qs url.Values, v *validator.Validator) *locations.BoundingBox {
+	corners := app.readFloats(qs, "bbox", 4, v)
+	if corners == nil {
+		return nil
+	}
+
+	b := &locations.BoundingBox{
+		MinLongitude: corners[0],
+		MinLatitude:  corners[1],
+		MaxLongitude: corners[2],
+		MaxLatitude:  corners[3],
+	}
+	locations.ValidateBoundingBox(v, b)
+	return b
+}
+
+// versionETag formats a record version as a strong entity tag.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag sets the ETag response header to the record version, so clients can send it back in If-Match.
+func (app *application) setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", versionETag(version))
+}
+
+// ifMatch reports whether the request's If-Match header allows changing a record at version. A missing
+// header or "*" allows any version; otherwise one of the listed tags must match strongly, so weak tags never do.
+func (app *application) ifMatch(r *http.Request, version int) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	want := versionETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// background runs fn in a goroutine tracked by the application wait group, recovering any panic.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// changes lists the JSON names of the fields a partial update altered, in the order they were applied.
+type changes []string
+
+// patchField copies src over dst when src was sent and differs, recording the field as changed.
+func patchField[T comparable](c *changes, name string, dst *T, src *T) {
+	if src == nil || *src == *dst {
+		return
+	}
+	*dst = *src
+	*c = append(*c, name)
+}
+
+// patchNullable is patchField for a nullable column, where a nil dst is NULL.
+func patchNullable[T comparable](c *changes, name string, dst **T, src *T) {
+	if src == nil || (*dst != nil && **dst == *src) {
+		return
+	}
+	v := *src
+	*dst = &v
+	*c = append(*c, name)
+}
+
+// writePatched writes a record after a partial update, with its ETag and the fields that changed.
+func (app *application) writePatched(w http.ResponseWriter, r *http.Request, key string, record any, version int, changed changes) {
+	if changed == nil {
+		changed = changes{}
+	}
+
+	app.setETag(w, version)
+	err := app.writeJSON(w, http.StatusOK, envelope{key: record, "changed": changed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// canEdit reports whether the current user owns a record or holds the permission to edit anyone's.
+func (app *application) canEdit(r *http.Request, ownerID int64, code string) (bool, error) {
+	user := app.contextGetUser(r)
+	if user.ID == ownerID {
+		return true, nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		return false, err
+	}
+	return permissions.Includes(code), nil
+}
+
+// authorizeEdit is canEdit for handlers that only need a yes or no: it writes the error response and
+// returns false when the current user may not change a record owned by ownerID.
+func (app *application) authorizeEdit(w http.ResponseWriter, r *http.Request, ownerID int64, code string) bool {
+	allowed, err := app.canEdit(r, ownerID, code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// isDuplicate reports whether err is the models' duplicate value error for column.
+func isDuplicate(err error, column string) bool {
+	return err != nil && err.Error() == internalErrors.ErrDuplicateValue(column).Error()
+}
+
+// writeDeletion responds to a soft delete or restore with message, or maps err: a missing or already
+// deleted record is a 404 and restoring a record that is not deleted is a 409.
+func (app *application) writeDeletion(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case err == nil:
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": message}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	case errors.Is(err, internalErrors.ErrRecordNotFound), errors.Is(err, internalErrors.ErrAlreadyDeleted):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, internalErrors.ErrNotDeleted):
+		app.notDeletedResponse(w, r)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
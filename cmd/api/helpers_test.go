@@ -0,0 +1,65 @@
+// File: cmd/api/helpers_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/mailer"
+)
+
+// newTestApplication returns an application on a fresh in-memory store that sends emails nowhere.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+	return &application{
+		config: config{env: "testing", webURL: "http://web"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.NewMemoryModels(),
+		mailer: mailer.New(mailer.NewMemoryTransport(), "no-reply@cashcow.bz"),
+	}
+}
+
+// login returns an authentication token for the user.
+func login(t *testing.T, app *application, user *users.User) string {
+	t.Helper()
+	token, err := app.models.Tokens.New(t.Context(), user.ID, time.Hour, users.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.Plaintext
+}
+
+// send sends a request with body encoded as JSON through the application's routes, authenticated
+// by token unless it is empty, and decodes the response into dst unless it is nil.
+func send(t *testing.T, app *application, method, path, token string, body, dst any) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(js)
+	}
+	r := httptest.NewRequestWithContext(t.Context(), method, path, reader)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, r)
+	if dst != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), dst); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, path, w.Body, err)
+		}
+	}
+	return w.Code
+}
@@ -0,0 +1,217 @@
+// File: cmd/api/jobs.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// jobTimeout bounds a single run of a job, well within jobs.Lease.
+const jobTimeout = 5 * time.Minute
+
+// jobHandlers maps every kind of job to its handler. Workers only claim the kinds listed here.
+func (app *application) jobHandlers() map[string]jobs.Handler {
+	return map[string]jobs.Handler{
+		outbox.JobKind: jobs.Handle(app.deliverEmail),
+	}
+}
+
+/****************************************************************************************
+ *										Workers											*
+ ***************************************************************************************/
+
+// work claims the job that has been due longest and runs it, and reports whether there was one.
+// A job that fails is put back to be retried after a backoff, or dead lettered once it is out of
+// attempts.
+func (app *application) work(ctx context.Context, handlers map[string]jobs.Handler) (bool, error) {
+	job, err := app.models.Jobs.Claim(ctx, slices.Collect(maps.Keys(handlers)), time.Now())
+	if err != nil {
+		if errors.Is(err, internalErrors.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	err = runJob(runCtx, handlers[job.Kind], job)
+	cancel()
+
+	// The outcome is recorded even when shutting down, so that the job is not run again needlessly
+	ctx = context.WithoutCancel(ctx)
+	if err == nil {
+		err = app.models.Jobs.Complete(ctx, job)
+	} else {
+		app.logger.Error(err.Error(), "job_id", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+		err = app.models.Jobs.Fail(ctx, job, err.Error(), time.Now().Add(jobs.Backoff(job.Attempts)))
+		if err == nil && job.Status == jobs.StatusDead {
+			app.logger.Error("dead lettered job", "job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts)
+		}
+	}
+	if errors.Is(err, internalErrors.ErrEditConflict) {
+		app.logger.Warn("lost job to another worker after its lease ran out", "job_id", job.ID, "kind", job.Kind)
+		err = nil
+	}
+	return true, err
+}
+
+// runJob runs a job with its handler, turning a panic into an error so that it counts as a
+// failed attempt.
+func runJob(ctx context.Context, handler jobs.Handler, job *jobs.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// runDueJobs runs every job that is due, one after another, until none is left.
+func (app *application) runDueJobs(ctx context.Context) error {
+	handlers := app.jobHandlers()
+	ran := 0
+	for {
+		worked, err := app.work(ctx, handlers)
+		if err != nil {
+			return err
+		}
+		if !worked {
+			break
+		}
+		ran++
+	}
+	app.logger.Info("ran due jobs", "jobs", ran)
+	return nil
+}
+
+// runWorker runs jobs as they fall due until ctx is cancelled, checking the queue every poll
+// interval while it is empty. A worker that fails to claim a job logs it and tries again later.
+func (app *application) runWorker(ctx context.Context) {
+	handlers := app.jobHandlers()
+	for {
+		worked, err := app.work(ctx, handlers)
+		if err != nil && ctx.Err() == nil {
+			app.logger.Error(err.Error())
+		}
+		if worked && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(app.config.jobs.pollInterval):
+		}
+	}
+}
+
+/****************************************************************************************
+ *										Endpoints										*
+ ***************************************************************************************/
+
+// listJobsHandler lists the job queue, newest first, optionally only the jobs of one status
+// ("status=dead") or kind ("kind=deliver_email").
+func (app *application) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filter := app.readJobFilter(r.URL.Query(), v)
+	fields := app.readFields(r.URL.Query(), jobs.Job{}, v)
+	if jobs.ValidateJobFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	queue, metadata, err := app.models.Jobs.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"jobs": fields.project(queue), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showJobHandler shows a job, including the error of its last failed attempt.
+func (app *application) showJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.Jobs.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"job": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// retryJobHandler puts a dead job back in the queue with a fresh set of attempts, to run straight
+// away. Only dead jobs can be retried.
+func (app *application) retryJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Jobs.Retry(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, internalErrors.ErrNotDead):
+			app.notDeadResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	job, err := app.models.Jobs.GetByID(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"job": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readJobFilter reads the job queue filters from the query string.
+func (app *application) readJobFilter(qs url.Values, v *validator.Validator) *jobs.JobFilter {
+	var filter jobs.JobFilter
+	filter.Status = app.readString(qs, "status", "")
+	filter.Kind = app.readString(qs, "kind", "")
+
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", "-created_at")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "run_at", "created_at", "updated_at", "-id", "-run_at", "-created_at", "-updated_at"}
+	return &filter
+}
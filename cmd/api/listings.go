@@ -0,0 +1,298 @@
+// File: cmd/api/listings.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// listListingsHandler lists listings, optionally within a radius of a point ("near=lat,lng&radius_km=50")
+// or inside a bounding box ("bbox=min_lng,min_lat,max_lng,max_lat"). Proximity results are sorted nearest first.
+// Buyers can narrow them by price and head count ("cattle_classes=steer,heifer&min_price_per_kg=3&min_total_head=10"),
+// and search them in English or Spanish ("q=novillos brahman"), best matches first with highlighted snippets.
+func (app *application) listListingsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filter := app.readListingFilter(r.URL.Query(), v)
+	fields := app.readFields(r.URL.Query(), listings.Listing{}, v)
+	if listings.ValidateListingFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	list, metadata, err := app.models.Listings.GetAll(r.Context(), filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"listings": fields.project(list), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// patchListingHandler applies a partial update to a listing, changing only the fields sent. Moving the
+// listing to another area also moves it to that area's region, and setting publish_at takes it off the
+// market until the scheduler publishes it then. The seller can edit their listings, and holders of
+// write:listings can edit anyone's.
+func (app *application) patchListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	l, err := app.models.Listings.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canEdit(r, l.UserID, "write:listings")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	if l.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, l.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Title       *string                `json:"title"`
+		Description *string                `json:"description"`
+		AreaID      *int64                 `json:"area_id"`
+		Coordinates *locations.Coordinates `json:"coordinates"`
+		IsActive    *bool                  `json:"is_active"`
+		PublishAt   *time.Time             `json:"publish_at"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var changed changes
+	patchField(&changed, "title", &l.Title, input.Title)
+	patchField(&changed, "description", &l.Description, input.Description)
+	patchField(&changed, "area_id", &l.AreaID, input.AreaID)
+	patchField(&changed, "coordinates", &l.Coordinates, input.Coordinates)
+	patchNullable(&changed, "is_active", &l.IsActive, input.IsActive)
+
+	v := validator.New()
+	now := time.Now()
+	switch {
+	case input.PublishAt != nil:
+		// A listing scheduled for later stays off the market until then, and gets its full lifetime
+		// once it is published
+		v.Check(input.PublishAt.After(now), "publish_at", "must be in the future")
+		v.Check(l.SoldAt == nil, "publish_at", "must not be set once the listing is sold")
+		v.Check(input.IsActive == nil || !*input.IsActive, "is_active", "must be false when publish_at is set")
+		inactive, until := false, input.PublishAt.Add(listings.Lifetime)
+		patchNullable(&changed, "publish_at", &l.PublishAt, input.PublishAt)
+		patchNullable(&changed, "is_active", &l.IsActive, &inactive)
+		if l.ExpiresAt.Before(until) {
+			patchField(&changed, "expires_at", &l.ExpiresAt, &until)
+		}
+	case input.IsActive != nil && *input.IsActive:
+		// Publishing by hand overrides the schedule, but an expired listing has to be renewed
+		v.Check(l.ExpiresAt.After(now), "is_active", "must be false once the listing has expired; renew it instead")
+		if l.PublishAt != nil {
+			l.PublishAt = nil
+			changed = append(changed, "publish_at")
+		}
+	}
+	v.Check(l.SoldAt == nil || l.IsActive == nil || !*l.IsActive, "is_active", "must be false once the listing is sold")
+	if listings.ValidateListing(v, l); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		regionID := l.RegionID
+		l.RegionID = 0 // taken from the area
+
+		err = app.models.Listings.Update(r.Context(), l)
+		if err != nil {
+			switch {
+			case errors.Is(err, internalErrors.ErrForeignKeyViolation):
+				v.AddError("area_id", "must refer to an existing area")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrRegionMismatch):
+				v.AddError("coordinates", "must be within the region of the area")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		if l.RegionID != regionID {
+			changed = append(changed, "region_id")
+		}
+	}
+
+	app.writePatched(w, r, "listing", l, l.Version, changed)
+}
+
+// deleteListingHandler soft deletes a listing. The seller can delete their listings, and holders of
+// write:listings can delete anyone's.
+func (app *application) deleteListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	l, err := app.models.Listings.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.authorizeEdit(w, r, l.UserID, "write:listings") {
+		return
+	}
+
+	err = app.models.Listings.SoftDelete(r.Context(), l.ID, app.contextGetUser(r).ID)
+	app.writeDeletion(w, r, err, "listing successfully deleted")
+}
+
+// restoreListingHandler restores a soft deleted listing for the same users who may delete it.
+func (app *application) restoreListingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	l, err := app.models.Listings.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.authorizeEdit(w, r, l.UserID, "write:listings") {
+		return
+	}
+
+	err = app.models.Listings.Restore(r.Context(), l.ID)
+	app.writeDeletion(w, r, err, "listing successfully restored")
+}
+
+// renewListingHandler puts a listing back on the market for another listings.Lifetime from now, or
+// until its current expiry if that is later, publishing it straight away if it was scheduled for
+// later. Sold listings cannot be renewed. The seller can renew their listings, and holders of
+// write:listings anyone's.
+func (app *application) renewListingHandler(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readListing(w, r)
+	if !ok || !app.authorizeEdit(w, r, l.UserID, "write:listings") {
+		return
+	}
+	if !app.ifMatch(r, l.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	until := time.Now().Add(listings.Lifetime)
+	if l.ExpiresAt.After(until) {
+		until = l.ExpiresAt
+	}
+
+	err := app.models.Listings.Renew(r.Context(), l.ID, until)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, internalErrors.ErrAlreadySold):
+			app.alreadySoldResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	renewed, err := app.models.Listings.GetByID(r.Context(), l.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.setETag(w, renewed.Version)
+	err = app.writeJSON(w, http.StatusOK, envelope{"listing": renewed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readListingFilter reads the listing search parameters shared by the JSON and GeoJSON endpoints.
+func (app *application) readListingFilter(qs url.Values, v *validator.Validator) *listings.ListingFilter {
+	var filter listings.ListingFilter
+	filter.UserID = app.readOptionalInt64(qs, "user_id", v)
+	filter.AreaID = app.readOptionalInt64(qs, "area_id", v)
+	filter.RegionID = app.readOptionalInt64(qs, "region_id", v)
+	filter.Title = app.readString(qs, "title", "")
+	filter.Search = app.readString(qs, "q", "")
+	filter.IsActive = app.readOptionalBool(qs, "is_active", v)
+	filter.Near = app.readProximity(qs, v)
+	filter.Box = app.readBoundingBox(qs, v)
+	filter.AreaIDs = app.readInt64List(qs, "area_ids", v)
+	filter.RegionIDs = app.readInt64List(qs, "region_ids", v)
+	for _, class := range app.readList(qs, "cattle_classes") {
+		filter.CattleClasses = append(filter.CattleClasses, listings.CattleClass(class))
+	}
+	filter.MinPricePerKg = app.readOptionalInt64(qs, "min_price_per_kg", v)
+	filter.MaxPricePerKg = app.readOptionalInt64(qs, "max_price_per_kg", v)
+	filter.MinTotalHead = app.readOptionalInt64(qs, "min_total_head", v)
+	filter.MaxTotalHead = app.readOptionalInt64(qs, "max_total_head", v)
+
+	defaultSort := "-created_at"
+	switch {
+	case filter.Search != "":
+		defaultSort = "-relevance"
+	case filter.Near != nil:
+		defaultSort = "distance_km"
+	}
+
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", defaultSort)
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "title", "created_at", "updated_at", "-id", "-title", "-created_at", "-updated_at"}
+	if filter.Near != nil {
+		filter.Default.SortSafelist = append(filter.Default.SortSafelist, "distance_km", "-distance_km")
+	}
+	if filter.Search != "" {
+		filter.Default.SortSafelist = append(filter.Default.SortSafelist, "relevance", "-relevance")
+	}
+	return &filter
+}
@@ -0,0 +1,299 @@
+// File: cmd/api/main.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/mailer"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/storage"
+	_ "github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// version is the application version reported by the server.
+const version = "1.0.0"
+
+// config holds all runtime configuration for the API server.
+type config struct {
+	port int
+	env  string
+	// cursorSecret signs pagination cursors. It is required outside development: a random secret
+	// would not survive a restart or work across instances.
+	cursorSecret string
+	// webURL is the address of the web client, which the links in emails point to.
+	webURL string
+	db     struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  time.Duration
+		queryTimeout time.Duration
+		// migrateOnStart applies pending migrations before the server starts listening.
+		migrateOnStart bool
+	}
+	mail    mailer.Config
+	storage storage.Config
+	purge   struct {
+		// retention is how long soft deleted records are kept before they are purged.
+		retention time.Duration
+		// interval is the time between purges while serving; zero disables them.
+		interval time.Duration
+	}
+	alerts struct {
+		// interval is the time between runs of the saved search and watchlist alerts while
+		// serving; zero disables them.
+		interval time.Duration
+	}
+	schedule struct {
+		// interval is the time between runs of the listing scheduler while serving; zero
+		// disables it.
+		interval time.Duration
+		// reminder is how long before a listing expires its seller is reminded.
+		reminder time.Duration
+	}
+	jobs struct {
+		// workers is how many jobs run at once while serving; zero leaves the queue to others.
+		workers int
+		// pollInterval is how often an idle worker checks the queue for due jobs.
+		pollInterval time.Duration
+	}
+}
+
+// application holds the dependencies shared by handlers, helpers and middleware.
+type application struct {
+	config  config
+	logger  *slog.Logger
+	models  data.Models
+	mailer  *mailer.Mailer
+	storage storage.BlobStore
+	wg      sync.WaitGroup
+}
+
+/****************************************************************************************
+ *										Main											*
+ ***************************************************************************************/
+
+func main() {
+	var cfg config
+
+	// Server settings
+	flag.IntVar(&cfg.port, "port", envInt("PORT", 8080), "API server port")
+	flag.StringVar(&cfg.env, "env", envString("ENV", "development"), "Environment (development|staging|production)")
+
+	// Database settings
+	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN")
+	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
+	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
+	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	flag.DurationVar(&cfg.db.queryTimeout, "db-query-timeout", envDuration("DB_QUERY_TIMEOUT", database.DefaultTimeout), "Maximum duration of a single data model call")
+	flag.BoolVar(&cfg.db.migrateOnStart, "migrate-on-start", envBool("MIGRATE_ON_START", false), "Apply pending migrations on startup")
+
+	// Mail settings
+	flag.StringVar(&cfg.mail.Transport, "mail-transport", envString("MAIL_TRANSPORT", mailer.TransportSMTP), "Mail transport (smtp|file)")
+	flag.StringVar(&cfg.mail.Dir, "mail-dir", envString("MAIL_DIR", "./mail"), "Directory the file mail transport writes .eml files to")
+	flag.StringVar(&cfg.mail.Host, "smtp-host", envString("SMTP_HOST", "localhost"), "SMTP host")
+	flag.IntVar(&cfg.mail.Port, "smtp-port", envInt("SMTP_PORT", 25), "SMTP port")
+	flag.StringVar(&cfg.mail.Username, "smtp-username", os.Getenv("SMTP_USERNAME"), "SMTP username")
+	flag.StringVar(&cfg.mail.Password, "smtp-password", os.Getenv("SMTP_PASSWORD"), "SMTP password")
+	flag.StringVar(&cfg.mail.Sender, "smtp-sender", envString("SMTP_SENDER", "Cash Cow <no-reply@cashcow.bz>"), "SMTP sender")
+
+	// Media storage settings
+	flag.StringVar(&cfg.storage.Driver, "storage-driver", envString("STORAGE_DRIVER", storage.DriverLocal), "Media storage driver (local|s3)")
+	flag.StringVar(&cfg.storage.LocalRoot, "storage-local-root", envString("STORAGE_LOCAL_ROOT", "./uploads"), "Directory for the local storage driver")
+	flag.StringVar(&cfg.storage.Endpoint, "storage-s3-endpoint", os.Getenv("STORAGE_S3_ENDPOINT"), "S3 compatible endpoint, e.g. http://localhost:9000")
+	flag.StringVar(&cfg.storage.Region, "storage-s3-region", envString("STORAGE_S3_REGION", "us-east-1"), "S3 region")
+	flag.StringVar(&cfg.storage.Bucket, "storage-s3-bucket", os.Getenv("STORAGE_S3_BUCKET"), "S3 bucket")
+	flag.StringVar(&cfg.storage.AccessKey, "storage-s3-access-key", os.Getenv("STORAGE_S3_ACCESS_KEY"), "S3 access key")
+	flag.StringVar(&cfg.storage.SecretKey, "storage-s3-secret-key", os.Getenv("STORAGE_S3_SECRET_KEY"), "S3 secret key")
+	flag.BoolVar(&cfg.storage.PathStyle, "storage-s3-path-style", envBool("STORAGE_S3_PATH_STYLE", true), "Use path-style bucket addressing (MinIO)")
+
+	// Purge settings
+	flag.DurationVar(&cfg.purge.retention, "purge-retention", envDuration("PURGE_RETENTION", 30*24*time.Hour), "How long soft deleted records are kept before they are purged")
+	flag.DurationVar(&cfg.purge.interval, "purge-interval", envDuration("PURGE_INTERVAL", 24*time.Hour), "Time between purges while serving (0 disables)")
+
+	// Saved search and watchlist alert settings
+	flag.DurationVar(&cfg.alerts.interval, "alert-interval", envDuration("ALERT_INTERVAL", time.Minute), "Time between runs of the saved search and watchlist alerts while serving (0 disables)")
+	flag.StringVar(&cfg.webURL, "web-url", envString("WEB_URL", "http://localhost:3000"), "Base URL of the web client that links in emails point to")
+
+	// Listing schedule settings
+	flag.DurationVar(&cfg.schedule.interval, "schedule-interval", envDuration("SCHEDULE_INTERVAL", time.Minute), "Time between runs of the listing publishing and expiry scheduler while serving (0 disables)")
+	flag.DurationVar(&cfg.schedule.reminder, "expiry-reminder", envDuration("EXPIRY_REMINDER", 72*time.Hour), "How long before a listing expires its seller is reminded")
+
+	// Job queue settings
+	flag.IntVar(&cfg.jobs.workers, "job-workers", envInt("JOB_WORKERS", 2), "Number of background job workers while serving (0 disables)")
+	flag.DurationVar(&cfg.jobs.pollInterval, "job-poll-interval", envDuration("JOB_POLL_INTERVAL", time.Second), "How often an idle job worker checks the queue")
+
+	// Pagination settings
+	flag.StringVar(&cfg.cursorSecret, "cursor-secret", os.Getenv("CURSOR_SECRET"), "Secret that pagination cursors are signed with")
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	switch {
+	case cfg.cursorSecret != "":
+		filters.SetCursorKey([]byte(cfg.cursorSecret))
+	case cfg.env == "development":
+		logger.Warn("CURSOR_SECRET is not set; page cursors are signed with a random key and will not survive a restart")
+	default:
+		logger.Error("CURSOR_SECRET must be set outside development", "env", cfg.env)
+		os.Exit(1)
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+	logger.Info("database connection pool established")
+
+	// "api migrate ..." runs a migration command and exits instead of serving.
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrateCommand(db, logger, args[1:]); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.db.migrateOnStart {
+		if err := runMigrateCommand(db, logger, []string{"up"}); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	store, err := storage.New(cfg.storage)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	transport, err := mailer.NewTransport(cfg.mail)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	app := &application{
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModels(db, cfg.db.queryTimeout),
+		mailer:  mailer.New(transport, cfg.mail.Sender),
+		storage: store,
+	}
+
+	// "api purge" purges soft deleted records past the retention period once and exits.
+	if args := flag.Args(); len(args) > 0 && args[0] == "purge" {
+		if err := app.purge(context.Background()); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "api alerts" matches saved searches, sends the digests that are due and the notifications
+	// queued for watchers once and exits.
+	if args := flag.Args(); len(args) > 0 && args[0] == "alerts" {
+		if err := app.alert(context.Background()); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "api schedule" publishes and expires the listings that are due and sends expiry reminders
+	// once and exits.
+	if args := flag.Args(); len(args) > 0 && args[0] == "schedule" {
+		if err := app.schedule(context.Background()); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "api jobs" runs every background job that is due, such as queued emails, and exits.
+	if args := flag.Args(); len(args) > 0 && args[0] == "jobs" {
+		if err := app.runDueJobs(context.Background()); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := app.serve(); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// openDB opens a connection pool to PostgreSQL and verifies it with a ping.
+func openDB(cfg config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.db.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.db.maxOpenConns)
+	db.SetMaxIdleConns(cfg.db.maxIdleConns)
+	db.SetConnMaxIdleTime(cfg.db.maxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// envString returns the environment variable key or fallback when it is unset.
+func envString(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// envInt returns the environment variable key as an int or fallback when it is unset or invalid.
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// envBool returns the environment variable key as a bool or fallback when it is unset or invalid.
+func envBool(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// envDuration returns the environment variable key as a duration or fallback when it is unset or invalid.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
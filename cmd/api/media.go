@@ -0,0 +1,487 @@
+// File: cmd/api/media.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/media"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/storage"
+)
+
+// streamTimeout is how long a stored file may take to download. It replaces the server's write
+// timeout, which is sized for JSON responses, on the routes that stream files.
+const streamTimeout = 10 * time.Minute
+
+// errNotOwner is returned when a user tries to change media on an animal or listing they do not own.
+var errNotOwner = errors.New("not the owner")
+
+// mediaOwner identifies the animal or listing a set of media belongs to.
+type mediaOwner struct {
+	cattleID  *int64
+	listingID *int64
+}
+
+// prefix returns the storage key prefix for the owner's files.
+func (o mediaOwner) prefix() string {
+	if o.cattleID != nil {
+		return fmt.Sprintf("cattle/%d", *o.cattleID)
+	}
+	return fmt.Sprintf("listings/%d", *o.listingID)
+}
+
+/****************************************************************************************
+ *										Handlers										*
+ ***************************************************************************************/
+
+// uploadCattleMediaHandler attaches a photo or document to an animal.
+func (app *application) uploadCattleMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.uploadMedia(w, r, mediaOwner{cattleID: &id})
+}
+
+// uploadListingMediaHandler attaches a photo or document to a listing.
+func (app *application) uploadListingMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.uploadMedia(w, r, mediaOwner{listingID: &id})
+}
+
+// listCattleMediaHandler lists the media attached to an animal.
+func (app *application) listCattleMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.listMedia(w, r, mediaOwner{cattleID: &id})
+}
+
+// listListingMediaHandler lists the media attached to a listing.
+func (app *application) listListingMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.listMedia(w, r, mediaOwner{listingID: &id})
+}
+
+// reorderCattleMediaHandler sets the display order of an animal's media.
+func (app *application) reorderCattleMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.reorderMedia(w, r, mediaOwner{cattleID: &id})
+}
+
+// reorderListingMediaHandler sets the display order of a listing's media.
+func (app *application) reorderListingMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.reorderMedia(w, r, mediaOwner{listingID: &id})
+}
+
+// showMediaContentHandler streams the original file.
+func (app *application) showMediaContentHandler(w http.ResponseWriter, r *http.Request) {
+	md, ok := app.loadMedia(w, r)
+	if !ok {
+		return
+	}
+	app.streamObject(w, r, md.StorageKey, md.ContentType, md.FileName)
+}
+
+// showMediaThumbnailHandler streams the JPEG thumbnail of an image.
+func (app *application) showMediaThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	md, ok := app.loadMedia(w, r)
+	if !ok {
+		return
+	}
+	if md.ThumbnailKey == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.streamObject(w, r, md.ThumbnailKey, "image/jpeg", "")
+}
+
+// setMediaCoverHandler makes an image the cover photo of its animal or listing.
+func (app *application) setMediaCoverHandler(w http.ResponseWriter, r *http.Request) {
+	md, ok := app.loadMedia(w, r)
+	if !ok {
+		return
+	}
+	if !app.authorizeMediaOwner(w, r, mediaOwner{cattleID: md.CattleID, listingID: md.ListingID}) {
+		return
+	}
+
+	err := app.models.Media.SetCover(r.Context(), md)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrNotAnImage):
+			v := validator.New()
+			v.AddError("kind", "only images can be used as a cover photo")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"media": md}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteMediaHandler removes an attachment and its stored files.
+func (app *application) deleteMediaHandler(w http.ResponseWriter, r *http.Request) {
+	md, ok := app.loadMedia(w, r)
+	if !ok {
+		return
+	}
+	if !app.authorizeMediaOwner(w, r, mediaOwner{cattleID: md.CattleID, listingID: md.ListingID}) {
+		return
+	}
+
+	deleted, err := app.models.Media.Delete(r.Context(), md.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The row is gone, so failing to remove a file only leaves an orphan behind; log it and carry on.
+	app.deleteObjects(r, deleted.StorageKey, deleted.ThumbnailKey)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "media successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// uploadMedia reads a multipart "file" field, sniffs and validates it, generates a thumbnail for images,
+// stores the files and records the attachment.
+func (app *application) uploadMedia(w http.ResponseWriter, r *http.Request, owner mediaOwner) {
+	if !app.authorizeMediaOwner(w, r, owner) {
+		return
+	}
+	user := app.contextGetUser(r)
+
+	// Leave a little room on top of the file limit for the other multipart fields.
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadBytes+1<<20)
+	if err := r.ParseMultipartForm(media.MaxImageBytes); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			app.requestEntityTooLargeResponse(w, r, fmt.Sprintf("upload must not be larger than %d MB", media.MaxUploadBytes>>20))
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New(`multipart field "file" must be provided`))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadBytes+1))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	contentType := media.SniffContentType(data)
+	kind, _ := media.KindFor(contentType)
+
+	md := &media.Media{
+		CattleID:    owner.cattleID,
+		ListingID:   owner.listingID,
+		UploadedBy:  user.ID,
+		Kind:        kind,
+		FileName:    media.CleanFileName(header.Filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		Caption:     strings.TrimSpace(r.FormValue("caption")),
+	}
+
+	v := validator.New()
+	if media.ValidateMedia(v, md); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ext := media.ExtensionFor(contentType)
+	md.StorageKey, err = storage.NewKey(owner.prefix(), ext)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var thumb []byte
+	if md.Kind == media.KindImage {
+		thumb, md.Width, md.Height, err = media.Thumbnail(data, media.ThumbnailSize)
+		if errors.Is(err, internalErrors.ErrImageTooLarge) {
+			app.requestEntityTooLargeResponse(w, r, fmt.Sprintf("image must not be larger than %d megapixels", media.MaxImagePixels/1_000_000))
+			return
+		}
+		if err != nil {
+			v.AddError("file", "must be a valid image")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		md.ThumbnailKey = strings.TrimSuffix(md.StorageKey, ext) + "_thumb.jpg"
+	}
+
+	err = app.storage.Put(r.Context(), md.StorageKey, bytes.NewReader(data), md.SizeBytes, md.ContentType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if thumb != nil {
+		err = app.storage.Put(r.Context(), md.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg")
+		if err != nil {
+			app.deleteObjects(r, md.StorageKey)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Media.Insert(r.Context(), md)
+	if err != nil {
+		app.deleteObjects(r, md.StorageKey, md.ThumbnailKey)
+		switch {
+		case errors.Is(err, internalErrors.ErrForeignKeyViolation):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/media/%d/content", md.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"media": md}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listMedia writes the owner's media in display order.
+func (app *application) listMedia(w http.ResponseWriter, r *http.Request, owner mediaOwner) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := media.MediaFilter{
+		CattleID:  owner.cattleID,
+		ListingID: owner.listingID,
+	}
+	if kind := app.readString(qs, "kind", ""); kind != "" {
+		k := media.Kind(kind)
+		v.Check(k == media.KindImage || k == media.KindDocument, "kind", "must be image or document")
+		filter.Kind = &k
+	}
+
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", "position")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"position", "created_at", "size_bytes", "-position", "-created_at", "-size_bytes"}
+	fields := app.readFields(qs, media.Media{}, v)
+
+	if filters.ValidateFilters(v, filter.Default); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	list, metadata, err := app.models.Media.GetAll(r.Context(), &filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"media": fields.project(list), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reorderMedia applies a new display order given as {"ids": [...]}.
+func (app *application) reorderMedia(w http.ResponseWriter, r *http.Request, owner mediaOwner) {
+	if !app.authorizeMediaOwner(w, r, owner) {
+		return
+	}
+
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.IDs) > 0, "ids", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Media.Reorder(r.Context(), owner.cattleID, owner.listingID, input.IDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrInvalidUpdateData):
+			v.AddError("ids", "must list every attachment exactly once")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.listMedia(w, r, owner)
+}
+
+// loadMedia reads the id parameter and fetches the media record, writing an error response on failure.
+func (app *application) loadMedia(w http.ResponseWriter, r *http.Request) (*media.Media, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	md, err := app.models.Media.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return md, true
+}
+
+// authorizeMediaOwner checks that the current user owns the animal or listing, writing an error response if not.
+func (app *application) authorizeMediaOwner(w http.ResponseWriter, r *http.Request, owner mediaOwner) bool {
+	err := app.checkMediaOwner(r.Context(), app.contextGetUser(r), owner)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, errNotOwner):
+			app.notPermittedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+	return true
+}
+
+// checkMediaOwner returns errNotOwner unless user owns the animal or listing.
+func (app *application) checkMediaOwner(ctx context.Context, user *users.User, owner mediaOwner) error {
+	var ownerID int64
+	switch {
+	case owner.cattleID != nil:
+		c, err := app.models.Cattle.GetByID(ctx, int(*owner.cattleID))
+		if err != nil {
+			return err
+		}
+		ownerID = int64(c.OwnerID)
+	case owner.listingID != nil:
+		l, err := app.models.Listings.GetByID(ctx, *owner.listingID)
+		if err != nil {
+			return err
+		}
+		ownerID = l.UserID
+	}
+
+	if ownerID != user.ID {
+		return errNotOwner
+	}
+	return nil
+}
+
+// streamObject copies a stored object to the response.
+func (app *application) streamObject(w http.ResponseWriter, r *http.Request, key, contentType, fileName string) {
+	obj, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrObjectNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if disposition := mime.FormatMediaType("inline", map[string]string{"filename": fileName}); fileName != "" && disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+
+	err = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(streamTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.logError(r, err)
+	}
+
+	if _, err := io.Copy(w, obj); err != nil {
+		app.logError(r, err)
+	}
+}
+
+// deleteObjects removes stored files, logging rather than returning failures.
+func (app *application) deleteObjects(r *http.Request, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		err := app.storage.Delete(r.Context(), key)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			app.logError(r, err)
+		}
+	}
+}
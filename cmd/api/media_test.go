@@ -0,0 +1,45 @@
+// File: cmd/api/media_test.go
+package main
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/storage"
+)
+
+func TestStreamObjectContentDisposition(t *testing.T) {
+	app := newTestApplication(t)
+	store, err := storage.New(storage.Config{Driver: storage.DriverLocal, LocalRoot: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.storage = store
+
+	const key = "cattle/7/papers.pdf"
+	if err := store.Put(t.Context(), key, strings.NewReader("%PDF"), 4, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"papers.pdf", `bull "Tío" 2024.pdf`, `back\slash.pdf`} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/v1/media/1/content", nil)
+			app.streamObject(w, r, key, "application/pdf", name)
+
+			if w.Code != http.StatusOK || w.Body.String() != "%PDF" {
+				t.Fatalf("response = %d %q, want the file", w.Code, w.Body)
+			}
+			disposition, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+			if err != nil {
+				t.Fatalf("Content-Disposition %q: %v", w.Header().Get("Content-Disposition"), err)
+			}
+			if disposition != "inline" || params["filename"] != name {
+				t.Errorf("Content-Disposition = %s %v, want inline with filename %q", disposition, params, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// File: cmd/api/migrate.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/migrate"
+	"github.com/Pedro-J-Kukul/cash-cow-api/migrations"
+)
+
+// migrateTimeout bounds a migration run, including the wait for another replica's lock.
+const migrateTimeout = 5 * time.Minute
+
+// runMigrateCommand handles "migrate up", "migrate down [N]", "migrate status" and "migrate force VERSION"
+// using the migrations embedded in the binary.
+func runMigrateCommand(db *sql.DB, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [N] | status | force VERSION")
+	}
+
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrations up to date", "applied", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New("migrate down: N must be a positive integer")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrations reverted", "reverted", reverted)
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("migration status", "version", status.Version, "dirty", status.Dirty, "pending", len(status.Pending))
+		for _, m := range status.Pending {
+			logger.Info("pending migration", "version", m.Version, "name", m.Name)
+		}
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New("migrate force: VERSION is required")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return errors.New("migrate force: VERSION must be a non-negative integer")
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		logger.Info("migration version forced", "version", version)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// File: cmd/api/purge.go
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/storage"
+)
+
+// purgeTimeout bounds a single purge run, including removing the purged media files.
+const purgeTimeout = 5 * time.Minute
+
+// purge permanently deletes every record soft deleted longer than the retention period ago, then
+// removes the media files that belonged to them. Files that fail to delete are logged and left
+// behind as orphans, since their rows are already gone.
+func (app *application) purge(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, purgeTimeout)
+	defer cancel()
+
+	before := time.Now().Add(-app.config.purge.retention)
+	result, err := app.models.Purge(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range result.StorageKeys {
+		err := app.storage.Delete(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			app.logger.Error(err.Error(), "key", key)
+		}
+	}
+
+	args := []any{"before", before.Format(time.RFC3339), "files", len(result.StorageKeys)}
+	for table, n := range result.Purged {
+		args = append(args, table, n)
+	}
+	app.logger.Info("purged soft deleted records", args...)
+	return nil
+}
+
+// runPurges purges on every tick of the purge interval until ctx is cancelled. A failed run is
+// logged and retried on the next tick.
+func (app *application) runPurges(ctx context.Context) {
+	ticker := time.NewTicker(app.config.purge.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.purge(ctx); err != nil && ctx.Err() == nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	}
+}
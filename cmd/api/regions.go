@@ -0,0 +1,199 @@
+// File: cmd/api/regions.go
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// updateRegionBoundaryHandler replaces a region's boundary with a GeoJSON Polygon or MultiPolygon
+// geometry, or clears it when the boundary is null. An If-Match header, when sent, must name the
+// region's current version.
+func (app *application) updateRegionBoundaryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	region, err := app.models.Regions.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if region.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, region.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Boundary locations.MultiPolygon `json:"boundary"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	region.Boundary = input.Boundary
+
+	v := validator.New()
+	if locations.ValidateRegion(v, region); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := app.models.Regions.Update(r.Context(), region); err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.setETag(w, region.Version)
+	err = app.writeJSON(w, http.StatusOK, envelope{"region": region}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// patchRegionHandler applies a partial update to a region's name and code. Boundaries are replaced
+// through updateRegionBoundaryHandler.
+func (app *application) patchRegionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	region, err := app.models.Regions.GetByID(r.Context(), int(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if region.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, region.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+		Code *string `json:"code"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var changed changes
+	patchField(&changed, "name", &region.Name, input.Name)
+	patchField(&changed, "code", &region.Code, input.Code)
+
+	v := validator.New()
+	if locations.ValidateRegion(v, region); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		err = app.models.Regions.Update(r.Context(), region)
+		if err != nil {
+			switch {
+			case errors.Is(err, internalErrors.ErrDuplicateName):
+				v.AddError("name", "a region with this name already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrDuplicateCode):
+				v.AddError("code", "a region with this code already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.writePatched(w, r, "region", region, region.Version, changed)
+}
+
+// deleteRegionHandler soft deletes a region. Deleted regions are left out of region lookups by point.
+func (app *application) deleteRegionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Regions.SoftDelete(r.Context(), int(id), app.contextGetUser(r).ID)
+	app.writeDeletion(w, r, err, "region successfully deleted")
+}
+
+// restoreRegionHandler restores a soft deleted region.
+func (app *application) restoreRegionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Regions.Restore(r.Context(), int(id))
+	app.writeDeletion(w, r, err, "region successfully restored")
+}
+
+// locateRegionHandler returns the region whose boundary contains the "near=lat,lng" point.
+func (app *application) locateRegionHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	center := app.readFloats(r.URL.Query(), "near", 2, v)
+	v.Check(center != nil || !v.Valid(), "near", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	c := locations.Coordinates{Latitude: center[0], Longitude: center[1]}
+	if locations.ValidateCoordinates(v, c); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	region, err := app.models.Regions.Locate(r.Context(), c)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"region": region}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,98 @@
+// File: cmd/api/routes.go
+package main
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// routes registers every endpoint and wraps the router in the global middleware chain.
+func (app *application) routes() http.Handler {
+	router := httprouter.New()
+
+	router.NotFound = http.HandlerFunc(app.notFoundResponse)
+	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+
+	// Users
+	router.HandlerFunc(http.MethodPatch, "/v1/users/:id", app.requireActivatedUser(app.patchUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id", app.requireActivatedUser(app.deleteUserHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/:id/restore", app.requirePermission("write:users", app.restoreUserHandler))
+
+	// Cattle
+	router.HandlerFunc(http.MethodPatch, "/v1/cattle/:id", app.requireActivatedUser(app.patchCattleHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/cattle/:id", app.requireActivatedUser(app.deleteCattleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/cattle/:id/restore", app.requireActivatedUser(app.restoreCattleHandler))
+
+	// Breeds
+	router.HandlerFunc(http.MethodPatch, "/v1/breeds/:id", app.requirePermission("write:breeds", app.patchBreedHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/breeds/:id", app.requirePermission("write:breeds", app.deleteBreedHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/breeds/:id/restore", app.requirePermission("write:breeds", app.restoreBreedHandler))
+
+	// Areas
+	router.HandlerFunc(http.MethodGet, "/v1/areas", app.listAreasHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/areas/:id", app.requirePermission("write:areas", app.patchAreaHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/areas/:id", app.requirePermission("write:areas", app.deleteAreaHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/areas/:id/restore", app.requirePermission("write:areas", app.restoreAreaHandler))
+
+	// Regions
+	router.HandlerFunc(http.MethodGet, "/v1/regions/locate", app.locateRegionHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/regions/:id", app.requirePermission("write:regions", app.patchRegionHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/regions/:id/boundary", app.requirePermission("write:regions", app.updateRegionBoundaryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/regions/:id", app.requirePermission("write:regions", app.deleteRegionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/regions/:id/restore", app.requirePermission("write:regions", app.restoreRegionHandler))
+
+	// Listings
+	router.HandlerFunc(http.MethodGet, "/v1/listings", app.listListingsHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/listings/:id", app.requireActivatedUser(app.patchListingHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/listings/:id", app.requireActivatedUser(app.deleteListingHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/listings/:id/restore", app.requireActivatedUser(app.restoreListingHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/listings/:id/sold", app.requireActivatedUser(app.markListingSoldHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/listings/:id/renew", app.requireActivatedUser(app.renewListingHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/listings/:id/watchers", app.requireActivatedUser(app.listingWatchersHandler))
+
+	// Saved Searches
+	router.HandlerFunc(http.MethodGet, "/v1/saved-searches", app.requireActivatedUser(app.listSavedSearchesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/saved-searches", app.requireActivatedUser(app.createSavedSearchHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/saved-searches/unsubscribe", app.unsubscribeSavedSearchHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/saved-searches/:id", app.requireActivatedUser(app.showSavedSearchHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/saved-searches/:id", app.requireActivatedUser(app.patchSavedSearchHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/saved-searches/:id", app.requireActivatedUser(app.deleteSavedSearchHandler))
+
+	// Watchlist
+	router.HandlerFunc(http.MethodGet, "/v1/watchlist", app.requireActivatedUser(app.listWatchlistHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/watchlist", app.requireActivatedUser(app.createWatchHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/watchlist/:id", app.requireActivatedUser(app.deleteWatchHandler))
+
+	// GeoJSON
+	router.HandlerFunc(http.MethodGet, "/v1/geojson/areas", app.areasGeoJSONHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/geojson/regions", app.regionsGeoJSONHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/geojson/listings", app.listingsGeoJSONHandler)
+
+	// Media
+	router.HandlerFunc(http.MethodGet, "/v1/cattle/:id/media", app.listCattleMediaHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/cattle/:id/media", app.requireActivatedUser(app.uploadCattleMediaHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/cattle/:id/media/order", app.requireActivatedUser(app.reorderCattleMediaHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/listings/:id/media", app.listListingMediaHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/listings/:id/media", app.requireActivatedUser(app.uploadListingMediaHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/listings/:id/media/order", app.requireActivatedUser(app.reorderListingMediaHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/media/:id/content", app.showMediaContentHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/media/:id/thumbnail", app.showMediaThumbnailHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/media/:id/cover", app.requireActivatedUser(app.setMediaCoverHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/media/:id", app.requireActivatedUser(app.deleteMediaHandler))
+
+	// Audit
+	router.HandlerFunc(http.MethodGet, "/v1/audit", app.requirePermission("read:audit", app.auditLogHandler))
+
+	// Jobs
+	router.HandlerFunc(http.MethodGet, "/v1/jobs", app.requirePermission("read:jobs", app.listJobsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/jobs/:id", app.requirePermission("read:jobs", app.showJobHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/jobs/:id/retry", app.requirePermission("write:jobs", app.retryJobHandler))
+
+	// Emails
+	router.HandlerFunc(http.MethodGet, "/v1/emails", app.requirePermission("read:emails", app.listEmailsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/emails/:id", app.requirePermission("read:emails", app.showEmailHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/emails/:id/resend", app.requirePermission("write:emails", app.resendEmailHandler))
+
+	return app.recoverPanic(app.requestID(app.authenticate(router)))
+}
@@ -0,0 +1,270 @@
+// File: cmd/api/savedsearches.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// pagingParams are the listing parameters that page, sort or trim results. They mean nothing to
+// alerts, so they are dropped from saved queries.
+var pagingParams = []string{"page", "page_size", "sort", "cursor", "fields"}
+
+// listSavedSearchesHandler lists the current user's saved searches.
+func (app *application) listSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := savedsearches.SavedSearchFilter{UserID: &app.contextGetUser(r).ID}
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", "-created_at")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "name", "created_at", "updated_at", "-id", "-name", "-created_at", "-updated_at"}
+	fields := app.readFields(qs, savedsearches.SavedSearch{}, v)
+
+	if filters.ValidateFilters(v, filter.Default); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	searches, metadata, err := app.models.SavedSearches.GetAll(r.Context(), &filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_searches": fields.project(searches), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createSavedSearchHandler saves a listing search for the current user. The query takes the same
+// parameters as GET /v1/listings ("q=brahman&max_price_per_kg=5"); matches are emailed immediately,
+// daily (the default) or weekly, for listings created or edited from now on.
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name          string  `json:"name"`
+		Query         string  `json:"query"`
+		Frequency     *string `json:"frequency"`
+		AlertsEnabled *bool   `json:"alerts_enabled"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	s := &savedsearches.SavedSearch{
+		UserID:        app.contextGetUser(r).ID,
+		Name:          strings.TrimSpace(input.Name),
+		Frequency:     savedsearches.FrequencyDaily,
+		AlertsEnabled: true,
+	}
+	if input.Frequency != nil {
+		s.Frequency = savedsearches.Frequency(*input.Frequency)
+	}
+	if input.AlertsEnabled != nil {
+		s.AlertsEnabled = *input.AlertsEnabled
+	}
+
+	v := validator.New()
+	s.Query = app.readSavedQuery(input.Query, v)
+	if savedsearches.ValidateSavedSearch(v, s); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := app.models.SavedSearches.Insert(r.Context(), s); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/saved-searches/%d", s.ID))
+
+	err := app.writeJSON(w, http.StatusCreated, envelope{"saved_search": s}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showSavedSearchHandler shows one of the current user's saved searches.
+func (app *application) showSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.readOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	app.setETag(w, s.Version)
+	err := app.writeJSON(w, http.StatusOK, envelope{"saved_search": s}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// patchSavedSearchHandler applies a partial update to one of the current user's saved searches.
+// Changing the query drops the matches of the old one that have not been emailed yet.
+func (app *application) patchSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.readOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+	if !app.ifMatch(r, s.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name          *string `json:"name"`
+		Query         *string `json:"query"`
+		Frequency     *string `json:"frequency"`
+		AlertsEnabled *bool   `json:"alerts_enabled"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if input.Name != nil {
+		*input.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Query != nil {
+		*input.Query = app.readSavedQuery(*input.Query, v)
+	}
+
+	var changed changes
+	patchField(&changed, "name", &s.Name, input.Name)
+	patchField(&changed, "query", &s.Query, input.Query)
+	patchField(&changed, "frequency", &s.Frequency, (*savedsearches.Frequency)(input.Frequency))
+	patchField(&changed, "alerts_enabled", &s.AlertsEnabled, input.AlertsEnabled)
+
+	if savedsearches.ValidateSavedSearch(v, s); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		err := app.models.SavedSearches.Update(r.Context(), s)
+		if err != nil {
+			switch {
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.writePatched(w, r, "saved_search", s, s.Version, changed)
+}
+
+// deleteSavedSearchHandler deletes one of the current user's saved searches for good.
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.readOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.SavedSearches.Delete(r.Context(), s.ID)
+	app.writeDeletion(w, r, err, "saved search successfully deleted")
+}
+
+// unsubscribeSavedSearchHandler turns off the alerts of a saved search given the token from the
+// unsubscribe link of a digest, without signing in.
+func (app *application) unsubscribeSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if savedsearches.ValidateUnsubscribeToken(v, input.Token); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	s, err := app.models.SavedSearches.Unsubscribe(r.Context(), input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			v.AddError("token", "invalid unsubscribe token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	message := fmt.Sprintf("alerts for %q are turned off", s.Name)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readOwnSavedSearch reads the saved search named by the id parameter. Other users' searches are
+// reported as not found, as they are nobody else's business. It writes the error response and
+// returns false when there is no such search.
+func (app *application) readOwnSavedSearch(w http.ResponseWriter, r *http.Request) (*savedsearches.SavedSearch, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	s, err := app.models.SavedSearches.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if s.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	return s, true
+}
+
+// readSavedQuery checks that query is a valid listing search, reporting any problem under "query",
+// and returns it in canonical form without the paging parameters.
+func (app *application) readSavedQuery(query string, v *validator.Validator) string {
+	qs, err := url.ParseQuery(strings.TrimPrefix(query, "?"))
+	if err != nil {
+		v.AddError("query", "must be a valid query string")
+		return query
+	}
+	for _, key := range pagingParams {
+		qs.Del(key)
+	}
+
+	search := validator.New()
+	if listings.ValidateListingFilter(search, app.readListingFilter(qs, search)); !search.Valid() {
+		keys := make([]string, 0, len(search.Errors))
+		for key := range search.Errors {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		v.AddError("query", fmt.Sprintf("%s %s", keys[0], search.Errors[keys[0]]))
+	}
+	return qs.Encode()
+}
@@ -0,0 +1,117 @@
+// File: cmd/api/scheduler.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+)
+
+const (
+	// scheduleTimeout bounds a single run of the listing scheduler.
+	scheduleTimeout = 5 * time.Minute
+	// scheduleLockKey is the advisory lock that elects the one API instance running the scheduler.
+	// It must differ from the migration lock key.
+	scheduleLockKey int64 = 0x636173685f736368 // "cash_sch"
+)
+
+// schedule publishes the listings whose publish time has come, takes expired listings off the
+// market and reminds sellers of the listings expiring within the reminder period. Only one API
+// instance runs it at a time; the others skip the run. A reminder that fails is logged and retried
+// on the next run.
+func (app *application) schedule(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, scheduleTimeout)
+	defer cancel()
+
+	ran, err := app.models.Exclusive(ctx, scheduleLockKey, func() error { return app.runSchedule(ctx) })
+	if err != nil {
+		return err
+	}
+	if !ran {
+		app.logger.Debug("skipped listing schedule", "reason", "another instance holds the lock")
+	}
+	return nil
+}
+
+// runSchedule is a single run of the scheduler, once the lock is held.
+func (app *application) runSchedule(ctx context.Context) error {
+	now := time.Now()
+	published, err := app.models.Listings.Publish(ctx, now)
+	if err != nil {
+		return err
+	}
+	expired, err := app.models.Listings.Expire(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	expiring, err := app.models.Listings.GetExpiring(ctx, now.Add(app.config.schedule.reminder))
+	if err != nil {
+		return err
+	}
+	reminded := 0
+	for _, id := range expiring {
+		if err := app.sendExpiryReminder(ctx, id); err != nil {
+			app.logger.Error(err.Error(), "listing_id", id)
+			continue
+		}
+		reminded++
+	}
+
+	app.logger.Info("ran listing schedule", "published", len(published), "expired", len(expired), "reminded", reminded)
+	return nil
+}
+
+// sendExpiryReminder emails the seller of a listing that it is about to expire, with a link to
+// renew it. The email is queued in the same transaction that records the reminder, so that it is
+// sent once per expiry.
+func (app *application) sendExpiryReminder(ctx context.Context, id int64) error {
+	l, err := app.models.Listings.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	seller, err := app.models.Users.GetByID(ctx, l.UserID)
+	if err != nil {
+		return err
+	}
+
+	listingURL := fmt.Sprintf("%s/listings/%d", app.config.webURL, l.ID)
+	reminder, err := outbox.New(seller.Email, "listing_expiry_reminder.tmpl", map[string]any{
+		"firstName":  seller.FirstName,
+		"title":      l.Title,
+		"expiresAt":  l.ExpiresAt.UTC().Format("2 Jan 2006 15:04 MST"),
+		"listingURL": listingURL,
+		"renewURL":   listingURL + "/renew",
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.models.WithTx(ctx, func(tx data.Models) error {
+		if err := tx.Emails.Insert(ctx, reminder); err != nil {
+			return err
+		}
+		return tx.Listings.MarkReminded(ctx, l.ID, time.Now())
+	})
+}
+
+// runScheduler runs the listing scheduler on every tick of the schedule interval until ctx is
+// cancelled. A failed run is logged and retried on the next tick.
+func (app *application) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(app.config.schedule.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.schedule(ctx); err != nil && ctx.Err() == nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	}
+}
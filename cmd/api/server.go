@@ -0,0 +1,80 @@
+// File: cmd/api/server.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve starts the HTTP server and blocks until it has shut down gracefully.
+func (app *application) serve() error {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.port),
+		Handler:      app.routes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  30 * time.Second, // media uploads need more than the usual few seconds
+		WriteTimeout: 30 * time.Second, // file downloads extend it, see streamTimeout
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
+
+	shutdownError := make(chan error)
+
+	// Purge soft deleted records, run saved search and watchlist alerts, the listing scheduler and
+	// the job workers in the background until shutdown.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if app.config.purge.interval > 0 {
+		app.background(func() { app.runPurges(jobsCtx) })
+	}
+	if app.config.alerts.interval > 0 {
+		app.background(func() { app.runAlerts(jobsCtx) })
+	}
+	if app.config.schedule.interval > 0 {
+		app.background(func() { app.runScheduler(jobsCtx) })
+	}
+	for range app.config.jobs.workers {
+		app.background(func() { app.runWorker(jobsCtx) })
+	}
+
+	// Listen for SIGINT/SIGTERM and shut the server down gracefully.
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Info("shutting down server", "signal", s.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			shutdownError <- err
+		}
+
+		app.logger.Info("completing background tasks", "addr", srv.Addr)
+		stopJobs()
+		app.wg.Wait()
+		shutdownError <- nil
+	}()
+
+	app.logger.Info("starting server", "addr", srv.Addr, "env", app.config.env, "version", version)
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if err := <-shutdownError; err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server", "addr", srv.Addr)
+	return nil
+}
@@ -0,0 +1,139 @@
+// File: cmd/api/users.go
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// patchUserHandler applies a partial update to a user's profile, changing only the fields sent. Users
+// can edit their own profile; holders of write:users can edit anyone's and are the only ones who may
+// change is_activated and is_verified.
+func (app *application) patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	current := app.contextGetUser(r)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), current.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	admin := permissions.Includes("write:users")
+	if !admin && current.ID != id {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if user.DeletedAt != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ifMatch(r, user.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FarmerID    *string `json:"farmer_id"`
+		Email       *string `json:"email"`
+		PhoneNumber *string `json:"phone_number"`
+		FirstName   *string `json:"first_name"`
+		LastName    *string `json:"last_name"`
+		IsActivated *bool   `json:"is_activated"`
+		IsVerified  *bool   `json:"is_verified"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if !admin && (input.IsActivated != nil || input.IsVerified != nil) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var changed changes
+	patchField(&changed, "farmer_id", &user.FarmerID, input.FarmerID)
+	patchField(&changed, "email", &user.Email, input.Email)
+	patchField(&changed, "phone_number", &user.PhoneNumber, input.PhoneNumber)
+	patchField(&changed, "first_name", &user.FirstName, input.FirstName)
+	patchField(&changed, "last_name", &user.LastName, input.LastName)
+	patchNullable(&changed, "is_activated", &user.IsActivated, input.IsActivated)
+	patchNullable(&changed, "is_verified", &user.IsVerified, input.IsVerified)
+
+	v := validator.New()
+	if users.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(changed) > 0 {
+		err = app.models.Users.Update(r.Context(), user)
+		if err != nil {
+			switch {
+			case isDuplicate(err, "email"):
+				v.AddError("email", "a user with this email address already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case isDuplicate(err, "farmer_id"):
+				v.AddError("farmer_id", "a user with this farmer ID already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case isDuplicate(err, "phone_number"):
+				v.AddError("phone_number", "a user with this phone number already exists")
+				app.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, internalErrors.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.writePatched(w, r, "user", user, user.Version, changed)
+}
+
+// deleteUserHandler soft deletes a user, which signs them out everywhere since their tokens stop
+// authenticating. Users can delete their own account; holders of write:users can delete anyone's.
+func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.authorizeEdit(w, r, id, "write:users") {
+		return
+	}
+
+	err = app.models.Users.SoftDelete(r.Context(), id, app.contextGetUser(r).ID)
+	app.writeDeletion(w, r, err, "user successfully deleted")
+}
+
+// restoreUserHandler restores a soft deleted user. Only holders of write:users can restore accounts,
+// since a deleted user can no longer authenticate.
+func (app *application) restoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Users.Restore(r.Context(), id)
+	app.writeDeletion(w, r, err, "user successfully restored")
+}
@@ -0,0 +1,233 @@
+// File: cmd/api/watchlists.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// listWatchlistHandler lists the listings and sellers the current user is watching, optionally only
+// one kind of them ("kind=listing" or "kind=seller").
+func (app *application) listWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := watchlists.WatchFilter{UserID: &app.contextGetUser(r).ID}
+	filter.Kind = app.readString(qs, "kind", "")
+	filter.Default.Page = app.readInt(qs, "page", 1, v)
+	filter.Default.PageSize = app.readInt(qs, "page_size", 20, v)
+	filter.Default.Sort = app.readString(qs, "sort", "-created_at")
+	filter.Default.Cursor = app.readString(qs, "cursor", "")
+	filter.Default.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+	fields := app.readFields(qs, watchlists.Watch{}, v)
+
+	if watchlists.ValidateWatchFilter(v, &filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	watches, metadata, err := app.models.Watches.GetAll(r.Context(), &filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"watchlist": fields.project(watches), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createWatchHandler adds a listing ({"listing_id": 7}) or a seller, and so every listing of theirs
+// ({"seller_id": 3}), to the current user's watchlist. Watchers are emailed when the price of a
+// watched listing changes or it is sold.
+func (app *application) createWatchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ListingID *int64 `json:"listing_id"`
+		SellerID  *int64 `json:"seller_id"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	watch := &watchlists.Watch{UserID: user.ID, ListingID: input.ListingID, SellerID: input.SellerID}
+
+	v := validator.New()
+	if watchlists.ValidateWatch(v, watch); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Deleted listings and sellers are not worth watching, and nor are your own
+	if watch.ListingID != nil {
+		l, err := app.models.Listings.GetByID(r.Context(), *watch.ListingID)
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound) || (err == nil && l.IsDeleted()):
+			v.AddError("listing_id", "must refer to an existing listing")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		default:
+			v.Check(l.UserID != user.ID, "listing_id", "must not be one of your own listings")
+		}
+	}
+	if watch.SellerID != nil {
+		seller, err := app.models.Users.GetByID(r.Context(), *watch.SellerID)
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound) || (err == nil && seller.Deletion.IsDeleted()):
+			v.AddError("seller_id", "must refer to an existing user")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		default:
+			v.Check(seller.ID != user.ID, "seller_id", "must not be yourself")
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Watches.Insert(r.Context(), watch)
+	if err != nil {
+		switch {
+		case isDuplicate(err, "listing_id"):
+			v.AddError("listing_id", "is already on your watchlist")
+			app.failedValidationResponse(w, r, v.Errors)
+		case isDuplicate(err, "seller_id"):
+			v.AddError("seller_id", "is already on your watchlist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/watchlist/%d", watch.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"watch": watch}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteWatchHandler takes a listing or seller off the current user's watchlist. Other users'
+// watches are reported as not found.
+func (app *application) deleteWatchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	watch, err := app.models.Watches.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if watch.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Watches.Delete(r.Context(), watch.ID)
+	app.writeDeletion(w, r, err, "removed from your watchlist")
+}
+
+// listingWatchersHandler shows how many users are watching a listing, directly or through its
+// seller, as a signal of interest. Only the seller and holders of write:listings can see it.
+func (app *application) listingWatchersHandler(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readListing(w, r)
+	if !ok || !app.authorizeEdit(w, r, l.UserID, "write:listings") {
+		return
+	}
+
+	count, err := app.models.Watches.CountWatchers(r.Context(), l.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"listing_id": l.ID, "watchers": count}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// markListingSoldHandler marks a listing as sold, which takes it off the market for good and tells
+// its watchers. The seller can mark their listings sold, and holders of write:listings anyone's.
+func (app *application) markListingSoldHandler(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readListing(w, r)
+	if !ok || !app.authorizeEdit(w, r, l.UserID, "write:listings") {
+		return
+	}
+	if !app.ifMatch(r, l.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	err := app.models.Listings.MarkSold(r.Context(), l.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, internalErrors.ErrAlreadySold):
+			app.alreadySoldResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	sold, err := app.models.Listings.GetByID(r.Context(), l.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.setETag(w, sold.Version)
+	err = app.writeJSON(w, http.StatusOK, envelope{"listing": sold}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readListing reads the listing named by the id parameter, treating soft deleted listings as
+// missing. It writes the error response and returns false when there is no such listing.
+func (app *application) readListing(w http.ResponseWriter, r *http.Request) (*listings.Listing, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	l, err := app.models.Listings.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if l.IsDeleted() {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	return l, true
+}
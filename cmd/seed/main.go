@@ -0,0 +1,43 @@
+// File: cmd/seed/main.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/seed"
+	_ "github.com/lib/pq"
+)
+
+// main upserts the reference data (permissions, Belize districts, towns and villages, breeds).
+// It is safe to run on every deploy.
+func main() {
+	var dsn string
+	var timeout time.Duration
+
+	flag.StringVar(&dsn, "db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN")
+	flag.DurationVar(&timeout, "timeout", time.Minute, "Maximum time to spend seeding")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := seed.Run(ctx, db, logger); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	logger.Info("reference data seeded")
+}
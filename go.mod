@@ -7,6 +7,7 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.43.0
+	golang.org/x/image v0.33.0
 	golang.org/x/time v0.14.0
 )
 
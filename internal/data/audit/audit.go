@@ -0,0 +1,403 @@
+// File: internal/data/audit/audit.go
+
+// Package audit keeps the trail of who changed what. Every write made through the models records
+// an entry with the acting user, the request it came from and the fields it changed, inside the
+// same transaction as the write itself.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Action is what a write did to a record.
+type Action string
+
+const (
+	ActionInsert  Action = "insert"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+	ActionPurge   Action = "purge"
+)
+
+// Actions lists every action, for validating filters.
+var Actions = []string{
+	string(ActionInsert), string(ActionUpdate), string(ActionDelete), string(ActionRestore), string(ActionPurge),
+}
+
+// Change holds the JSON value of a field before and after a write. Old is left out for inserts and
+// New for deletes; a field whose values are not recorded, such as a password, has neither.
+type Change struct {
+	Old json.RawMessage `json:"old,omitempty"`
+	New json.RawMessage `json:"new,omitempty"`
+}
+
+// Changes maps the JSON names of the fields a write changed to their values.
+type Changes map[string]Change
+
+// Entry is one write in the audit trail.
+type Entry struct {
+	ID        int64     `json:"id"`
+	ActorID   *int64    `json:"actor_id"` // nil for changes made by the system, e.g. the purge job
+	Entity    string    `json:"entity"`
+	EntityID  int64     `json:"entity_id"`
+	Action    Action    `json:"action"`
+	Changes   Changes   `json:"changes"`
+	RequestID string    `json:"request_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Actor identifies who is making the writes of a request. It travels in the context so that the
+// models can record it without every method taking it as an argument.
+type Actor struct {
+	UserID    int64 // zero for anonymous requests and background jobs
+	RequestID string
+	IP        string
+}
+
+// actorKey is the context key the actor is stored under.
+type actorKey struct{}
+
+// EntryFilter holds the filters for querying the audit trail.
+type EntryFilter struct {
+	ActorID   *int64
+	Entity    string
+	EntityID  *int64
+	Action    string
+	RequestID string
+	Since     *time.Time
+	Until     *time.Time
+	Default   filters.Filters
+}
+
+// AuditRepository is the storage the audit trail is read back from.
+type AuditRepository interface {
+	GetAll(ctx context.Context, filter *EntryFilter) ([]*Entry, filters.MetaData, error)
+}
+
+// AuditModel reads the audit trail from Postgres.
+type AuditModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// ignoredFields are bookkeeping columns that change with every write and would only add noise
+// to a diff. The entity ID is recorded on the entry itself.
+var ignoredFields = map[string]bool{"id": true, "version": true, "created_at": true, "updated_at": true}
+
+/****************************************************************************************
+ *										Actor											*
+ ***************************************************************************************/
+
+// WithActor returns a copy of ctx carrying actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, or the zero Actor when there is none.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+/****************************************************************************************
+ *										Entries											*
+ ***************************************************************************************/
+
+// NewEntry builds the entry for a write to entity by the actor in ctx.
+func NewEntry(ctx context.Context, entity string, entityID int64, action Action, changes Changes) *Entry {
+	actor := ActorFrom(ctx)
+	entry := &Entry{
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Changes:   changes,
+		RequestID: actor.RequestID,
+		IP:        actor.IP,
+	}
+	if actor.UserID != 0 {
+		entry.ActorID = &actor.UserID
+	}
+	return entry
+}
+
+// Diff compares the JSON form of two versions of a record field by field, leaving out the
+// bookkeeping fields. Fields the record does not marshal, such as password hashes, are never
+// recorded. A nil before or after records every field as new or old respectively.
+func Diff(before, after any) (Changes, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Changes{}
+	for name, value := range cur {
+		if ignoredFields[name] {
+			continue
+		}
+		if prev, ok := old[name]; !ok || !bytes.Equal(prev, value) {
+			changes[name] = Change{Old: old[name], New: value}
+		}
+	}
+	for name, value := range old {
+		if _, ok := cur[name]; !ok && !ignoredFields[name] {
+			changes[name] = Change{Old: value}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, nil
+}
+
+// Field builds the change of a single field from its old and new values, for writes that only
+// touch a field or two of a record. A nil side is left out of the change.
+func Field(from, to any) Change {
+	var change Change
+	if from != nil {
+		change.Old, _ = json.Marshal(from)
+	}
+	if to != nil {
+		change.New, _ = json.Marshal(to)
+	}
+	return change
+}
+
+// fields marshals a record and splits it into its top-level JSON fields.
+func fields(record any) (map[string]json.RawMessage, error) {
+	if record == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(js, &m); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	return m, nil
+}
+
+// Value stores changes as JSONB, or NULL when there are none.
+func (c Changes) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan reads changes from a JSONB column.
+func (c *Changes) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, c)
+	case string:
+		return json.Unmarshal([]byte(src), c)
+	default:
+		return fmt.Errorf("audit: cannot scan %T into Changes", src)
+	}
+}
+
+/****************************************************************************************
+ *										Recording										*
+ ***************************************************************************************/
+
+// Record writes the entry for a write to entity, diffing before against after. Either may be nil:
+// before for inserts and after for deletes. An update that changed nothing worth recording leaves
+// no entry. Models call Record with the transaction of the write so that the two are committed or
+// rolled back together.
+func Record(ctx context.Context, db database.DBTX, entity string, entityID int64, action Action, before, after any) error {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+	if action == ActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	return insert(ctx, db, NewEntry(ctx, entity, entityID, action, changes))
+}
+
+// RecordChanges writes an entry with changes given by the caller, for writes whose values must
+// not be kept, such as a new password.
+func RecordChanges(ctx context.Context, db database.DBTX, entity string, entityID int64, action Action, changes Changes) error {
+	return insert(ctx, db, NewEntry(ctx, entity, entityID, action, changes))
+}
+
+// insert adds an entry to the audit log.
+func insert(ctx context.Context, db database.DBTX, entry *Entry) error {
+	query := `
+		INSERT INTO audit_log (actor_id, entity, entity_id, action, changes, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::inet)`
+
+	_, err := db.ExecContext(ctx, query,
+		entry.ActorID, entry.Entity, entry.EntityID, entry.Action, entry.Changes, entry.RequestID, entry.IP)
+	if err != nil {
+		return errors.WrapInsertError(err, "AuditLog")
+	}
+	return nil
+}
+
+// RecordAll writes an entry without changes for each of ids, for bulk writes such as purges.
+func RecordAll(ctx context.Context, db database.DBTX, entity string, ids []int64, action Action) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	actor := ActorFrom(ctx)
+
+	query := `
+		INSERT INTO audit_log (actor_id, entity, entity_id, action, request_id, ip)
+		SELECT NULLIF($1::bigint, 0), $2, id, $3, $4, NULLIF($5, '')::inet
+		FROM unnest($6::bigint[]) AS id`
+
+	_, err := db.ExecContext(ctx, query, actor.UserID, entity, action, actor.RequestID, actor.IP, pq.Array(ids))
+	if err != nil {
+		return errors.WrapInsertError(err, "AuditLog")
+	}
+	return nil
+}
+
+// SoftDelete is database.SoftDelete with a delete entry recorded for the row.
+func SoftDelete(ctx context.Context, db database.DBTX, table string, id, deletedBy int64) error {
+	return inTx(ctx, db, func(tx database.DBTX) error {
+		if err := database.SoftDelete(ctx, tx, table, id, deletedBy); err != nil {
+			return err
+		}
+		return RecordAll(ctx, tx, table, []int64{id}, ActionDelete)
+	})
+}
+
+// Restore is database.Restore with a restore entry recorded for the row.
+func Restore(ctx context.Context, db database.DBTX, table string, id int64) error {
+	return inTx(ctx, db, func(tx database.DBTX) error {
+		if err := database.Restore(ctx, tx, table, id); err != nil {
+			return err
+		}
+		return RecordAll(ctx, tx, table, []int64{id}, ActionRestore)
+	})
+}
+
+// Purge is database.Purge with a purge entry recorded for every row it deletes.
+func Purge(ctx context.Context, db database.DBTX, table string, before time.Time, guard string) (int64, error) {
+	var ids []int64
+	err := inTx(ctx, db, func(tx database.DBTX) error {
+		var err error
+		ids, err = database.Purge(ctx, tx, table, before, guard)
+		if err != nil {
+			return err
+		}
+		return RecordAll(ctx, tx, table, ids, ActionPurge)
+	})
+	return int64(len(ids)), err
+}
+
+// inTx runs fn in a transaction on db, joining the caller's when db already is one.
+func inTx(ctx context.Context, db database.DBTX, fn func(tx database.DBTX) error) error {
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/****************************************************************************************
+ *										Queries											*
+ ***************************************************************************************/
+
+// ValidateEntryFilter checks the filters of an audit trail query.
+func ValidateEntryFilter(v *validator.Validator, f *EntryFilter) {
+	filters.ValidateFilters(v, f.Default)
+	v.Check(f.Action == "" || v.IsPermitted(f.Action, Actions...), "action", "must be one of insert, update, delete, restore or purge")
+	v.Check(f.Since == nil || f.Until == nil || !f.Until.Before(*f.Since), "until", "must not be before since")
+}
+
+// GetAll returns a page of the audit trail matching filter.
+func (m *AuditModel) GetAll(ctx context.Context, filter *EntryFilter) ([]*Entry, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 10)
+	query := fmt.Sprintf(`
+		SELECT %s, %s, id, actor_id, entity, entity_id, action, changes, request_id,
+			COALESCE(host(ip), ''), created_at
+		FROM audit_log
+		WHERE ($1::bigint IS NULL OR actor_id = $1)
+		AND ($2 = '' OR entity = $2)
+		AND ($3::bigint IS NULL OR entity_id = $3)
+		AND ($4 = '' OR action = $4::audit_action_enum)
+		AND ($5 = '' OR request_id = $5)
+		AND ($6::timestamptz IS NULL OR created_at >= $6)
+		AND ($7::timestamptz IS NULL OR created_at < $7)
+		AND %s
+		ORDER BY %s
+		LIMIT $8 OFFSET $9`, page.Count, page.SortKey, page.Where, page.OrderBy)
+
+	args := []any{
+		filter.ActorID,
+		filter.Entity,
+		filter.EntityID,
+		filter.Action,
+		filter.RequestID,
+		filter.Since,
+		filter.Until,
+		filter.Default.Limit(),
+		filter.Default.Offset(),
+	}
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "AuditLog")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*Entry{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var e Entry
+		var key filters.Key
+		err := rows.Scan(&totalRecords, &key.Values, &e.ID, &e.ActorID, &e.Entity, &e.EntityID, &e.Action, &e.Changes,
+			&e.RequestID, &e.IP, &e.CreatedAt)
+		if err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "AuditLog")
+		}
+		key.ID = e.ID
+		entries = append(entries, &e)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	entries, metaData := filters.Paginate(filter.Default, entries, keys, totalRecords)
+	return entries, metaData, nil
+}
@@ -0,0 +1,187 @@
+// File: internal/data/audit/audit_test.go
+package audit_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestAuditTrail(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+
+	admin := f.User()
+	actor := audit.Actor{UserID: admin.ID, RequestID: "req-1", IP: "192.0.2.7"}
+	ctx := audit.WithActor(t.Context(), actor)
+
+	b := &cattle.Breed{Name: "Audited", Description: "Before"}
+	if err := models.Breeds.Insert(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	b.Description = "After"
+	if err := models.Breeds.Update(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	// Saving the breed unchanged leaves no entry
+	if err := models.Breeds.Update(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Breeds.SoftDelete(ctx, b.ID, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Breeds.Restore(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, metadata, err := models.Audit.GetAll(ctx, &audit.EntryFilter{
+		Entity:   "breeds",
+		EntityID: testdb.Ptr(int64(b.ID)),
+		Default:  testdb.FirstPage("id"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []audit.Action{audit.ActionInsert, audit.ActionUpdate, audit.ActionDelete, audit.ActionRestore}
+	if len(entries) != len(want) || metadata.TotalRecords != len(want) {
+		t.Fatalf("got %d entries (%d in total), want %d", len(entries), metadata.TotalRecords, len(want))
+	}
+	for i, e := range entries {
+		if e.Action != want[i] {
+			t.Errorf("entry %d action = %q, want %q", i, e.Action, want[i])
+		}
+		if e.ActorID == nil || *e.ActorID != admin.ID || e.RequestID != actor.RequestID || e.IP != actor.IP {
+			t.Errorf("entry %d = %+v, want it made by %+v", i, e, actor)
+		}
+	}
+
+	inserted := entries[0].Changes
+	if _, ok := inserted["name"]; !ok || inserted["name"].Old != nil {
+		t.Errorf("insert changes = %v, want the new name only", inserted)
+	}
+	if _, ok := inserted["version"]; ok {
+		t.Errorf("insert changes = %v, want no version", inserted)
+	}
+
+	updated := entries[1].Changes
+	description, ok := updated["description"]
+	if len(updated) != 1 || !ok || !jsonEqual(description.Old, "Before") || !jsonEqual(description.New, "After") {
+		t.Errorf("update changes = %v, want only the description changed from Before to After", updated)
+	}
+}
+
+func TestAuditPasswordAndPermissions(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	u := f.User()
+	if err := u.Password.Set("a-new-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.UpdatePassword(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Permissions.AssignToUser(ctx, u.ID, "write:cattle"); err != nil {
+		t.Fatal(err)
+	}
+	// Granting a permission the user already holds leaves no entry
+	if err := models.Permissions.AssignToUser(ctx, u.ID, "write:cattle"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, err := models.Audit.GetAll(ctx, &audit.EntryFilter{
+		Entity:   "users",
+		EntityID: &u.ID,
+		Action:   string(audit.ActionUpdate),
+		Default:  testdb.FirstPage("id"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d update entries, want 2", len(entries))
+	}
+
+	password, ok := entries[0].Changes["password"]
+	if !ok || password.Old != nil || password.New != nil {
+		t.Errorf("password change = %+v, want it recorded without values", password)
+	}
+	if entries[0].ActorID != nil {
+		t.Errorf("ActorID = %d, want nil without an actor", *entries[0].ActorID)
+	}
+	if granted := entries[1].Changes["permissions"]; !jsonEqual(granted.New, []string{"write:cattle"}) {
+		t.Errorf("permissions change = %s, want write:cattle granted", granted.New)
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	f.Breed()
+	start := time.Now()
+	f.Breed()
+
+	all, _, err := models.Audit.GetAll(ctx, &audit.EntryFilter{Entity: "breeds", Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("got %d entries, want 2", len(all))
+	}
+
+	until := all[1].CreatedAt
+	before, _, err := models.Audit.GetAll(ctx, &audit.EntryFilter{Entity: "breeds", Until: &until, Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 1 || before[0].ID != all[0].ID {
+		t.Errorf("until %v got %d entries, want only the first", until, len(before))
+	}
+
+	since := start.Add(-time.Minute)
+	recent, _, err := models.Audit.GetAll(ctx, &audit.EntryFilter{Entity: "breeds", Since: &since, Default: testdb.FirstPage("-id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].ID != all[1].ID {
+		t.Errorf("since %v got %d entries, want both newest first", since, len(recent))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type record struct {
+		ID      int    `json:"id"`
+		Name    string `json:"name"`
+		Note    string `json:"note,omitempty"`
+		Version int    `json:"version"`
+	}
+
+	changes, err := audit.Diff(record{ID: 1, Name: "a", Note: "x", Version: 1}, record{ID: 1, Name: "b", Version: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || !jsonEqual(changes["name"].New, "b") || !jsonEqual(changes["note"].Old, "x") || changes["note"].New != nil {
+		t.Errorf("Diff = %v, want name changed and note removed", changes)
+	}
+
+	changes, err = audit.Diff(record{ID: 1, Name: "a", Version: 1}, record{ID: 1, Name: "a", Version: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes != nil {
+		t.Errorf("Diff = %v, want nil when only bookkeeping fields change", changes)
+	}
+}
+
+// jsonEqual reports whether raw is the JSON encoding of want.
+func jsonEqual(raw json.RawMessage, want any) bool {
+	b, err := json.Marshal(want)
+	return err == nil && string(raw) == string(b)
+}
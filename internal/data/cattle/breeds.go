@@ -3,10 +3,11 @@ package cattle
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
@@ -21,8 +22,10 @@ type Breed struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	IsActive    *bool  `json:"is_active"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
+	Version     int    `json:"version"`
+	database.Deletion
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 // Breeds is a slice of Breed.
@@ -30,7 +33,8 @@ type Breeds []Breed
 
 // BreedModel represents the model for cattle breeds.
 type BreedModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
 }
 
 // BreedFilter represents filtering options for querying cattle breeds.
@@ -38,95 +42,150 @@ type BreedFilter struct {
 	Name     string
 	IsActive *bool
 	Default  filters.Filters
+
+	// WithDeleted includes soft deleted breeds, which are left out by default.
+	WithDeleted bool
+}
+
+// BreedRepository is the interface for storing and querying breeds and the breed make-up of cattle.
+type BreedRepository interface {
+	Insert(ctx context.Context, b *Breed) error
+	Update(ctx context.Context, b *Breed) error
+	SoftDelete(ctx context.Context, id int, deletedBy int64) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetByID(ctx context.Context, id int) (*Breed, error)
+	GetAll(ctx context.Context, filter *BreedFilter) (Breeds, filters.MetaData, error)
+	GetComposition(ctx context.Context, cattleID int) (Composition, error)
+	SetComposition(ctx context.Context, cattleID int, composition Composition) error
 }
 
 // Validate validates the fields of a Breed.
 func (f *BreedFilter) Validate(v *validator.Validator, b *Breed) {
+	ValidateBreed(v, b)
+}
+
+// ValidateBreed validates the fields of a Breed.
+func ValidateBreed(v *validator.Validator, b *Breed) {
 	v.Check(b.Name != "", "name", "must be provided")
 	v.Check(len(b.Name) <= 255, "name", "must not be more than 255 characters long")
+	v.Check(len(b.Description) <= 5000, "description", "must not be more than 5000 characters long")
 }
 
 /****************************************************************************************
  *										Methods										*
  ***************************************************************************************/
 //  Insert inserts a new cattle breed into the database.
-func (m *BreedModel) Insert(b *Breed) error {
+func (m *BreedModel) Insert(ctx context.Context, b *Breed) error {
 	query := `
-		INSERT INTO cattle_breeds (name, description, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id, created_at, updated_at`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		INSERT INTO breeds (name, description, is_active, created_at, updated_at)
+		VALUES ($1, $2, COALESCE($3, TRUE), NOW(), NOW())
+		RETURNING id, is_active, version, created_at, updated_at`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, b.Name, b.Description, b.IsActive).Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, b.Name, b.Description, b.IsActive).Scan(&b.ID, &b.IsActive, &b.Version, &b.CreatedAt, &b.UpdatedAt)
 	if err != nil {
 		switch {
-		case errors.IsUniqueViolation(err, "cattle_breeds_name_key"):
+		case errors.IsUniqueViolation(err, "name"):
 			return errors.ErrDuplicateValue("name")
 		default:
 			return err
 		}
 	}
-	return nil
+	if err := audit.Record(ctx, tx, "breeds", int64(b.ID), audit.ActionInsert, nil, b); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Update updates an existing cattle breed in the database.
-func (m *BreedModel) Update(b *Breed) error {
+func (m *BreedModel) Update(ctx context.Context, b *Breed) error {
 	query := `
-		UPDATE cattle_breeds
-		SET name = $1, description = $2, is_active = $3, updated_at = NOW()
-		WHERE id = $4
-		RETURNING updated_at`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		UPDATE breeds
+		SET name = $1, description = $2, is_active = COALESCE($3, is_active), updated_at = NOW(), version = version + 1
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+		RETURNING updated_at, version`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, b.Name, b.Description, b.IsActive, b.ID).Scan(&b.UpdatedAt)
+	tx, err := database.Begin(ctx, m.DB)
 	if err != nil {
-		switch {
-		case errors.IsUniqueViolation(err, "cattle_breeds_name_key"):
-			return errors.ErrDuplicateValue("name")
-		case errors.IsEditConflict(err):
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &BreedModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, b.ID)
+	if err != nil {
+		if errors.IsRecordNotFound(err) {
 			return errors.ErrEditConflict
-		default:
-			return err
 		}
+		return err
 	}
-	return nil
-}
-
-// Delete Permanently deletes a cattle breed from the database.
-func (m *BreedModel) Delete(id int) error {
-	query := `
-		DELETE FROM cattle_breeds
-		WHERE id = $1
-	`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	err = tx.QueryRowContext(ctx, query, b.Name, b.Description, b.IsActive, b.ID, b.Version).Scan(&b.UpdatedAt, &b.Version)
 	if err != nil {
 		switch {
-		case errors.IsForeignKeyViolation(err):
-			return errors.ErrForeignKeyViolation
+		case errors.IsUniqueViolation(err, "name"):
+			return errors.ErrDuplicateValue("name")
+		case errors.IsEditConflict(err):
+			return errors.ErrEditConflict
 		default:
 			return err
 		}
 	}
-	rowsAffected, err := result.RowsAffected()
+
+	after, err := current.GetByID(ctx, b.ID)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return errors.ErrRecordNotFound
+	if err := audit.Record(ctx, tx, "breeds", int64(b.ID), audit.ActionUpdate, before, after); err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
+}
+
+// SoftDelete marks a cattle breed as deleted by deletedBy. Cattle of the breed keep it until it is
+// purged.
+func (m *BreedModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.SoftDelete(ctx, m.DB, "breeds", int64(id), deletedBy)
+}
+
+// Restore brings a soft deleted cattle breed back.
+func (m *BreedModel) Restore(ctx context.Context, id int) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Restore(ctx, m.DB, "breeds", int64(id))
+}
+
+// Purge permanently deletes the cattle breeds soft deleted before the cutoff. Breeds still recorded
+// on any animal are kept.
+func (m *BreedModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	guard := `
+		NOT EXISTS (SELECT 1 FROM cattle AS c WHERE c.breed_id = breeds.id) AND
+		NOT EXISTS (SELECT 1 FROM cattle_breed_composition AS cb WHERE cb.breed_id = breeds.id)`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Purge(ctx, m.DB, "breeds", before, guard)
 }
 
 // GetByField retrieves a cattle breed by a specified field and value.
-func (m *BreedModel) GetByID(id int) (*Breed, error) {
+func (m *BreedModel) GetByID(ctx context.Context, id int) (*Breed, error) {
 	query := `
-		SELECT id, name, description, is_active, created_at, updated_at
-		FROM cattle_breeds
+		SELECT id, name, COALESCE(description, ''), is_active, version, deleted_at, deleted_by, created_at, updated_at
+		FROM breeds
 		WHERE id = $1
 	`
 	var b Breed
@@ -135,17 +194,18 @@ func (m *BreedModel) GetByID(id int) (*Breed, error) {
 		&b.Name,
 		&b.Description,
 		&b.IsActive,
+		&b.Version,
+		&b.DeletedAt,
+		&b.DeletedBy,
 		&b.CreatedAt,
 		&b.UpdatedAt,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(scan...)
 	if err != nil {
 		switch {
-		case errors.IsEditConflict(err):
-			return nil, errors.ErrEditConflict
 		case errors.ErrNoRows(err):
 			return nil, errors.ErrRecordNotFound
 		default:
@@ -156,22 +216,28 @@ func (m *BreedModel) GetByID(id int) (*Breed, error) {
 }
 
 // GetAll retrieves all cattle breeds from the database.
-func (m *BreedModel) GetAll(filter *BreedFilter) (Breeds, filters.MetaData, error) {
+func (m *BreedModel) GetAll(ctx context.Context, filter *BreedFilter) (Breeds, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 6)
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, name, description, is_active, created_at, updated_at
-		FROM cattle_breeds
+		SELECT %s, %s, id, name, COALESCE(description, ''), is_active, version, deleted_at, deleted_by, created_at, updated_at
+		FROM breeds
 		WHERE ($1 = '' OR LOWER(name) LIKE LOWER('%%' || $1 || '%%'))
 		AND ($2::boolean IS NULL OR is_active = $2)
-		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filter.Default.SortColumn(), filter.Default.SortDirection())
+		AND ($5 OR deleted_at IS NULL)
+		AND %s
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, page.Count, page.SortKey, page.Where, page.OrderBy)
 
 	args := []any{
 		filter.Name,
 		filter.IsActive,
 		filter.Default.Limit(),
 		filter.Default.Offset(),
+		filter.WithDeleted,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -182,15 +248,21 @@ func (m *BreedModel) GetAll(filter *BreedFilter) (Breeds, filters.MetaData, erro
 
 	totalRecords := 0
 	breeds := Breeds{}
+	keys := []filters.Key{}
 	for rows.Next() {
 		var count int
 		var b Breed
+		var key filters.Key
 		scan := []any{
 			&count,
+			&key.Values,
 			&b.ID,
 			&b.Name,
 			&b.Description,
 			&b.IsActive,
+			&b.Version,
+			&b.DeletedAt,
+			&b.DeletedBy,
 			&b.CreatedAt,
 			&b.UpdatedAt,
 		}
@@ -199,12 +271,14 @@ func (m *BreedModel) GetAll(filter *BreedFilter) (Breeds, filters.MetaData, erro
 			return nil, filters.EmptyMetaData, err
 		}
 		totalRecords = count
+		key.ID = int64(b.ID)
 		breeds = append(breeds, b)
+		keys = append(keys, key)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, filters.EmptyMetaData, err
 	}
 
-	metaData := filters.CalculateMetaData(totalRecords, filter.Default.Page, filter.Default.PageSize)
+	breeds, metaData := filters.Paginate(filter.Default, breeds, keys, totalRecords)
 	return breeds, metaData, nil
 }
@@ -0,0 +1,236 @@
+// File: internal/data/cattle/breeds_test.go
+package cattle_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestBreedCRUD(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	b := f.Breed(func(b *cattle.Breed) { b.Description = "" })
+	got, err := models.Breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != b.Name || got.Description != "" || !*got.IsActive {
+		t.Errorf("got %+v, want %+v", got, b)
+	}
+
+	duplicate := &cattle.Breed{Name: b.Name}
+	if err := models.Breeds.Insert(ctx, duplicate); err == nil || err.Error() != internalErrors.ErrDuplicateValue("name").Error() {
+		t.Errorf("Insert error = %v, want duplicate name", err)
+	}
+
+	got.Name = "Renamed"
+	got.IsActive = testdb.Ptr(false)
+	if err := models.Breeds.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Renamed" || *got.IsActive || got.Version != 2 {
+		t.Errorf("got %+v, want the update applied", got)
+	}
+
+	missing := &cattle.Breed{ID: 404, Name: "Missing"}
+	if err := models.Breeds.Update(ctx, missing); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a missing breed error = %v, want ErrEditConflict", err)
+	}
+
+	stale := *got
+	stale.Version = 1
+	if err := models.Breeds.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a stale breed error = %v, want ErrEditConflict", err)
+	}
+}
+
+func TestBreedSoftDelete(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	b := f.Breed()
+	admin := f.User()
+	if err := models.Breeds.SoftDelete(ctx, b.ID, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt == nil || got.DeletedBy == nil || *got.DeletedBy != admin.ID || got.Version != 2 {
+		t.Errorf("got %+v, want it deleted by %d", got, admin.ID)
+	}
+	if err := models.Breeds.Update(ctx, got); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a deleted breed error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Breeds.SoftDelete(ctx, b.ID, admin.ID); !errors.Is(err, internalErrors.ErrAlreadyDeleted) {
+		t.Errorf("second SoftDelete error = %v, want ErrAlreadyDeleted", err)
+	}
+
+	if err := models.Breeds.Restore(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt != nil || got.DeletedBy != nil || got.Version != 3 {
+		t.Errorf("got %+v, want it restored", got)
+	}
+	if err := models.Breeds.Restore(ctx, b.ID); !errors.Is(err, internalErrors.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want ErrNotDeleted", err)
+	}
+
+	if err := models.Breeds.SoftDelete(ctx, 404, 0); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("SoftDelete of a missing breed error = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Breeds.Restore(ctx, 404); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Restore of a missing breed error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestBreedPurge(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	unused := f.Breed()
+	c := f.Cattle()
+	kept := f.Breed()
+	for _, id := range []int{unused.ID, c.BreedID} {
+		if err := models.Breeds.SoftDelete(ctx, id, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := models.Breeds.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge before the deletions = %d, %v, want nothing purged", n, err)
+	}
+	if n, err := models.Breeds.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want 1 purged", n, err)
+	}
+
+	if _, err := models.Breeds.GetByID(ctx, unused.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of the purged breed error = %v, want ErrRecordNotFound", err)
+	}
+	for _, id := range []int{c.BreedID, kept.ID} {
+		if _, err := models.Breeds.GetByID(ctx, id); err != nil {
+			t.Errorf("GetByID(%d) error = %v, want the breed kept", id, err)
+		}
+	}
+}
+
+func TestBreedGetAll(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	angus := f.Breed(func(b *cattle.Breed) { b.Name = "Angus" })
+	brahman := f.Breed(func(b *cattle.Breed) { b.Name = "Brahman" })
+	redPoll := f.Breed(func(b *cattle.Breed) { b.Name = "Red Poll"; b.IsActive = testdb.Ptr(false) })
+	nelore := f.Breed(func(b *cattle.Breed) { b.Name = "Nelore" })
+	if err := models.Breeds.SoftDelete(ctx, nelore.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter cattle.BreedFilter
+		want   []int
+	}{
+		{"by name descending", cattle.BreedFilter{Default: testdb.FirstPage("-name")}, []int{redPoll.ID, brahman.ID, angus.ID}},
+		{"name", cattle.BreedFilter{Name: "BRA", Default: testdb.FirstPage("id")}, []int{brahman.ID}},
+		{"active", cattle.BreedFilter{IsActive: testdb.Ptr(true), Default: testdb.FirstPage("id")}, []int{angus.ID, brahman.ID}},
+		{"deleted left out", cattle.BreedFilter{Name: "Nelore", Default: testdb.FirstPage("id")}, nil},
+		{"with deleted", cattle.BreedFilter{WithDeleted: true, Default: testdb.FirstPage("id")}, []int{angus.ID, brahman.ID, redPoll.ID, nelore.ID}},
+		{"no match", cattle.BreedFilter{Name: "Gir", Default: testdb.FirstPage("id")}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := models.Breeds.GetAll(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, b := range got {
+				ids = append(ids, b.ID)
+			}
+			if !equal(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("total records = %d, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestBreedComposition(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	angus := f.Breed()
+	brahman := f.Breed()
+	c := f.Cattle(func(c *cattle.Cattle) { c.BreedID = angus.ID })
+
+	mix := cattle.Composition{{BreedID: angus.ID, Percentage: 25}, {BreedID: brahman.ID, Percentage: 75}}
+	if err := models.Breeds.SetComposition(ctx, c.ID, mix); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Breeds.GetComposition(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].BreedID != brahman.ID || got[0].Percentage != 75 || got[0].BreedName != brahman.Name {
+		t.Errorf("composition = %+v, want 75%% %s first", got, brahman.Name)
+	}
+
+	animal, err := models.Cattle.GetByID(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.BreedID != brahman.ID {
+		t.Errorf("primary breed = %d, want %d", animal.BreedID, brahman.ID)
+	}
+
+	deleted := f.Cattle()
+	if err := models.Cattle.SoftDelete(ctx, deleted.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		cattleID    int
+		composition cattle.Composition
+		want        error
+	}{
+		"over 100%":      {c.ID, cattle.Composition{{BreedID: angus.ID, Percentage: 60}, {BreedID: brahman.ID, Percentage: 60}}, internalErrors.ErrInvalidComposition},
+		"missing breed":  {c.ID, cattle.Composition{{BreedID: 404, Percentage: 100}}, internalErrors.ErrForeignKeyViolation},
+		"missing cattle": {404, cattle.Purebred(angus.ID), internalErrors.ErrRecordNotFound},
+		"deleted cattle": {deleted.ID, cattle.Purebred(angus.ID), internalErrors.ErrRecordNotFound},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := models.Breeds.SetComposition(ctx, tt.cattleID, tt.composition); !errors.Is(err, tt.want) {
+				t.Errorf("SetComposition error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := models.Breeds.GetComposition(ctx, 404); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetComposition error = %v, want ErrRecordNotFound", err)
+	}
+}
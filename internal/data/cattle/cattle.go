@@ -3,12 +3,16 @@ package cattle
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
 )
 
 /****************************************************************************************
@@ -40,7 +44,11 @@ type Cattle struct {
 	IsPregnant     *bool  `json:"is_pregnant"`
 	IsCastrated    *bool  `json:"is_castrated"`
 	// For Simplicity IsActive is for soft deletion, sold or deceased cattle.
-	IsActive  *bool  `json:"is_active"`
+	IsActive *bool `json:"is_active"`
+	// Composition is the breed make-up of the animal; BreedID is derived from it as the primary breed.
+	Composition Composition `json:"composition"`
+	Version     int         `json:"version"`
+	database.Deletion
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -61,19 +69,93 @@ type CattleFilter struct {
 	CreatedAt   string
 	UpdatedAt   string
 	Default     filters.Filters
+
+	// ContainsBreedID matches crossbreds with at least MinBreedPercentage of the breed (any share if nil).
+	ContainsBreedID    *int
+	MinBreedPercentage *float64
+
+	// Ranges are inclusive, except CreatedUntil which is exclusive as in the audit trail.
+	MinAgeMonths *int
+	MaxAgeMonths *int
+	MinWeightKg  *int
+	MaxWeightKg  *int
+	CreatedSince *time.Time
+	CreatedUntil *time.Time
+
+	// Lists match any of their values; an empty list matches everything.
+	OwnerIDs []int
+	BreedIDs []int
+	Sexes    []Sex
+
+	// WithDeleted includes soft deleted cattle, which are left out by default.
+	WithDeleted bool
 }
 
 // CattleModel represents the model for cattle.
 type CattleModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// CattleRepository is the interface for storing and querying cattle.
+type CattleRepository interface {
+	Insert(ctx context.Context, c *Cattle) error
+	Update(ctx context.Context, c *Cattle) error
+	SoftDelete(ctx context.Context, id int, deletedBy int64) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetByID(ctx context.Context, id int) (*Cattle, error)
+	GetAll(ctx context.Context, filter *CattleFilter) (Cattles, filters.MetaData, error)
+}
+
+// ValidateCattle validates the fields of a Cattle record. An animal without a composition must name
+// its breed, and is recorded as purebred.
+func ValidateCattle(v *validator.Validator, c *Cattle) {
+	v.Check(c.TagNumber != "", "tag_number", "must be provided")
+	v.Check(len(c.TagNumber) <= 50, "tag_number", "must not be more than 50 bytes long")
+	v.Check(c.Sex == Male || c.Sex == Female || c.Sex == Unknown, "sex", "must be male, female or unknown")
+	v.Check(c.AgeMonths >= 0, "age_months", "must not be negative")
+	v.Check(c.WeightKg >= 0, "weight_kg", "must not be negative")
+	v.Check(len(c.Vaccinations) <= 5000, "vaccinations", "must not be more than 5000 bytes long")
+	v.Check(len(c.MedicalHistory) <= 5000, "medical_history", "must not be more than 5000 bytes long")
+	v.Check(c.IsPregnant == nil || !*c.IsPregnant || c.Sex != Male, "is_pregnant", "must not be true for a male")
+	if len(c.Composition) > 0 {
+		ValidateComposition(v, c.Composition)
+	} else {
+		v.Check(c.BreedID > 0, "breed_id", "must be provided and greater than zero")
+	}
+}
+
+// ValidateCattleFilter validates the ranges and lists of a cattle search.
+func ValidateCattleFilter(v *validator.Validator, f *CattleFilter) {
+	filters.ValidateFilters(v, f.Default)
+	v.Check(f.MinAgeMonths == nil || *f.MinAgeMonths >= 0, "min_age_months", "must not be negative")
+	v.Check(f.MinAgeMonths == nil || f.MaxAgeMonths == nil || *f.MaxAgeMonths >= *f.MinAgeMonths, "max_age_months", "must not be less than min_age_months")
+	v.Check(f.MinWeightKg == nil || *f.MinWeightKg >= 0, "min_weight_kg", "must not be negative")
+	v.Check(f.MinWeightKg == nil || f.MaxWeightKg == nil || *f.MaxWeightKg >= *f.MinWeightKg, "max_weight_kg", "must not be less than min_weight_kg")
+	v.Check(f.CreatedSince == nil || f.CreatedUntil == nil || !f.CreatedUntil.Before(*f.CreatedSince), "created_until", "must not be before created_since")
+
+	v.Check(len(f.OwnerIDs) <= 100, "owner_ids", "must not contain more than 100 values")
+	v.Check(!slices.ContainsFunc(f.OwnerIDs, func(id int) bool { return id <= 0 }), "owner_ids", "must only contain ids greater than zero")
+	v.Check(len(f.BreedIDs) <= 100, "breed_ids", "must not contain more than 100 values")
+	v.Check(!slices.ContainsFunc(f.BreedIDs, func(id int) bool { return id <= 0 }), "breed_ids", "must only contain ids greater than zero")
+	for _, sex := range f.Sexes {
+		v.Check(sex == Male || sex == Female || sex == Unknown, "sexes", "must only contain male, female or unknown")
+	}
 }
 
 /****************************************************************************************
  *										Methods											*
  ***************************************************************************************/
 
-// Insert inserts a new cattle record into the database.
-func (m *CattleModel) Insert(c *Cattle) error {
+// Insert inserts a new cattle record into the database together with its breed composition.
+// An animal without a composition is recorded as purebred in BreedID.
+func (m *CattleModel) Insert(ctx context.Context, c *Cattle) error {
+	if len(c.Composition) == 0 {
+		c.Composition = Purebred(c.BreedID)
+	}
+	c.BreedID = c.Composition.PrimaryBreed()
+
 	query := `
 		INSERT INTO cattle (
 			owner_id, breed_id, tag_number, sex, age_months, weight_kg,
@@ -82,21 +164,27 @@ func (m *CattleModel) Insert(c *Cattle) error {
 		)
 		VALUES (
 			$1, $2, $3, $4, $5, $6,
-			$7, $8, $9, $10, $11,
+			$7, $8, $9, $10, COALESCE($11, TRUE),
 			NOW(), NOW()
 		)
-		RETURNING id, created_at, updated_at
+		RETURNING id, is_active, version, created_at, updated_at
 	`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query,
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query,
 		c.OwnerID, c.BreedID, c.TagNumber, c.Sex, c.AgeMonths, c.WeightKg,
 		c.Vaccinations, c.MedicalHistory, c.IsPregnant, c.IsCastrated, c.IsActive,
-	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	).Scan(&c.ID, &c.IsActive, &c.Version, &c.CreatedAt, &c.UpdatedAt)
 	if err != nil {
 		switch {
-		case errors.IsUniqueViolation(err, "cattle_tag_number_key"):
+		case errors.IsUniqueViolation(err, "tag_number"):
 			return errors.ErrDuplicateValue("tag_number")
 		case errors.IsForeignKeyViolation(err):
 			return errors.ErrForeignKeyViolation
@@ -104,11 +192,27 @@ func (m *CattleModel) Insert(c *Cattle) error {
 			return err
 		}
 	}
+
+	if err := replaceComposition(ctx, tx, c.ID, c.Composition); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "cattle", int64(c.ID), audit.ActionInsert, nil, c); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return wrapCompositionError(err)
+	}
 	return nil
 }
 
-// Update updates an existing cattle record in the database.
-func (m *CattleModel) Update(c *Cattle) error {
+// Update updates an existing cattle record and its breed composition in the database.
+// An animal without a composition is recorded as purebred in BreedID.
+func (m *CattleModel) Update(ctx context.Context, c *Cattle) error {
+	if len(c.Composition) == 0 {
+		c.Composition = Purebred(c.BreedID)
+	}
+	c.BreedID = c.Composition.PrimaryBreed()
+
 	query := `
 		UPDATE cattle
 		SET
@@ -122,22 +226,38 @@ func (m *CattleModel) Update(c *Cattle) error {
 			medical_history = $8,
 			is_pregnant = $9,
 			is_castrated = $10,
-			is_active = $11,
-			updated_at = NOW()
-		WHERE id = $12
-		RETURNING updated_at
+			is_active = COALESCE($11, is_active),
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $12 AND version = $13 AND deleted_at IS NULL
+		RETURNING updated_at, version
 	`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query,
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &CattleModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, c.ID)
+	if err != nil {
+		if errors.IsRecordNotFound(err) {
+			return errors.ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query,
 		c.OwnerID, c.BreedID, c.TagNumber, c.Sex, c.AgeMonths, c.WeightKg,
 		c.Vaccinations, c.MedicalHistory, c.IsPregnant, c.IsCastrated, c.IsActive,
-		c.ID,
-	).Scan(&c.UpdatedAt)
+		c.ID, c.Version,
+	).Scan(&c.UpdatedAt, &c.Version)
 	if err != nil {
 		switch {
-		case errors.IsUniqueViolation(err, "cattle_tag_number_key"):
+		case errors.IsUniqueViolation(err, "tag_number"):
 			return errors.ErrDuplicateValue("tag_number")
 		case errors.IsEditConflict(err):
 			return errors.ErrEditConflict
@@ -147,58 +267,73 @@ func (m *CattleModel) Update(c *Cattle) error {
 			return err
 		}
 	}
-	return nil
-}
 
-// Delete permanently deletes a cattle record from the database.
-func (m *CattleModel) Delete(id int) error {
-	query := `DELETE FROM cattle WHERE id = $1`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	result, err := m.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		switch {
-		case errors.IsForeignKeyViolation(err):
-			return errors.ErrForeignKeyViolation
-		default:
-			return err
-		}
+	if err := replaceComposition(ctx, tx, c.ID, c.Composition); err != nil {
+		return err
 	}
-	rowsAffected, err := result.RowsAffected()
+	after, err := current.GetByID(ctx, c.ID)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return errors.ErrRecordNotFound
+	if err := audit.Record(ctx, tx, "cattle", int64(c.ID), audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return wrapCompositionError(err)
 	}
 	return nil
 }
 
+// SoftDelete marks a cattle record as deleted by deletedBy. The animal keeps its composition and
+// listings until it is purged.
+func (m *CattleModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.SoftDelete(ctx, m.DB, "cattle", int64(id), deletedBy)
+}
+
+// Restore brings a soft deleted cattle record back.
+func (m *CattleModel) Restore(ctx context.Context, id int) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Restore(ctx, m.DB, "cattle", int64(id))
+}
+
+// Purge permanently deletes the cattle soft deleted before the cutoff, together with their
+// composition and listing entries.
+func (m *CattleModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Purge(ctx, m.DB, "cattle", before, "")
+}
+
 // GetByID retrieves a cattle record by its ID.
-func (m *CattleModel) GetByID(id int) (*Cattle, error) {
+func (m *CattleModel) GetByID(ctx context.Context, id int) (*Cattle, error) {
 	query := `
 		SELECT
-			id, owner_id, breed_id, tag_number, sex, age_months, weight_kg,
-			vaccinations, medical_history, is_pregnant, is_castrated, is_active,
-			created_at, updated_at
-		FROM cattle
-		WHERE id = $1
+			c.id, c.owner_id, c.breed_id, c.tag_number, c.sex, c.age_months, c.weight_kg,
+			c.vaccinations, c.medical_history, c.is_pregnant, c.is_castrated, c.is_active,
+			` + compositionSelect + `,
+			c.version, c.deleted_at, c.deleted_by, c.created_at, c.updated_at
+		FROM cattle AS c
+		WHERE c.id = $1
 	`
 	var c Cattle
 	scan := []any{
 		&c.ID, &c.OwnerID, &c.BreedID, &c.TagNumber, &c.Sex, &c.AgeMonths, &c.WeightKg,
 		&c.Vaccinations, &c.MedicalHistory, &c.IsPregnant, &c.IsCastrated, &c.IsActive,
-		&c.CreatedAt, &c.UpdatedAt,
+		&c.Composition,
+		&c.Version, &c.DeletedAt, &c.DeletedBy, &c.CreatedAt, &c.UpdatedAt,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(scan...)
 	if err != nil {
 		switch {
-		case errors.IsEditConflict(err):
-			return nil, errors.ErrEditConflict
 		case errors.ErrNoRows(err):
 			return nil, errors.ErrRecordNotFound
 		default:
@@ -209,25 +344,43 @@ func (m *CattleModel) GetByID(id int) (*Cattle, error) {
 }
 
 // GetAll retrieves all cattle records from the database with optional filtering.
-func (m *CattleModel) GetAll(filter *CattleFilter) (Cattles, filters.MetaData, error) {
+func (m *CattleModel) GetAll(ctx context.Context, filter *CattleFilter) (Cattles, filters.MetaData, error) {
+	page := filter.Default.SQL(func(name string) string { return "c." + name }, "c.id", 24)
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(),
-			id, owner_id, breed_id, tag_number, sex, age_months, weight_kg,
-			vaccinations, medical_history, is_pregnant, is_castrated, is_active,
-			created_at, updated_at
-		FROM cattle
+		SELECT %s, %s,
+			c.id, c.owner_id, c.breed_id, c.tag_number, c.sex, c.age_months, c.weight_kg,
+			c.vaccinations, c.medical_history, c.is_pregnant, c.is_castrated, c.is_active,
+			`+compositionSelect+`,
+			c.version, c.deleted_at, c.deleted_by, c.created_at, c.updated_at
+		FROM cattle AS c
 		WHERE
-			($1::int IS NULL OR owner_id = $1) AND
-			($2::int IS NULL OR breed_id = $2) AND
-			($3::text IS NULL OR LOWER(tag_number) LIKE LOWER('%%' || $3 || '%%')) AND
-			($4::text IS NULL OR sex = $4) AND
-			($5::int IS NULL OR age_months = $5) AND
-			($6::int IS NULL OR weight_kg = $6) AND
-			($7::boolean IS NULL OR is_pregnant = $7) AND
-			($8::boolean IS NULL OR is_castrated = $8) AND
-			($9::boolean IS NULL OR is_active = $9)
-		ORDER BY %s %s, id ASC
-		LIMIT $10 OFFSET $11`, filter.Default.SortColumn(), filter.Default.SortDirection())
+			($1::int IS NULL OR c.owner_id = $1) AND
+			($2::int IS NULL OR c.breed_id = $2) AND
+			($3::text IS NULL OR LOWER(c.tag_number) LIKE LOWER('%%' || $3 || '%%')) AND
+			($4::text IS NULL OR c.sex::text = $4) AND
+			($5::int IS NULL OR c.age_months = $5) AND
+			($6::int IS NULL OR c.weight_kg = $6) AND
+			($7::boolean IS NULL OR c.is_pregnant = $7) AND
+			($8::boolean IS NULL OR c.is_castrated = $8) AND
+			($9::boolean IS NULL OR c.is_active = $9) AND
+			($12::bigint IS NULL OR EXISTS (
+				SELECT 1 FROM cattle_breed_composition AS cb
+				WHERE cb.cattle_id = c.id AND cb.breed_id = $12
+				AND ($13::numeric IS NULL OR cb.percentage >= $13)
+			)) AND
+			($14 OR c.deleted_at IS NULL) AND
+			($15::int IS NULL OR c.age_months >= $15) AND
+			($16::int IS NULL OR c.age_months <= $16) AND
+			($17::int IS NULL OR c.weight_kg >= $17) AND
+			($18::int IS NULL OR c.weight_kg <= $18) AND
+			($19::timestamptz IS NULL OR c.created_at >= $19) AND
+			($20::timestamptz IS NULL OR c.created_at < $20) AND
+			(COALESCE(cardinality($21::int[]), 0) = 0 OR c.owner_id = ANY($21)) AND
+			(COALESCE(cardinality($22::int[]), 0) = 0 OR c.breed_id = ANY($22)) AND
+			(COALESCE(cardinality($23::text[]), 0) = 0 OR c.sex::text = ANY($23)) AND
+			%s
+		ORDER BY %s
+		LIMIT $10 OFFSET $11`, page.Count, page.SortKey, page.Where, page.OrderBy)
 
 	args := []any{
 		filter.OwnerID,
@@ -241,8 +394,22 @@ func (m *CattleModel) GetAll(filter *CattleFilter) (Cattles, filters.MetaData, e
 		filter.IsActive,
 		filter.Default.Limit(),
 		filter.Default.Offset(),
+		filter.ContainsBreedID,
+		filter.MinBreedPercentage,
+		filter.WithDeleted,
+		filter.MinAgeMonths,
+		filter.MaxAgeMonths,
+		filter.MinWeightKg,
+		filter.MaxWeightKg,
+		filter.CreatedSince,
+		filter.CreatedUntil,
+		pq.Array(filter.OwnerIDs),
+		pq.Array(filter.BreedIDs),
+		pq.Array(filter.Sexes),
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -253,23 +420,29 @@ func (m *CattleModel) GetAll(filter *CattleFilter) (Cattles, filters.MetaData, e
 
 	totalRecords := 0
 	cattles := Cattles{}
+	keys := []filters.Key{}
 	for rows.Next() {
 		var c Cattle
+		var key filters.Key
 		scan := []any{
+			&totalRecords, &key.Values,
 			&c.ID, &c.OwnerID, &c.BreedID, &c.TagNumber, &c.Sex, &c.AgeMonths, &c.WeightKg,
 			&c.Vaccinations, &c.MedicalHistory, &c.IsPregnant, &c.IsCastrated, &c.IsActive,
-			&c.CreatedAt, &c.UpdatedAt,
+			&c.Composition,
+			&c.Version, &c.DeletedAt, &c.DeletedBy, &c.CreatedAt, &c.UpdatedAt,
 		}
 		err := rows.Scan(scan...)
 		if err != nil {
 			return nil, filters.EmptyMetaData, err
 		}
+		key.ID = int64(c.ID)
 		cattles = append(cattles, c)
+		keys = append(keys, key)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, filters.EmptyMetaData, err
 	}
 
-	metaData := filters.CalculateMetaData(totalRecords, filter.Default.Page, filter.Default.PageSize)
+	cattles, metaData := filters.Paginate(filter.Default, cattles, keys, totalRecords)
 	return cattles, metaData, nil
 }
@@ -0,0 +1,309 @@
+// File: internal/data/cattle/cattle_test.go
+package cattle_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestCattleInsertAndGet(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+
+	want := f.Cattle(func(c *cattle.Cattle) { c.Vaccinations = "anthrax" })
+	if want.ID == 0 || want.IsActive == nil || !*want.IsActive {
+		t.Fatalf("Insert did not return the id and default flags: %+v", want)
+	}
+
+	got, err := models.Cattle.GetByID(t.Context(), want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TagNumber != want.TagNumber || got.Sex != want.Sex || got.WeightKg != want.WeightKg || got.Vaccinations != "anthrax" {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got.BreedID != want.BreedID || len(got.Composition) != 1 || got.Composition[0].Percentage != 100 {
+		t.Errorf("composition = %+v, want purebred %d", got.Composition, want.BreedID)
+	}
+}
+
+func TestCattleInsertErrors(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	existing := f.Cattle()
+
+	tests := map[string]struct {
+		cattle cattle.Cattle
+		want   error
+	}{
+		"duplicate tag": {
+			cattle: cattle.Cattle{OwnerID: existing.OwnerID, BreedID: existing.BreedID, TagNumber: existing.TagNumber, Sex: cattle.Male},
+			want:   internalErrors.ErrDuplicateValue("tag_number"),
+		},
+		"missing owner": {
+			cattle: cattle.Cattle{OwnerID: 404, BreedID: existing.BreedID, TagNumber: "TAG-OWNER", Sex: cattle.Male},
+			want:   internalErrors.ErrForeignKeyViolation,
+		},
+		"missing breed": {
+			cattle: cattle.Cattle{OwnerID: existing.OwnerID, BreedID: 404, TagNumber: "TAG-BREED", Sex: cattle.Male},
+			want:   internalErrors.ErrForeignKeyViolation,
+		},
+		"composition under 100%": {
+			cattle: cattle.Cattle{
+				OwnerID: existing.OwnerID, TagNumber: "TAG-MIX", Sex: cattle.Male,
+				Composition: cattle.Composition{{BreedID: existing.BreedID, Percentage: 60}},
+			},
+			want: internalErrors.ErrInvalidComposition,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := models.Cattle.Insert(t.Context(), &tt.cattle)
+			if err == nil || err.Error() != tt.want.Error() {
+				t.Errorf("Insert error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCattleUpdateAndDelete(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	c := f.Cattle()
+	c.WeightKg = 410
+	c.IsPregnant = testdb.Ptr(true)
+	c.IsActive = nil // keeps the stored flag
+	if err := models.Cattle.Update(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Cattle.GetByID(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.WeightKg != 410 || got.IsPregnant == nil || !*got.IsPregnant || !*got.IsActive || got.Version != 2 {
+		t.Errorf("got %+v, want the update applied", got)
+	}
+
+	other := f.Cattle()
+	got.TagNumber = other.TagNumber
+	if err := models.Cattle.Update(ctx, got); err == nil || err.Error() != internalErrors.ErrDuplicateValue("tag_number").Error() {
+		t.Errorf("Update error = %v, want duplicate tag_number", err)
+	}
+
+	missing := *c
+	missing.ID = 404
+	missing.TagNumber = "TAG-MISSING"
+	if err := models.Cattle.Update(ctx, &missing); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a missing animal error = %v, want ErrEditConflict", err)
+	}
+
+	stale := *got
+	stale.Version = 1
+	if err := models.Cattle.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a stale animal error = %v, want ErrEditConflict", err)
+	}
+
+	if err := models.Cattle.SoftDelete(ctx, c.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Cattle.GetByID(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt == nil || got.DeletedBy != nil || len(got.Composition) == 0 {
+		t.Errorf("got %+v, want it deleted by nobody with its composition kept", got)
+	}
+	if err := models.Cattle.Update(ctx, got); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a deleted animal error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Cattle.SoftDelete(ctx, c.ID, 0); !errors.Is(err, internalErrors.ErrAlreadyDeleted) {
+		t.Errorf("second SoftDelete error = %v, want ErrAlreadyDeleted", err)
+	}
+	if err := models.Cattle.Restore(ctx, c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Cattle.Restore(ctx, c.ID); !errors.Is(err, internalErrors.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want ErrNotDeleted", err)
+	}
+
+	if err := models.Cattle.SoftDelete(ctx, c.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := models.Cattle.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want 1 purged", n, err)
+	}
+	if _, err := models.Cattle.GetByID(ctx, c.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID error = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Cattle.SoftDelete(ctx, c.ID, 0); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("SoftDelete of a purged animal error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestCattleGetAll(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	owner := f.User()
+	brahman := f.Breed(func(b *cattle.Breed) { b.Name = "Brahman" })
+	angus := f.Breed(func(b *cattle.Breed) { b.Name = "Angus" })
+
+	cow := f.Cattle(func(c *cattle.Cattle) {
+		c.OwnerID, c.BreedID, c.TagNumber, c.AgeMonths, c.WeightKg = int(owner.ID), brahman.ID, "BZ-COW", 48, 450
+		c.IsPregnant = testdb.Ptr(true)
+	})
+	bull := f.Cattle(func(c *cattle.Cattle) {
+		c.OwnerID, c.TagNumber, c.Sex, c.AgeMonths, c.WeightKg = int(owner.ID), "BZ-BULL", cattle.Male, 36, 600
+		c.Composition = cattle.Composition{{BreedID: brahman.ID, Percentage: 50}, {BreedID: angus.ID, Percentage: 50}}
+	})
+	steer := f.Cattle(func(c *cattle.Cattle) {
+		c.BreedID, c.TagNumber, c.Sex, c.AgeMonths, c.WeightKg = angus.ID, "OW-STEER", cattle.Male, 20, 300
+		c.IsCastrated = testdb.Ptr(true)
+		c.IsActive = testdb.Ptr(false)
+	})
+	sold := f.Cattle(func(c *cattle.Cattle) { c.OwnerID, c.BreedID, c.TagNumber = int(owner.ID), brahman.ID, "BZ-SOLD" })
+	if err := models.Cattle.SoftDelete(ctx, sold.ID, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter cattle.CattleFilter
+		want   []int
+	}{
+		{"all by id", cattle.CattleFilter{Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID, steer.ID}},
+		{"heaviest first", cattle.CattleFilter{Default: testdb.FirstPage("-weight_kg")}, []int{bull.ID, cow.ID, steer.ID}},
+		{"youngest first", cattle.CattleFilter{Default: testdb.FirstPage("age_months")}, []int{steer.ID, bull.ID, cow.ID}},
+		{"castrated then lightest", cattle.CattleFilter{Default: testdb.FirstPage("is_castrated,weight_kg")}, []int{steer.ID, cow.ID, bull.ID}},
+		{"uncastrated then heaviest", cattle.CattleFilter{Default: testdb.FirstPage("-is_castrated,-weight_kg")}, []int{bull.ID, cow.ID, steer.ID}},
+		{"owner", cattle.CattleFilter{OwnerID: testdb.Ptr(int(owner.ID)), Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID}},
+		{"primary breed", cattle.CattleFilter{BreedID: testdb.Ptr(angus.ID), Default: testdb.FirstPage("id")}, []int{steer.ID}},
+		{"tag number", cattle.CattleFilter{TagNumber: "bz-", Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID}},
+		{"sex", cattle.CattleFilter{Sex: testdb.Ptr(cattle.Male), Default: testdb.FirstPage("id")}, []int{bull.ID, steer.ID}},
+		{"age", cattle.CattleFilter{AgeMonths: testdb.Ptr(48), Default: testdb.FirstPage("id")}, []int{cow.ID}},
+		{"weight", cattle.CattleFilter{WeightKg: testdb.Ptr(600), Default: testdb.FirstPage("id")}, []int{bull.ID}},
+		{"pregnant", cattle.CattleFilter{IsPregnant: testdb.Ptr(true), Default: testdb.FirstPage("id")}, []int{cow.ID}},
+		{"castrated", cattle.CattleFilter{IsCastrated: testdb.Ptr(true), Default: testdb.FirstPage("id")}, []int{steer.ID}},
+		{"inactive", cattle.CattleFilter{IsActive: testdb.Ptr(false), Default: testdb.FirstPage("id")}, []int{steer.ID}},
+		{"contains breed", cattle.CattleFilter{ContainsBreedID: testdb.Ptr(angus.ID), Default: testdb.FirstPage("id")}, []int{bull.ID, steer.ID}},
+		{"deleted included", cattle.CattleFilter{OwnerID: testdb.Ptr(int(owner.ID)), WithDeleted: true, Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID, sold.ID}},
+		{"contains breed share", cattle.CattleFilter{ContainsBreedID: testdb.Ptr(angus.ID), MinBreedPercentage: testdb.Ptr(75.0), Default: testdb.FirstPage("id")}, []int{steer.ID}},
+		{"age range", cattle.CattleFilter{MinAgeMonths: testdb.Ptr(24), MaxAgeMonths: testdb.Ptr(40), Default: testdb.FirstPage("id")}, []int{bull.ID}},
+		{"weight range", cattle.CattleFilter{MinWeightKg: testdb.Ptr(400), Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID}},
+		{"created since", cattle.CattleFilter{CreatedSince: testdb.Ptr(time.Now().Add(-time.Hour)), Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID, steer.ID}},
+		{"created until", cattle.CattleFilter{CreatedUntil: testdb.Ptr(time.Now().Add(-time.Hour)), Default: testdb.FirstPage("id")}, nil},
+		{"owners", cattle.CattleFilter{OwnerIDs: []int{int(owner.ID)}, Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID}},
+		{"breeds", cattle.CattleFilter{BreedIDs: []int{brahman.ID, angus.ID}, Default: testdb.FirstPage("id")}, []int{cow.ID, bull.ID, steer.ID}},
+		{"sexes", cattle.CattleFilter{Sexes: []cattle.Sex{cattle.Male}, Default: testdb.FirstPage("id")}, []int{bull.ID, steer.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := models.Cattle.GetAll(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, c := range got {
+				ids = append(ids, c.ID)
+			}
+			if !equal(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("total records = %d, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCattleGetAllCursor(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	// Repeated weights and unset flags exercise the id tie breaker and NULL ordering
+	weights := []int{300, 450, 300, 600, 450, 300, 520}
+	for i, w := range weights {
+		f.Cattle(func(c *cattle.Cattle) {
+			c.WeightKg = w
+			if i%3 != 0 {
+				c.IsPregnant = testdb.Ptr(i%2 == 0)
+			}
+		})
+	}
+
+	for _, sort := range []string{"id", "weight_kg", "-weight_kg", "is_pregnant", "-is_pregnant", "-created_at", "is_pregnant,-weight_kg", "-weight_kg,-is_pregnant"} {
+		t.Run(sort, func(t *testing.T) {
+			all, _, err := models.Cattle.GetAll(ctx, &cattle.CattleFilter{Default: testdb.FirstPage(sort)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []int
+			for _, c := range all {
+				want = append(want, c.ID)
+			}
+
+			// Walk forwards from the first page, then back again from the last
+			filter := cattle.CattleFilter{Default: testdb.FirstPage(sort)}
+			filter.Default.PageSize = 3
+			var forward, backward []int
+			for {
+				page, metadata, err := models.Cattle.GetAll(ctx, &filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				backward = nil
+				for _, c := range page {
+					forward = append(forward, c.ID)
+					backward = append(backward, c.ID)
+				}
+				if metadata.NextCursor == "" {
+					filter.Default.Cursor = metadata.PrevCursor
+					break
+				}
+				filter.Default.Cursor = metadata.NextCursor
+			}
+			for filter.Default.Cursor != "" {
+				page, metadata, err := models.Cattle.GetAll(ctx, &filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []int
+				for _, c := range page {
+					ids = append(ids, c.ID)
+				}
+				backward = append(ids, backward...)
+				filter.Default.Cursor = metadata.PrevCursor
+			}
+
+			if !equal(forward, want) {
+				t.Errorf("forwards got %v, want %v", forward, want)
+			}
+			if !equal(backward, want) {
+				t.Errorf("backwards got %v, want %v", backward, want)
+			}
+		})
+	}
+}
+
+// equal reports whether two id lists hold the same ids in the same order.
+func equal(got, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+// File: internal/data/cattle/composition.go
+package cattle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// MaxCompositionBreeds caps how many breeds a single animal can be recorded as.
+const MaxCompositionBreeds = 8
+
+// BreedShare is the percentage of one breed in an animal's make-up.
+type BreedShare struct {
+	BreedID    int     `json:"breed_id"`
+	BreedName  string  `json:"breed_name,omitempty"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Composition is the full breed make-up of an animal, largest share first.
+type Composition []BreedShare
+
+// Scan decodes the JSON array built by compositionSelect.
+func (c *Composition) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*c = Composition{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into Composition", src)
+	}
+}
+
+// compositionSelect is a correlated sub-select returning the composition of cattle row "c" as a JSON array.
+const compositionSelect = `
+	COALESCE((
+		SELECT json_agg(json_build_object('breed_id', cb.breed_id, 'breed_name', b.name, 'percentage', cb.percentage)
+			ORDER BY cb.percentage DESC, cb.breed_id ASC)
+		FROM cattle_breed_composition AS cb
+		INNER JOIN breeds AS b ON b.id = cb.breed_id
+		WHERE cb.cattle_id = c.id
+	), '[]')`
+
+// ValidateComposition checks that every share is valid and that the shares add up to 100%.
+func ValidateComposition(v *validator.Validator, composition Composition) {
+	v.Check(len(composition) > 0, "composition", "must contain at least one breed")
+	v.Check(len(composition) <= MaxCompositionBreeds, "composition", fmt.Sprintf("must not contain more than %d breeds", MaxCompositionBreeds))
+
+	seen := make(map[int]bool, len(composition))
+	total := 0.0
+	for _, share := range composition {
+		v.Check(share.BreedID > 0, "composition", "breed_id must be greater than zero")
+		v.Check(!seen[share.BreedID], "composition", "must not list a breed more than once")
+		v.Check(share.Percentage > 0 && share.Percentage <= 100, "composition", "percentage must be greater than 0 and at most 100")
+		v.Check(hundredths(share.Percentage), "composition", "percentage must have at most two decimal places")
+		seen[share.BreedID] = true
+		total += share.Percentage
+	}
+	v.Check(len(composition) == 0 || math.Abs(total-100) < 0.005, "composition", "percentages must add up to 100")
+}
+
+// PrimaryBreed returns the breed with the largest share, preferring the lowest breed ID on ties.
+func (c Composition) PrimaryBreed() int {
+	primary := BreedShare{}
+	for _, share := range c {
+		if share.Percentage > primary.Percentage || (share.Percentage == primary.Percentage && share.BreedID < primary.BreedID) {
+			primary = share
+		}
+	}
+	return primary.BreedID
+}
+
+// Equal reports whether two compositions record the same share of the same breeds. Breed names
+// and the order of the shares are ignored, as neither is stored.
+func (c Composition) Equal(other Composition) bool {
+	if len(c) != len(other) {
+		return false
+	}
+	shares := make(map[int]float64, len(c))
+	for _, share := range c {
+		shares[share.BreedID] = math.Round(share.Percentage * 100)
+	}
+	for _, share := range other {
+		p, ok := shares[share.BreedID]
+		if !ok || p != math.Round(share.Percentage*100) {
+			return false
+		}
+		delete(shares, share.BreedID)
+	}
+	return true
+}
+
+// Purebred returns the composition of an animal that is 100% of one breed.
+func Purebred(breedID int) Composition {
+	return Composition{{BreedID: breedID, Percentage: 100}}
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// GetComposition retrieves the breed make-up of an animal.
+func (m *BreedModel) GetComposition(ctx context.Context, cattleID int) (Composition, error) {
+	query := `SELECT ` + compositionSelect + ` FROM cattle AS c WHERE c.id = $1`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var composition Composition
+	err := m.DB.QueryRowContext(ctx, query, cattleID).Scan(&composition)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "Breed composition")
+		}
+	}
+	return composition, nil
+}
+
+// SetComposition replaces the breed make-up of an animal and updates its derived primary breed.
+// Soft deleted cattle are reported as not found.
+func (m *BreedModel) SetComposition(ctx context.Context, cattleID int, composition Composition) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Breed composition")
+	}
+	defer tx.Rollback()
+
+	animal := &CattleModel{DB: tx, Timeout: m.Timeout}
+	before, err := animal.GetByID(ctx, cattleID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE cattle SET breed_id = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 AND deleted_at IS NULL`, composition.PrimaryBreed(), cattleID)
+	if err != nil {
+		if errors.IsForeignKeyViolation(err) {
+			return errors.ErrForeignKeyViolation
+		}
+		return errors.WrapUpdateError(err, "Breed composition")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.WrapUpdateError(err, "Breed composition")
+	}
+	if rowsAffected == 0 {
+		return errors.ErrRecordNotFound
+	}
+
+	if err := replaceComposition(ctx, tx, cattleID, composition); err != nil {
+		return err
+	}
+	after, err := animal.GetByID(ctx, cattleID)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "cattle", int64(cattleID), audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wrapCompositionError(err)
+	}
+	return nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// hundredths reports whether p has at most two decimal places. Most such values have no exact
+// binary representation, e.g. 4.35*100 is 434.99999999999994, so p*100 only has to be within a
+// rounding error of a whole number.
+func hundredths(p float64) bool {
+	return math.Abs(p*100-math.Round(p*100)) < 1e-6
+}
+
+// replaceComposition swaps the stored composition of an animal for a new one inside tx.
+func replaceComposition(ctx context.Context, tx database.DBTX, cattleID int, composition Composition) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM cattle_breed_composition WHERE cattle_id = $1`, cattleID)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Breed composition")
+	}
+
+	breedIDs := make([]int64, len(composition))
+	percentages := make([]float64, len(composition))
+	for i, share := range composition {
+		breedIDs[i] = int64(share.BreedID)
+		percentages[i] = share.Percentage
+	}
+
+	query := `
+		INSERT INTO cattle_breed_composition (cattle_id, breed_id, percentage)
+		SELECT $1, breed_id, percentage
+		FROM UNNEST($2::bigint[], $3::numeric[]) AS s(breed_id, percentage)
+	`
+	_, err = tx.ExecContext(ctx, query, cattleID, pq.Array(breedIDs), pq.Array(percentages))
+	if err != nil {
+		return wrapCompositionError(err)
+	}
+	return nil
+}
+
+// wrapCompositionError maps database errors raised by composition writes.
+func wrapCompositionError(err error) error {
+	switch {
+	case errors.IsForeignKeyViolation(err):
+		return errors.ErrForeignKeyViolation
+	case errors.IsCheckViolation(err):
+		return errors.ErrInvalidComposition
+	default:
+		return errors.WrapUpdateError(err, "Breed composition")
+	}
+}
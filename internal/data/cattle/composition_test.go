@@ -0,0 +1,75 @@
+// File: internal/data/cattle/composition_test.go
+package cattle_test
+
+import (
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+func TestValidateComposition(t *testing.T) {
+	// rest completes a share of breed 1 with breed 2 to make up 100%.
+	rest := func(p float64) cattle.Composition {
+		return cattle.Composition{{BreedID: 1, Percentage: p}, {BreedID: 2, Percentage: 100 - p}}
+	}
+
+	tests := []struct {
+		name        string
+		composition cattle.Composition
+		valid       bool
+	}{
+		{"purebred", cattle.Purebred(1), true},
+		{"33.34", rest(33.34), true},
+		{"16.67", rest(16.67), true},
+		{"4.35", rest(4.35), true},
+		{"1.15", rest(1.15), true},
+		{"0.29", rest(0.29), true},
+		{"0.57", rest(0.57), true},
+		{"thirds", cattle.Composition{{BreedID: 1, Percentage: 33.33}, {BreedID: 2, Percentage: 33.33}, {BreedID: 3, Percentage: 33.34}}, true},
+		{"three decimal places", rest(33.333), false},
+		{"short of 100", cattle.Composition{{BreedID: 1, Percentage: 33.33}, {BreedID: 2, Percentage: 33.33}, {BreedID: 3, Percentage: 33.33}}, false},
+		{"empty", cattle.Composition{}, false},
+		{"zero share", cattle.Composition{{BreedID: 1, Percentage: 100}, {BreedID: 2, Percentage: 0}}, false},
+		{"duplicate breed", cattle.Composition{{BreedID: 1, Percentage: 50}, {BreedID: 1, Percentage: 50}}, false},
+		{"missing breed", cattle.Composition{{Percentage: 100}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			cattle.ValidateComposition(v, tt.composition)
+			if v.Valid() != tt.valid {
+				t.Errorf("ValidateComposition(%v) errors = %v, want valid %v", tt.composition, v.Errors, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCompositionEqual(t *testing.T) {
+	stored := cattle.Composition{
+		{BreedID: 1, BreedName: "Brahman", Percentage: 62.5},
+		{BreedID: 2, BreedName: "Nelore", Percentage: 37.5},
+	}
+
+	tests := []struct {
+		name  string
+		other cattle.Composition
+		equal bool
+	}{
+		{"without names", cattle.Composition{{BreedID: 1, Percentage: 62.5}, {BreedID: 2, Percentage: 37.5}}, true},
+		{"reordered", cattle.Composition{{BreedID: 2, Percentage: 37.5}, {BreedID: 1, Percentage: 62.5}}, true},
+		{"rounding error", cattle.Composition{{BreedID: 1, Percentage: 62.500000001}, {BreedID: 2, Percentage: 37.5}}, true},
+		{"other shares", cattle.Composition{{BreedID: 1, Percentage: 50}, {BreedID: 2, Percentage: 50}}, false},
+		{"other breed", cattle.Composition{{BreedID: 1, Percentage: 62.5}, {BreedID: 3, Percentage: 37.5}}, false},
+		{"repeated breed", cattle.Composition{{BreedID: 1, Percentage: 62.5}, {BreedID: 1, Percentage: 62.5}}, false},
+		{"purebred", cattle.Purebred(1), false},
+		{"empty", cattle.Composition{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stored.Equal(tt.other); got != tt.equal {
+				t.Errorf("Equal(%v) = %v, want %v", tt.other, got, tt.equal)
+			}
+		})
+	}
+}
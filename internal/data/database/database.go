@@ -0,0 +1,35 @@
+// File: internal/data/database/database.go
+
+// Package database holds the plumbing shared by every data model.
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout bounds a single model call when no timeout has been configured.
+const DefaultTimeout = 3 * time.Second
+
+// WithTimeout derives the context a model call runs its queries under: it is cancelled when ctx is
+// (for example when the client disconnects) or after timeout, falling back to DefaultTimeout when zero.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Now returns the database's clock. Rows stamped with NOW() carry the time their transaction
+// started on this clock, so windows over them must be measured on it too rather than on the
+// API's own clock.
+func Now(ctx context.Context, db DBTX) (time.Time, error) {
+	ctx, cancel := WithTimeout(ctx, 0)
+	defer cancel()
+
+	var now time.Time
+	if err := db.QueryRowContext(ctx, `SELECT statement_timestamp()`).Scan(&now); err != nil {
+		return time.Time{}, err
+	}
+	return now, nil
+}
@@ -0,0 +1,30 @@
+// File: internal/data/database/lock.go
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TryLock runs fn while holding the session advisory lock key on a dedicated connection, and
+// reports whether it ran. When another session already holds the lock fn is skipped, so of several
+// API instances sharing a database only one runs fn at a time.
+func TryLock(ctx context.Context, db *sql.DB, key int64, fn func() error) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		return false, fmt.Errorf("acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		return false, nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+
+	return true, fn()
+}
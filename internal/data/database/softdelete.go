@@ -0,0 +1,119 @@
+// File: internal/data/database/softdelete.go
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Deletion records when, and by whom, a row was soft deleted. Entities embed it so the
+// deleted_at and deleted_by columns read and marshal the same way everywhere. A soft deleted row
+// keeps its data until it is restored or purged.
+type Deletion struct {
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int64     `json:"deleted_by,omitempty"`
+}
+
+// IsDeleted reports whether the row has been soft deleted.
+func (d Deletion) IsDeleted() bool {
+	return d.DeletedAt != nil
+}
+
+/****************************************************************************************
+ *										Soft Delete										*
+ ***************************************************************************************/
+
+// SoftDelete marks a row of table as deleted by deletedBy, or by nobody when it is zero, and
+// bumps its version. It returns ErrAlreadyDeleted if the row is already deleted and
+// ErrRecordNotFound if there is no such row. table must be a trusted table name.
+func SoftDelete(ctx context.Context, db DBTX, table string, id, deletedBy int64) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET deleted_at = NOW(), deleted_by = NULLIF($2, 0), version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL`, table)
+
+	result, err := db.ExecContext(ctx, query, id, deletedBy)
+	if err != nil {
+		return errors.WrapDeleteError(err, table)
+	}
+	return checkDeletion(ctx, db, table, id, result, errors.ErrAlreadyDeleted)
+}
+
+// Restore clears the deletion of a row of table and bumps its version. It returns ErrNotDeleted
+// if the row is not deleted and ErrRecordNotFound if there is no such row.
+func Restore(ctx context.Context, db DBTX, table string, id int64) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL`, table)
+
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return errors.WrapUpdateError(err, table)
+	}
+	return checkDeletion(ctx, db, table, id, result, errors.ErrNotDeleted)
+}
+
+// Purge permanently deletes the rows of table soft deleted before the cutoff, except those
+// excluded by guard, an optional SQL condition on the table's rows that keeps rows still
+// referenced by live data. It returns the ids of the rows deleted.
+func Purge(ctx context.Context, db DBTX, table string, before time.Time, guard string) ([]int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE deleted_at < $1`, table)
+	if guard != "" {
+		query += ` AND ` + guard
+	}
+	query += ` RETURNING id`
+
+	rows, err := db.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, errors.WrapDeleteError(err, table)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.WrapDeleteError(err, table)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WrapDeleteError(err, table)
+	}
+	return ids, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// checkDeletion turns an update that matched no row into ErrRecordNotFound when the row does not
+// exist, or into stateErr when it exists but was already in the requested state.
+func checkDeletion(ctx context.Context, db DBTX, table string, id int64, result sql.Result, stateErr error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)`, table)
+	if err := db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.ErrRecordNotFound
+	}
+	return stateErr
+}
@@ -0,0 +1,108 @@
+// File: internal/data/database/tx.go
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// MaxTxAttempts is how many times RunInTx tries a transaction that keeps failing with a
+// serialization failure or deadlock.
+const MaxTxAttempts = 3
+
+// DBTX is what models run their queries against: a *sql.DB, or a *sql.Tx when the model is
+// taking part in a larger unit of work.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx is a transaction opened by a model method.
+type Tx interface {
+	DBTX
+	Commit() error
+	Rollback() error
+}
+
+// joinedTx is a model method's view of a transaction it did not open. Commit and Rollback are
+// no-ops: the outer unit of work decides the outcome.
+type joinedTx struct {
+	DBTX
+}
+
+func (joinedTx) Commit() error   { return nil }
+func (joinedTx) Rollback() error { return nil }
+
+/****************************************************************************************
+ *										Transactions									*
+ ***************************************************************************************/
+
+// Begin starts a transaction on db for a model method that needs several statements to be atomic.
+// When db is already a transaction the method joins it instead.
+func Begin(ctx context.Context, db DBTX) (Tx, error) {
+	beginner, ok := db.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return joinedTx{db}, nil
+	}
+	return beginner.BeginTx(ctx, nil)
+}
+
+// RunInTx runs fn inside a transaction, committing if it returns nil and rolling back if it returns
+// an error or panics (the panic is re-raised). Transactions that fail with a serialization failure
+// or deadlock are retried with a short backoff up to MaxTxAttempts times, so fn must be safe to
+// run more than once and should not have side effects outside the database.
+func RunInTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= MaxTxAttempts; attempt++ {
+		err = runOnce(ctx, db, opts, fn)
+		if err == nil || !errors.IsSerializationFailure(err) || attempt == MaxTxAttempts {
+			break
+		}
+
+		backoff := time.Duration(attempt*attempt)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return err
+}
+
+// runOnce makes a single attempt at the transaction.
+func runOnce(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
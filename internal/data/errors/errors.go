@@ -16,7 +16,7 @@ var (
 	ErrRecordNotFound      = errors.New("record not found")
 	ErrEditConflict        = errors.New("edit conflict detected")
 	ErrInvalidCredentials  = errors.New("invalid credentials")
-	ErrAlreadyDeleted      = errors.New("user already deleted")
+	ErrAlreadyDeleted      = errors.New("record already deleted")
 	ErrNoMatch             = errors.New("no matching records found")
 	ErrForeignKeyViolation = errors.New("constraint violation")
 	ErrInvalidUpdateData   = errors.New("invalid update data")
@@ -27,9 +27,18 @@ var (
 	ErrUpdateFailed        = errors.New("update failed: ")
 	ErrDeleteFailed        = errors.New("delete failed: ")
 
-	ErrDuplicateCode     = errors.New("duplicate value for column: code")
-	ErrDuplicateName     = errors.New("duplicate value for column: name")
-	ErrInvalidDistrictID = errors.New("invalid district ID")
+	ErrDuplicateCode      = errors.New("duplicate value for column: code")
+	ErrDuplicateName      = errors.New("duplicate value for column: name")
+	ErrInvalidRegionID    = errors.New("invalid region ID")
+	ErrNotAnImage         = errors.New("media is not an image")
+	ErrImageTooLarge      = errors.New("image dimensions are too large")
+	ErrInvalidComposition = errors.New("breed composition must add up to 100 percent")
+	ErrRegionMismatch     = errors.New("region does not match the area or coordinates")
+	ErrRegionUnknown      = errors.New("region could not be determined")
+	ErrNotDeleted         = errors.New("record is not deleted")
+	ErrAlreadySold        = errors.New("listing already sold")
+	ErrNotDead            = errors.New("job is not dead")
+	ErrEmailQueued        = errors.New("email is already queued")
 )
 
 // isUniqueViolation checks where the error is a unique constraint violation
@@ -54,6 +63,18 @@ func IsForeignKeyViolation(err error) bool {
 	return errors.As(err, &pqErr) && pqErr.Code == "23503"
 }
 
+// for check constraint violations
+func IsCheckViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23514"
+}
+
+// for transactions that lost a serialization race or deadlock and can simply be retried
+func IsSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+}
+
 // WrapInsertError wraps an insert error with additional context
 func WrapInsertError(err error, model string) error {
 	return fmt.Errorf("%s insert failed: %w", model, err)
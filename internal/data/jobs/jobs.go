@@ -0,0 +1,400 @@
+// File: internal/data/jobs/jobs.go
+
+// Package jobs is a durable queue of background work kept in Postgres. Workers claim due jobs with
+// SELECT ... FOR UPDATE SKIP LOCKED, so any number of them can share the queue without running a
+// job twice. A job that fails is retried with exponential backoff until it runs out of attempts,
+// when it is dead lettered to wait for an admin to retry it.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Status is where a job is in its life.
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // waiting for its run_at, or for a worker after it
+	StatusRunning   Status = "running"   // claimed by a worker
+	StatusSucceeded Status = "succeeded" // done
+	StatusDead      Status = "dead"      // failed max_attempts times; only a retry runs it again
+)
+
+// Statuses lists every job status, for filtering the queue.
+var Statuses = []string{string(StatusPending), string(StatusRunning), string(StatusSucceeded), string(StatusDead)}
+
+const (
+	// DefaultMaxAttempts is how many times a job is tried unless it says otherwise.
+	DefaultMaxAttempts = 8
+	// Lease is how long a worker may run a job before it is presumed dead and the job is handed to
+	// another worker. Handlers must finish well within it.
+	Lease = 10 * time.Minute
+	// LeaseExpired is the last error of a job dead lettered because its worker held it past the
+	// Lease on its last attempt.
+	LeaseExpired = "the lease ran out on the last attempt"
+
+	minBackoff = 30 * time.Second
+	maxBackoff = 6 * time.Hour
+)
+
+// Job is a unit of background work of some kind, with its payload as JSON.
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      Status          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error"`
+	LockedAt    *time.Time      `json:"locked_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// JobFilter holds the filters for inspecting the queue.
+type JobFilter struct {
+	Status  string
+	Kind    string
+	Default filters.Filters
+}
+
+// Handler runs a job. An error fails the attempt, and the job is retried after a backoff.
+type Handler func(ctx context.Context, job *Job) error
+
+// JobModel stores the queue in Postgres.
+type JobModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// JobRepository is the interface for queueing and running jobs. Running a job is bookkeeping of
+// the workers, so only retries by an admin are audited.
+type JobRepository interface {
+	Enqueue(ctx context.Context, job *Job) error
+	Claim(ctx context.Context, kinds []string, now time.Time) (*Job, error)
+	Complete(ctx context.Context, job *Job) error
+	Fail(ctx context.Context, job *Job, reason string, retryAt time.Time) error
+	Retry(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*Job, error)
+	GetAll(ctx context.Context, filter *JobFilter) ([]*Job, filters.MetaData, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// New builds a job of the given kind that is due straight away, with payload encoded as JSON.
+func New[T any](kind string, payload T) (*Job, error) {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: encode %s payload: %w", kind, err)
+	}
+	return &Job{Kind: kind, Payload: js}, nil
+}
+
+// Handle adapts a function of a typed payload to a Handler, decoding the payload of each job into a T.
+func Handle[T any](fn func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, job *Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("jobs: decode %s payload: %w", job.Kind, err)
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Backoff is how long to wait before trying a job again after its attempts-th attempt failed:
+// 30 seconds, doubling with each attempt up to 6 hours.
+func Backoff(attempts int) time.Duration {
+	backoff := minBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// ValidateJobFilter validates the status and paging of a queue query.
+func ValidateJobFilter(v *validator.Validator, f *JobFilter) {
+	filters.ValidateFilters(v, f.Default)
+	v.Check(f.Status == "" || v.IsPermitted(f.Status, Statuses...), "status", "must be pending, running, succeeded or dead")
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Enqueue adds a job to the queue, due at its RunAt or straight away when that is zero, and tried
+// up to its MaxAttempts or DefaultMaxAttempts times. Enqueue on a transaction to queue work only
+// if the change that calls for it is committed.
+func (m *JobModel) Enqueue(ctx context.Context, job *Job) error {
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage("{}")
+	}
+
+	query := `
+		INSERT INTO jobs (kind, payload, max_attempts, run_at)
+		VALUES ($1, $2::jsonb, $3, COALESCE($4, NOW()))
+		RETURNING id, status, run_at, created_at, updated_at
+	`
+	var runAt *time.Time
+	if !job.RunAt.IsZero() {
+		runAt = &job.RunAt
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, job.Kind, string(job.Payload), job.MaxAttempts, runAt).
+		Scan(&job.ID, &job.Status, &job.RunAt, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return errors.WrapInsertError(err, "Jobs")
+	}
+	return nil
+}
+
+// Claim hands the worker the job of one of the given kinds that has been due longest, counting it
+// as an attempt. Jobs whose worker has held them longer than the Lease are handed out again, or
+// dead lettered when that was their last attempt. It returns ErrRecordNotFound when nothing is due.
+func (m *JobModel) Claim(ctx context.Context, kinds []string, now time.Time) (*Job, error) {
+	query := `
+		WITH abandoned AS (
+			UPDATE jobs
+			SET status = 'dead', last_error = $4, locked_at = NULL, finished_at = NOW(), updated_at = NOW()
+			WHERE kind = ANY($1::text[]) AND status = 'running' AND locked_at <= $3 AND attempts >= max_attempts
+		)
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_at = $2, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE kind = ANY($1::text[])
+			AND ((status = 'pending' AND run_at <= $2) OR (status = 'running' AND locked_at <= $3 AND attempts < max_attempts))
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, payload, status, attempts, max_attempts, run_at, last_error, locked_at, finished_at, created_at, updated_at`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	job, err := scanJob(m.DB.QueryRowContext(ctx, query, pq.Array(kinds), now, now.Add(-Lease), LeaseExpired))
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapUpdateError(err, "Jobs")
+		}
+	}
+	return job, nil
+}
+
+// Complete records that a claimed job succeeded. It returns ErrEditConflict when the worker lost
+// the job to another after its lease ran out.
+func (m *JobModel) Complete(ctx context.Context, job *Job) error {
+	query := `
+		UPDATE jobs
+		SET status = 'succeeded', locked_at = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_at = $2
+		RETURNING status, finished_at, updated_at`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, job.ID, job.LockedAt).Scan(&job.Status, &job.FinishedAt, &job.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return errors.ErrEditConflict
+		default:
+			return errors.WrapUpdateError(err, "Jobs")
+		}
+	}
+	job.LockedAt = nil
+	return nil
+}
+
+// Fail records that an attempt at a claimed job failed for reason. The job runs again at retryAt,
+// unless that was its last attempt and it is dead lettered. It returns ErrEditConflict when the
+// worker lost the job to another after its lease ran out.
+func (m *JobModel) Fail(ctx context.Context, job *Job, reason string, retryAt time.Time) error {
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END::job_status_enum,
+			run_at = $3, last_error = $4, locked_at = NULL,
+			finished_at = CASE WHEN attempts >= max_attempts THEN NOW() END, updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_at = $2
+		RETURNING status, run_at, finished_at, updated_at`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, job.ID, job.LockedAt, retryAt, reason).
+		Scan(&job.Status, &job.RunAt, &job.FinishedAt, &job.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return errors.ErrEditConflict
+		default:
+			return errors.WrapUpdateError(err, "Jobs")
+		}
+	}
+	job.LastError, job.LockedAt = reason, nil
+	return nil
+}
+
+// Retry puts a dead job back in the queue with a fresh set of attempts, due straight away. Its last
+// error is cleared, and kept only in the audit trail. It returns ErrNotDead for jobs that are not
+// dead.
+func (m *JobModel) Retry(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &JobModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, last_error = '', run_at = NOW(), finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'dead'`, id)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Jobs")
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.ErrNotDead
+	}
+
+	after, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "jobs", id, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByID retrieves a job by its ID.
+func (m *JobModel) GetByID(ctx context.Context, id int64) (*Job, error) {
+	query := `
+		SELECT id, kind, payload, status, attempts, max_attempts, run_at, last_error, locked_at, finished_at, created_at, updated_at
+		FROM jobs
+		WHERE id = $1`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	job, err := scanJob(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "Jobs")
+		}
+	}
+	return job, nil
+}
+
+// GetAll returns a page of the jobs matching filter.
+func (m *JobModel) GetAll(ctx context.Context, filter *JobFilter) ([]*Job, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 5)
+	query := fmt.Sprintf(`
+		SELECT %s, %s, id, kind, payload, status, attempts, max_attempts, run_at, last_error, locked_at, finished_at, created_at, updated_at
+		FROM jobs
+		WHERE ($1 = '' OR status::text = $1)
+		AND ($2 = '' OR kind = $2)
+		AND %s
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, page.Count, page.SortKey, page.Where, page.OrderBy)
+
+	args := []any{filter.Status, filter.Kind, filter.Default.Limit(), filter.Default.Offset()}
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Jobs")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	jobs := []*Job{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var j Job
+		var key filters.Key
+		err := rows.Scan(&totalRecords, &key.Values, &j.ID, &j.Kind, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts,
+			&j.RunAt, &j.LastError, &j.LockedAt, &j.FinishedAt, &j.CreatedAt, &j.UpdatedAt)
+		if err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Jobs")
+		}
+		key.ID = j.ID
+		jobs = append(jobs, &j)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	jobs, metaData := filters.Paginate(filter.Default, jobs, keys, totalRecords)
+	return jobs, metaData, nil
+}
+
+// Purge permanently removes the jobs that succeeded before the cutoff. Dead jobs are kept until
+// they are retried.
+func (m *JobModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM jobs WHERE status = 'succeeded' AND finished_at < $1`, before)
+	if err != nil {
+		return 0, errors.WrapDeleteError(err, "Jobs")
+	}
+	return result.RowsAffected()
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// scanJob reads a job from a row of all its columns in table order.
+func scanJob(row *sql.Row) (*Job, error) {
+	var j Job
+	err := row.Scan(&j.ID, &j.Kind, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt, &j.LastError,
+		&j.LockedAt, &j.FinishedAt, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
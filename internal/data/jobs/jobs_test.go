@@ -0,0 +1,250 @@
+// File: internal/data/jobs/jobs_test.go
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func TestJobHandle(t *testing.T) {
+	job, err := jobs.New("greet", greeting{Name: "Ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got greeting
+	handler := jobs.Handle(func(ctx context.Context, g greeting) error { got = g; return nil })
+	if err := handler(t.Context(), job); err != nil || got.Name != "Ana" {
+		t.Errorf("handler got %+v, %v, want the payload decoded", got, err)
+	}
+
+	job.Payload = []byte(`"not an object"`)
+	if err := handler(t.Context(), job); err == nil {
+		t.Error("handler of an undecodable payload succeeded, want an error")
+	}
+}
+
+func TestJobBackoff(t *testing.T) {
+	tests := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 11: 6 * time.Hour, 50: 6 * time.Hour}
+	for attempts, want := range tests {
+		if got := jobs.Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestJobClaimAndComplete(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	later := &jobs.Job{Kind: "greet", RunAt: time.Now().Add(time.Hour)}
+	due, err := jobs.New("greet", greeting{Name: "Ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &jobs.Job{Kind: "other"}
+	for _, job := range []*jobs.Job{later, due, other} {
+		if err := models.Jobs.Enqueue(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if due.ID == 0 || due.Status != jobs.StatusPending || due.MaxAttempts != jobs.DefaultMaxAttempts {
+		t.Fatalf("enqueued job = %+v, want it pending with the default attempts", due)
+	}
+	now := time.Now()
+
+	claimed, err := models.Jobs.Claim(ctx, []string{"greet"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload greeting
+	if err := json.Unmarshal(claimed.Payload, &payload); err != nil || payload.Name != "Ana" {
+		t.Errorf("claimed payload %s, want the greeting of Ana", claimed.Payload)
+	}
+	if claimed.ID != due.ID || claimed.Status != jobs.StatusRunning || claimed.Attempts != 1 || claimed.LockedAt == nil {
+		t.Errorf("claimed %+v, want the due job running on its first attempt", claimed)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{"greet"}, now); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("second Claim error = %v, want ErrRecordNotFound while the other job is not due", err)
+	}
+
+	if err := models.Jobs.Complete(ctx, claimed); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Jobs.GetByID(ctx, due.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != jobs.StatusSucceeded || got.FinishedAt == nil || got.LockedAt != nil {
+		t.Errorf("completed job = %+v, want it succeeded", got)
+	}
+	if err := models.Jobs.Complete(ctx, claimed); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("second Complete error = %v, want ErrEditConflict", err)
+	}
+
+	page, metadata, err := models.Jobs.GetAll(ctx, &jobs.JobFilter{Status: "pending", Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != later.ID || page[1].ID != other.ID || metadata.TotalRecords != 2 {
+		t.Errorf("pending jobs = %+v, want the later and other jobs", page)
+	}
+
+	if n, err := models.Jobs.Purge(ctx, now.Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge before the job finished = %d, %v, want nothing purged", n, err)
+	}
+	if n, err := models.Jobs.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want the succeeded job purged", n, err)
+	}
+}
+
+func TestJobFailAndRetry(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	job := &jobs.Job{Kind: "greet", MaxAttempts: 2}
+	if err := models.Jobs.Enqueue(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	claimed, err := models.Jobs.Claim(ctx, []string{"greet"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retryAt := now.Add(time.Minute)
+	if err := models.Jobs.Fail(ctx, claimed, "smtp is down", retryAt); err != nil {
+		t.Fatal(err)
+	}
+	if claimed.Status != jobs.StatusPending || claimed.LastError != "smtp is down" {
+		t.Errorf("failed job = %+v, want it pending a retry", claimed)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{"greet"}, now); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Claim before the backoff error = %v, want ErrRecordNotFound", err)
+	}
+
+	claimed, err = models.Jobs.Claim(ctx, []string{"greet"}, retryAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Jobs.Fail(ctx, claimed, "smtp is still down", retryAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if claimed.Status != jobs.StatusDead || claimed.Attempts != 2 || claimed.FinishedAt == nil {
+		t.Errorf("job failed on its last attempt = %+v, want it dead", claimed)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{"greet"}, now.Add(time.Hour)); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Claim of a dead job error = %v, want ErrRecordNotFound", err)
+	}
+
+	if err := models.Jobs.Retry(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Jobs.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != jobs.StatusPending || got.Attempts != 0 || got.FinishedAt != nil || got.LastError != "" {
+		t.Errorf("retried job = %+v, want it pending with fresh attempts and no error", got)
+	}
+	entries, _, err := models.Audit.GetAll(ctx, &audit.EntryFilter{Entity: "jobs", EntityID: &job.ID, Default: testdb.FirstPage("-id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 || string(entries[0].Changes["last_error"].Old) != `"smtp is still down"` {
+		t.Errorf("audit of the retry = %+v, want the cleared error kept there", entries)
+	}
+	if err := models.Jobs.Retry(ctx, job.ID); !errors.Is(err, internalErrors.ErrNotDead) {
+		t.Errorf("Retry of a pending job error = %v, want ErrNotDead", err)
+	}
+	if err := models.Jobs.Retry(ctx, 1<<40); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Retry of a missing job error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestJobLease(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	if err := models.Jobs.Enqueue(ctx, &jobs.Job{Kind: "greet"}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	first, err := models.Jobs.Claim(ctx, []string{"greet"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{"greet"}, now.Add(jobs.Lease/2)); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Claim within the lease error = %v, want ErrRecordNotFound", err)
+	}
+
+	second, err := models.Jobs.Claim(ctx, []string{"greet"}, now.Add(jobs.Lease+time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID || second.Attempts != 2 {
+		t.Errorf("Claim after the lease = %+v, want the job handed out again", second)
+	}
+	if err := models.Jobs.Complete(ctx, first); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Complete by the first worker error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Jobs.Complete(ctx, second); err != nil {
+		t.Errorf("Complete by the second worker error = %v", err)
+	}
+}
+
+func TestJobLeaseOnLastAttempt(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	job := &jobs.Job{Kind: "greet", MaxAttempts: 2}
+	if err := models.Jobs.Enqueue(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, err := models.Jobs.Claim(ctx, []string{"greet"}, now); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(jobs.Lease + time.Second)
+	last, err := models.Jobs.Claim(ctx, []string{"greet"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Attempts != 2 {
+		t.Fatalf("reclaimed job = %+v, want its last attempt", last)
+	}
+
+	// The worker of the last attempt died too: the job must not be handed out a third time.
+	if _, err := models.Jobs.Claim(ctx, []string{"greet"}, now.Add(jobs.Lease+time.Second)); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Claim after the last lease ran out error = %v, want ErrRecordNotFound", err)
+	}
+	got, err := models.Jobs.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != jobs.StatusDead || got.Attempts != 2 || got.LastError != jobs.LeaseExpired || got.LockedAt != nil || got.FinishedAt == nil {
+		t.Errorf("job after the last lease ran out = %+v, want it dead lettered", got)
+	}
+	if err := models.Jobs.Complete(ctx, last); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Complete by the last worker error = %v, want ErrEditConflict", err)
+	}
+
+	if err := models.Jobs.Retry(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if retried, err := models.Jobs.Claim(ctx, []string{"greet"}, time.Now()); err != nil || retried.ID != job.ID || retried.Attempts != 1 {
+		t.Errorf("Claim after Retry = %+v, %v, want the job's first attempt", retried, err)
+	}
+}
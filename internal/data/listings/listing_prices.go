@@ -3,10 +3,13 @@ package listings
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
 )
@@ -38,6 +41,14 @@ const ( // Calves (Under 11 months)
 	CattleClassBull         CattleClass = "bull"
 )
 
+// CattleClasses lists every class of cattle a listing can price.
+var CattleClasses = []CattleClass{
+	CattleClassCalf, CattleClassHeiferCalf, CattleClassSteerCalf, CattleClassBullCalf,
+	CattleClassWeaner, CattleClassHeiferWeaner, CattleClassSteerWeaner, CattleClassBullWeaner,
+	CattleClassYearling, CattleClassHeifer, CattleClassCow, CattleClassSpayedHeifer,
+	CattleClassSpayedCow, CattleClassSteer, CattleClassBull,
+}
+
 // ListingPrices represents a listing for cattle sale.
 type ListingPrice struct {
 	ListingID   int64       `json:"listing_id"`
@@ -49,7 +60,8 @@ type ListingPrice struct {
 type ListingsPrices []ListingPrice
 
 type ListingPricesModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
 }
 
 type ListingPricesFilter struct {
@@ -57,6 +69,12 @@ type ListingPricesFilter struct {
 	Default     filters.Filters
 }
 
+// ListingPriceRepository is the interface for storing the prices of a listing.
+type ListingPriceRepository interface {
+	Insert(ctx context.Context, lp *ListingPrice) error
+	Update(ctx context.Context, lp *ListingPrice) error
+}
+
 // ValidateListingPrice validates the listing price fields.
 func ValidateListingPrice(v *validator.Validator, lp *ListingPrice) {
 	// Cattle class must be provided
@@ -66,52 +84,90 @@ func ValidateListingPrice(v *validator.Validator, lp *ListingPrice) {
 	v.Check(lp.PricePerKg > 0, "price_per_kg", "must be greater than zero")
 }
 
+// PriceChange describes a change of price for the watchers of a listing, e.g. "cow from 4 to 3 per kg".
+func PriceChange(before, after ListingPrice) string {
+	return fmt.Sprintf("%s from %d to %d per kg", after.CattleClass, before.PricePerKg, after.PricePerKg)
+}
+
 /****************************************************************************************
  *									Database Operations								*
  ***************************************************************************************/
 //  Insert
-func (lpm ListingPricesModel) Insert(lp *ListingPrice) error {
+func (lpm *ListingPricesModel) Insert(ctx context.Context, lp *ListingPrice) error {
 	query := `INSERT INTO listing_prices (listing_id, cattle_class, price_per_kg, quantity)
-			  VALUES ($1, $2, $3. $4)`
+			  VALUES ($1, $2, $3, $4)`
 
-	_, err := lpm.DB.Exec(query, lp.ListingID, lp.CattleClass, lp.PricePerKg, lp.Quantity)
+	ctx, cancel := database.WithTimeout(ctx, lpm.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, lpm.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query, lp.ListingID, lp.CattleClass, lp.PricePerKg, lp.Quantity)
 
 	if err != nil {
 		switch {
 		case errors.IsForeignKeyViolation(err):
-			return errors.ErrInvalidDistrictID
+			return errors.ErrForeignKeyViolation
+		case errors.IsUniqueViolation(err, "listing_id, cattle_class"):
+			return errors.ErrDuplicateValue("cattle_class")
 		default:
 			return err
 		}
 	}
-	return nil
+	if err := audit.Record(ctx, tx, "listing_prices", lp.ListingID, audit.ActionInsert, nil, lp); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Update
-func (lpm *ListingPricesModel) Update(lp *ListingPrice) error {
+func (lpm *ListingPricesModel) Update(ctx context.Context, lp *ListingPrice) error {
 	query := `UPDATE listing_prices
-			SET cattle_class = $1, price_per_kg = $2, quantity = $3
-			WHERE listing_price = $4`
+			SET price_per_kg = $1, quantity = $2
+			WHERE listing_id = $3 AND cattle_class = $4`
 
-	args := []any{}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []any{lp.PricePerKg, lp.Quantity, lp.ListingID, lp.CattleClass}
+	ctx, cancel := database.WithTimeout(ctx, lpm.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&a.UpdatedAt)
+	tx, err := database.Begin(ctx, lpm.DB)
 	if err != nil {
-		switch {
-		case errors.IsUniqueViolation(err, "areas_name_key"):
-			return errors.ErrDuplicateName
-		case errors.IsForeignKeyViolation(err):
-			return errors.ErrInvalidDistrictID
-		case errors.IsEditConflict(err):
-			return errors.ErrEditConflict
-		default:
-			return err
+		return err
+	}
+	defer tx.Rollback()
+
+	// The price as it was, for the audit trail
+	before := ListingPrice{ListingID: lp.ListingID, CattleClass: lp.CattleClass}
+	err = tx.QueryRowContext(ctx, `
+		SELECT price_per_kg, quantity FROM listing_prices
+		WHERE listing_id = $1 AND cattle_class = $2
+		FOR UPDATE`, lp.ListingID, lp.CattleClass).Scan(&before.PricePerKg, &before.Quantity)
+	if err != nil {
+		if errors.ErrNoRows(err) {
+			return errors.ErrRecordNotFound
 		}
+		return errors.WrapUpdateError(err, "Listing prices")
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Listing prices")
 	}
 
-	return nil
+	if err := audit.Record(ctx, tx, "listing_prices", lp.ListingID, audit.ActionUpdate, before, lp); err != nil {
+		return err
+	}
+	if before.PricePerKg != lp.PricePerKg {
+		err = watchlists.Notify(ctx, tx, lp.ListingID, watchlists.EventPriceChanged, PriceChange(before, *lp))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 // Delete
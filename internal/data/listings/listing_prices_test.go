@@ -0,0 +1,51 @@
+// File: internal/data/listings/listing_prices_test.go
+package listings_test
+
+import (
+	"errors"
+	"testing"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestListingPrices(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	l := f.Listing()
+
+	// Every class the model knows must be accepted by the cattle_class_enum column.
+	classes := []listings.CattleClass{
+		listings.CattleClassCalf, listings.CattleClassHeiferCalf, listings.CattleClassSteerCalf, listings.CattleClassBullCalf,
+		listings.CattleClassWeaner, listings.CattleClassHeiferWeaner, listings.CattleClassSteerWeaner, listings.CattleClassBullWeaner,
+		listings.CattleClassYearling, listings.CattleClassHeifer, listings.CattleClassCow, listings.CattleClassSpayedHeifer,
+		listings.CattleClassSpayedCow, listings.CattleClassSteer, listings.CattleClassBull,
+	}
+	for _, class := range classes {
+		f.ListingPrice(func(lp *listings.ListingPrice) { lp.ListingID, lp.CattleClass = l.ID, class })
+	}
+
+	duplicate := &listings.ListingPrice{ListingID: l.ID, CattleClass: listings.CattleClassCow, PricePerKg: 5, Quantity: 1}
+	if err := models.ListingPrices.Insert(ctx, duplicate); err == nil || err.Error() != internalErrors.ErrDuplicateValue("cattle_class").Error() {
+		t.Errorf("Insert error = %v, want duplicate cattle_class", err)
+	}
+
+	orphan := &listings.ListingPrice{ListingID: 404, CattleClass: listings.CattleClassCow, PricePerKg: 5, Quantity: 1}
+	if err := models.ListingPrices.Insert(ctx, orphan); !errors.Is(err, internalErrors.ErrForeignKeyViolation) {
+		t.Errorf("Insert error = %v, want ErrForeignKeyViolation", err)
+	}
+
+	duplicate.Quantity = 3
+	if err := models.ListingPrices.Update(ctx, duplicate); err != nil {
+		t.Fatal(err)
+	}
+
+	unpriced := f.Listing()
+	missing := &listings.ListingPrice{ListingID: unpriced.ID, CattleClass: listings.CattleClassBull, PricePerKg: 5, Quantity: 1}
+	if err := models.ListingPrices.Update(ctx, missing); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Update error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -1 +1,544 @@
+// File: internal/data/listings/listings.go
 package listings
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/search"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Listing represents a cattle sale listing.
+type Listing struct {
+	ID          int64                 `json:"id"`
+	UserID      int64                 `json:"user_id"`
+	AreaID      int64                 `json:"area_id"`
+	RegionID    int64                 `json:"region_id"` // always the region of AreaID
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	Coordinates locations.Coordinates `json:"coordinates"`
+	IsActive    *bool                 `json:"is_active"`
+	SoldAt      *time.Time            `json:"sold_at"`
+	PublishAt   *time.Time            `json:"publish_at"` // set while the listing waits to be published
+	ExpiresAt   time.Time             `json:"expires_at"`
+	RemindedAt  *time.Time            `json:"-"` // when the seller was reminded of the current expiry
+	Version     int                   `json:"version"`
+	database.Deletion
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DistanceKm is only set by proximity searches.
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+	// Rank and Snippet are only set by text searches. Snippet is escaped HTML with the matches in
+	// <mark> tags.
+	Rank    *float64 `json:"rank,omitempty"`
+	Snippet *string  `json:"snippet,omitempty"`
+}
+
+// Listings is a slice of Listing.
+type Listings []Listing
+
+// ListingFilter represents filtering options for querying listings.
+type ListingFilter struct {
+	UserID   *int64
+	AreaID   *int64
+	RegionID *int64
+	Title    string
+	Search   string // full-text search of the title, description, breeds and places
+	IsActive *bool
+	Near     *locations.Proximity
+	Box      *locations.BoundingBox
+	Default  filters.Filters
+
+	// Lists match any of their values; an empty list matches everything.
+	AreaIDs   []int64
+	RegionIDs []int64
+
+	// The price filters match listings with a price per kg in range for any of CattleClasses, or
+	// for any class when it is empty. Head counts are totals over every class of the listing, and
+	// all ranges are inclusive.
+	CattleClasses []CattleClass
+	MinPricePerKg *int64
+	MaxPricePerKg *int64
+	MinTotalHead  *int64
+	MaxTotalHead  *int64
+
+	// UpdatedSince limits results to listings created or edited at or after it.
+	UpdatedSince *time.Time
+
+	// HasCoordinates limits results to listings that can be placed on a map.
+	HasCoordinates bool
+	// WithDeleted includes soft deleted listings, which are left out by default.
+	WithDeleted bool
+}
+
+// ListingModel represents the model for listings.
+type ListingModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// ListingRepository is the interface for storing and querying listings.
+type ListingRepository interface {
+	Insert(ctx context.Context, l *Listing) error
+	Update(ctx context.Context, l *Listing) error
+	SoftDelete(ctx context.Context, id int64, deletedBy int64) error
+	Restore(ctx context.Context, id int64) error
+	MarkSold(ctx context.Context, id int64) error
+	Renew(ctx context.Context, id int64, until time.Time) error
+	Publish(ctx context.Context, now time.Time) ([]int64, error)
+	Expire(ctx context.Context, now time.Time) ([]int64, error)
+	GetExpiring(ctx context.Context, before time.Time) ([]int64, error)
+	MarkReminded(ctx context.Context, id int64, at time.Time) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetByID(ctx context.Context, id int64) (*Listing, error)
+	GetAll(ctx context.Context, filter *ListingFilter) (Listings, filters.MetaData, error)
+}
+
+// ValidateListing validates the fields of a Listing.
+func ValidateListing(v *validator.Validator, l *Listing) {
+	v.Check(l.Title != "", "title", "must be provided")
+	v.Check(len(l.Title) <= 255, "title", "must not be more than 255 bytes long")
+	v.Check(len(l.Description) <= 5000, "description", "must not be more than 5000 bytes long")
+	v.Check(l.AreaID > 0, "area_id", "must be provided and greater than zero")
+	v.Check(l.RegionID >= 0, "region_id", "must be greater than zero")
+	locations.ValidateCoordinates(v, l.Coordinates)
+	v.Check(l.PublishAt == nil || l.ExpiresAt.After(*l.PublishAt), "expires_at", "must be after publish_at")
+}
+
+// ValidateListingFilter validates the ranges and lists of a listing search.
+func ValidateListingFilter(v *validator.Validator, f *ListingFilter) {
+	filters.ValidateFilters(v, f.Default)
+	search.Validate(v, "q", f.Search)
+	v.Check(len(f.AreaIDs) <= 100, "area_ids", "must not contain more than 100 values")
+	v.Check(!slices.ContainsFunc(f.AreaIDs, func(id int64) bool { return id <= 0 }), "area_ids", "must only contain ids greater than zero")
+	v.Check(len(f.RegionIDs) <= 100, "region_ids", "must not contain more than 100 values")
+	v.Check(!slices.ContainsFunc(f.RegionIDs, func(id int64) bool { return id <= 0 }), "region_ids", "must only contain ids greater than zero")
+	for _, class := range f.CattleClasses {
+		v.Check(slices.Contains(CattleClasses, class), "cattle_classes", "must only contain known cattle classes")
+	}
+
+	v.Check(f.MinPricePerKg == nil || *f.MinPricePerKg >= 0, "min_price_per_kg", "must not be negative")
+	v.Check(f.MinPricePerKg == nil || f.MaxPricePerKg == nil || *f.MaxPricePerKg >= *f.MinPricePerKg, "max_price_per_kg", "must not be less than min_price_per_kg")
+	v.Check(f.MinTotalHead == nil || *f.MinTotalHead >= 0, "min_total_head", "must not be negative")
+	v.Check(f.MinTotalHead == nil || f.MaxTotalHead == nil || *f.MaxTotalHead >= *f.MinTotalHead, "max_total_head", "must not be less than min_total_head")
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Insert adds a new listing to the database, taking its region from its area. See Schedule for
+// when it is published and when it expires.
+func (m *ListingModel) Insert(ctx context.Context, l *Listing) error {
+	if err := m.assignRegion(ctx, l); err != nil {
+		return err
+	}
+	l.Schedule(time.Now())
+
+	query := `
+		INSERT INTO listings (user_id, area_id, region_id, title, description, latitude, longitude, is_active, publish_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6::float8, 0), NULLIF($7::float8, 0), COALESCE($8, TRUE), $9, $10)
+		RETURNING id, is_active, version, created_at, updated_at
+	`
+	args := []any{l.UserID, l.AreaID, l.RegionID, l.Title, l.Description, l.Coordinates.Latitude, l.Coordinates.Longitude, l.IsActive, l.PublishAt, l.ExpiresAt}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&l.ID, &l.IsActive, &l.Version, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.IsForeignKeyViolation(err):
+			return errors.ErrForeignKeyViolation
+		case errors.IsCheckViolation(err):
+			return errors.ErrRegionMismatch
+		default:
+			return errors.WrapInsertError(err, "Listings")
+		}
+	}
+	if err := audit.Record(ctx, tx, "listings", l.ID, audit.ActionInsert, nil, l); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update modifies an existing listing in the database, taking its region from its area.
+func (m *ListingModel) Update(ctx context.Context, l *Listing) error {
+	if err := m.assignRegion(ctx, l); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE listings
+		SET area_id = $1, region_id = $2, title = $3, description = $4, latitude = NULLIF($5::float8, 0), longitude = NULLIF($6::float8, 0), is_active = COALESCE($7, is_active),
+			publish_at = $10, expires_at = $11, expiry_reminded_at = CASE WHEN expires_at = $11 THEN expiry_reminded_at END,
+			updated_at = NOW(), version = version + 1
+		WHERE id = $8 AND version = $9 AND deleted_at IS NULL
+		RETURNING updated_at, version
+	`
+	args := []any{l.AreaID, l.RegionID, l.Title, l.Description, l.Coordinates.Latitude, l.Coordinates.Longitude, l.IsActive, l.ID, l.Version, l.PublishAt, l.ExpiresAt}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &ListingModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, l.ID)
+	if err != nil {
+		if errors.IsRecordNotFound(err) {
+			return errors.ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&l.UpdatedAt, &l.Version)
+	if err != nil {
+		switch {
+		case errors.IsForeignKeyViolation(err):
+			return errors.ErrForeignKeyViolation
+		case errors.IsCheckViolation(err):
+			return errors.ErrRegionMismatch
+		case errors.IsEditConflict(err):
+			return errors.ErrEditConflict
+		default:
+			return errors.WrapUpdateError(err, "Listings")
+		}
+	}
+
+	after, err := current.GetByID(ctx, l.ID)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "listings", l.ID, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SoftDelete marks a listing as deleted by deletedBy.
+func (m *ListingModel) SoftDelete(ctx context.Context, id int64, deletedBy int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.SoftDelete(ctx, m.DB, "listings", id, deletedBy)
+}
+
+// Restore brings a soft deleted listing back.
+func (m *ListingModel) Restore(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Restore(ctx, m.DB, "listings", id)
+}
+
+// MarkSold records that a listing has been sold, which takes it off the market, and queues a
+// notification for its watchers. A listing can only be sold once.
+func (m *ListingModel) MarkSold(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &ListingModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if before.IsDeleted() {
+		return errors.ErrRecordNotFound
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE listings
+		SET sold_at = NOW(), is_active = FALSE, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND sold_at IS NULL AND deleted_at IS NULL`, id)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Listings")
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.ErrAlreadySold
+	}
+
+	after, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "listings", id, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	if err := watchlists.Notify(ctx, tx, id, watchlists.EventSold, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Purge permanently deletes the listings soft deleted before the cutoff, together with their
+// cattle entries and prices.
+func (m *ListingModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Purge(ctx, m.DB, "listings", before, "")
+}
+
+// GetByID retrieves a listing by its ID.
+func (m *ListingModel) GetByID(ctx context.Context, id int64) (*Listing, error) {
+	query := `
+		SELECT id, user_id, area_id, region_id, title, COALESCE(description, ''),
+			COALESCE(latitude, 0), COALESCE(longitude, 0), is_active, sold_at, publish_at, expires_at, expiry_reminded_at, version, deleted_at, deleted_by, created_at, updated_at
+		FROM listings
+		WHERE id = $1
+	`
+	var l Listing
+	scan := []any{
+		&l.ID,
+		&l.UserID,
+		&l.AreaID,
+		&l.RegionID,
+		&l.Title,
+		&l.Description,
+		&l.Coordinates.Latitude,
+		&l.Coordinates.Longitude,
+		&l.IsActive,
+		&l.SoldAt,
+		&l.PublishAt,
+		&l.ExpiresAt,
+		&l.RemindedAt,
+		&l.Version,
+		&l.DeletedAt,
+		&l.DeletedBy,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(scan...)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "Listings")
+		}
+	}
+	return &l, nil
+}
+
+// GetAll retrieves all listings matching the provided filter criteria.
+// Proximity searches only match listings with coordinates and report each listing's distance.
+func (m *ListingModel) GetAll(ctx context.Context, filter *ListingFilter) (Listings, filters.MetaData, error) {
+	// distance_km and relevance are only column aliases, so sort by the expressions themselves
+	column := func(name string) string {
+		switch name {
+		case "distance_km":
+			return "haversine_km($12, $13::float8, latitude, longitude)"
+		case "relevance":
+			return search.Rank(24)
+		}
+		return name
+	}
+	page := filter.Default.SQL(column, "id", 26)
+
+	query := fmt.Sprintf(`
+		SELECT %s, %s, id, user_id, area_id, region_id, title, COALESCE(description, ''),
+			COALESCE(latitude, 0), COALESCE(longitude, 0), is_active, sold_at, publish_at, expires_at, expiry_reminded_at, version, deleted_at, deleted_by, created_at, updated_at,
+			CASE WHEN $12::float8 IS NULL THEN NULL ELSE haversine_km($12, $13::float8, latitude, longitude) END AS distance_km,
+			CASE WHEN $24 = '' THEN NULL ELSE %s END AS relevance,
+			CASE WHEN $24 = '' THEN NULL ELSE %s END AS snippet
+		FROM listings
+		WHERE ($1::bigint IS NULL OR user_id = $1)
+		AND ($2::bigint IS NULL OR area_id = $2)
+		AND ($3::bigint IS NULL OR region_id = $3)
+		AND ($4 = '' OR title ILIKE '%%' || $4 || '%%')
+		AND ($5::boolean IS NULL OR is_active = $5)
+		AND ($8::float8 IS NULL OR (latitude BETWEEN $8 AND $10::float8 AND longitude BETWEEN $9::float8 AND $11::float8))
+		AND ($12::float8 IS NULL OR haversine_km($12, $13, latitude, longitude) <= $14::float8)
+		AND (NOT $15::boolean OR (latitude IS NOT NULL AND longitude IS NOT NULL))
+		AND ($16 OR deleted_at IS NULL)
+		AND (COALESCE(cardinality($17::bigint[]), 0) = 0 OR area_id = ANY($17))
+		AND (COALESCE(cardinality($18::bigint[]), 0) = 0 OR region_id = ANY($18))
+		AND ((COALESCE(cardinality($19::text[]), 0) = 0 AND $20::numeric IS NULL AND $21::numeric IS NULL) OR EXISTS (
+			SELECT 1 FROM listing_prices AS lp
+			WHERE lp.listing_id = listings.id
+			AND (COALESCE(cardinality($19), 0) = 0 OR lp.cattle_class::text = ANY($19))
+			AND ($20 IS NULL OR lp.price_per_kg >= $20)
+			AND ($21 IS NULL OR lp.price_per_kg <= $21)
+		))
+		AND (($22::bigint IS NULL AND $23::bigint IS NULL) OR (
+			SELECT COALESCE(SUM(lp.quantity), 0) FROM listing_prices AS lp WHERE lp.listing_id = listings.id
+		) BETWEEN COALESCE($22, 0) AND COALESCE($23, 9223372036854775807))
+		AND ($25::timestamptz IS NULL OR updated_at >= $25)
+		AND %s
+		AND %s
+		ORDER BY %s
+		LIMIT $6 OFFSET $7`,
+		page.Count, page.SortKey, search.Rank(24), search.Snippet(24, "title || ' ' || COALESCE(description, '')"),
+		search.Match(24), page.Where, page.OrderBy)
+
+	args := []any{
+		filter.UserID,
+		filter.AreaID,
+		filter.RegionID,
+		filter.Title,
+		filter.IsActive,
+		filter.Default.Limit(),
+		filter.Default.Offset(),
+	}
+	args = append(args, locations.SearchBox(filter.Near, filter.Box).Args()...)
+	args = append(args, filter.Near.Args()...)
+	args = append(args, filter.HasCoordinates, filter.WithDeleted)
+	args = append(args,
+		pq.Array(filter.AreaIDs), pq.Array(filter.RegionIDs), pq.Array(filter.CattleClasses),
+		filter.MinPricePerKg, filter.MaxPricePerKg, filter.MinTotalHead, filter.MaxTotalHead,
+		filter.Search, filter.UpdatedSince,
+	)
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Listings")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	listings := Listings{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var l Listing
+		var key filters.Key
+		scan := []any{
+			&totalRecords,
+			&key.Values,
+			&l.ID,
+			&l.UserID,
+			&l.AreaID,
+			&l.RegionID,
+			&l.Title,
+			&l.Description,
+			&l.Coordinates.Latitude,
+			&l.Coordinates.Longitude,
+			&l.IsActive,
+			&l.SoldAt,
+			&l.PublishAt,
+			&l.ExpiresAt,
+			&l.RemindedAt,
+			&l.Version,
+			&l.DeletedAt,
+			&l.DeletedBy,
+			&l.CreatedAt,
+			&l.UpdatedAt,
+			&l.DistanceKm,
+			&l.Rank,
+			&l.Snippet,
+		}
+		if err := rows.Scan(scan...); err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Listings")
+		}
+		key.ID = l.ID
+		listings = append(listings, l)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	listings, metaData := filters.Paginate(filter.Default, listings, keys, totalRecords)
+	return listings, metaData, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// assignRegion sets the listing's region to that of its area. A region supplied by the caller, or
+// one found from the listing's coordinates, must agree with it.
+func (m *ListingModel) assignRegion(ctx context.Context, l *Listing) error {
+	areas := locations.AreaModel{DB: m.DB, Timeout: m.Timeout}
+	area, err := areas.GetByID(ctx, int(l.AreaID))
+	if err != nil {
+		if err == errors.ErrRecordNotFound {
+			return errors.ErrForeignKeyViolation
+		}
+		return err
+	}
+	if l.RegionID != 0 && l.RegionID != int64(area.RegionID) {
+		return errors.ErrRegionMismatch
+	}
+
+	regions := locations.RegionModel{DB: m.DB, Timeout: m.Timeout}
+	regionID, err := regions.ResolveRegion(ctx, area.RegionID, l.Coordinates)
+	if err != nil {
+		return err
+	}
+	l.RegionID = int64(regionID)
+	return nil
+}
+
+/****************************************************************************************
+ *										GeoJSON											*
+ ***************************************************************************************/
+
+// Feature returns the listing as a Point feature, or a feature with null geometry if it has no coordinates.
+func (l Listing) Feature() locations.Feature {
+	var geometry *locations.Geometry
+	if l.Coordinates.Latitude != 0 || l.Coordinates.Longitude != 0 {
+		geometry = locations.NewPoint(l.Coordinates)
+	}
+
+	properties := map[string]any{
+		"title":      l.Title,
+		"user_id":    l.UserID,
+		"area_id":    l.AreaID,
+		"region_id":  l.RegionID,
+		"created_at": l.CreatedAt,
+	}
+	if l.DistanceKm != nil {
+		properties["distance_km"] = *l.DistanceKm
+	}
+	return locations.Feature{Type: locations.GeoJSONFeature, ID: l.ID, Geometry: geometry, Properties: properties}
+}
+
+// FeatureCollection returns the listings as a GeoJSON feature collection.
+func (l Listings) FeatureCollection(metadata filters.MetaData) *locations.FeatureCollection {
+	features := make([]locations.Feature, len(l))
+	for i, listing := range l {
+		features[i] = listing.Feature()
+	}
+	return locations.NewFeatureCollection(features, metadata)
+}
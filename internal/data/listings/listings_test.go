@@ -0,0 +1,346 @@
+// File: internal/data/listings/listings_test.go
+package listings_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestListingInsertAndGet(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+
+	area := f.Area()
+	want := f.Listing(func(l *listings.Listing) { l.AreaID = int64(area.ID); l.Description = "" })
+	if want.ID == 0 || want.RegionID != int64(area.RegionID) || want.IsActive == nil || !*want.IsActive {
+		t.Fatalf("Insert did not return the id, region and default flags: %+v", want)
+	}
+
+	got, err := models.Listings.GetByID(t.Context(), want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != want.Title || got.UserID != want.UserID || got.AreaID != want.AreaID || got.RegionID != want.RegionID || got.Description != "" {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestListingInsertErrors(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+
+	seller := f.User()
+	area := f.Area()
+	other := f.Region()
+
+	tests := map[string]struct {
+		listing listings.Listing
+		want    error
+	}{
+		"missing seller": {listings.Listing{UserID: 404, AreaID: int64(area.ID), Title: "T"}, internalErrors.ErrForeignKeyViolation},
+		"missing area":   {listings.Listing{UserID: seller.ID, AreaID: 404, Title: "T"}, internalErrors.ErrForeignKeyViolation},
+		"wrong region":   {listings.Listing{UserID: seller.ID, AreaID: int64(area.ID), RegionID: int64(other.ID), Title: "T"}, internalErrors.ErrRegionMismatch},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := models.Listings.Insert(t.Context(), &tt.listing); !errors.Is(err, tt.want) {
+				t.Errorf("Insert error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestListingUpdateAndDelete(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	l := f.Listing()
+	elsewhere := f.Area()
+
+	l.Title = "Renamed"
+	l.AreaID = int64(elsewhere.ID)
+	l.RegionID = 0 // taken from the new area
+	l.IsActive = nil
+	if err := models.Listings.Update(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Listings.GetByID(ctx, l.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Renamed" || got.RegionID != int64(elsewhere.RegionID) || !*got.IsActive || got.Version != 2 {
+		t.Errorf("got %+v, want the update applied in region %d", got, elsewhere.RegionID)
+	}
+
+	missing := *got
+	missing.ID = 404
+	if err := models.Listings.Update(ctx, &missing); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a missing listing error = %v, want ErrEditConflict", err)
+	}
+
+	stale := *got
+	stale.Version = 1
+	if err := models.Listings.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a stale listing error = %v, want ErrEditConflict", err)
+	}
+
+	if err := models.Listings.SoftDelete(ctx, l.ID, l.UserID); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Listings.GetByID(ctx, l.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt == nil || got.DeletedBy == nil || *got.DeletedBy != l.UserID || got.Version != 3 {
+		t.Errorf("got %+v, want it deleted by its owner", got)
+	}
+	if err := models.Listings.Update(ctx, got); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a deleted listing error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Listings.SoftDelete(ctx, l.ID, 0); !errors.Is(err, internalErrors.ErrAlreadyDeleted) {
+		t.Errorf("second SoftDelete error = %v, want ErrAlreadyDeleted", err)
+	}
+	if err := models.Listings.Restore(ctx, l.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Listings.Restore(ctx, l.ID); !errors.Is(err, internalErrors.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want ErrNotDeleted", err)
+	}
+}
+
+func TestListingPurge(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	l := f.Listing()
+	live := f.Listing()
+	price := f.ListingPrice(func(lp *listings.ListingPrice) { lp.ListingID = l.ID })
+	if err := models.Listings.SoftDelete(ctx, l.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := models.Listings.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge before the deletion = %d, %v, want nothing purged", n, err)
+	}
+	if n, err := models.Listings.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want 1 purged", n, err)
+	}
+	if _, err := models.Listings.GetByID(ctx, l.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID error = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.ListingPrices.Update(ctx, price); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("price of a purged listing: Update error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Listings.GetByID(ctx, live.ID); err != nil {
+		t.Errorf("GetByID of the live listing error = %v, want it kept", err)
+	}
+}
+
+func TestListingGetAll(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	seller := f.User()
+	belmopan := f.Area(func(a *locations.Area) { a.Coordinates = locations.Coordinates{Latitude: 17.25, Longitude: -88.77} })
+	orangeWalk := f.Area()
+
+	heifers := f.Listing(func(l *listings.Listing) {
+		l.UserID, l.AreaID, l.Title = seller.ID, int64(belmopan.ID), "Brahman heifers"
+		l.Coordinates = locations.Coordinates{Latitude: 17.26, Longitude: -88.78}
+	})
+	steers := f.Listing(func(l *listings.Listing) {
+		l.UserID, l.AreaID, l.Title = seller.ID, int64(orangeWalk.ID), "Steers for finishing"
+		l.Coordinates = locations.Coordinates{Latitude: 18.08, Longitude: -88.56}
+	})
+	bulls := f.Listing(func(l *listings.Listing) {
+		l.AreaID, l.Title = int64(orangeWalk.ID), "Angus bulls"
+		l.IsActive = testdb.Ptr(false)
+	})
+	sold := f.Listing(func(l *listings.Listing) {
+		l.UserID, l.AreaID, l.Title = seller.ID, int64(orangeWalk.ID), "Sold weaners"
+	})
+	if err := models.Listings.SoftDelete(ctx, sold.ID, seller.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	f.ListingPrice(func(lp *listings.ListingPrice) {
+		lp.ListingID, lp.CattleClass, lp.PricePerKg, lp.Quantity = heifers.ID, listings.CattleClassHeifer, 5, 12
+	})
+	f.ListingPrice(func(lp *listings.ListingPrice) {
+		lp.ListingID, lp.CattleClass, lp.PricePerKg, lp.Quantity = steers.ID, listings.CattleClassSteer, 4, 20
+	})
+	f.ListingPrice(func(lp *listings.ListingPrice) {
+		lp.ListingID, lp.CattleClass, lp.PricePerKg, lp.Quantity = steers.ID, listings.CattleClassSteerWeaner, 6, 8
+	})
+
+	near := &locations.Proximity{Center: locations.Coordinates{Latitude: 17.25, Longitude: -88.77}, RadiusKm: 10}
+	wide := &locations.Proximity{Center: near.Center, RadiusKm: 200}
+	box := &locations.BoundingBox{MinLatitude: 18, MinLongitude: -89, MaxLatitude: 18.5, MaxLongitude: -88}
+
+	tests := []struct {
+		name   string
+		filter listings.ListingFilter
+		want   []int64
+	}{
+		{"by title", listings.ListingFilter{Default: testdb.FirstPage("title")}, []int64{bulls.ID, heifers.ID, steers.ID}},
+		{"newest first", listings.ListingFilter{Default: testdb.FirstPage("-id")}, []int64{bulls.ID, steers.ID, heifers.ID}},
+		{"seller", listings.ListingFilter{UserID: testdb.Ptr(seller.ID), Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID}},
+		{"seller with deleted", listings.ListingFilter{UserID: testdb.Ptr(seller.ID), WithDeleted: true, Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID, sold.ID}},
+		{"area", listings.ListingFilter{AreaID: testdb.Ptr(int64(orangeWalk.ID)), Default: testdb.FirstPage("id")}, []int64{steers.ID, bulls.ID}},
+		{"region", listings.ListingFilter{RegionID: testdb.Ptr(int64(belmopan.RegionID)), Default: testdb.FirstPage("id")}, []int64{heifers.ID}},
+		{"title", listings.ListingFilter{Title: "STEER", Default: testdb.FirstPage("id")}, []int64{steers.ID}},
+		{"active", listings.ListingFilter{IsActive: testdb.Ptr(true), Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID}},
+		{"has coordinates", listings.ListingFilter{HasCoordinates: true, Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID}},
+		{"near", listings.ListingFilter{Near: near, Default: testdb.FirstPage("distance_km")}, []int64{heifers.ID}},
+		{"nearest first", listings.ListingFilter{Near: wide, Default: testdb.FirstPage("distance_km")}, []int64{heifers.ID, steers.ID}},
+		{"box", listings.ListingFilter{Box: box, Default: testdb.FirstPage("id")}, []int64{steers.ID}},
+		{"areas", listings.ListingFilter{AreaIDs: []int64{int64(belmopan.ID), int64(orangeWalk.ID)}, Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID, bulls.ID}},
+		{"regions", listings.ListingFilter{RegionIDs: []int64{int64(belmopan.RegionID)}, Default: testdb.FirstPage("id")}, []int64{heifers.ID}},
+		{"cattle classes", listings.ListingFilter{CattleClasses: []listings.CattleClass{listings.CattleClassSteer, listings.CattleClassHeifer}, Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID}},
+		{"min price", listings.ListingFilter{MinPricePerKg: testdb.Ptr(int64(5)), Default: testdb.FirstPage("id")}, []int64{heifers.ID, steers.ID}},
+		{"max price", listings.ListingFilter{MaxPricePerKg: testdb.Ptr(int64(4)), Default: testdb.FirstPage("id")}, []int64{steers.ID}},
+		{"class price", listings.ListingFilter{CattleClasses: []listings.CattleClass{listings.CattleClassSteer}, MinPricePerKg: testdb.Ptr(int64(5)), Default: testdb.FirstPage("id")}, nil},
+		{"min head", listings.ListingFilter{MinTotalHead: testdb.Ptr(int64(25)), Default: testdb.FirstPage("id")}, []int64{steers.ID}},
+		{"max head", listings.ListingFilter{MaxTotalHead: testdb.Ptr(int64(12)), Default: testdb.FirstPage("id")}, []int64{heifers.ID, bulls.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := models.Listings.GetAll(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int64
+			for _, l := range got {
+				ids = append(ids, l.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", ids, tt.want)
+				}
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("total records = %d, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+
+	paged := listings.ListingFilter{Default: testdb.FirstPage("id")}
+	paged.Default.Page, paged.Default.PageSize = 3, 1
+	got, metadata, err := models.Listings.GetAll(ctx, &paged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != bulls.ID || metadata.CurrentPage != 3 || metadata.LastPage != 3 {
+		t.Errorf("page 3 = %d listings, metadata %+v; want the bulls on the last page", len(got), metadata)
+	}
+}
+
+func TestListingSearch(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	belmopan := f.Area(func(a *locations.Area) { a.Name = "Belmopan" })
+
+	heifers := f.Listing(func(l *listings.Listing) {
+		l.AreaID, l.Title, l.Description = int64(belmopan.ID), "Brahman heifers", "Gentle heifers, ready to breed"
+	})
+	novillos := f.Listing(func(l *listings.Listing) {
+		l.Title, l.Description = "Novillos para engorde", "Novillos de buena genética"
+	})
+	bulls := f.Listing(func(l *listings.Listing) {
+		l.Title, l.Description = "Angus bulls", "Registered sires"
+	})
+	herd := f.Listing(func(l *listings.Listing) {
+		l.Title, l.Description = "Mixed herd", "Cows, calves and two bulls"
+	})
+
+	tests := []struct {
+		name   string
+		search string
+		want   []int64
+	}{
+		{"plural", "heifer", []int64{heifers.ID}},
+		{"spanish", "novillo", []int64{novillos.ID}},
+		{"typo", "brahmn", []int64{heifers.ID}},
+		{"place", "belmopan", []int64{heifers.ID}},
+		{"title first", "bulls", []int64{bulls.ID, herd.ID}},
+		{"no match", "buffalo", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := listings.ListingFilter{Search: tt.search, Default: testdb.FirstPage("-relevance")}
+			got, _, err := models.Listings.GetAll(ctx, &filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int64
+			for _, l := range got {
+				ids = append(ids, l.ID)
+				if l.Rank == nil || l.Snippet == nil {
+					t.Errorf("listing %d has no rank or snippet", l.ID)
+				}
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+
+	filter := listings.ListingFilter{Search: "bulls", Default: testdb.FirstPage("-relevance")}
+	got, _, err := models.Listings.GetAll(ctx, &filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || got[0].Snippet == nil || !strings.Contains(*got[0].Snippet, "<mark>") {
+		t.Errorf("snippet of %+v does not mark the match", got)
+	}
+
+	// Markup the seller wrote is escaped, so only the marks are HTML
+	f.Listing(func(l *listings.Listing) {
+		l.Title, l.Description = "Brangus steers", `Fat steers <script>alert("steers")</script> & more`
+	})
+	filter = listings.ListingFilter{Search: "steers", Default: testdb.FirstPage("-relevance")}
+	got, _, err = models.Listings.GetAll(ctx, &filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Snippet == nil {
+		t.Fatalf("search for steers = %+v, want one listing with a snippet", got)
+	}
+	snippet := *got[0].Snippet
+	if strings.Contains(snippet, "<script>") || !strings.Contains(snippet, "&lt;script&gt;") || !strings.Contains(snippet, "&amp;") {
+		t.Errorf("snippet = %q, want the description's markup escaped", snippet)
+	}
+	if !strings.Contains(snippet, "<mark>") {
+		t.Errorf("snippet = %q, want the match marked", snippet)
+	}
+
+	all, _, err := models.Listings.GetAll(ctx, &listings.ListingFilter{Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range all {
+		if l.Rank != nil || l.Snippet != nil {
+			t.Errorf("listing %d has a rank or snippet without a search", l.ID)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// File: internal/data/listings/schedule.go
+package listings
+
+import (
+	"context"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Lifetime is how long a listing stays on the market once it is published or renewed.
+const Lifetime = 30 * 24 * time.Hour
+
+// Schedule fills in the schedule of a new listing as of now. A listing to be published later is
+// kept inactive until then, and by default a listing expires a Lifetime after it is published.
+func (l *Listing) Schedule(now time.Time) {
+	if l.PublishAt != nil && !l.PublishAt.After(now) {
+		l.PublishAt = nil
+	}
+	start := now
+	if l.PublishAt != nil {
+		inactive := false
+		l.IsActive, start = &inactive, *l.PublishAt
+	}
+	if l.ExpiresAt.IsZero() {
+		l.ExpiresAt = start.Add(Lifetime)
+	}
+}
+
+// ExpiryDetail describes when a listing expires, for the notifications of its watchers.
+func ExpiryDetail(expiresAt time.Time) string {
+	return "on " + expiresAt.UTC().Format("2 Jan 2006")
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Renew puts a listing back on the market until the given time, publishing it straight away if
+// it was waiting to be published. Sold listings cannot be renewed.
+func (m *ListingModel) Renew(ctx context.Context, id int64, until time.Time) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &ListingModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if before.IsDeleted() {
+		return errors.ErrRecordNotFound
+	}
+	if before.SoldAt != nil {
+		return errors.ErrAlreadySold
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE listings
+		SET expires_at = $2, expiry_reminded_at = NULL, publish_at = NULL, is_active = TRUE, updated_at = NOW(), version = version + 1
+		WHERE id = $1`, id, until)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Listings")
+	}
+
+	after, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "listings", id, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Publish activates the listings whose publish_at has come by now and returns their ids.
+func (m *ListingModel) Publish(ctx context.Context, now time.Time) ([]int64, error) {
+	query := `
+		WITH due AS (
+			SELECT id, publish_at FROM listings
+			WHERE publish_at <= $1 AND sold_at IS NULL AND deleted_at IS NULL
+			FOR UPDATE
+		)
+		UPDATE listings AS l
+		SET is_active = TRUE, publish_at = NULL, updated_at = NOW(), version = l.version + 1
+		FROM due
+		WHERE l.id = due.id
+		RETURNING l.id, due.publish_at`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, errors.WrapUpdateError(err, "Listings")
+	}
+	defer rows.Close()
+
+	published := map[int64]time.Time{}
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var publishAt time.Time
+		if err := rows.Scan(&id, &publishAt); err != nil {
+			return nil, errors.WrapUpdateError(err, "Listings")
+		}
+		published[id] = publishAt
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		err := audit.RecordChanges(ctx, tx, "listings", id, audit.ActionUpdate, audit.Changes{
+			"is_active":  audit.Field(false, true),
+			"publish_at": audit.Field(published[id], nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit()
+}
+
+// Expire takes the listings whose expires_at has passed by now off the market and returns their
+// ids. They stay inactive until they are renewed.
+func (m *ListingModel) Expire(ctx context.Context, now time.Time) ([]int64, error) {
+	query := `
+		UPDATE listings
+		SET is_active = FALSE, updated_at = NOW(), version = version + 1
+		WHERE is_active AND expires_at <= $1 AND sold_at IS NULL AND deleted_at IS NULL
+		RETURNING id`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, errors.WrapUpdateError(err, "Listings")
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.WrapUpdateError(err, "Listings")
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		err := audit.RecordChanges(ctx, tx, "listings", id, audit.ActionUpdate, audit.Changes{"is_active": audit.Field(true, false)})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit()
+}
+
+// GetExpiring returns the ids of the active listings of undeleted sellers that expire by before
+// and whose sellers have not been reminded yet, soonest first.
+func (m *ListingModel) GetExpiring(ctx context.Context, before time.Time) ([]int64, error) {
+	query := `
+		SELECT id FROM listings
+		WHERE is_active AND expiry_reminded_at IS NULL AND expires_at <= $1
+		AND sold_at IS NULL AND deleted_at IS NULL
+		AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		ORDER BY expires_at, id`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, errors.WrapGetAllError(err, "Listings")
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.WrapGetAllError(err, "Listings")
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkReminded records that the seller of a listing was reminded of its expiry at the given time,
+// and lets its watchers know it is expiring. The reminder is bookkeeping of the scheduler, so the
+// listing keeps its version and the audit trail leaves it out.
+func (m *ListingModel) MarkReminded(ctx context.Context, id int64, at time.Time) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		UPDATE listings SET expiry_reminded_at = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING expires_at`, id, at).Scan(&expiresAt)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return errors.ErrRecordNotFound
+		default:
+			return errors.WrapUpdateError(err, "Listings")
+		}
+	}
+	if err := watchlists.Notify(ctx, tx, id, watchlists.EventExpiring, ExpiryDetail(expiresAt)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,120 @@
+// File: internal/data/listings/schedule_test.go
+package listings_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestListingScheduleDefaults(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	now := time.Now().Truncate(time.Second)
+
+	plain := f.Listing()
+	if plain.PublishAt != nil || !*plain.IsActive || plain.ExpiresAt.Before(now.Add(listings.Lifetime)) {
+		t.Errorf("plain listing = %+v, want it active and expiring a lifetime from now", plain)
+	}
+
+	publishAt := now.Add(time.Hour)
+	scheduled := f.Listing(func(l *listings.Listing) { l.PublishAt = &publishAt })
+	got, err := models.Listings.GetByID(t.Context(), scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PublishAt == nil || !got.PublishAt.Equal(publishAt) || *got.IsActive || !got.ExpiresAt.Equal(publishAt.Add(listings.Lifetime)) {
+		t.Errorf("scheduled listing = %+v, want it inactive until %v and expiring a lifetime after", got, publishAt)
+	}
+
+	past := now.Add(-time.Hour)
+	late := f.Listing(func(l *listings.Listing) { l.PublishAt = &past })
+	if late.PublishAt != nil || !*late.IsActive {
+		t.Errorf("listing scheduled in the past = %+v, want it published straight away", late)
+	}
+}
+
+func TestListingSchedule(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+	now := time.Now().Truncate(time.Second)
+
+	publishAt := now.Add(time.Hour)
+	scheduled := f.Listing(func(l *listings.Listing) { l.PublishAt = &publishAt })
+	expiring := f.Listing(func(l *listings.Listing) { l.ExpiresAt = now.Add(time.Hour) })
+	expired := f.Listing(func(l *listings.Listing) { l.ExpiresAt = now.Add(-time.Hour) })
+	sold := f.Listing(func(l *listings.Listing) { l.ExpiresAt = now.Add(-time.Hour) })
+	if err := models.Listings.MarkSold(ctx, sold.ID); err != nil {
+		t.Fatal(err)
+	}
+	watcher := f.Watch(func(w *watchlists.Watch) { w.ListingID = &expiring.ID })
+
+	if ids, err := models.Listings.Publish(ctx, now); err != nil || len(ids) != 0 {
+		t.Errorf("Publish before the publish time = %v, %v, want nothing published", ids, err)
+	}
+	if ids, err := models.Listings.Publish(ctx, publishAt); err != nil || !slices.Equal(ids, []int64{scheduled.ID}) {
+		t.Errorf("Publish = %v, %v, want [%d]", ids, err, scheduled.ID)
+	}
+	got, err := models.Listings.GetByID(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PublishAt != nil || !*got.IsActive || got.Version != scheduled.Version+1 {
+		t.Errorf("published listing = %+v, want it active at the next version", got)
+	}
+
+	if ids, err := models.Listings.Expire(ctx, now); err != nil || !slices.Equal(ids, []int64{expired.ID}) {
+		t.Errorf("Expire = %v, %v, want [%d]", ids, err, expired.ID)
+	}
+	if ids, err := models.Listings.Expire(ctx, now); err != nil || len(ids) != 0 {
+		t.Errorf("second Expire = %v, %v, want nothing expired", ids, err)
+	}
+
+	if ids, err := models.Listings.GetExpiring(ctx, now.Add(2*time.Hour)); err != nil || !slices.Equal(ids, []int64{expiring.ID}) {
+		t.Errorf("GetExpiring = %v, %v, want [%d]", ids, err, expiring.ID)
+	}
+	if err := models.Listings.MarkReminded(ctx, expiring.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := models.Listings.GetExpiring(ctx, now.Add(2*time.Hour)); err != nil || len(ids) != 0 {
+		t.Errorf("GetExpiring after the reminder = %v, %v, want none", ids, err)
+	}
+	pending, err := models.Watches.GetPendingNotifications(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detail := listings.ExpiryDetail(expiring.ExpiresAt)
+	if len(pending) != 1 || pending[0].UserID != watcher.UserID || pending[0].Event != watchlists.EventExpiring || pending[0].Detail != detail {
+		t.Errorf("pending notifications = %+v, want the watcher told it expires %s", pending, detail)
+	}
+
+	until := now.Add(listings.Lifetime)
+	for _, id := range []int64{expired.ID, expiring.ID} {
+		if err := models.Listings.Renew(ctx, id, until); err != nil {
+			t.Fatal(err)
+		}
+		got, err := models.Listings.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !*got.IsActive || !got.ExpiresAt.Equal(until) || got.RemindedAt != nil {
+			t.Errorf("renewed listing = %+v, want it active until %v and due a new reminder", got, until)
+		}
+	}
+	if err := models.Listings.Renew(ctx, sold.ID, until); !errors.Is(err, internalErrors.ErrAlreadySold) {
+		t.Errorf("Renew of a sold listing error = %v, want ErrAlreadySold", err)
+	}
+	if err := models.Listings.SoftDelete(ctx, expired.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Listings.Renew(ctx, expired.ID, until); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Renew of a deleted listing error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -1,12 +1,13 @@
-// File: internal/data/locations/districts.go
+// File: internal/data/locations/areas.go
 package locations
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
@@ -31,16 +32,20 @@ const (
 	AreaTypeVillage AreaType = "village"
 )
 
-// Area represents a geographical area within a district.
+// Area represents a geographical area within a region.
 type Area struct {
 	ID          int         `json:"id"`
 	Name        string      `json:"name"`
-	DistrictID  int         `json:"district_id"`
+	RegionID    int         `json:"region_id"`
 	AreaType    AreaType    `json:"area_type"`
 	Coordinates Coordinates `json:"coordinates"`
 	IsActive    *bool       `json:"is_active"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
+	Version     int         `json:"version"`
+	database.Deletion
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DistanceKm is only set by proximity searches.
+	DistanceKm *float64 `json:"distance_km,omitempty"`
 }
 
 // Areas is a slice of Area.
@@ -48,16 +53,35 @@ type Areas []Area
 
 // AreaFilter represents filtering options for querying areas.
 type AreaFilter struct {
-	Name       string
-	DistrictID *int
-	AreaType   *AreaType
-	IsActive   *bool
-	Default    filters.Filters
+	Name     string
+	RegionID *int
+	AreaType *AreaType
+	IsActive *bool
+	Near     *Proximity
+	Box      *BoundingBox
+	Default  filters.Filters
+
+	// HasCoordinates limits results to areas that can be placed on a map.
+	HasCoordinates bool
+	// WithDeleted includes soft deleted areas, which are left out by default.
+	WithDeleted bool
 }
 
 // AreasModel represents the model for areas.
 type AreaModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// AreaRepository is the interface for storing and querying areas.
+type AreaRepository interface {
+	Insert(ctx context.Context, a *Area) error
+	Update(ctx context.Context, a *Area) error
+	SoftDelete(ctx context.Context, id int, deletedBy int64) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetByID(ctx context.Context, id int) (*Area, error)
+	GetAll(ctx context.Context, filter *AreaFilter) (Areas, filters.MetaData, error)
 }
 
 // ValidateCoordinates validates the latitude and longitude values.
@@ -72,7 +96,8 @@ func ValidateCoordinates(v *validator.Validator, c Coordinates) {
 func ValidateArea(v *validator.Validator, a *Area) {
 	v.Check(a.Name != "", "name", "must be provided")
 	v.Check(len(a.Name) <= 255, "name", "must not be more than 255 bytes long")
-	v.Check(a.DistrictID > 0, "district_id", "must be provided and greater than zero")
+	v.Check(a.RegionID >= 0, "region_id", "must be greater than zero")
+	v.Check(a.RegionID > 0 || a.Coordinates.Latitude != 0 || a.Coordinates.Longitude != 0, "region_id", "must be provided when coordinates are not")
 	v.Check(a.AreaType == AreaTypeCity || a.AreaType == AreaTypeTown || a.AreaType == AreaTypeVillage, "area_type", "must be a valid area type")
 	ValidateCoordinates(v, a.Coordinates)
 }
@@ -81,53 +106,86 @@ func ValidateArea(v *validator.Validator, a *Area) {
  *										Methods											*
  ***************************************************************************************/
 
-// Insert adds a new area to the database.
-func (m *AreaModel) Insert(a Area) error {
+// Insert adds a new area to the database, deriving its region from its coordinates where a boundary covers them.
+func (m *AreaModel) Insert(ctx context.Context, a *Area) error {
+	if err := m.assignRegion(ctx, a); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO areas (name, district_id, area_type, latitude, longitude, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at
+		INSERT INTO areas (name, region_id, area_type, latitude, longitude, is_active)
+		VALUES ($1, $2, $3, NULLIF($4::float8, 0), NULLIF($5::float8, 0), COALESCE($6, TRUE))
+		RETURNING id, is_active, version, created_at, updated_at
 	`
-	args := []any{a.Name, a.DistrictID, a.AreaType, a.Coordinates.Latitude, a.Coordinates.Longitude, a.IsActive}
+	args := []any{a.Name, a.RegionID, a.AreaType, a.Coordinates.Latitude, a.Coordinates.Longitude, a.IsActive}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&a.ID, &a.IsActive, &a.Version, &a.CreatedAt, &a.UpdatedAt)
 	if err != nil {
 		switch {
-		case errors.IsUniqueViolation(err, "areas_name_key"):
+		case errors.IsUniqueViolation(err, "region_id, name"):
 			return errors.ErrDuplicateValue("name")
 		case errors.IsForeignKeyViolation(err):
-			return errors.ErrInvalidDistrictID
+			return errors.ErrInvalidRegionID
 		default:
 			return err
 		}
 	}
 
-	return nil
+	if err := audit.Record(ctx, tx, "areas", int64(a.ID), audit.ActionInsert, nil, a); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Update modifies an existing area in the database.
-func (m *AreaModel) Update(a *Area) error {
+// Update modifies an existing area in the database, deriving its region from its coordinates where a boundary covers them.
+// Listings in the area follow it into its new region.
+func (m *AreaModel) Update(ctx context.Context, a *Area) error {
+	if err := m.assignRegion(ctx, a); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE areas
-		SET name = $1, district_id = $2, area_type = $3, latitude = $4, longitude = $5, is_active = $6, updated_at = NOW()
-		WHERE id = $7
-		RETURNING updated_at
+		SET name = $1, region_id = $2, area_type = $3, latitude = NULLIF($4::float8, 0), longitude = NULLIF($5::float8, 0), is_active = COALESCE($6, is_active), updated_at = NOW(), version = version + 1
+		WHERE id = $7 AND version = $8 AND deleted_at IS NULL
+		RETURNING updated_at, version
 	`
-	args := []any{a.Name, a.DistrictID, a.AreaType, a.Coordinates.Latitude, a.Coordinates.Longitude, a.IsActive, a.ID}
+	args := []any{a.Name, a.RegionID, a.AreaType, a.Coordinates.Latitude, a.Coordinates.Longitude, a.IsActive, a.ID, a.Version}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&a.UpdatedAt)
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &AreaModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, a.ID)
+	if err != nil {
+		if errors.IsRecordNotFound(err) {
+			return errors.ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&a.UpdatedAt, &a.Version)
 	if err != nil {
 		switch {
-		case errors.IsUniqueViolation(err, "areas_name_key"):
-			return errors.ErrDuplicateName
+		case errors.IsUniqueViolation(err, "region_id, name"):
+			return errors.ErrDuplicateValue("name")
 		case errors.IsForeignKeyViolation(err):
-			return errors.ErrInvalidDistrictID
+			return errors.ErrInvalidRegionID
 		case errors.IsEditConflict(err):
 			return errors.ErrEditConflict
 		default:
@@ -135,39 +193,46 @@ func (m *AreaModel) Update(a *Area) error {
 		}
 	}
 
-	return nil
+	after, err := current.GetByID(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "areas", int64(a.ID), audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Delete Permanently removes an area from the database.
-func (m *AreaModel) Delete(id int) error {
-	query := `
-		DELETE FROM areas
-		WHERE id = $1
-	`
+// SoftDelete marks an area as deleted by deletedBy. Listings in the area keep it until it is purged.
+func (m *AreaModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.SoftDelete(ctx, m.DB, "areas", int64(id), deletedBy)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// Restore brings a soft deleted area back.
+func (m *AreaModel) Restore(ctx context.Context, id int) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+	return audit.Restore(ctx, m.DB, "areas", int64(id))
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
-		return errors.ErrRecordNotFound
-	}
+// Purge permanently deletes the areas soft deleted before the cutoff. Areas that still have
+// listings are kept, since purging them would take the listings with them.
+func (m *AreaModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	guard := `NOT EXISTS (SELECT 1 FROM listings AS l WHERE l.area_id = areas.id)`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	return nil
+	return audit.Purge(ctx, m.DB, "areas", before, guard)
 }
 
 // Get retrieves a specific area by its ID.
-func (m *AreaModel) GetByID(id int) (*Area, error) {
+func (m *AreaModel) GetByID(ctx context.Context, id int) (*Area, error) {
 	query := `
-		SELECT id, name, district_id, area_type, latitude, longitude, is_active, created_at, updated_at
+		SELECT id, name, region_id, area_type, COALESCE(latitude, 0), COALESCE(longitude, 0), is_active, version, deleted_at, deleted_by, created_at, updated_at
 		FROM areas
 		WHERE id = $1
 	`
@@ -177,16 +242,19 @@ func (m *AreaModel) GetByID(id int) (*Area, error) {
 	scan := []any{
 		&a.ID,
 		&a.Name,
-		&a.DistrictID,
+		&a.RegionID,
 		&a.AreaType,
 		&a.Coordinates.Latitude,
 		&a.Coordinates.Longitude,
 		&a.IsActive,
+		&a.Version,
+		&a.DeletedAt,
+		&a.DeletedBy,
 		&a.CreatedAt,
 		&a.UpdatedAt,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(scan...)
@@ -203,26 +271,47 @@ func (m *AreaModel) GetByID(id int) (*Area, error) {
 }
 
 // GetAll retrieves all areas matching the provided filter criteria.
-func (m *AreaModel) GetAll(filter *AreaFilter) (Areas, filters.MetaData, error) {
+// Proximity searches only match areas with coordinates and report each area's distance.
+func (m *AreaModel) GetAll(ctx context.Context, filter *AreaFilter) (Areas, filters.MetaData, error) {
+	// distance_km is only a column alias, so sort by the expression itself
+	column := func(name string) string {
+		if name == "distance_km" {
+			return "haversine_km($11, $12::float8, latitude, longitude)"
+		}
+		return name
+	}
+	page := filter.Default.SQL(column, "id", 16)
+
 	query := fmt.Sprintf(`
-        SELECT COUNT(*) OVER(), id, name, district_id, area_type, latitude, longitude, is_active, created_at, updated_at
+        SELECT %s, %s, id, name, region_id, area_type, COALESCE(latitude, 0), COALESCE(longitude, 0), is_active, version, deleted_at, deleted_by, created_at, updated_at,
+            CASE WHEN $11::float8 IS NULL THEN NULL ELSE haversine_km($11, $12::float8, latitude, longitude) END AS distance_km
         FROM areas
         WHERE ($1 = '' OR LOWER(name) ILIKE LOWER('%%' || $1 || '%%'))
-        AND ($2::bigint IS NULL OR district_id = $2)
-        AND ($3::text IS NULL OR area_type = $3)
+        AND ($2::bigint IS NULL OR region_id = $2)
+        AND ($3::area_type_enum IS NULL OR area_type = $3)
         AND ($4::boolean IS NULL OR is_active = $4)
-        ORDER BY %s %s, id ASC
-        LIMIT $5 OFFSET $6`, filter.Default.SortColumn(), filter.Default.SortDirection())
+        AND ($7::float8 IS NULL OR (latitude BETWEEN $7 AND $9::float8 AND longitude BETWEEN $8::float8 AND $10::float8))
+        AND ($11::float8 IS NULL OR haversine_km($11, $12, latitude, longitude) <= $13::float8)
+        AND (NOT $14::boolean OR (latitude IS NOT NULL AND longitude IS NOT NULL))
+        AND ($15 OR deleted_at IS NULL)
+        AND %s
+        ORDER BY %s
+        LIMIT $5 OFFSET $6`, page.Count, page.SortKey, page.Where, page.OrderBy)
 
 	args := []any{
 		filter.Name,
-		filter.DistrictID,
+		filter.RegionID,
 		filter.AreaType,
+		filter.IsActive,
 		filter.Default.Limit(),
 		filter.Default.Offset(),
 	}
+	args = append(args, SearchBox(filter.Near, filter.Box).Args()...)
+	args = append(args, filter.Near.Args()...)
+	args = append(args, filter.HasCoordinates, filter.WithDeleted)
+	args = append(args, page.Args...)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -233,20 +322,27 @@ func (m *AreaModel) GetAll(filter *AreaFilter) (Areas, filters.MetaData, error)
 
 	totalRecords := 0
 	areas := Areas{}
+	keys := []filters.Key{}
 
 	for rows.Next() {
 		var a Area
+		var key filters.Key
 		scan := []any{
 			&totalRecords,
+			&key.Values,
 			&a.ID,
 			&a.Name,
-			&a.DistrictID,
+			&a.RegionID,
 			&a.AreaType,
 			&a.Coordinates.Latitude,
 			&a.Coordinates.Longitude,
 			&a.IsActive,
+			&a.Version,
+			&a.DeletedAt,
+			&a.DeletedBy,
 			&a.CreatedAt,
 			&a.UpdatedAt,
+			&a.DistanceKm,
 		}
 
 		err := rows.Scan(scan...)
@@ -254,14 +350,31 @@ func (m *AreaModel) GetAll(filter *AreaFilter) (Areas, filters.MetaData, error)
 			return nil, filters.EmptyMetaData, err
 		}
 
+		key.ID = int64(a.ID)
 		areas = append(areas, a)
+		keys = append(keys, key)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, filters.EmptyMetaData, err
 	}
 
-	metadata := filters.CalculateMetaData(totalRecords, filter.Default.Page, filter.Default.PageSize)
+	areas, metadata := filters.Paginate(filter.Default, areas, keys, totalRecords)
 
 	return areas, metadata, nil
 }
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// assignRegion sets the area's region from its coordinates, or checks the one it was given.
+func (m *AreaModel) assignRegion(ctx context.Context, a *Area) error {
+	regions := RegionModel{DB: m.DB, Timeout: m.Timeout}
+	regionID, err := regions.ResolveRegion(ctx, a.RegionID, a.Coordinates)
+	if err != nil {
+		return err
+	}
+	a.RegionID = regionID
+	return nil
+}
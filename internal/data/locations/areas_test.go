@@ -0,0 +1,228 @@
+// File: internal/data/locations/areas_test.go
+package locations_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestAreaCRUD(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	a := f.Area(func(a *locations.Area) { a.AreaType = locations.AreaTypeVillage })
+	if a.IsActive == nil || !*a.IsActive {
+		t.Errorf("new area is_active = %v, want true", a.IsActive)
+	}
+	got, err := models.Areas.GetByID(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != a.Name || got.RegionID != a.RegionID || got.AreaType != locations.AreaTypeVillage || got.Coordinates != (locations.Coordinates{}) {
+		t.Errorf("got %+v, want %+v", got, a)
+	}
+
+	// Names are unique within a region only.
+	clash := &locations.Area{Name: a.Name, RegionID: a.RegionID, AreaType: locations.AreaTypeTown}
+	if err := models.Areas.Insert(ctx, clash); err == nil || err.Error() != internalErrors.ErrDuplicateValue("name").Error() {
+		t.Errorf("Insert error = %v, want duplicate name", err)
+	}
+	f.Area(func(other *locations.Area) { other.Name = a.Name })
+
+	invalid := &locations.Area{Name: "Nowhere", RegionID: 404, AreaType: locations.AreaTypeTown}
+	if err := models.Areas.Insert(ctx, invalid); !errors.Is(err, internalErrors.ErrInvalidRegionID) {
+		t.Errorf("Insert error = %v, want ErrInvalidRegionID", err)
+	}
+
+	got.Name = "Renamed"
+	got.Coordinates = locations.Coordinates{Latitude: 17.25, Longitude: -88.77}
+	got.IsActive = nil // keeps the stored flag
+	if err := models.Areas.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Areas.GetByID(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Renamed" || got.Coordinates.Latitude != 17.25 || !*got.IsActive || got.Version != 2 {
+		t.Errorf("got %+v, want the update applied", got)
+	}
+
+	missing := *got
+	missing.ID = 404
+	if err := models.Areas.Update(ctx, &missing); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a missing area error = %v, want ErrEditConflict", err)
+	}
+
+	stale := *got
+	stale.Version = 1
+	if err := models.Areas.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a stale area error = %v, want ErrEditConflict", err)
+	}
+
+	if err := models.Areas.SoftDelete(ctx, a.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Areas.GetByID(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt == nil || got.Version != 3 {
+		t.Errorf("got %+v, want it deleted", got)
+	}
+	if err := models.Areas.Update(ctx, got); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a deleted area error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Areas.SoftDelete(ctx, a.ID, 0); !errors.Is(err, internalErrors.ErrAlreadyDeleted) {
+		t.Errorf("second SoftDelete error = %v, want ErrAlreadyDeleted", err)
+	}
+	if err := models.Areas.Restore(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Areas.Restore(ctx, a.ID); !errors.Is(err, internalErrors.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want ErrNotDeleted", err)
+	}
+}
+
+func TestAreaPurge(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	// An area is only purged once its listings are gone.
+	a := f.Area()
+	l := f.Listing(func(l *listings.Listing) { l.AreaID = int64(a.ID) })
+	if err := models.Areas.SoftDelete(ctx, a.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	cutoff := time.Now().Add(time.Hour)
+	if n, err := models.Areas.Purge(ctx, cutoff); err != nil || n != 0 {
+		t.Errorf("Purge with a listing = %d, %v, want nothing purged", n, err)
+	}
+
+	if err := models.Listings.SoftDelete(ctx, l.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := models.Listings.Purge(ctx, cutoff); err != nil || n != 1 {
+		t.Errorf("listing Purge = %d, %v, want 1 purged", n, err)
+	}
+	if n, err := models.Areas.Purge(ctx, cutoff); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want 1 purged", n, err)
+	}
+	if _, err := models.Areas.GetByID(ctx, a.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestAreaRegion(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	bounded := f.Region(func(r *locations.Region) { r.Boundary = square })
+	other := f.Region()
+	inside := locations.Coordinates{Latitude: 17.5, Longitude: -88.5}
+
+	a := f.Area(func(a *locations.Area) { a.Coordinates = inside })
+	if a.RegionID != bounded.ID {
+		t.Errorf("area region = %d, want %d from its coordinates", a.RegionID, bounded.ID)
+	}
+
+	conflicting := &locations.Area{Name: "Conflict", RegionID: other.ID, AreaType: locations.AreaTypeTown, Coordinates: inside}
+	if err := models.Areas.Insert(ctx, conflicting); !errors.Is(err, internalErrors.ErrRegionMismatch) {
+		t.Errorf("Insert error = %v, want ErrRegionMismatch", err)
+	}
+
+	unknown := &locations.Area{Name: "Unknown", AreaType: locations.AreaTypeTown}
+	if err := models.Areas.Insert(ctx, unknown); !errors.Is(err, internalErrors.ErrRegionUnknown) {
+		t.Errorf("Insert error = %v, want ErrRegionUnknown", err)
+	}
+
+	// Listings follow their area into its new region.
+	moved := f.Area(func(a *locations.Area) { a.RegionID = other.ID })
+	l := f.Listing(func(l *listings.Listing) { l.AreaID = int64(moved.ID) })
+	moved.RegionID = bounded.ID
+	if err := models.Areas.Update(ctx, moved); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Listings.GetByID(ctx, l.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RegionID != int64(bounded.ID) {
+		t.Errorf("listing region = %d, want %d", got.RegionID, bounded.ID)
+	}
+}
+
+func TestAreaGetAll(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	cayo := f.Region()
+	belmopan := f.Area(func(a *locations.Area) {
+		a.Name, a.RegionID, a.AreaType = "Belmopan", cayo.ID, locations.AreaTypeCity
+		a.Coordinates = locations.Coordinates{Latitude: 17.25, Longitude: -88.77}
+	})
+	orangeWalk := f.Area(func(a *locations.Area) {
+		a.Name, a.AreaType = "Orange Walk Town", locations.AreaTypeTown
+		a.Coordinates = locations.Coordinates{Latitude: 18.08, Longitude: -88.56}
+	})
+	sanIgnacio := f.Area(func(a *locations.Area) {
+		a.Name, a.RegionID, a.AreaType = "San Ignacio", cayo.ID, locations.AreaTypeTown
+		a.IsActive = testdb.Ptr(false)
+	})
+	benque := f.Area(func(a *locations.Area) { a.Name, a.RegionID = "Benque Viejo", cayo.ID })
+	if err := models.Areas.SoftDelete(ctx, benque.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	near := &locations.Proximity{Center: locations.Coordinates{Latitude: 17.25, Longitude: -88.77}, RadiusKm: 10}
+	wide := &locations.Proximity{Center: near.Center, RadiusKm: 200}
+	box := &locations.BoundingBox{MinLatitude: 18, MinLongitude: -89, MaxLatitude: 18.5, MaxLongitude: -88}
+
+	tests := []struct {
+		name   string
+		filter locations.AreaFilter
+		want   []int
+	}{
+		{"by name descending", locations.AreaFilter{Default: testdb.FirstPage("-name")}, []int{sanIgnacio.ID, orangeWalk.ID, belmopan.ID}},
+		{"name", locations.AreaFilter{Name: "walk", Default: testdb.FirstPage("id")}, []int{orangeWalk.ID}},
+		{"region", locations.AreaFilter{RegionID: testdb.Ptr(cayo.ID), Default: testdb.FirstPage("id")}, []int{belmopan.ID, sanIgnacio.ID}},
+		{"with deleted", locations.AreaFilter{RegionID: testdb.Ptr(cayo.ID), WithDeleted: true, Default: testdb.FirstPage("id")}, []int{belmopan.ID, sanIgnacio.ID, benque.ID}},
+		{"type", locations.AreaFilter{AreaType: testdb.Ptr(locations.AreaTypeTown), Default: testdb.FirstPage("id")}, []int{orangeWalk.ID, sanIgnacio.ID}},
+		{"active", locations.AreaFilter{IsActive: testdb.Ptr(true), Default: testdb.FirstPage("id")}, []int{belmopan.ID, orangeWalk.ID}},
+		{"has coordinates", locations.AreaFilter{HasCoordinates: true, Default: testdb.FirstPage("id")}, []int{belmopan.ID, orangeWalk.ID}},
+		{"near", locations.AreaFilter{Near: near, Default: testdb.FirstPage("distance_km")}, []int{belmopan.ID}},
+		{"nearest first", locations.AreaFilter{Near: wide, Default: testdb.FirstPage("distance_km")}, []int{belmopan.ID, orangeWalk.ID}},
+		{"box", locations.AreaFilter{Box: box, Default: testdb.FirstPage("id")}, []int{orangeWalk.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := models.Areas.GetAll(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, a := range got {
+				ids = append(ids, a.ID)
+				if (tt.filter.Near != nil) != (a.DistanceKm != nil) {
+					t.Errorf("area %d distance = %v with proximity %v", a.ID, a.DistanceKm, tt.filter.Near)
+				}
+			}
+			if !equal(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("total records = %d, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// File: internal/data/locations/boundary.go
+package locations
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// MaxBoundaryPositions caps the size of a region boundary; simplify shapes before uploading them.
+const MaxBoundaryPositions = 20000
+
+// MultiPolygon is a region boundary in GeoJSON MultiPolygon layout: polygons made of rings made of
+// [longitude, latitude] positions. The first ring of each polygon is its outline, any others are holes.
+type MultiPolygon [][][][]float64
+
+// MarshalJSON encodes the boundary as a GeoJSON MultiPolygon geometry.
+func (mp MultiPolygon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Geometry{Type: GeoJSONMultiPolygon, Coordinates: [][][][]float64(mp)})
+}
+
+// UnmarshalJSON accepts either a GeoJSON Polygon or MultiPolygon geometry, or null for no boundary.
+func (mp *MultiPolygon) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*mp = nil
+		return nil
+	}
+
+	var g struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return err
+	}
+
+	switch g.Type {
+	case GeoJSONPolygon:
+		var polygon [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return err
+		}
+		*mp = MultiPolygon{polygon}
+	case GeoJSONMultiPolygon:
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return err
+		}
+		*mp = MultiPolygon(polygons)
+	default:
+		return fmt.Errorf("boundary must be a Polygon or MultiPolygon, got %q", g.Type)
+	}
+	return nil
+}
+
+// Value stores the boundary as JSONB, or NULL when there is none.
+func (mp MultiPolygon) Value() (driver.Value, error) {
+	if mp == nil {
+		return nil, nil
+	}
+	return mp.MarshalJSON()
+}
+
+// Scan decodes a boundary stored as JSONB, or NULL.
+func (mp *MultiPolygon) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*mp = nil
+		return nil
+	case []byte:
+		return mp.UnmarshalJSON(v)
+	case string:
+		return mp.UnmarshalJSON([]byte(v))
+	default:
+		return fmt.Errorf("cannot scan %T into MultiPolygon", src)
+	}
+}
+
+// ValidateBoundary checks that every ring is closed, has at least three corners and uses valid positions.
+func ValidateBoundary(v *validator.Validator, mp MultiPolygon) {
+	v.Check(len(mp) > 0, "boundary", "must contain at least one polygon")
+
+	positions := 0
+	for _, polygon := range mp {
+		v.Check(len(polygon) > 0, "boundary", "polygons must contain at least one ring")
+		for _, ring := range polygon {
+			positions += len(ring)
+			if len(ring) < 4 {
+				v.AddError("boundary", "rings must contain at least four positions")
+				continue
+			}
+			pairs := true
+			for _, position := range ring {
+				if len(position) != 2 {
+					pairs = false
+					break
+				}
+				v.Check(position[0] >= -180 && position[0] <= 180, "boundary", "longitudes must be between -180 and 180")
+				v.Check(position[1] >= -90 && position[1] <= 90, "boundary", "latitudes must be between -90 and 90")
+			}
+			if !pairs {
+				v.AddError("boundary", "positions must be [longitude, latitude] pairs")
+				continue
+			}
+			first, last := ring[0], ring[len(ring)-1]
+			v.Check(first[0] == last[0] && first[1] == last[1], "boundary", "rings must be closed")
+		}
+	}
+	v.Check(positions <= MaxBoundaryPositions, "boundary", fmt.Sprintf("must not contain more than %d positions", MaxBoundaryPositions))
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// Bounds returns the smallest box containing every outline of the boundary.
+func (mp MultiPolygon) Bounds() BoundingBox {
+	b := BoundingBox{MinLatitude: math.Inf(1), MinLongitude: math.Inf(1), MaxLatitude: math.Inf(-1), MaxLongitude: math.Inf(-1)}
+	for _, polygon := range mp {
+		if len(polygon) == 0 {
+			continue
+		}
+		for _, position := range polygon[0] {
+			b.MinLongitude, b.MaxLongitude = math.Min(b.MinLongitude, position[0]), math.Max(b.MaxLongitude, position[0])
+			b.MinLatitude, b.MaxLatitude = math.Min(b.MinLatitude, position[1]), math.Max(b.MaxLatitude, position[1])
+		}
+	}
+	return b
+}
+
+// Contains reports whether c falls inside the boundary: inside the outline of some polygon and
+// outside all of that polygon's holes. Points exactly on an edge may fall on either side.
+func (mp MultiPolygon) Contains(c Coordinates) bool {
+	for _, polygon := range mp {
+		if len(polygon) == 0 || !ringContains(polygon[0], c) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, c) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains is the even-odd ray casting test: a ray cast east from c crosses the ring an odd
+// number of times exactly when c is inside it.
+func ringContains(ring [][]float64, c Coordinates) bool {
+	x, y := c.Longitude, c.Latitude
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
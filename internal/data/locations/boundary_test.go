@@ -0,0 +1,69 @@
+// File: internal/data/locations/boundary_test.go
+package locations_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+)
+
+func TestMultiPolygonUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		want  locations.MultiPolygon
+		error bool
+	}{
+		{"null clears the boundary", `{"boundary": null}`, nil, false},
+		{"missing keeps the boundary", `{}`, square, false},
+		{"polygon", `{"boundary": {"type": "Polygon", "coordinates": [[[-89, 17], [-88, 17], [-88, 18], [-89, 18], [-89, 17]]]}}`, square, false},
+		{"multipolygon", `{"boundary": {"type": "MultiPolygon", "coordinates": [[[[-89, 17], [-88, 17], [-88, 18], [-89, 18], [-89, 17]]]]}}`, square, false},
+		{"point", `{"boundary": {"type": "Point", "coordinates": [-89, 17]}}`, nil, true},
+		{"bad coordinates", `{"boundary": {"type": "Polygon", "coordinates": [-89, 17]}}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The input of updateRegionBoundaryHandler, decoded over a boundary so that null must clear it.
+			input := struct {
+				Boundary locations.MultiPolygon `json:"boundary"`
+			}{Boundary: square}
+
+			err := json.Unmarshal([]byte(tt.body), &input)
+			if tt.error {
+				if err == nil {
+					t.Errorf("Unmarshal(%s) succeeded, want an error", tt.body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.body, err)
+			}
+			if !reflect.DeepEqual(input.Boundary, tt.want) {
+				t.Errorf("boundary = %v, want %v", input.Boundary, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiPolygonRoundTrip(t *testing.T) {
+	js, err := json.Marshal(square)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got locations.MultiPolygon
+	if err := json.Unmarshal(js, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, square) {
+		t.Errorf("round trip of %s = %v, want %v", js, got, square)
+	}
+
+	if err := got.Scan(nil); err != nil || got != nil {
+		t.Errorf("Scan(nil) = %v, %v, want no boundary", got, err)
+	}
+	if v, err := got.Value(); err != nil || v != nil {
+		t.Errorf("Value of no boundary = %v, %v, want NULL", v, err)
+	}
+}
@@ -0,0 +1,118 @@
+// File: internal/data/locations/geo.go
+package locations
+
+import (
+	"math"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Earth and search constants.
+const (
+	EarthRadiusKm     = 6371.0088 // mean Earth radius, matches haversine_km in the database
+	MaxSearchRadiusKm = 500       // comfortably covers all of Belize
+	kmPerDegree       = math.Pi * EarthRadiusKm / 180
+)
+
+// Proximity is a "within RadiusKm of Center" search.
+type Proximity struct {
+	Center   Coordinates
+	RadiusKm float64
+}
+
+// BoundingBox is a latitude/longitude rectangle. Boxes crossing the antimeridian are not supported.
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+/****************************************************************************************
+ *										Validation										*
+ ***************************************************************************************/
+
+// ValidateProximity validates the centre and radius of a proximity search.
+func ValidateProximity(v *validator.Validator, p *Proximity) {
+	v.Check(p.Center.Latitude >= -90 && p.Center.Latitude <= 90, "near", "latitude must be between -90 and 90")
+	v.Check(p.Center.Longitude >= -180 && p.Center.Longitude <= 180, "near", "longitude must be between -180 and 180")
+	v.Check(p.RadiusKm > 0, "radius_km", "must be greater than zero")
+	v.Check(p.RadiusKm <= MaxSearchRadiusKm, "radius_km", "must not be more than 500")
+}
+
+// ValidateBoundingBox validates the corners of a bounding box.
+func ValidateBoundingBox(v *validator.Validator, b *BoundingBox) {
+	v.Check(b.MinLatitude >= -90 && b.MaxLatitude <= 90, "bbox", "latitudes must be between -90 and 90")
+	v.Check(b.MinLongitude >= -180 && b.MaxLongitude <= 180, "bbox", "longitudes must be between -180 and 180")
+	v.Check(b.MinLatitude <= b.MaxLatitude, "bbox", "minimum latitude must not be greater than maximum latitude")
+	v.Check(b.MinLongitude <= b.MaxLongitude, "bbox", "minimum longitude must not be greater than maximum longitude")
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// HaversineKm returns the great-circle distance between two points in kilometres.
+func HaversineKm(a, b Coordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundingBox returns the smallest box containing the search circle, used as an index-friendly pre-filter.
+func (p *Proximity) BoundingBox() BoundingBox {
+	dLat := p.RadiusKm / kmPerDegree
+	dLng := 180.0 // near the poles every longitude is in range
+	if cos := math.Cos(p.Center.Latitude * math.Pi / 180); cos > 1e-6 {
+		dLng = math.Min(180, dLat/cos)
+	}
+	return BoundingBox{
+		MinLatitude:  math.Max(-90, p.Center.Latitude-dLat),
+		MinLongitude: math.Max(-180, p.Center.Longitude-dLng),
+		MaxLatitude:  math.Min(90, p.Center.Latitude+dLat),
+		MaxLongitude: math.Min(180, p.Center.Longitude+dLng),
+	}
+}
+
+// SearchBox combines an optional proximity search and an optional bounding box into the
+// box rows must fall inside, or nil when neither is set.
+func SearchBox(near *Proximity, box *BoundingBox) *BoundingBox {
+	switch {
+	case near == nil && box == nil:
+		return nil
+	case near == nil:
+		return box
+	}
+
+	b := near.BoundingBox()
+	if box != nil {
+		b.MinLatitude = math.Max(b.MinLatitude, box.MinLatitude)
+		b.MinLongitude = math.Max(b.MinLongitude, box.MinLongitude)
+		b.MaxLatitude = math.Min(b.MaxLatitude, box.MaxLatitude)
+		b.MaxLongitude = math.Min(b.MaxLongitude, box.MaxLongitude)
+	}
+	return &b
+}
+
+// Args returns the query arguments (min lat, min lng, max lat, max lng) for a box, or NULLs if b is nil.
+func (b *BoundingBox) Args() []any {
+	if b == nil {
+		return []any{nil, nil, nil, nil}
+	}
+	return []any{b.MinLatitude, b.MinLongitude, b.MaxLatitude, b.MaxLongitude}
+}
+
+// Args returns the query arguments (lat, lng, radius) for a proximity search, or NULLs if p is nil.
+func (p *Proximity) Args() []any {
+	if p == nil {
+		return []any{nil, nil, nil}
+	}
+	return []any{p.Center.Latitude, p.Center.Longitude, p.RadiusKm}
+}
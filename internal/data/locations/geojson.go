@@ -0,0 +1,138 @@
+// File: internal/data/locations/geojson.go
+package locations
+
+import (
+	"math"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// GeoJSON object types (RFC 7946).
+const (
+	GeoJSONFeatureCollection = "FeatureCollection"
+	GeoJSONFeature           = "Feature"
+	GeoJSONPoint             = "Point"
+	GeoJSONPolygon           = "Polygon"
+	GeoJSONMultiPolygon      = "MultiPolygon"
+)
+
+// Geometry is a GeoJSON geometry. Positions are always [longitude, latitude].
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// Feature is a GeoJSON feature. A nil Geometry is encoded as null, meaning the feature has no known location.
+type Feature struct {
+	Type       string         `json:"type"`
+	ID         any            `json:"id,omitempty"`
+	Geometry   *Geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// FeatureCollection is a GeoJSON feature collection. Metadata is a foreign member carrying pagination details.
+type FeatureCollection struct {
+	Type     string            `json:"type"`
+	BBox     []float64         `json:"bbox,omitempty"`
+	Features []Feature         `json:"features"`
+	Metadata *filters.MetaData `json:"metadata,omitempty"`
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// NewPoint returns a Point geometry for c.
+func NewPoint(c Coordinates) *Geometry {
+	return &Geometry{Type: GeoJSONPoint, Coordinates: []float64{c.Longitude, c.Latitude}}
+}
+
+// NewFeatureCollection builds a collection from features, computing its bbox from their geometries.
+func NewFeatureCollection(features []Feature, metadata filters.MetaData) *FeatureCollection {
+	fc := &FeatureCollection{Type: GeoJSONFeatureCollection, Features: features}
+	if features == nil {
+		fc.Features = []Feature{}
+	}
+	if metadata != filters.EmptyMetaData {
+		fc.Metadata = &metadata
+	}
+
+	box := BoundingBox{MinLatitude: math.Inf(1), MinLongitude: math.Inf(1), MaxLatitude: math.Inf(-1), MaxLongitude: math.Inf(-1)}
+	found := false
+	for _, f := range features {
+		var b BoundingBox
+		switch {
+		case f.Geometry == nil:
+			continue
+		case f.Geometry.Type == GeoJSONPoint:
+			position := f.Geometry.Coordinates.([]float64)
+			b = BoundingBox{MinLatitude: position[1], MinLongitude: position[0], MaxLatitude: position[1], MaxLongitude: position[0]}
+		case f.Geometry.Type == GeoJSONMultiPolygon:
+			b = MultiPolygon(f.Geometry.Coordinates.([][][][]float64)).Bounds()
+		default:
+			continue
+		}
+		box.MinLongitude, box.MaxLongitude = math.Min(box.MinLongitude, b.MinLongitude), math.Max(box.MaxLongitude, b.MaxLongitude)
+		box.MinLatitude, box.MaxLatitude = math.Min(box.MinLatitude, b.MinLatitude), math.Max(box.MaxLatitude, b.MaxLatitude)
+		found = true
+	}
+	if found {
+		fc.BBox = []float64{box.MinLongitude, box.MinLatitude, box.MaxLongitude, box.MaxLatitude}
+	}
+	return fc
+}
+
+// Feature returns the area as a Point feature, or a feature with null geometry if it has no coordinates.
+func (a Area) Feature() Feature {
+	var geometry *Geometry
+	if a.Coordinates.Latitude != 0 || a.Coordinates.Longitude != 0 {
+		geometry = NewPoint(a.Coordinates)
+	}
+
+	properties := map[string]any{
+		"name":      a.Name,
+		"region_id": a.RegionID,
+		"area_type": a.AreaType,
+		"is_active": a.IsActive,
+	}
+	if a.DistanceKm != nil {
+		properties["distance_km"] = *a.DistanceKm
+	}
+	return Feature{Type: GeoJSONFeature, ID: a.ID, Geometry: geometry, Properties: properties}
+}
+
+// FeatureCollection returns the areas as a GeoJSON feature collection.
+func (a Areas) FeatureCollection(metadata filters.MetaData) *FeatureCollection {
+	features := make([]Feature, len(a))
+	for i, area := range a {
+		features[i] = area.Feature()
+	}
+	return NewFeatureCollection(features, metadata)
+}
+
+// Feature returns the region as a MultiPolygon feature, or a feature with null geometry if it has no boundary.
+func (r Region) Feature() Feature {
+	var geometry *Geometry
+	if r.Boundary != nil {
+		geometry = &Geometry{Type: GeoJSONMultiPolygon, Coordinates: [][][][]float64(r.Boundary)}
+	}
+
+	properties := map[string]any{
+		"name": r.Name,
+		"code": r.Code,
+	}
+	return Feature{Type: GeoJSONFeature, ID: r.ID, Geometry: geometry, Properties: properties}
+}
+
+// FeatureCollection returns the regions as a GeoJSON feature collection.
+func (r Regions) FeatureCollection(metadata filters.MetaData) *FeatureCollection {
+	features := make([]Feature, len(r))
+	for i, region := range r {
+		features[i] = region.Feature()
+	}
+	return NewFeatureCollection(features, metadata)
+}
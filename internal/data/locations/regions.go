@@ -3,10 +3,11 @@ package locations
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
@@ -18,14 +19,18 @@ import (
 
 // Region represents a geographical region.
 type Region struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-	Code string `json:"code"`
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Code     string       `json:"code"`
+	Boundary MultiPolygon `json:"boundary,omitempty"`
+	Version  int          `json:"version"`
+	database.Deletion
 }
 
 // RegionsModel represents the model for regions.
 type RegionModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
 }
 
 // Regions is a slice of Region.
@@ -36,6 +41,24 @@ type RegionFilter struct {
 	Name    string
 	Code    string
 	Default filters.Filters
+
+	// WithBoundary includes each region's boundary polygons, which can be large.
+	WithBoundary bool
+	// WithDeleted includes soft deleted regions, which are left out by default.
+	WithDeleted bool
+}
+
+// RegionRepository is the interface for storing and querying regions.
+type RegionRepository interface {
+	Insert(ctx context.Context, r *Region) error
+	Update(ctx context.Context, r *Region) error
+	SoftDelete(ctx context.Context, id int, deletedBy int64) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetByID(ctx context.Context, id int) (*Region, error)
+	GetAll(ctx context.Context, filter *RegionFilter) (Regions, filters.MetaData, error)
+	Locate(ctx context.Context, c Coordinates) (*Region, error)
+	ResolveRegion(ctx context.Context, regionID int, c Coordinates) (int, error)
 }
 
 // ValidateRegion validates the fields of a Region.
@@ -44,6 +67,9 @@ func ValidateRegion(v *validator.Validator, r *Region) {
 	v.Check(len(r.Name) <= 255, "name", "must not be more than 255 bytes long")
 	v.Check(r.Code != "", "code", "must be provided")
 	v.Check(len(r.Code) <= 10, "code", "must not be more than 10 bytes long")
+	if r.Boundary != nil {
+		ValidateBoundary(v, r.Boundary)
+	}
 }
 
 /****************************************************************************************
@@ -51,17 +77,24 @@ func ValidateRegion(v *validator.Validator, r *Region) {
  ***************************************************************************************/
 
 // Insert adds a new region to the database.
-func (m *RegionModel) Insert(r Region) error {
+func (m *RegionModel) Insert(ctx context.Context, r *Region) error {
 	query := `
-		INSERT INTO regions (name, code)
-		VALUES ($1, $2)
+		INSERT INTO regions (name, code, boundary, min_latitude, min_longitude, max_latitude, max_longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version
 	`
-	args := []any{r.Name, r.Code}
+	args := append([]any{r.Name, r.Code, r.Boundary}, boundaryArgs(r.Boundary)...)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, args...)
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&r.ID, &r.Version)
 	if err != nil {
 		switch {
 		case errors.IsUniqueViolation(err, "code"):
@@ -74,29 +107,42 @@ func (m *RegionModel) Insert(r Region) error {
 			return err
 		}
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	if err := audit.Record(ctx, tx, "regions", int64(r.ID), audit.ActionInsert, nil, r); err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return errors.ErrInsertFailed
-	}
-	return nil
+	return tx.Commit()
 }
 
 // Update modifies an existing region in the database.
-func (m *RegionModel) Update(r *Region) error {
+func (m *RegionModel) Update(ctx context.Context, r *Region) error {
 	query := `
 		UPDATE regions
-		SET name = $1, code = $2
-		WHERE id = $3
+		SET name = $1, code = $2, boundary = $3, min_latitude = $4, min_longitude = $5, max_latitude = $6, max_longitude = $7, version = version + 1
+		WHERE id = $8 AND version = $9 AND deleted_at IS NULL
+		RETURNING version
 	`
-	args := []any{r.Name, r.Code, r.ID}
+	args := append([]any{r.Name, r.Code, r.Boundary}, boundaryArgs(r.Boundary)...)
+	args = append(args, r.ID, r.Version)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, args...)
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &RegionModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, r.ID)
+	if err != nil {
+		if errors.IsRecordNotFound(err) {
+			return errors.ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&r.Version)
 	if err != nil {
 		switch {
 		case errors.IsUniqueViolation(err, "code"):
@@ -112,53 +158,49 @@ func (m *RegionModel) Update(r *Region) error {
 		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	after, err := current.GetByID(ctx, r.ID)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	if err := audit.Record(ctx, tx, "regions", int64(r.ID), audit.ActionUpdate, before, after); err != nil {
+		return err
 	}
-
-	return nil
+	return tx.Commit()
 }
 
-// Delete permanently removes a region from the database.
-func (m *RegionModel) Delete(id int) error {
-	query := `
-		DELETE FROM regions
-		WHERE id = $1
-	`
-	args := []any{id}
+// SoftDelete marks a region as deleted by deletedBy. A deleted region is no longer located from
+// coordinates, but its areas and listings keep it until it is purged.
+func (m *RegionModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	return audit.SoftDelete(ctx, m.DB, "regions", int64(id), deletedBy)
+}
+
+// Restore brings a soft deleted region back.
+func (m *RegionModel) Restore(ctx context.Context, id int) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		switch {
-		case errors.IsForeignKeyViolation(err):
-			return errors.ErrForeignKeyViolation
-		default:
-			return err
-		}
-	}
+	return audit.Restore(ctx, m.DB, "regions", int64(id))
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
+// Purge permanently deletes the regions soft deleted before the cutoff. Regions that still have
+// areas or listings are kept.
+func (m *RegionModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	guard := `
+		NOT EXISTS (SELECT 1 FROM areas AS a WHERE a.region_id = regions.id) AND
+		NOT EXISTS (SELECT 1 FROM listings AS l WHERE l.region_id = regions.id)`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	return nil
+	return audit.Purge(ctx, m.DB, "regions", before, guard)
 }
 
 // GetByID retrieves a region by its ID.
-func (m *RegionModel) GetByID(id int) (*Region, error) {
+func (m *RegionModel) GetByID(ctx context.Context, id int) (*Region, error) {
 	query := `
-		SELECT id, name, code
+		SELECT id, name, code, boundary, version, deleted_at, deleted_by
 		FROM regions
 		WHERE id = $1
 	`
@@ -166,7 +208,7 @@ func (m *RegionModel) GetByID(id int) (*Region, error) {
 
 	var r Region
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	// dryscan pattern
@@ -174,13 +216,15 @@ func (m *RegionModel) GetByID(id int) (*Region, error) {
 		&r.ID,
 		&r.Name,
 		&r.Code,
+		&r.Boundary,
+		&r.Version,
+		&r.DeletedAt,
+		&r.DeletedBy,
 	}
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(scan...)
 	if err != nil {
 		switch {
-		case errors.IsEditConflict(err):
-			return nil, errors.ErrEditConflict
 		case errors.ErrNoRows(err):
 			return nil, errors.ErrRecordNotFound
 		default:
@@ -192,18 +236,22 @@ func (m *RegionModel) GetByID(id int) (*Region, error) {
 }
 
 // GetAll retrieves all regions from the database.
-func (m *RegionModel) GetAll(r *RegionFilter) (Regions, filters.MetaData, error) {
+func (m *RegionModel) GetAll(ctx context.Context, r *RegionFilter) (Regions, filters.MetaData, error) {
+	page := r.Default.SQL(nil, "id", 7)
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, name, code
+		SELECT %s, %s, id, name, code, CASE WHEN $5 THEN boundary END, version, deleted_at, deleted_by
 		FROM regions
 		WHERE (LOWER(name) ILIKE LOWER('%%' || $1 || '%%') OR $1 = '')
 		AND (LOWER(code) ILIKE LOWER('%%' || $2 || '%%') OR $2 = '')
-		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, r.Default.SortColumn(), r.Default.SortDirection())
+		AND ($6 OR deleted_at IS NULL)
+		AND %s
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, page.Count, page.SortKey, page.Where, page.OrderBy)
 
-	args := []any{r.Name, r.Code, r.Default.Limit(), r.Default.Offset()}
+	args := []any{r.Name, r.Code, r.Default.Limit(), r.Default.Offset(), r.WithBoundary, r.WithDeleted}
+	args = append(args, page.Args...)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -214,16 +262,23 @@ func (m *RegionModel) GetAll(r *RegionFilter) (Regions, filters.MetaData, error)
 
 	totalRecords := 0
 	regions := Regions{}
+	keys := []filters.Key{}
 
 	for rows.Next() {
 		var r Region
+		var key filters.Key
 
 		// dryscan pattern
 		scan := []any{
 			&totalRecords,
+			&key.Values,
 			&r.ID,
 			&r.Name,
 			&r.Code,
+			&r.Boundary,
+			&r.Version,
+			&r.DeletedAt,
+			&r.DeletedBy,
 		}
 
 		err := rows.Scan(scan...)
@@ -231,14 +286,94 @@ func (m *RegionModel) GetAll(r *RegionFilter) (Regions, filters.MetaData, error)
 			return nil, filters.EmptyMetaData, err
 		}
 
+		key.ID = int64(r.ID)
 		regions = append(regions, r)
+		keys = append(keys, key)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, filters.EmptyMetaData, err
 	}
 
-	metaData := filters.CalculateMetaData(totalRecords, r.Default.Page, r.Default.PageSize)
+	regions, metaData := filters.Paginate(r.Default, regions, keys, totalRecords)
 
 	return regions, metaData, nil
 }
+
+// Locate returns the live region whose boundary contains c, or ErrRecordNotFound if no stored boundary does.
+func (m *RegionModel) Locate(ctx context.Context, c Coordinates) (*Region, error) {
+	query := `
+		SELECT id, name, code, boundary, version, deleted_at, deleted_by
+		FROM regions
+		WHERE boundary IS NOT NULL AND deleted_at IS NULL
+		AND $1::float8 BETWEEN min_latitude AND max_latitude
+		AND $2::float8 BETWEEN min_longitude AND max_longitude
+		ORDER BY id ASC
+	`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, c.Latitude, c.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The bounding boxes only narrow the candidates down; the polygons decide.
+	for rows.Next() {
+		var r Region
+		if err := rows.Scan(&r.ID, &r.Name, &r.Code, &r.Boundary, &r.Version, &r.DeletedAt, &r.DeletedBy); err != nil {
+			return nil, err
+		}
+		if r.Boundary.Contains(c) {
+			return &r, nil
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.ErrRecordNotFound
+}
+
+// ResolveRegion works out the region of something placed at c. When c falls inside a stored
+// boundary that region wins, and a conflicting regionID is rejected with ErrRegionMismatch.
+// Otherwise regionID is kept, and ErrRegionUnknown is returned if it is zero.
+func (m *RegionModel) ResolveRegion(ctx context.Context, regionID int, c Coordinates) (int, error) {
+	return ResolveRegion(ctx, m, regionID, c)
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// ResolveRegion is RegionModel.ResolveRegion for any RegionRepository, so other stores share its rules.
+func ResolveRegion(ctx context.Context, regions RegionRepository, regionID int, c Coordinates) (int, error) {
+	if c.Latitude != 0 || c.Longitude != 0 {
+		region, err := regions.Locate(ctx, c)
+		switch {
+		case err == nil:
+			if regionID != 0 && regionID != region.ID {
+				return 0, errors.ErrRegionMismatch
+			}
+			return region.ID, nil
+		case err != errors.ErrRecordNotFound:
+			return 0, err
+		}
+	}
+
+	if regionID == 0 {
+		return 0, errors.ErrRegionUnknown
+	}
+	return regionID, nil
+}
+
+// boundaryArgs returns the bounding box query arguments for a boundary, or NULLs if there is none.
+func boundaryArgs(mp MultiPolygon) []any {
+	if mp == nil {
+		return (*BoundingBox)(nil).Args()
+	}
+	b := mp.Bounds()
+	return b.Args()
+}
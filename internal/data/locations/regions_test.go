@@ -0,0 +1,247 @@
+// File: internal/data/locations/regions_test.go
+package locations_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+// square is a boundary covering latitudes 17 to 18 and longitudes -89 to -88.
+var square = locations.MultiPolygon{{{{-89, 17}, {-88, 17}, {-88, 18}, {-89, 18}, {-89, 17}}}}
+
+func TestRegionCRUD(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	r := f.Region(func(r *locations.Region) { r.Boundary = square })
+	got, err := models.Regions.GetByID(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != r.Name || got.Code != r.Code || len(got.Boundary) != 1 {
+		t.Errorf("got %+v, want %+v", got, r)
+	}
+
+	for column, clash := range map[string]locations.Region{
+		"code": {Name: "Other", Code: r.Code},
+		"name": {Name: r.Name, Code: "OTH"},
+	} {
+		if err := models.Regions.Insert(ctx, &clash); err == nil || err.Error() != internalErrors.ErrDuplicateValue(column).Error() {
+			t.Errorf("Insert error = %v, want duplicate %s", err, column)
+		}
+	}
+
+	other := f.Region()
+	got.Code = other.Code
+	if err := models.Regions.Update(ctx, got); !errors.Is(err, internalErrors.ErrDuplicateCode) {
+		t.Errorf("Update error = %v, want ErrDuplicateCode", err)
+	}
+	got.Code, got.Name = r.Code, other.Name
+	if err := models.Regions.Update(ctx, got); !errors.Is(err, internalErrors.ErrDuplicateName) {
+		t.Errorf("Update error = %v, want ErrDuplicateName", err)
+	}
+
+	got.Name, got.Boundary = "Renamed", nil
+	if err := models.Regions.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Regions.GetByID(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Renamed" || got.Boundary != nil || got.Version != 2 {
+		t.Errorf("got %+v, want renamed without a boundary", got)
+	}
+
+	missing := &locations.Region{ID: 404, Name: "Missing", Code: "MIS"}
+	if err := models.Regions.Update(ctx, missing); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a missing region error = %v, want ErrEditConflict", err)
+	}
+
+	stale := *got
+	stale.Version = 1
+	if err := models.Regions.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a stale region error = %v, want ErrEditConflict", err)
+	}
+
+	if err := models.Regions.SoftDelete(ctx, r.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Regions.GetByID(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt == nil || got.Version != 3 {
+		t.Errorf("got %+v, want it deleted", got)
+	}
+	if err := models.Regions.Update(ctx, got); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a deleted region error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Regions.SoftDelete(ctx, r.ID, 0); !errors.Is(err, internalErrors.ErrAlreadyDeleted) {
+		t.Errorf("second SoftDelete error = %v, want ErrAlreadyDeleted", err)
+	}
+	if err := models.Regions.Restore(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Regions.Restore(ctx, r.ID); !errors.Is(err, internalErrors.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want ErrNotDeleted", err)
+	}
+	if err := models.Regions.SoftDelete(ctx, 404, 0); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("SoftDelete of a missing region error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestRegionPurge(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	empty := f.Region()
+	withArea := f.Region()
+	f.Area(func(a *locations.Area) { a.RegionID = withArea.ID })
+	for _, id := range []int{empty.ID, withArea.ID} {
+		if err := models.Regions.SoftDelete(ctx, id, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := models.Regions.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want 1 purged", n, err)
+	}
+	if _, err := models.Regions.GetByID(ctx, empty.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of the purged region error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Regions.GetByID(ctx, withArea.ID); err != nil {
+		t.Errorf("GetByID of the region with an area error = %v, want it kept", err)
+	}
+}
+
+func TestRegionGetAll(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	belize := f.Region(func(r *locations.Region) { r.Name, r.Code = "Belize", "BZ"; r.Boundary = square })
+	cayo := f.Region(func(r *locations.Region) { r.Name, r.Code = "Cayo", "CY" })
+	orangeWalk := f.Region(func(r *locations.Region) { r.Name, r.Code = "Orange Walk", "OW" })
+	toledo := f.Region(func(r *locations.Region) { r.Name, r.Code = "Toledo", "TO" })
+	if err := models.Regions.SoftDelete(ctx, toledo.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter locations.RegionFilter
+		want   []int
+	}{
+		{"by name descending", locations.RegionFilter{Default: testdb.FirstPage("-name")}, []int{orangeWalk.ID, cayo.ID, belize.ID}},
+		{"by code", locations.RegionFilter{Default: testdb.FirstPage("code")}, []int{belize.ID, cayo.ID, orangeWalk.ID}},
+		{"name", locations.RegionFilter{Name: "walk", Default: testdb.FirstPage("id")}, []int{orangeWalk.ID}},
+		{"code", locations.RegionFilter{Code: "cy", Default: testdb.FirstPage("id")}, []int{cayo.ID}},
+		{"deleted left out", locations.RegionFilter{Code: "TO", Default: testdb.FirstPage("id")}, nil},
+		{"with deleted", locations.RegionFilter{Code: "TO", WithDeleted: true, Default: testdb.FirstPage("id")}, []int{toledo.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := models.Regions.GetAll(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, r := range got {
+				ids = append(ids, r.ID)
+				if r.Boundary != nil {
+					t.Errorf("region %d has a boundary without WithBoundary", r.ID)
+				}
+			}
+			if !equal(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("total records = %d, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+
+	got, _, err := models.Regions.GetAll(ctx, &locations.RegionFilter{Code: "BZ", WithBoundary: true, Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Boundary) != 1 {
+		t.Errorf("got %+v, want Belize with its boundary", got)
+	}
+}
+
+func TestRegionLocate(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	inside := locations.Coordinates{Latitude: 17.5, Longitude: -88.5}
+	outside := locations.Coordinates{Latitude: 16.5, Longitude: -88.5}
+
+	if _, err := models.Regions.Locate(ctx, inside); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Locate without boundaries error = %v, want ErrRecordNotFound", err)
+	}
+
+	r := f.Region(func(r *locations.Region) { r.Boundary = square })
+	other := f.Region()
+
+	got, err := models.Regions.Locate(ctx, inside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != r.ID {
+		t.Errorf("Locate = region %d, want %d", got.ID, r.ID)
+	}
+	if _, err := models.Regions.Locate(ctx, outside); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Locate outside error = %v, want ErrRecordNotFound", err)
+	}
+
+	tests := map[string]struct {
+		regionID    int
+		coordinates locations.Coordinates
+		want        int
+		wantErr     error
+	}{
+		"from boundary":        {0, inside, r.ID, nil},
+		"agrees with boundary": {r.ID, inside, r.ID, nil},
+		"outside boundaries":   {other.ID, outside, other.ID, nil},
+		"conflicts":            {other.ID, inside, 0, internalErrors.ErrRegionMismatch},
+		"unknown":              {0, outside, 0, internalErrors.ErrRegionUnknown},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := models.Regions.ResolveRegion(ctx, tt.regionID, tt.coordinates)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("ResolveRegion = %d, %v; want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if err := models.Regions.SoftDelete(ctx, r.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Regions.Locate(ctx, inside); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Locate in a deleted region error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+// equal reports whether two id lists hold the same ids in the same order.
+func equal(got, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,514 @@
+// File: internal/data/media/media.go
+package media
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Kind distinguishes photos from documents such as vet certificates.
+type Kind string
+
+const (
+	KindImage    Kind = "image"
+	KindDocument Kind = "document"
+)
+
+// Media is a photo or document attached to either an animal or a listing.
+type Media struct {
+	ID           int64     `json:"id"`
+	CattleID     *int64    `json:"cattle_id,omitempty"`
+	ListingID    *int64    `json:"listing_id,omitempty"`
+	UploadedBy   int64     `json:"uploaded_by"`
+	Kind         Kind      `json:"kind"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	Caption      string    `json:"caption"`
+	Position     int       `json:"position"`
+	IsCover      bool      `json:"is_cover"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MediaList is a slice of Media.
+type MediaList []Media
+
+// MediaFilter represents filtering options for querying media.
+type MediaFilter struct {
+	CattleID  *int64
+	ListingID *int64
+	Kind      *Kind
+	Default   filters.Filters
+}
+
+// MediaModel represents the model for media attachments.
+type MediaModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// ValidateMedia validates the fields of a Media record before it is stored.
+func ValidateMedia(v *validator.Validator, m *Media) {
+	v.Check((m.CattleID == nil) != (m.ListingID == nil), "owner", "must belong to exactly one animal or listing")
+	v.Check(m.FileName != "", "file_name", "must be provided")
+	v.Check(len(m.FileName) <= 255, "file_name", "must not be more than 255 bytes long")
+	v.Check(len(m.Caption) <= 500, "caption", "must not be more than 500 bytes long")
+
+	kind, ok := KindFor(m.ContentType)
+	v.Check(ok, "file", "must be a JPEG, PNG, GIF or WebP image or a PDF document")
+	v.Check(!ok || kind == m.Kind, "kind", "does not match the file contents")
+	v.Check(m.SizeBytes > 0, "file", "must not be empty")
+	v.Check(m.SizeBytes <= MaxBytesFor(m.Kind), "file", fmt.Sprintf("must not be more than %d MB", MaxBytesFor(m.Kind)>>20))
+}
+
+// ownerClause matches every media row that shares an owner with the parameters at $1 and $2.
+const ownerClause = `cattle_id IS NOT DISTINCT FROM $1::bigint AND listing_id IS NOT DISTINCT FROM $2::bigint`
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Insert adds a new media record, appending it after the owner's existing attachments.
+// The first image attached to an owner becomes its cover photo.
+func (m *MediaModel) Insert(ctx context.Context, md *Media) error {
+	query := `
+		INSERT INTO media (
+			cattle_id, listing_id, uploaded_by, kind, file_name, content_type, size_bytes,
+			storage_key, thumbnail_key, width, height, caption, position, is_cover
+		)
+		SELECT
+			$1::bigint, $2::bigint, $3::bigint, $4::media_kind_enum, $5::text, $6::text, $7::bigint,
+			$8::text, $9::text, $10::int, $11::int, $12::text,
+			COALESCE(MAX(position) + 1, 0),
+			$4::media_kind_enum = 'image' AND NOT COALESCE(BOOL_OR(is_cover), FALSE)
+		FROM media
+		WHERE ` + ownerClause + `
+		RETURNING id, position, is_cover, created_at
+	`
+	args := []any{
+		md.CattleID, md.ListingID, md.UploadedBy, md.Kind, md.FileName, md.ContentType, md.SizeBytes,
+		md.StorageKey, md.ThumbnailKey, md.Width, md.Height, md.Caption,
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return errors.WrapInsertError(err, "Media")
+	}
+	defer tx.Rollback()
+
+	if err := lockOwner(ctx, tx, md.CattleID, md.ListingID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&md.ID, &md.Position, &md.IsCover, &md.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.IsUniqueViolation(err, "storage_key"):
+			return errors.ErrDuplicateValue("storage_key")
+		case errors.IsForeignKeyViolation(err):
+			return errors.ErrForeignKeyViolation
+		default:
+			return errors.WrapInsertError(err, "Media")
+		}
+	}
+
+	if err := audit.Record(ctx, tx, "media", md.ID, audit.ActionInsert, nil, md); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.WrapInsertError(err, "Media")
+	}
+	return nil
+}
+
+// GetByID retrieves a media record by its ID.
+func (m *MediaModel) GetByID(ctx context.Context, id int64) (*Media, error) {
+	query := `
+		SELECT id, cattle_id, listing_id, uploaded_by, kind, file_name, content_type, size_bytes,
+			storage_key, thumbnail_key, width, height, caption, position, is_cover, created_at
+		FROM media
+		WHERE id = $1
+	`
+	var md Media
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(md.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "Media")
+		}
+	}
+	return &md, nil
+}
+
+// GetAll retrieves the media attached to an animal or listing.
+func (m *MediaModel) GetAll(ctx context.Context, filter *MediaFilter) (MediaList, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 6)
+	query := fmt.Sprintf(`
+		SELECT %s, %s,
+			id, cattle_id, listing_id, uploaded_by, kind, file_name, content_type, size_bytes,
+			storage_key, thumbnail_key, width, height, caption, position, is_cover, created_at
+		FROM media
+		WHERE ($1::bigint IS NULL OR cattle_id = $1)
+		AND ($2::bigint IS NULL OR listing_id = $2)
+		AND ($3::media_kind_enum IS NULL OR kind = $3)
+		AND %s
+		ORDER BY %s
+		LIMIT $4 OFFSET $5`, page.Count, page.SortKey, page.Where, page.OrderBy)
+
+	args := []any{
+		filter.CattleID,
+		filter.ListingID,
+		filter.Kind,
+		filter.Default.Limit(),
+		filter.Default.Offset(),
+	}
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Media")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	list := MediaList{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var md Media
+		var key filters.Key
+		scan := append([]any{&totalRecords, &key.Values}, md.scanDest()...)
+		if err := rows.Scan(scan...); err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Media")
+		}
+		key.ID = md.ID
+		list = append(list, md)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	list, metaData := filters.Paginate(filter.Default, list, keys, totalRecords)
+	return list, metaData, nil
+}
+
+// Delete removes a media record and returns it so the caller can remove the stored files.
+// If the deleted record was the cover photo the next image in order is promoted.
+func (m *MediaModel) Delete(ctx context.Context, id int64) (*Media, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return nil, errors.WrapDeleteError(err, "Media")
+	}
+	defer tx.Rollback()
+
+	// Lock the owner before the row, in the same order as the other writes, so that promoting the
+	// next cover cannot deadlock with them.
+	var owner Media
+	query := `SELECT cattle_id, listing_id FROM media WHERE id = $1`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&owner.CattleID, &owner.ListingID)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapDeleteError(err, "Media")
+		}
+	}
+	if err := lockOwner(ctx, tx, owner.CattleID, owner.ListingID); err != nil {
+		return nil, err
+	}
+
+	query = `
+		DELETE FROM media
+		WHERE id = $1
+		RETURNING id, cattle_id, listing_id, uploaded_by, kind, file_name, content_type, size_bytes,
+			storage_key, thumbnail_key, width, height, caption, position, is_cover, created_at
+	`
+	var md Media
+	err = tx.QueryRowContext(ctx, query, id).Scan(md.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapDeleteError(err, "Media")
+		}
+	}
+
+	if md.IsCover {
+		query = `
+			UPDATE media SET is_cover = TRUE
+			WHERE id = (
+				SELECT id FROM media
+				WHERE ` + ownerClause + ` AND kind = 'image'
+				ORDER BY position, id
+				LIMIT 1
+			)
+		`
+		if _, err := tx.ExecContext(ctx, query, md.CattleID, md.ListingID); err != nil {
+			return nil, errors.WrapDeleteError(err, "Media")
+		}
+	}
+
+	if err := audit.Record(ctx, tx, "media", md.ID, audit.ActionDelete, &md, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.WrapDeleteError(err, "Media")
+	}
+	return &md, nil
+}
+
+// DeletePurged removes the media of the cattle and listings soft deleted before the cutoff, of
+// those owned by users soft deleted before it, and everything such users uploaded. It runs ahead
+// of the purge so that the storage and thumbnail keys it returns can be removed once the purge
+// has committed.
+func (m *MediaModel) DeletePurged(ctx context.Context, before time.Time) ([]string, error) {
+	query := `
+		WITH purged_users AS (
+			SELECT id FROM users WHERE deleted_at < $1
+		)
+		DELETE FROM media
+		WHERE uploaded_by IN (SELECT id FROM purged_users)
+		OR cattle_id IN (
+			SELECT id FROM cattle
+			WHERE deleted_at < $1 OR owner_id IN (SELECT id FROM purged_users)
+		)
+		OR listing_id IN (
+			SELECT id FROM listings
+			WHERE deleted_at < $1 OR user_id IN (SELECT id FROM purged_users)
+		)
+		RETURNING id, storage_key, thumbnail_key
+	`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, errors.WrapDeleteError(err, "Media")
+	}
+	defer rows.Close()
+
+	var (
+		ids  []int64
+		keys []string
+	)
+	for rows.Next() {
+		var id int64
+		var storageKey, thumbnailKey string
+		if err := rows.Scan(&id, &storageKey, &thumbnailKey); err != nil {
+			return nil, errors.WrapDeleteError(err, "Media")
+		}
+		ids = append(ids, id)
+		keys = append(keys, storageKey)
+		if thumbnailKey != "" {
+			keys = append(keys, thumbnailKey)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WrapDeleteError(err, "Media")
+	}
+	rows.Close()
+
+	if err := audit.RecordAll(ctx, m.DB, "media", ids, audit.ActionPurge); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// SetCover makes the given image the cover photo of its animal or listing.
+func (m *MediaModel) SetCover(ctx context.Context, md *Media) error {
+	if md.Kind != KindImage {
+		return errors.ErrNotAnImage
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	defer tx.Rollback()
+
+	if err := lockOwner(ctx, tx, md.CattleID, md.ListingID); err != nil {
+		return err
+	}
+
+	// Clear the old cover first so the partial unique index is never violated.
+	var oldCover int64
+	query := `UPDATE media SET is_cover = FALSE WHERE ` + ownerClause + ` AND is_cover RETURNING id`
+	err = tx.QueryRowContext(ctx, query, md.CattleID, md.ListingID).Scan(&oldCover)
+	if err != nil && !errors.ErrNoRows(err) {
+		return errors.WrapUpdateError(err, "Media")
+	}
+
+	query = `UPDATE media SET is_cover = TRUE WHERE id = $1 RETURNING is_cover`
+	err = tx.QueryRowContext(ctx, query, md.ID).Scan(&md.IsCover)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return errors.ErrRecordNotFound
+		default:
+			return errors.WrapUpdateError(err, "Media")
+		}
+	}
+
+	if oldCover != md.ID {
+		if oldCover != 0 {
+			changes := audit.Changes{"is_cover": audit.Field(true, false)}
+			if err := audit.RecordChanges(ctx, tx, "media", oldCover, audit.ActionUpdate, changes); err != nil {
+				return err
+			}
+		}
+		changes := audit.Changes{"is_cover": audit.Field(false, true)}
+		if err := audit.RecordChanges(ctx, tx, "media", md.ID, audit.ActionUpdate, changes); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	return nil
+}
+
+// Reorder sets the display order of an owner's media to the order of ids.
+// Every id must belong to the owner and every attachment of the owner must be listed.
+func (m *MediaModel) Reorder(ctx context.Context, cattleID, listingID *int64, ids []int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	defer tx.Rollback()
+
+	if err := lockOwner(ctx, tx, cattleID, listingID); err != nil {
+		return err
+	}
+
+	// The positions as they were, for the audit trail
+	positions := make(map[int64]int)
+	query := `SELECT id, position FROM media WHERE ` + ownerClause
+	rows, err := tx.QueryContext(ctx, query, cattleID, listingID)
+	if err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var position int
+		if err := rows.Scan(&id, &position); err != nil {
+			return errors.WrapUpdateError(err, "Media")
+		}
+		positions[id] = position
+	}
+	if err := rows.Err(); err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	rows.Close()
+	if len(positions) != len(ids) {
+		return errors.ErrInvalidUpdateData
+	}
+
+	query = `
+		UPDATE media
+		SET position = array_position($3::bigint[], id) - 1
+		WHERE ` + ownerClause + ` AND id = ANY($3::bigint[])
+	`
+	result, err := tx.ExecContext(ctx, query, cattleID, listingID, pq.Array(ids))
+	if err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	if rowsAffected != int64(len(ids)) {
+		return errors.ErrInvalidUpdateData
+	}
+
+	for position, id := range ids {
+		if positions[id] == position {
+			continue
+		}
+		changes := audit.Changes{"position": audit.Field(positions[id], position)}
+		if err := audit.RecordChanges(ctx, tx, "media", id, audit.ActionUpdate, changes); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.WrapUpdateError(err, "Media")
+	}
+	return nil
+}
+
+// lockOwner locks the animal or listing that media belong to until the transaction ends. Writes
+// that number an owner's media or pick its cover take it first, so that concurrent uploads queue
+// up instead of reading the same next position or both claiming the cover. It returns
+// ErrForeignKeyViolation when there is no such owner.
+func lockOwner(ctx context.Context, tx database.DBTX, cattleID, listingID *int64) error {
+	query := `SELECT id FROM cattle WHERE id = $1 FOR NO KEY UPDATE`
+	id := cattleID
+	if listingID != nil {
+		query = `SELECT id FROM listings WHERE id = $1 FOR NO KEY UPDATE`
+		id = listingID
+	}
+	if id == nil {
+		return errors.ErrForeignKeyViolation
+	}
+
+	var locked int64
+	err := tx.QueryRowContext(ctx, query, *id).Scan(&locked)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return errors.ErrForeignKeyViolation
+		default:
+			return errors.WrapGetError(err, "Media")
+		}
+	}
+	return nil
+}
+
+// scanDest returns the scan destinations matching the column order used by every media query.
+func (md *Media) scanDest() []any {
+	return []any{
+		&md.ID, &md.CattleID, &md.ListingID, &md.UploadedBy, &md.Kind, &md.FileName, &md.ContentType, &md.SizeBytes,
+		&md.StorageKey, &md.ThumbnailKey, &md.Width, &md.Height, &md.Caption, &md.Position, &md.IsCover, &md.CreatedAt,
+	}
+}
@@ -0,0 +1,86 @@
+// File: internal/data/media/media_test.go
+package media_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/media"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+// Media has no in-memory implementation, so these tests always need Postgres.
+func TestMediaInsertConcurrent(t *testing.T) {
+	models := data.NewModels(testdb.New(t), 0)
+	ctx := t.Context()
+	c := testdb.NewFactory(t, models).Cattle()
+	cattleID := int64(c.ID)
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Go(func() {
+			errs <- models.Media.Insert(ctx, &media.Media{
+				CattleID:    &cattleID,
+				UploadedBy:  int64(c.OwnerID),
+				Kind:        media.KindImage,
+				FileName:    fmt.Sprintf("photo-%d.jpg", i),
+				ContentType: "image/jpeg",
+				SizeBytes:   1024,
+				StorageKey:  fmt.Sprintf("cattle/%d/photo-%d.jpg", c.ID, i),
+			})
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Insert error = %v", err)
+		}
+	}
+
+	list, _, err := models.Media.GetAll(ctx, &media.MediaFilter{CattleID: &cattleID, Default: testdb.FirstPage("position")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := []int{}
+	covers := 0
+	for _, md := range list {
+		positions = append(positions, md.Position)
+		if md.IsCover {
+			covers++
+		}
+	}
+	slices.Sort(positions)
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7}; !slices.Equal(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+	if covers != 1 {
+		t.Errorf("covers = %d, want exactly one", covers)
+	}
+}
+
+func TestMediaInsertMissingOwner(t *testing.T) {
+	models := data.NewModels(testdb.New(t), 0)
+	user := testdb.NewFactory(t, models).User()
+
+	missing := int64(1 << 40)
+	err := models.Media.Insert(t.Context(), &media.Media{
+		CattleID:    &missing,
+		UploadedBy:  user.ID,
+		Kind:        media.KindDocument,
+		FileName:    "papers.pdf",
+		ContentType: "application/pdf",
+		SizeBytes:   1024,
+		StorageKey:  "cattle/missing/papers.pdf",
+	})
+	if !errors.Is(err, internalErrors.ErrForeignKeyViolation) {
+		t.Errorf("Insert for a missing animal error = %v, want ErrForeignKeyViolation", err)
+	}
+}
@@ -0,0 +1,136 @@
+// File: internal/data/media/process.go
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/png"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Upload limits and thumbnail settings.
+const (
+	MaxImageBytes    = 10 << 20 // 10 MB
+	MaxDocumentBytes = 20 << 20 // 20 MB
+	MaxUploadBytes   = MaxDocumentBytes
+	MaxImagePixels   = 40_000_000 // width × height; a small file can declare a huge canvas
+	ThumbnailSize    = 320        // longest edge in pixels
+	thumbnailQuality = 80
+)
+
+// permittedTypes maps every accepted sniffed content type onto its media kind.
+var permittedTypes = map[string]Kind{
+	"image/jpeg":      KindImage,
+	"image/png":       KindImage,
+	"image/gif":       KindImage,
+	"image/webp":      KindImage,
+	"application/pdf": KindDocument,
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// SniffContentType detects the content type from the file contents, ignoring whatever the client claimed.
+func SniffContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+// KindFor reports the media kind for a sniffed content type and whether the type is permitted at all.
+func KindFor(contentType string) (Kind, bool) {
+	kind, ok := permittedTypes[contentType]
+	return kind, ok
+}
+
+// MaxBytesFor returns the upload size limit for a media kind.
+func MaxBytesFor(kind Kind) int64 {
+	if kind == KindImage {
+		return MaxImageBytes
+	}
+	return MaxDocumentBytes
+}
+
+// ExtensionFor returns the file extension used for storage keys of a content type.
+func ExtensionFor(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "application/pdf":
+		return ".pdf"
+	default:
+		return ""
+	}
+}
+
+// CleanFileName strips any client supplied directories from an uploaded file name.
+func CleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// Thumbnail decodes an image and returns a JPEG scaled so its longest edge is at most maxEdge,
+// along with the original width and height. The header is checked first so an image declaring
+// more than MaxImagePixels is rejected with ErrImageTooLarge before any pixels are allocated.
+func Thumbnail(data []byte, maxEdge int) (thumb []byte, width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, 0, 0, fmt.Errorf("%w: %dx%d", errors.ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	bounds := src.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+
+	tw, th := width, height
+	if width > maxEdge || height > maxEdge {
+		if width >= height {
+			tw, th = maxEdge, max(1, height*maxEdge/width)
+		} else {
+			tw, th = max(1, width*maxEdge/height), maxEdge
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src) // JPEG has no alpha channel
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), width, height, nil
+}
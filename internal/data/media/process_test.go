@@ -0,0 +1,59 @@
+// File: internal/data/media/process_test.go
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/media"
+)
+
+// encodePNG returns a w×h PNG whose IHDR then claims to be declaredW×declaredH.
+func encodePNG(t *testing.T, w, h, declaredW, declaredH int) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	// 8-byte signature, then the IHDR chunk: length(4) type(4) width(4) height(4) ... crc(4).
+	ihdr := b[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(declaredW))
+	binary.BigEndian.PutUint32(ihdr[8:12], uint32(declaredH))
+	binary.BigEndian.PutUint32(b[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	return b
+}
+
+func TestThumbnail(t *testing.T) {
+	thumb, width, height, err := media.Thumbnail(encodePNG(t, 640, 480, 640, 480), media.ThumbnailSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 640 || height != 480 {
+		t.Errorf("size = %dx%d, want 640x480", width, height)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" || cfg.Width != media.ThumbnailSize || cfg.Height != 240 {
+		t.Errorf("thumbnail = %s %dx%d, want jpeg %dx240", format, cfg.Width, cfg.Height, media.ThumbnailSize)
+	}
+}
+
+func TestThumbnailDecompressionBomb(t *testing.T) {
+	// A 1×1 image whose header declares 60000×60000: decoding it would allocate gigabytes.
+	bomb := encodePNG(t, 1, 1, 60000, 60000)
+
+	_, _, _, err := media.Thumbnail(bomb, media.ThumbnailSize)
+	if !errors.Is(err, internalErrors.ErrImageTooLarge) {
+		t.Errorf("Thumbnail error = %v, want ErrImageTooLarge", err)
+	}
+}
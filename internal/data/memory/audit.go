@@ -0,0 +1,62 @@
+// File: internal/data/memory/audit.go
+package memory
+
+import (
+	"context"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// AuditModel is the in-memory audit.AuditRepository. The entries themselves are appended by the
+// other in-memory repositories as they write.
+type AuditModel struct {
+	Store *Store
+}
+
+var _ audit.AuditRepository = (*AuditModel)(nil)
+
+// auditColumns are the columns the audit trail can be sorted by.
+var auditColumns = columns[audit.Entry]{
+	"id":         func(e audit.Entry) any { return e.ID },
+	"created_at": func(e audit.Entry) any { return e.CreatedAt },
+}
+
+/****************************************************************************************
+ *										Audit Log										*
+ ***************************************************************************************/
+
+// GetAll returns a page of the audit trail matching filter.
+func (m *AuditModel) GetAll(ctx context.Context, f *audit.EntryFilter) ([]*audit.Entry, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := []audit.Entry{}
+	for _, e := range m.Store.t.auditLog {
+		switch {
+		case f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID),
+			f.Entity != "" && e.Entity != f.Entity,
+			f.EntityID != nil && e.EntityID != *f.EntityID,
+			f.Action != "" && string(e.Action) != f.Action,
+			f.RequestID != "" && e.RequestID != f.RequestID,
+			f.Since != nil && e.CreatedAt.Before(*f.Since),
+			f.Until != nil && !e.CreatedAt.Before(*f.Until):
+			continue
+		}
+		rows = append(rows, e)
+	}
+	m.Store.mu.RUnlock()
+
+	page, metadata, err := paginate(rows, f.Default, auditColumns, func(e audit.Entry) int64 { return e.ID })
+	if err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	result := make([]*audit.Entry, len(page))
+	for i := range page {
+		result[i] = &page[i]
+	}
+	return result, metadata, nil
+}
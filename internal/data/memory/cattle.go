@@ -0,0 +1,481 @@
+// File: internal/data/memory/cattle.go
+package memory
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// CattleModel is the in-memory cattle.CattleRepository.
+type CattleModel struct {
+	Store *Store
+}
+
+// BreedModel is the in-memory cattle.BreedRepository.
+type BreedModel struct {
+	Store *Store
+}
+
+var (
+	_ cattle.CattleRepository = (*CattleModel)(nil)
+	_ cattle.BreedRepository  = (*BreedModel)(nil)
+)
+
+// cattleColumns are the columns cattle can be sorted by.
+var cattleColumns = columns[cattle.Cattle]{
+	"id":           func(c cattle.Cattle) any { return c.ID },
+	"owner_id":     func(c cattle.Cattle) any { return c.OwnerID },
+	"breed_id":     func(c cattle.Cattle) any { return c.BreedID },
+	"tag_number":   func(c cattle.Cattle) any { return c.TagNumber },
+	"sex":          func(c cattle.Cattle) any { return string(c.Sex) },
+	"age_months":   func(c cattle.Cattle) any { return c.AgeMonths },
+	"weight_kg":    func(c cattle.Cattle) any { return c.WeightKg },
+	"is_pregnant":  func(c cattle.Cattle) any { return nullable(c.IsPregnant) },
+	"is_castrated": func(c cattle.Cattle) any { return nullable(c.IsCastrated) },
+	"is_active":    func(c cattle.Cattle) any { return nullable(c.IsActive) },
+	"created_at":   func(c cattle.Cattle) any { return c.CreatedAt },
+	"updated_at":   func(c cattle.Cattle) any { return c.UpdatedAt },
+}
+
+// breedColumns are the columns breeds can be sorted by.
+var breedColumns = columns[cattle.Breed]{
+	"id":         func(b cattle.Breed) any { return b.ID },
+	"name":       func(b cattle.Breed) any { return b.Name },
+	"is_active":  func(b cattle.Breed) any { return nullable(b.IsActive) },
+	"created_at": func(b cattle.Breed) any { return b.CreatedAt },
+	"updated_at": func(b cattle.Breed) any { return b.UpdatedAt },
+}
+
+// cattleFields gives the soft delete helpers access to an animal's deletion and version.
+func cattleFields(c *cattle.Cattle) (*database.Deletion, *int) { return &c.Deletion, &c.Version }
+
+// breedFields gives the soft delete helpers access to a breed's deletion and version.
+func breedFields(b *cattle.Breed) (*database.Deletion, *int) { return &b.Deletion, &b.Version }
+
+/****************************************************************************************
+ *										Cattle											*
+ ***************************************************************************************/
+
+// Insert adds a new animal together with its breed composition.
+// An animal without a composition is recorded as purebred in BreedID.
+func (m *CattleModel) Insert(ctx context.Context, c *cattle.Cattle) error {
+	if len(c.Composition) == 0 {
+		c.Composition = cattle.Purebred(c.BreedID)
+	}
+	c.BreedID = c.Composition.PrimaryBreed()
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := m.Store.checkCattle(c); err != nil {
+		return err
+	}
+
+	now := timestamp(time.Now())
+	c.ID = int(m.Store.nextID("cattle"))
+	c.CreatedAt, c.UpdatedAt = now, now
+	c.IsActive = boolOr(c.IsActive, true)
+	c.Version = 1
+
+	m.Store.t.cattle[c.ID] = cloneCattle(*c)
+	return m.Store.record(ctx, "cattle", int64(c.ID), audit.ActionInsert, nil, m.Store.readCattle(*c))
+}
+
+// Update replaces an animal and its breed composition, provided its version has not moved on since it
+// was read.
+// An animal without a composition is recorded as purebred in BreedID.
+func (m *CattleModel) Update(ctx context.Context, c *cattle.Cattle) error {
+	if len(c.Composition) == 0 {
+		c.Composition = cattle.Purebred(c.BreedID)
+	}
+	c.BreedID = c.Composition.PrimaryBreed()
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.cattle[c.ID]
+	if !ok || existing.Version != c.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+	if err := m.Store.checkCattle(c); err != nil {
+		return err
+	}
+
+	c.UpdatedAt = timestamp(time.Now())
+	c.Version++
+
+	row := cloneCattle(*c)
+	row.IsActive = boolOr(c.IsActive, *existing.IsActive)
+	row.Deletion = database.Deletion{}
+	row.CreatedAt = existing.CreatedAt
+	m.Store.t.cattle[c.ID] = row
+	return m.Store.record(ctx, "cattle", int64(c.ID), audit.ActionUpdate, m.Store.readCattle(existing), m.Store.readCattle(row))
+}
+
+// SoftDelete marks an animal as deleted by deletedBy.
+func (m *CattleModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := softDelete(m.Store.t.cattle, id, deletedBy, cattleFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "cattle", []int{id}, audit.ActionDelete)
+	return nil
+}
+
+// Restore brings a soft deleted animal back.
+func (m *CattleModel) Restore(ctx context.Context, id int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := restoreRow(m.Store.t.cattle, id, cattleFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "cattle", []int{id}, audit.ActionRestore)
+	return nil
+}
+
+// Purge permanently removes the cattle soft deleted before the cutoff.
+func (m *CattleModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := purgeable(m.Store.t.cattle, before, cattleFields)
+	for _, id := range ids {
+		delete(m.Store.t.cattle, id)
+	}
+	recordAll(m.Store, ctx, "cattle", ids, audit.ActionPurge)
+	return int64(len(ids)), nil
+}
+
+// GetByID retrieves an animal by id.
+func (m *CattleModel) GetByID(ctx context.Context, id int) (*cattle.Cattle, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	row, ok := m.Store.t.cattle[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	c := m.Store.readCattle(row)
+	return &c, nil
+}
+
+// GetAll retrieves a page of the cattle matching the filter.
+func (m *CattleModel) GetAll(ctx context.Context, f *cattle.CattleFilter) (cattle.Cattles, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := cattle.Cattles{}
+	for _, c := range m.Store.t.cattle {
+		switch {
+		case f.OwnerID != nil && c.OwnerID != *f.OwnerID,
+			f.BreedID != nil && c.BreedID != *f.BreedID,
+			!containsFold(c.TagNumber, f.TagNumber),
+			f.Sex != nil && c.Sex != *f.Sex,
+			f.AgeMonths != nil && c.AgeMonths != *f.AgeMonths,
+			f.WeightKg != nil && c.WeightKg != *f.WeightKg,
+			!matchesBool(c.IsPregnant, f.IsPregnant),
+			!matchesBool(c.IsCastrated, f.IsCastrated),
+			!matchesBool(c.IsActive, f.IsActive),
+			f.ContainsBreedID != nil && !containsBreed(c.Composition, *f.ContainsBreedID, f.MinBreedPercentage),
+			!inRange(c.AgeMonths, f.MinAgeMonths, f.MaxAgeMonths),
+			!inRange(c.WeightKg, f.MinWeightKg, f.MaxWeightKg),
+			f.CreatedSince != nil && c.CreatedAt < timestamp(*f.CreatedSince),
+			f.CreatedUntil != nil && c.CreatedAt >= timestamp(*f.CreatedUntil),
+			!inList(c.OwnerID, f.OwnerIDs),
+			!inList(c.BreedID, f.BreedIDs),
+			!inList(c.Sex, f.Sexes),
+			!visible(c.Deletion, f.WithDeleted):
+			continue
+		}
+		rows = append(rows, m.Store.readCattle(c))
+	}
+	m.Store.mu.RUnlock()
+
+	return paginate(rows, f.Default, cattleColumns, func(c cattle.Cattle) int64 { return int64(c.ID) })
+}
+
+/****************************************************************************************
+ *										Breeds											*
+ ***************************************************************************************/
+
+// Insert adds a new breed.
+func (m *BreedModel) Insert(ctx context.Context, b *cattle.Breed) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := m.Store.checkBreedUnique(b); err != nil {
+		return err
+	}
+
+	now := timestamp(time.Now())
+	b.ID = int(m.Store.nextID("breeds"))
+	b.CreatedAt, b.UpdatedAt = now, now
+	b.IsActive = boolOr(b.IsActive, true)
+	b.Version = 1
+
+	row := *b
+	row.IsActive = cloneBool(b.IsActive)
+	m.Store.t.breeds[b.ID] = row
+	return m.Store.record(ctx, "breeds", int64(b.ID), audit.ActionInsert, nil, row)
+}
+
+// Update replaces a breed, provided its version has not moved on since it was read.
+func (m *BreedModel) Update(ctx context.Context, b *cattle.Breed) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.breeds[b.ID]
+	if !ok || existing.Version != b.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+	if err := m.Store.checkBreedUnique(b); err != nil {
+		return err
+	}
+
+	b.UpdatedAt = timestamp(time.Now())
+	b.Version++
+
+	row := *b
+	row.IsActive = boolOr(b.IsActive, *existing.IsActive)
+	row.Deletion = database.Deletion{}
+	row.CreatedAt = existing.CreatedAt
+	m.Store.t.breeds[b.ID] = row
+	return m.Store.record(ctx, "breeds", int64(b.ID), audit.ActionUpdate, existing, row)
+}
+
+// SoftDelete marks a breed as deleted by deletedBy.
+func (m *BreedModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := softDelete(m.Store.t.breeds, id, deletedBy, breedFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "breeds", []int{id}, audit.ActionDelete)
+	return nil
+}
+
+// Restore brings a soft deleted breed back.
+func (m *BreedModel) Restore(ctx context.Context, id int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := restoreRow(m.Store.t.breeds, id, breedFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "breeds", []int{id}, audit.ActionRestore)
+	return nil
+}
+
+// Purge permanently removes the breeds soft deleted before the cutoff that no animal is recorded as.
+func (m *BreedModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var purged []int
+	for _, id := range purgeable(m.Store.t.breeds, before, breedFields) {
+		if m.Store.breedInUse(id) {
+			continue
+		}
+		delete(m.Store.t.breeds, id)
+		purged = append(purged, id)
+	}
+	recordAll(m.Store, ctx, "breeds", purged, audit.ActionPurge)
+	return int64(len(purged)), nil
+}
+
+// GetByID retrieves a breed by id.
+func (m *BreedModel) GetByID(ctx context.Context, id int) (*cattle.Breed, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	b, ok := m.Store.t.breeds[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	b.IsActive = cloneBool(b.IsActive)
+	b.Deletion = cloneDeletion(b.Deletion)
+	return &b, nil
+}
+
+// GetAll retrieves a page of the breeds matching the filter.
+func (m *BreedModel) GetAll(ctx context.Context, f *cattle.BreedFilter) (cattle.Breeds, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := cattle.Breeds{}
+	for _, b := range m.Store.t.breeds {
+		if !containsFold(b.Name, f.Name) || !matchesBool(b.IsActive, f.IsActive) || !visible(b.Deletion, f.WithDeleted) {
+			continue
+		}
+		b.IsActive = cloneBool(b.IsActive)
+		b.Deletion = cloneDeletion(b.Deletion)
+		rows = append(rows, b)
+	}
+	m.Store.mu.RUnlock()
+
+	return paginate(rows, f.Default, breedColumns, func(b cattle.Breed) int64 { return int64(b.ID) })
+}
+
+// GetComposition retrieves the breed make-up of an animal.
+func (m *BreedModel) GetComposition(ctx context.Context, cattleID int) (cattle.Composition, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	c, ok := m.Store.t.cattle[cattleID]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	return m.Store.readComposition(c.Composition), nil
+}
+
+// SetComposition replaces the breed make-up of an animal and updates its derived primary breed.
+// Soft deleted cattle are reported as not found.
+func (m *BreedModel) SetComposition(ctx context.Context, cattleID int, composition cattle.Composition) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.cattle[cattleID]
+	if !ok || existing.Deletion.IsDeleted() {
+		return errors.ErrRecordNotFound
+	}
+	if err := m.Store.checkComposition(composition); err != nil {
+		return err
+	}
+
+	c := existing
+	c.Composition = cloneComposition(composition)
+	c.BreedID = composition.PrimaryBreed()
+	c.UpdatedAt = timestamp(time.Now())
+	c.Version++
+	m.Store.t.cattle[cattleID] = c
+	return m.Store.record(ctx, "cattle", int64(cattleID), audit.ActionUpdate, m.Store.readCattle(existing), m.Store.readCattle(c))
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// checkCattle applies the constraints of the cattle and composition tables to c.
+// The caller must hold the lock.
+func (s *Store) checkCattle(c *cattle.Cattle) error {
+	for _, other := range s.t.cattle {
+		if other.ID != c.ID && other.TagNumber == c.TagNumber {
+			return errors.ErrDuplicateValue("tag_number")
+		}
+	}
+	if _, ok := s.t.users[int64(c.OwnerID)]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	return s.checkComposition(c.Composition)
+}
+
+// checkComposition applies the constraints of the composition table: every breed must exist and
+// the shares must add up to 100%. The caller must hold the lock.
+func (s *Store) checkComposition(composition cattle.Composition) error {
+	total := 0.0
+	for _, share := range composition {
+		if _, ok := s.t.breeds[share.BreedID]; !ok {
+			return errors.ErrForeignKeyViolation
+		}
+		if share.Percentage <= 0 || share.Percentage > 100 {
+			return errors.ErrInvalidComposition
+		}
+		total += share.Percentage
+	}
+	if math.Abs(total-100) >= 0.005 {
+		return errors.ErrInvalidComposition
+	}
+	return nil
+}
+
+// checkBreedUnique returns a duplicate value error if another breed has b's name.
+// The caller must hold the lock.
+func (s *Store) checkBreedUnique(b *cattle.Breed) error {
+	for _, other := range s.t.breeds {
+		if other.ID != b.ID && other.Name == b.Name {
+			return errors.ErrDuplicateValue("name")
+		}
+	}
+	return nil
+}
+
+// breedInUse reports whether any animal is recorded as the breed, which keeps it from being
+// purged. The caller must hold the lock.
+func (s *Store) breedInUse(id int) bool {
+	for _, c := range s.t.cattle {
+		if c.BreedID == id || containsBreed(c.Composition, id, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCattle returns a copy of a stored animal with its composition filled in as the
+// database returns it. The caller must hold the lock.
+func (s *Store) readCattle(c cattle.Cattle) cattle.Cattle {
+	c = cloneCattle(c)
+	c.Composition = s.readComposition(c.Composition)
+	return c
+}
+
+// readComposition names each share and orders them largest first. The caller must hold the lock.
+func (s *Store) readComposition(composition cattle.Composition) cattle.Composition {
+	result := cloneComposition(composition)
+	for i := range result {
+		result[i].BreedName = s.t.breeds[result[i].BreedID].Name
+	}
+	slices.SortFunc(result, func(a, b cattle.BreedShare) int {
+		if c := cmp.Compare(b.Percentage, a.Percentage); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.BreedID, b.BreedID)
+	})
+	return result
+}
+
+// cloneCattle copies an animal so that it shares no flags or composition with the stored row.
+func cloneCattle(c cattle.Cattle) cattle.Cattle {
+	c.IsPregnant = cloneBool(c.IsPregnant)
+	c.IsCastrated = cloneBool(c.IsCastrated)
+	c.IsActive = cloneBool(c.IsActive)
+	c.Composition = cloneComposition(c.Composition)
+	c.Deletion = cloneDeletion(c.Deletion)
+	return c
+}
+
+// cloneComposition copies a composition, dropping the breed names the database does not store.
+func cloneComposition(composition cattle.Composition) cattle.Composition {
+	result := make(cattle.Composition, len(composition))
+	for i, share := range composition {
+		result[i] = cattle.BreedShare{BreedID: share.BreedID, Percentage: share.Percentage}
+	}
+	return result
+}
+
+// containsBreed reports whether a composition has at least min percent of a breed, or any of it if min is nil.
+func containsBreed(composition cattle.Composition, breedID int, min *float64) bool {
+	for _, share := range composition {
+		if share.BreedID == breedID && (min == nil || share.Percentage >= *min) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesBool reports whether a nullable column matches an optional boolean filter.
+func matchesBool(value, filter *bool) bool {
+	return filter == nil || (value != nil && *value == *filter)
+}
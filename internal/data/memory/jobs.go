@@ -0,0 +1,249 @@
+// File: internal/data/memory/jobs.go
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// JobModel is the in-memory jobs.JobRepository.
+type JobModel struct {
+	Store *Store
+}
+
+var _ jobs.JobRepository = (*JobModel)(nil)
+
+// jobColumns are the columns jobs can be sorted by.
+var jobColumns = columns[jobs.Job]{
+	"id":         func(j jobs.Job) any { return j.ID },
+	"run_at":     func(j jobs.Job) any { return j.RunAt },
+	"created_at": func(j jobs.Job) any { return j.CreatedAt },
+	"updated_at": func(j jobs.Job) any { return j.UpdatedAt },
+}
+
+/****************************************************************************************
+ *										Jobs											*
+ ***************************************************************************************/
+
+// Enqueue adds a job to the queue, due at its RunAt or straight away when that is zero.
+func (m *JobModel) Enqueue(ctx context.Context, job *jobs.Job) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	m.Store.enqueue(job)
+	return nil
+}
+
+// Claim hands the worker the job of one of the given kinds that has been due longest, counting it
+// as an attempt. Jobs whose lease ran out on their last attempt are dead lettered instead of
+// handed out again. It returns ErrRecordNotFound when nothing is due.
+func (m *JobModel) Claim(ctx context.Context, kinds []string, now time.Time) (*jobs.Job, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	stale := now.Add(-jobs.Lease)
+	var due *jobs.Job
+	for _, j := range m.Store.t.jobs {
+		if slices.Contains(kinds, j.Kind) && j.Status == jobs.StatusRunning && !j.LockedAt.After(stale) && j.Attempts >= j.MaxAttempts {
+			finished := time.Now()
+			j.Status, j.LastError = jobs.StatusDead, jobs.LeaseExpired
+			j.LockedAt, j.FinishedAt, j.UpdatedAt = nil, &finished, finished
+			m.Store.t.jobs[j.ID] = j
+			continue
+		}
+		switch {
+		case !slices.Contains(kinds, j.Kind),
+			j.Status == jobs.StatusPending && j.RunAt.After(now),
+			j.Status == jobs.StatusRunning && j.LockedAt.After(stale),
+			j.Status == jobs.StatusSucceeded, j.Status == jobs.StatusDead:
+			continue
+		}
+		if due == nil || cmp.Or(j.RunAt.Compare(due.RunAt), cmp.Compare(j.ID, due.ID)) < 0 {
+			due = &j
+		}
+	}
+	if due == nil {
+		return nil, errors.ErrRecordNotFound
+	}
+
+	due.Status = jobs.StatusRunning
+	due.Attempts++
+	due.LockedAt = &now
+	due.UpdatedAt = time.Now()
+	m.Store.t.jobs[due.ID] = cloneJob(*due)
+
+	job := cloneJob(*due)
+	return &job, nil
+}
+
+// Complete records that a claimed job succeeded. It returns ErrEditConflict when the worker lost
+// the job to another after its lease ran out.
+func (m *JobModel) Complete(ctx context.Context, job *jobs.Job) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	row, ok := m.Store.claimed(job)
+	if !ok {
+		return errors.ErrEditConflict
+	}
+
+	now := time.Now()
+	row.Status = jobs.StatusSucceeded
+	row.LockedAt, row.FinishedAt = nil, &now
+	row.UpdatedAt = now
+	m.Store.t.jobs[row.ID] = row
+
+	*job = cloneJob(row)
+	return nil
+}
+
+// Fail records that an attempt at a claimed job failed for reason. The job runs again at retryAt,
+// unless that was its last attempt and it is dead lettered.
+func (m *JobModel) Fail(ctx context.Context, job *jobs.Job, reason string, retryAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	row, ok := m.Store.claimed(job)
+	if !ok {
+		return errors.ErrEditConflict
+	}
+
+	now := time.Now()
+	row.Status = jobs.StatusPending
+	if row.Attempts >= row.MaxAttempts {
+		row.Status, row.FinishedAt = jobs.StatusDead, &now
+	}
+	row.RunAt, row.LastError, row.LockedAt = retryAt, reason, nil
+	row.UpdatedAt = now
+	m.Store.t.jobs[row.ID] = row
+
+	*job = cloneJob(row)
+	return nil
+}
+
+// Retry puts a dead job back in the queue with a fresh set of attempts, due straight away.
+func (m *JobModel) Retry(ctx context.Context, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.jobs[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	if existing.Status != jobs.StatusDead {
+		return errors.ErrNotDead
+	}
+
+	now := time.Now()
+	row := cloneJob(existing)
+	row.Status, row.Attempts, row.LastError = jobs.StatusPending, 0, ""
+	row.RunAt, row.FinishedAt = now, nil
+	row.UpdatedAt = now
+	m.Store.t.jobs[id] = row
+	return m.Store.record(ctx, "jobs", id, audit.ActionUpdate, cloneJob(existing), cloneJob(row))
+}
+
+// GetByID retrieves a job by id.
+func (m *JobModel) GetByID(ctx context.Context, id int64) (*jobs.Job, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	j, ok := m.Store.t.jobs[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	j = cloneJob(j)
+	return &j, nil
+}
+
+// GetAll returns a page of the jobs matching filter.
+func (m *JobModel) GetAll(ctx context.Context, f *jobs.JobFilter) ([]*jobs.Job, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := []jobs.Job{}
+	for _, j := range m.Store.t.jobs {
+		if (f.Status != "" && string(j.Status) != f.Status) || (f.Kind != "" && j.Kind != f.Kind) {
+			continue
+		}
+		rows = append(rows, cloneJob(j))
+	}
+	m.Store.mu.RUnlock()
+
+	page, metadata, err := paginate(rows, f.Default, jobColumns, func(j jobs.Job) int64 { return j.ID })
+	if err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	result := make([]*jobs.Job, len(page))
+	for i := range page {
+		result[i] = &page[i]
+	}
+	return result, metadata, nil
+}
+
+// Purge permanently removes the jobs that succeeded before the cutoff.
+func (m *JobModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var n int64
+	for id, j := range m.Store.t.jobs {
+		if j.Status == jobs.StatusSucceeded && j.FinishedAt.Before(before) {
+			delete(m.Store.t.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// enqueue adds a job to the queue like JobModel.Enqueue. The caller must hold the write lock.
+func (s *Store) enqueue(job *jobs.Job) {
+	now := time.Now()
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = jobs.DefaultMaxAttempts
+	}
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage("{}")
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.ID = s.nextID("jobs")
+	job.Status = jobs.StatusPending
+	job.CreatedAt, job.UpdatedAt = now, now
+
+	s.t.jobs[job.ID] = cloneJob(*job)
+}
+
+// claimed returns the stored row of a job the caller claimed, provided no other worker has claimed
+// it since. The caller must hold the write lock.
+func (s *Store) claimed(job *jobs.Job) (jobs.Job, bool) {
+	row, ok := s.t.jobs[job.ID]
+	if !ok || row.Status != jobs.StatusRunning || row.LockedAt == nil || job.LockedAt == nil || !row.LockedAt.Equal(*job.LockedAt) {
+		return jobs.Job{}, false
+	}
+	return cloneJob(row), true
+}
+
+// cloneJob copies a job so that it shares no payload or times with the stored row.
+func cloneJob(j jobs.Job) jobs.Job {
+	j.Payload = slices.Clone(j.Payload)
+	j.LockedAt = cloneTime(j.LockedAt)
+	j.FinishedAt = cloneTime(j.FinishedAt)
+	return j
+}
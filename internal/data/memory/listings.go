@@ -0,0 +1,488 @@
+// File: internal/data/memory/listings.go
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// ListingModel is the in-memory listings.ListingRepository.
+type ListingModel struct {
+	Store *Store
+}
+
+// ListingPriceModel is the in-memory listings.ListingPriceRepository.
+type ListingPriceModel struct {
+	Store *Store
+}
+
+var (
+	_ listings.ListingRepository      = (*ListingModel)(nil)
+	_ listings.ListingPriceRepository = (*ListingPriceModel)(nil)
+)
+
+// listingColumns are the columns listings can be sorted by.
+var listingColumns = columns[listings.Listing]{
+	"id":          func(l listings.Listing) any { return l.ID },
+	"user_id":     func(l listings.Listing) any { return l.UserID },
+	"area_id":     func(l listings.Listing) any { return l.AreaID },
+	"region_id":   func(l listings.Listing) any { return l.RegionID },
+	"title":       func(l listings.Listing) any { return l.Title },
+	"is_active":   func(l listings.Listing) any { return nullable(l.IsActive) },
+	"created_at":  func(l listings.Listing) any { return l.CreatedAt },
+	"updated_at":  func(l listings.Listing) any { return l.UpdatedAt },
+	"distance_km": func(l listings.Listing) any { return nullable(l.DistanceKm) },
+	"relevance":   func(l listings.Listing) any { return nullable(l.Rank) },
+}
+
+// listingFields gives the soft delete helpers access to a listing's deletion and version.
+func listingFields(l *listings.Listing) (*database.Deletion, *int) { return &l.Deletion, &l.Version }
+
+/****************************************************************************************
+ *										Listings										*
+ ***************************************************************************************/
+
+// Insert adds a new listing, taking its region from its area. See listings.Listing.Schedule for
+// when it is published and when it expires.
+func (m *ListingModel) Insert(ctx context.Context, l *listings.Listing) error {
+	if err := m.assignRegion(ctx, l); err != nil {
+		return err
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.t.users[l.UserID]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	if err := m.Store.checkListing(l); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	l.Schedule(now)
+	l.ID = m.Store.nextID("listings")
+	l.IsActive = boolOr(l.IsActive, true)
+	l.Version = 1
+	l.CreatedAt, l.UpdatedAt = now, now
+
+	m.Store.t.listings[l.ID] = cloneListing(*l)
+	return m.Store.record(ctx, "listings", l.ID, audit.ActionInsert, nil, cloneListing(*l))
+}
+
+// Update replaces a listing, taking its region from its area, provided its version has not moved on
+// since it was read. The owner of a listing never changes.
+func (m *ListingModel) Update(ctx context.Context, l *listings.Listing) error {
+	if err := m.assignRegion(ctx, l); err != nil {
+		return err
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.listings[l.ID]
+	if !ok || existing.Version != l.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+	if err := m.Store.checkListing(l); err != nil {
+		return err
+	}
+
+	l.UpdatedAt = time.Now()
+	l.Version++
+
+	row := cloneListing(*l)
+	row.IsActive = boolOr(l.IsActive, *existing.IsActive)
+	row.UserID = existing.UserID
+	row.SoldAt = existing.SoldAt
+	row.RemindedAt = nil
+	if row.ExpiresAt.Equal(existing.ExpiresAt) {
+		row.RemindedAt = cloneTime(existing.RemindedAt)
+	}
+	row.Deletion = database.Deletion{}
+	row.CreatedAt = existing.CreatedAt
+	m.Store.t.listings[l.ID] = row
+	return m.Store.record(ctx, "listings", l.ID, audit.ActionUpdate, cloneListing(existing), cloneListing(row))
+}
+
+// SoftDelete marks a listing as deleted by deletedBy.
+func (m *ListingModel) SoftDelete(ctx context.Context, id int64, deletedBy int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := softDelete(m.Store.t.listings, id, deletedBy, listingFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "listings", []int64{id}, audit.ActionDelete)
+	return nil
+}
+
+// Restore brings a soft deleted listing back.
+func (m *ListingModel) Restore(ctx context.Context, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := restoreRow(m.Store.t.listings, id, listingFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "listings", []int64{id}, audit.ActionRestore)
+	return nil
+}
+
+// MarkSold records that a listing has been sold, which takes it off the market, and queues a
+// notification for its watchers.
+func (m *ListingModel) MarkSold(ctx context.Context, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.listings[id]
+	if !ok || existing.Deletion.IsDeleted() {
+		return errors.ErrRecordNotFound
+	}
+	if existing.SoldAt != nil {
+		return errors.ErrAlreadySold
+	}
+
+	now, inactive := time.Now(), false
+	row := cloneListing(existing)
+	row.SoldAt = &now
+	row.IsActive = &inactive
+	row.UpdatedAt = now
+	row.Version++
+	m.Store.t.listings[id] = row
+
+	if err := m.Store.record(ctx, "listings", id, audit.ActionUpdate, cloneListing(existing), cloneListing(row)); err != nil {
+		return err
+	}
+	m.Store.notify(id, watchlists.EventSold, "")
+	return nil
+}
+
+// Renew puts a listing back on the market until the given time, publishing it straight away if it
+// was waiting to be published. Sold listings cannot be renewed.
+func (m *ListingModel) Renew(ctx context.Context, id int64, until time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.listings[id]
+	if !ok || existing.Deletion.IsDeleted() {
+		return errors.ErrRecordNotFound
+	}
+	if existing.SoldAt != nil {
+		return errors.ErrAlreadySold
+	}
+
+	active := true
+	row := cloneListing(existing)
+	row.ExpiresAt, row.RemindedAt, row.PublishAt = until, nil, nil
+	row.IsActive = &active
+	row.UpdatedAt = time.Now()
+	row.Version++
+	m.Store.t.listings[id] = row
+	return m.Store.record(ctx, "listings", id, audit.ActionUpdate, cloneListing(existing), cloneListing(row))
+}
+
+// Publish activates the listings whose publish_at has come by now and returns their ids.
+func (m *ListingModel) Publish(ctx context.Context, now time.Time) ([]int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := []int64{}
+	for id, l := range m.Store.t.listings {
+		if l.PublishAt == nil || l.PublishAt.After(now) || l.SoldAt != nil || l.Deletion.IsDeleted() {
+			continue
+		}
+		active := true
+		changes := audit.Changes{"is_active": audit.Field(false, true), "publish_at": audit.Field(*l.PublishAt, nil)}
+		l.IsActive, l.PublishAt = &active, nil
+		l.UpdatedAt = time.Now()
+		l.Version++
+		m.Store.t.listings[id] = l
+		m.Store.recordChanges(ctx, "listings", id, audit.ActionUpdate, changes)
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// Expire takes the listings whose expires_at has passed by now off the market and returns their
+// ids.
+func (m *ListingModel) Expire(ctx context.Context, now time.Time) ([]int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := []int64{}
+	for id, l := range m.Store.t.listings {
+		if !*l.IsActive || l.ExpiresAt.After(now) || l.SoldAt != nil || l.Deletion.IsDeleted() {
+			continue
+		}
+		inactive := false
+		l.IsActive = &inactive
+		l.UpdatedAt = time.Now()
+		l.Version++
+		m.Store.t.listings[id] = l
+		m.Store.recordChanges(ctx, "listings", id, audit.ActionUpdate, audit.Changes{"is_active": audit.Field(true, false)})
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// GetExpiring returns the ids of the active listings of undeleted sellers that expire by before
+// and whose sellers have not been reminded yet, soonest first.
+func (m *ListingModel) GetExpiring(ctx context.Context, before time.Time) ([]int64, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	expiring := []listings.Listing{}
+	for _, l := range m.Store.t.listings {
+		seller, ok := m.Store.t.users[l.UserID]
+		switch {
+		case !*l.IsActive, l.RemindedAt != nil, l.ExpiresAt.After(before), l.SoldAt != nil,
+			l.Deletion.IsDeleted(), !ok || seller.Deletion.IsDeleted():
+			continue
+		}
+		expiring = append(expiring, l)
+	}
+	slices.SortFunc(expiring, func(a, b listings.Listing) int {
+		return cmp.Or(a.ExpiresAt.Compare(b.ExpiresAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	ids := make([]int64, len(expiring))
+	for i, l := range expiring {
+		ids[i] = l.ID
+	}
+	return ids, nil
+}
+
+// MarkReminded records that the seller of a listing was reminded of its expiry, and queues a
+// notification for its watchers.
+func (m *ListingModel) MarkReminded(ctx context.Context, id int64, at time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	l, ok := m.Store.t.listings[id]
+	if !ok || l.Deletion.IsDeleted() {
+		return errors.ErrRecordNotFound
+	}
+	l.RemindedAt = &at
+	m.Store.t.listings[id] = l
+	m.Store.notify(id, watchlists.EventExpiring, listings.ExpiryDetail(l.ExpiresAt))
+	return nil
+}
+
+// Purge permanently removes the listings soft deleted before the cutoff, and their prices.
+func (m *ListingModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := purgeable(m.Store.t.listings, before, listingFields)
+	for _, id := range ids {
+		m.Store.deleteListing(id)
+	}
+	recordAll(m.Store, ctx, "listings", ids, audit.ActionPurge)
+	return int64(len(ids)), nil
+}
+
+// GetByID retrieves a listing by id.
+func (m *ListingModel) GetByID(ctx context.Context, id int64) (*listings.Listing, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	l, ok := m.Store.t.listings[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	l = cloneListing(l)
+	return &l, nil
+}
+
+// GetAll retrieves a page of the listings matching the filter.
+// Proximity searches only match listings with coordinates and report each listing's distance.
+func (m *ListingModel) GetAll(ctx context.Context, f *listings.ListingFilter) (listings.Listings, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := listings.Listings{}
+	for _, l := range m.Store.t.listings {
+		distance, ok := matchesGeo(l.Coordinates, f.Near, f.Box, f.HasCoordinates)
+		switch {
+		case !ok,
+			f.UserID != nil && l.UserID != *f.UserID,
+			f.AreaID != nil && l.AreaID != *f.AreaID,
+			f.RegionID != nil && l.RegionID != *f.RegionID,
+			!containsFold(l.Title, f.Title),
+			!matchesBool(l.IsActive, f.IsActive),
+			!inList(l.AreaID, f.AreaIDs),
+			!inList(l.RegionID, f.RegionIDs),
+			!m.Store.matchesPrices(l.ID, f),
+			f.UpdatedSince != nil && l.UpdatedAt.Before(*f.UpdatedSince),
+			!visible(l.Deletion, f.WithDeleted):
+			continue
+		}
+		l = cloneListing(l)
+		l.DistanceKm = distance
+		if f.Search != "" {
+			rank, ok := searchDocument(m.Store.listingDocument(l), f.Search)
+			if !ok {
+				continue
+			}
+			text := snippet(strings.TrimSpace(l.Title+" "+l.Description), f.Search)
+			l.Rank, l.Snippet = &rank, &text
+		}
+		rows = append(rows, l)
+	}
+	m.Store.mu.RUnlock()
+
+	return paginate(rows, f.Default, listingColumns, func(l listings.Listing) int64 { return l.ID })
+}
+
+/****************************************************************************************
+ *										Listing Prices									*
+ ***************************************************************************************/
+
+// Insert adds a price for one class of cattle in a listing.
+func (m *ListingPriceModel) Insert(ctx context.Context, lp *listings.ListingPrice) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.t.listings[lp.ListingID]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	key := listingPriceKey{listingID: lp.ListingID, cattleClass: lp.CattleClass}
+	if _, ok := m.Store.t.listingPrices[key]; ok {
+		return errors.ErrDuplicateValue("cattle_class")
+	}
+
+	m.Store.t.listingPrices[key] = *lp
+	return m.Store.record(ctx, "listing_prices", lp.ListingID, audit.ActionInsert, nil, *lp)
+}
+
+// Update replaces the price and quantity for one class of cattle in a listing.
+func (m *ListingPriceModel) Update(ctx context.Context, lp *listings.ListingPrice) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	key := listingPriceKey{listingID: lp.ListingID, cattleClass: lp.CattleClass}
+	existing, ok := m.Store.t.listingPrices[key]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+
+	m.Store.t.listingPrices[key] = *lp
+	if err := m.Store.record(ctx, "listing_prices", lp.ListingID, audit.ActionUpdate, existing, *lp); err != nil {
+		return err
+	}
+	if existing.PricePerKg != lp.PricePerKg {
+		m.Store.notify(lp.ListingID, watchlists.EventPriceChanged, listings.PriceChange(existing, *lp))
+	}
+	return nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// assignRegion sets the listing's region to that of its area. A region supplied by the caller, or
+// one found from the listing's coordinates, must agree with it.
+func (m *ListingModel) assignRegion(ctx context.Context, l *listings.Listing) error {
+	areas := AreaModel{Store: m.Store}
+	area, err := areas.GetByID(ctx, int(l.AreaID))
+	if err != nil {
+		if err == errors.ErrRecordNotFound {
+			return errors.ErrForeignKeyViolation
+		}
+		return err
+	}
+	if l.RegionID != 0 && l.RegionID != int64(area.RegionID) {
+		return errors.ErrRegionMismatch
+	}
+
+	regions := RegionModel{Store: m.Store}
+	regionID, err := regions.ResolveRegion(ctx, area.RegionID, l.Coordinates)
+	if err != nil {
+		return err
+	}
+	l.RegionID = int64(regionID)
+	return nil
+}
+
+// listingDocument returns the text a search looks through for a listing. The store does not keep
+// the cattle of listings, so the breeds are left empty. The caller must hold the lock.
+func (s *Store) listingDocument(l listings.Listing) document {
+	places := s.t.areas[int(l.AreaID)].Name + " " + s.t.regions[int(l.RegionID)].Name
+	return document{l.Title, "", places, l.Description}
+}
+
+// matchesPrices reports whether a listing's prices pass the price and head count filters. The
+// caller must hold the lock.
+func (s *Store) matchesPrices(listingID int64, f *listings.ListingFilter) bool {
+	priced := len(f.CattleClasses) == 0 && f.MinPricePerKg == nil && f.MaxPricePerKg == nil
+	var head int64
+	for key, lp := range s.t.listingPrices {
+		if key.listingID != listingID {
+			continue
+		}
+		head += lp.Quantity
+		if inList(lp.CattleClass, f.CattleClasses) && inRange(lp.PricePerKg, f.MinPricePerKg, f.MaxPricePerKg) {
+			priced = true
+		}
+	}
+	return priced && inRange(head, f.MinTotalHead, f.MaxTotalHead)
+}
+
+// checkListing applies the foreign keys of the listings table and the rule that a listing is in
+// the region of its area. The caller must hold the lock.
+func (s *Store) checkListing(l *listings.Listing) error {
+	area, ok := s.t.areas[int(l.AreaID)]
+	if !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	if _, ok := s.t.regions[int(l.RegionID)]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	if int64(area.RegionID) != l.RegionID {
+		return errors.ErrRegionMismatch
+	}
+	return nil
+}
+
+// deleteListing removes a listing and, like ON DELETE CASCADE, its prices, saved search matches,
+// watches and watch notifications. The caller must hold the write lock.
+func (s *Store) deleteListing(id int64) {
+	delete(s.t.listings, id)
+	for key := range s.t.listingPrices {
+		if key.listingID == id {
+			delete(s.t.listingPrices, key)
+		}
+	}
+	for key := range s.t.searchMatches {
+		if key.listingID == id {
+			delete(s.t.searchMatches, key)
+		}
+	}
+	s.deleteWatchesOf(id, 0)
+}
+
+// cloneListing copies a listing so that it shares no flags with the stored row.
+func cloneListing(l listings.Listing) listings.Listing {
+	l.IsActive = cloneBool(l.IsActive)
+	l.SoldAt = cloneTime(l.SoldAt)
+	l.PublishAt = cloneTime(l.PublishAt)
+	l.RemindedAt = cloneTime(l.RemindedAt)
+	l.Deletion = cloneDeletion(l.Deletion)
+	l.DistanceKm = nil
+	return l
+}
@@ -0,0 +1,461 @@
+// File: internal/data/memory/locations.go
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// RegionModel is the in-memory locations.RegionRepository.
+type RegionModel struct {
+	Store *Store
+}
+
+// AreaModel is the in-memory locations.AreaRepository.
+type AreaModel struct {
+	Store *Store
+}
+
+var (
+	_ locations.RegionRepository = (*RegionModel)(nil)
+	_ locations.AreaRepository   = (*AreaModel)(nil)
+)
+
+// regionColumns are the columns regions can be sorted by.
+var regionColumns = columns[locations.Region]{
+	"id":   func(r locations.Region) any { return r.ID },
+	"name": func(r locations.Region) any { return r.Name },
+	"code": func(r locations.Region) any { return r.Code },
+}
+
+// areaColumns are the columns areas can be sorted by.
+var areaColumns = columns[locations.Area]{
+	"id":          func(a locations.Area) any { return a.ID },
+	"name":        func(a locations.Area) any { return a.Name },
+	"region_id":   func(a locations.Area) any { return a.RegionID },
+	"area_type":   func(a locations.Area) any { return string(a.AreaType) },
+	"is_active":   func(a locations.Area) any { return nullable(a.IsActive) },
+	"created_at":  func(a locations.Area) any { return a.CreatedAt },
+	"updated_at":  func(a locations.Area) any { return a.UpdatedAt },
+	"distance_km": func(a locations.Area) any { return nullable(a.DistanceKm) },
+}
+
+// regionFields gives the soft delete helpers access to a region's deletion and version.
+func regionFields(r *locations.Region) (*database.Deletion, *int) { return &r.Deletion, &r.Version }
+
+// areaFields gives the soft delete helpers access to an area's deletion and version.
+func areaFields(a *locations.Area) (*database.Deletion, *int) { return &a.Deletion, &a.Version }
+
+/****************************************************************************************
+ *										Regions											*
+ ***************************************************************************************/
+
+// Insert adds a new region.
+func (m *RegionModel) Insert(ctx context.Context, r *locations.Region) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, other := range m.Store.t.regions {
+		switch {
+		case other.Code == r.Code:
+			return errors.ErrDuplicateValue("code")
+		case other.Name == r.Name:
+			return errors.ErrDuplicateValue("name")
+		}
+	}
+
+	r.ID = int(m.Store.nextID("regions"))
+	r.Version = 1
+	m.Store.t.regions[r.ID] = cloneRegion(*r)
+	return m.Store.record(ctx, "regions", int64(r.ID), audit.ActionInsert, nil, cloneRegion(*r))
+}
+
+// Update replaces a region, provided its version has not moved on since it was read.
+func (m *RegionModel) Update(ctx context.Context, r *locations.Region) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.regions[r.ID]
+	if !ok || existing.Version != r.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+	for _, other := range m.Store.t.regions {
+		switch {
+		case other.ID == r.ID:
+		case other.Code == r.Code:
+			return errors.ErrDuplicateCode
+		case other.Name == r.Name:
+			return errors.ErrDuplicateName
+		}
+	}
+
+	r.Version++
+	row := cloneRegion(*r)
+	row.Deletion = database.Deletion{}
+	m.Store.t.regions[r.ID] = row
+	return m.Store.record(ctx, "regions", int64(r.ID), audit.ActionUpdate, cloneRegion(existing), cloneRegion(row))
+}
+
+// SoftDelete marks a region as deleted by deletedBy.
+func (m *RegionModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := softDelete(m.Store.t.regions, id, deletedBy, regionFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "regions", []int{id}, audit.ActionDelete)
+	return nil
+}
+
+// Restore brings a soft deleted region back.
+func (m *RegionModel) Restore(ctx context.Context, id int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := restoreRow(m.Store.t.regions, id, regionFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "regions", []int{id}, audit.ActionRestore)
+	return nil
+}
+
+// Purge permanently removes the regions soft deleted before the cutoff that no area or listing
+// belongs to.
+func (m *RegionModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var purged []int
+	for _, id := range purgeable(m.Store.t.regions, before, regionFields) {
+		if m.Store.regionInUse(id) {
+			continue
+		}
+		delete(m.Store.t.regions, id)
+		purged = append(purged, id)
+	}
+	recordAll(m.Store, ctx, "regions", purged, audit.ActionPurge)
+	return int64(len(purged)), nil
+}
+
+// GetByID retrieves a region by id.
+func (m *RegionModel) GetByID(ctx context.Context, id int) (*locations.Region, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	r, ok := m.Store.t.regions[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	r = cloneRegion(r)
+	return &r, nil
+}
+
+// GetAll retrieves a page of the regions matching the filter.
+func (m *RegionModel) GetAll(ctx context.Context, f *locations.RegionFilter) (locations.Regions, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := locations.Regions{}
+	for _, r := range m.Store.t.regions {
+		if !containsFold(r.Name, f.Name) || !containsFold(r.Code, f.Code) || !visible(r.Deletion, f.WithDeleted) {
+			continue
+		}
+		r = cloneRegion(r)
+		if !f.WithBoundary {
+			r.Boundary = nil
+		}
+		rows = append(rows, r)
+	}
+	m.Store.mu.RUnlock()
+
+	return paginate(rows, f.Default, regionColumns, func(r locations.Region) int64 { return int64(r.ID) })
+}
+
+// Locate returns the live region whose boundary contains c, or ErrRecordNotFound if no stored boundary does.
+func (m *RegionModel) Locate(ctx context.Context, c locations.Coordinates) (*locations.Region, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	ids := make([]int, 0, len(m.Store.t.regions))
+	for id := range m.Store.t.regions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		r := m.Store.t.regions[id]
+		if r.Boundary != nil && !r.Deletion.IsDeleted() && r.Boundary.Contains(c) {
+			r = cloneRegion(r)
+			return &r, nil
+		}
+	}
+	return nil, errors.ErrRecordNotFound
+}
+
+// ResolveRegion works out the region of something placed at c, as RegionModel.ResolveRegion does.
+func (m *RegionModel) ResolveRegion(ctx context.Context, regionID int, c locations.Coordinates) (int, error) {
+	return locations.ResolveRegion(ctx, m, regionID, c)
+}
+
+/****************************************************************************************
+ *										Areas											*
+ ***************************************************************************************/
+
+// Insert adds a new area, deriving its region from its coordinates where a boundary covers them.
+func (m *AreaModel) Insert(ctx context.Context, a *locations.Area) error {
+	if err := m.assignRegion(ctx, a); err != nil {
+		return err
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := m.Store.checkArea(a); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	a.ID = int(m.Store.nextID("areas"))
+	a.CreatedAt, a.UpdatedAt = now, now
+	a.IsActive = boolOr(a.IsActive, true)
+	a.Version = 1
+
+	m.Store.t.areas[a.ID] = cloneArea(*a)
+	return m.Store.record(ctx, "areas", int64(a.ID), audit.ActionInsert, nil, cloneArea(*a))
+}
+
+// Update replaces an area, deriving its region from its coordinates where a boundary covers them,
+// provided its version has not moved on since it was read.
+// Listings in the area follow it into its new region.
+func (m *AreaModel) Update(ctx context.Context, a *locations.Area) error {
+	if err := m.assignRegion(ctx, a); err != nil {
+		return err
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.areas[a.ID]
+	if !ok || existing.Version != a.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+	if err := m.Store.checkArea(a); err != nil {
+		return err
+	}
+
+	a.UpdatedAt = time.Now()
+	a.Version++
+
+	row := cloneArea(*a)
+	row.IsActive = boolOr(a.IsActive, *existing.IsActive)
+	row.Deletion = database.Deletion{}
+	row.CreatedAt = existing.CreatedAt
+	m.Store.t.areas[a.ID] = row
+
+	for id, l := range m.Store.t.listings {
+		if l.AreaID == int64(a.ID) {
+			l.RegionID = int64(a.RegionID)
+			m.Store.t.listings[id] = l
+		}
+	}
+	return m.Store.record(ctx, "areas", int64(a.ID), audit.ActionUpdate, cloneArea(existing), cloneArea(row))
+}
+
+// SoftDelete marks an area as deleted by deletedBy.
+func (m *AreaModel) SoftDelete(ctx context.Context, id int, deletedBy int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := softDelete(m.Store.t.areas, id, deletedBy, areaFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "areas", []int{id}, audit.ActionDelete)
+	return nil
+}
+
+// Restore brings a soft deleted area back.
+func (m *AreaModel) Restore(ctx context.Context, id int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := restoreRow(m.Store.t.areas, id, areaFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "areas", []int{id}, audit.ActionRestore)
+	return nil
+}
+
+// Purge permanently removes the areas soft deleted before the cutoff that have no listings.
+func (m *AreaModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var purged []int
+	for _, id := range purgeable(m.Store.t.areas, before, areaFields) {
+		if m.Store.areaInUse(id) {
+			continue
+		}
+		delete(m.Store.t.areas, id)
+		purged = append(purged, id)
+	}
+	recordAll(m.Store, ctx, "areas", purged, audit.ActionPurge)
+	return int64(len(purged)), nil
+}
+
+// GetByID retrieves an area by id.
+func (m *AreaModel) GetByID(ctx context.Context, id int) (*locations.Area, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	a, ok := m.Store.t.areas[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	a = cloneArea(a)
+	return &a, nil
+}
+
+// GetAll retrieves a page of the areas matching the filter.
+// Proximity searches only match areas with coordinates and report each area's distance.
+func (m *AreaModel) GetAll(ctx context.Context, f *locations.AreaFilter) (locations.Areas, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := locations.Areas{}
+	for _, a := range m.Store.t.areas {
+		distance, ok := matchesGeo(a.Coordinates, f.Near, f.Box, f.HasCoordinates)
+		switch {
+		case !ok,
+			!containsFold(a.Name, f.Name),
+			f.RegionID != nil && a.RegionID != *f.RegionID,
+			f.AreaType != nil && a.AreaType != *f.AreaType,
+			!matchesBool(a.IsActive, f.IsActive),
+			!visible(a.Deletion, f.WithDeleted):
+			continue
+		}
+		a = cloneArea(a)
+		a.DistanceKm = distance
+		rows = append(rows, a)
+	}
+	m.Store.mu.RUnlock()
+
+	return paginate(rows, f.Default, areaColumns, func(a locations.Area) int64 { return int64(a.ID) })
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// assignRegion sets the area's region from its coordinates, or checks the one it was given.
+func (m *AreaModel) assignRegion(ctx context.Context, a *locations.Area) error {
+	regions := RegionModel{Store: m.Store}
+	regionID, err := regions.ResolveRegion(ctx, a.RegionID, a.Coordinates)
+	if err != nil {
+		return err
+	}
+	a.RegionID = regionID
+	return nil
+}
+
+// checkArea applies the constraints of the areas table to a. The caller must hold the lock.
+func (s *Store) checkArea(a *locations.Area) error {
+	for _, other := range s.t.areas {
+		if other.ID != a.ID && other.RegionID == a.RegionID && other.Name == a.Name {
+			return errors.ErrDuplicateValue("name")
+		}
+	}
+	if _, ok := s.t.regions[a.RegionID]; !ok {
+		return errors.ErrInvalidRegionID
+	}
+	return nil
+}
+
+// regionInUse reports whether any area or listing is in the region, which keeps it from being
+// purged. The caller must hold the lock.
+func (s *Store) regionInUse(id int) bool {
+	for _, a := range s.t.areas {
+		if a.RegionID == id {
+			return true
+		}
+	}
+	for _, l := range s.t.listings {
+		if l.RegionID == int64(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// areaInUse reports whether any listing is in the area, which keeps it from being purged.
+// The caller must hold the lock.
+func (s *Store) areaInUse(id int) bool {
+	for _, l := range s.t.listings {
+		if l.AreaID == int64(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesGeo applies the bounding box, proximity and has-coordinates filters shared by areas and
+// listings to a row at c, and returns the distance to report for it. Like the NULL columns they
+// are stored in, zero coordinates never match a box or a radius.
+func matchesGeo(c locations.Coordinates, near *locations.Proximity, box *locations.BoundingBox, hasCoordinates bool) (*float64, bool) {
+	located := c.Latitude != 0 && c.Longitude != 0
+	if hasCoordinates && !located {
+		return nil, false
+	}
+
+	if b := locations.SearchBox(near, box); b != nil {
+		if !located ||
+			c.Latitude < b.MinLatitude || c.Latitude > b.MaxLatitude ||
+			c.Longitude < b.MinLongitude || c.Longitude > b.MaxLongitude {
+			return nil, false
+		}
+	}
+	if near == nil {
+		return nil, true
+	}
+
+	distance := locations.HaversineKm(near.Center, c)
+	if distance > near.RadiusKm {
+		return nil, false
+	}
+	return &distance, true
+}
+
+// cloneRegion copies a region so that it shares no boundary or deletion with the stored row.
+func cloneRegion(r locations.Region) locations.Region {
+	r.Deletion = cloneDeletion(r.Deletion)
+	if r.Boundary == nil {
+		return r
+	}
+	boundary := make(locations.MultiPolygon, len(r.Boundary))
+	for i, polygon := range r.Boundary {
+		boundary[i] = make([][][]float64, len(polygon))
+		for j, ring := range polygon {
+			boundary[i][j] = make([][]float64, len(ring))
+			for k, position := range ring {
+				boundary[i][j][k] = slices.Clone(position)
+			}
+		}
+	}
+	r.Boundary = boundary
+	return r
+}
+
+// cloneArea copies an area so that it shares no flags with the stored row.
+func cloneArea(a locations.Area) locations.Area {
+	a.IsActive = cloneBool(a.IsActive)
+	a.Deletion = cloneDeletion(a.Deletion)
+	a.DistanceKm = nil
+	return a
+}
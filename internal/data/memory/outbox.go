@@ -0,0 +1,197 @@
+// File: internal/data/memory/outbox.go
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// EmailModel is the in-memory outbox.EmailRepository.
+type EmailModel struct {
+	Store *Store
+}
+
+var _ outbox.EmailRepository = (*EmailModel)(nil)
+
+// emailColumns are the columns emails can be sorted by.
+var emailColumns = columns[outbox.Email]{
+	"id":         func(e outbox.Email) any { return e.ID },
+	"created_at": func(e outbox.Email) any { return e.CreatedAt },
+	"updated_at": func(e outbox.Email) any { return e.UpdatedAt },
+}
+
+/****************************************************************************************
+ *										Outbox											*
+ ***************************************************************************************/
+
+// Insert queues an email and the job that delivers it.
+func (m *EmailModel) Insert(ctx context.Context, e *outbox.Email) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	id := m.Store.nextID("email_outbox")
+	job, err := outbox.NewDeliveryJob(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if len(e.Data) == 0 {
+		e.Data = json.RawMessage("{}")
+	}
+	e.ID = id
+	e.Status, e.Attempts, e.LastError, e.SentAt = outbox.StatusQueued, 0, "", nil
+	e.CreatedAt, e.UpdatedAt = now, now
+	m.Store.t.emails[e.ID] = cloneEmail(*e)
+	m.Store.enqueue(job)
+	return nil
+}
+
+// MarkSent records a successful delivery attempt.
+func (m *EmailModel) MarkSent(ctx context.Context, id int64, sentAt time.Time) error {
+	return m.update(id, func(e *outbox.Email) {
+		e.Status, e.LastError, e.SentAt = outbox.StatusSent, "", &sentAt
+	})
+}
+
+// MarkFailed records a failed delivery attempt and why it failed.
+func (m *EmailModel) MarkFailed(ctx context.Context, id int64, reason string) error {
+	return m.update(id, func(e *outbox.Email) {
+		e.Status, e.LastError = outbox.StatusFailed, reason
+	})
+}
+
+// Resend queues an email that was sent, or whose delivery failed, to be delivered again. It returns
+// ErrEmailQueued while a job delivering the email is still pending or running.
+func (m *EmailModel) Resend(ctx context.Context, id int64) error {
+	job, err := outbox.NewDeliveryJob(id)
+	if err != nil {
+		return err
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.emails[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	if m.Store.delivering(id) {
+		return errors.ErrEmailQueued
+	}
+
+	row := cloneEmail(existing)
+	row.Status, row.UpdatedAt = outbox.StatusQueued, time.Now()
+	m.Store.t.emails[id] = row
+	m.Store.enqueue(job)
+	return m.Store.record(ctx, "email_outbox", id, audit.ActionUpdate, cloneEmail(existing), cloneEmail(row))
+}
+
+// GetByID retrieves an email by id.
+func (m *EmailModel) GetByID(ctx context.Context, id int64) (*outbox.Email, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	e, ok := m.Store.t.emails[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	e = cloneEmail(e)
+	return &e, nil
+}
+
+// GetAll returns a page of the emails matching filter.
+func (m *EmailModel) GetAll(ctx context.Context, f *outbox.EmailFilter) ([]*outbox.Email, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := []outbox.Email{}
+	for _, e := range m.Store.t.emails {
+		if (f.Status != "" && string(e.Status) != f.Status) || (f.Recipient != "" && !strings.EqualFold(e.Recipient, f.Recipient)) {
+			continue
+		}
+		rows = append(rows, cloneEmail(e))
+	}
+	m.Store.mu.RUnlock()
+
+	page, metadata, err := paginate(rows, f.Default, emailColumns, func(e outbox.Email) int64 { return e.ID })
+	if err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	result := make([]*outbox.Email, len(page))
+	for i := range page {
+		result[i] = &page[i]
+	}
+	return result, metadata, nil
+}
+
+// Purge permanently removes the emails sent before the cutoff.
+func (m *EmailModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var n int64
+	for id, e := range m.Store.t.emails {
+		if e.Status == outbox.StatusSent && e.SentAt.Before(before) {
+			delete(m.Store.t.emails, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// update records a delivery attempt on an email, changed by fn.
+func (m *EmailModel) update(id int64, fn func(e *outbox.Email)) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.emails[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+
+	row := cloneEmail(existing)
+	fn(&row)
+	row.Attempts++
+	row.UpdatedAt = time.Now()
+	m.Store.t.emails[id] = cloneEmail(row)
+	return nil
+}
+
+// delivering reports whether a job delivering the email is pending or running. The caller must
+// hold the lock.
+func (s *Store) delivering(id int64) bool {
+	for _, j := range s.t.jobs {
+		if j.Kind != outbox.JobKind || (j.Status != jobs.StatusPending && j.Status != jobs.StatusRunning) {
+			continue
+		}
+		var d outbox.Delivery
+		if json.Unmarshal(j.Payload, &d) == nil && d.EmailID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneEmail copies an email so that it shares no data or times with the stored row.
+func cloneEmail(e outbox.Email) outbox.Email {
+	e.Data = slices.Clone(e.Data)
+	e.SentAt = cloneTime(e.SentAt)
+	return e
+}
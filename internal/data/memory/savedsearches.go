@@ -0,0 +1,307 @@
+// File: internal/data/memory/savedsearches.go
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// SavedSearchModel is the in-memory savedsearches.SavedSearchRepository.
+type SavedSearchModel struct {
+	Store *Store
+}
+
+var _ savedsearches.SavedSearchRepository = (*SavedSearchModel)(nil)
+
+// searchMatchKey is the primary key of a saved search match.
+type searchMatchKey struct {
+	savedSearchID int64
+	listingID     int64
+}
+
+// searchMatch is a listing matched by a saved search.
+type searchMatch struct {
+	matchedAt time.Time
+	sentAt    *time.Time
+}
+
+// savedSearchColumns are the columns saved searches can be sorted by.
+var savedSearchColumns = columns[savedsearches.SavedSearch]{
+	"id":         func(s savedsearches.SavedSearch) any { return s.ID },
+	"name":       func(s savedsearches.SavedSearch) any { return s.Name },
+	"created_at": func(s savedsearches.SavedSearch) any { return s.CreatedAt },
+	"updated_at": func(s savedsearches.SavedSearch) any { return s.UpdatedAt },
+}
+
+/****************************************************************************************
+ *										Saved Searches									*
+ ***************************************************************************************/
+
+// Insert adds a new saved search with a fresh unsubscribe token.
+func (m *SavedSearchModel) Insert(ctx context.Context, s *savedsearches.SavedSearch) error {
+	token, err := savedsearches.GenerateUnsubscribeToken()
+	if err != nil {
+		return err
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.t.users[s.UserID]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+
+	now := time.Now()
+	s.ID = m.Store.nextID("saved_searches")
+	s.UnsubscribeToken = token
+	s.CheckedAt = now
+	s.SentAt = nil
+	s.Version = 1
+	s.CreatedAt, s.UpdatedAt = now, now
+
+	m.Store.t.savedSearches[s.ID] = *s
+	return m.Store.record(ctx, "saved_searches", s.ID, audit.ActionInsert, nil, *s)
+}
+
+// Update replaces the user's fields of a saved search, provided its version has not moved on since
+// it was read. Changing the query drops the matches of the old one that have not been sent yet.
+func (m *SavedSearchModel) Update(ctx context.Context, s *savedsearches.SavedSearch) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.savedSearches[s.ID]
+	if !ok || existing.Version != s.Version {
+		return errors.ErrEditConflict
+	}
+
+	s.UpdatedAt = time.Now()
+	s.Version++
+
+	row := existing
+	row.Name, row.Query, row.Frequency, row.AlertsEnabled = s.Name, s.Query, s.Frequency, s.AlertsEnabled
+	row.UpdatedAt, row.Version = s.UpdatedAt, s.Version
+	m.Store.t.savedSearches[s.ID] = row
+
+	if existing.Query != row.Query {
+		for key, match := range m.Store.t.searchMatches {
+			if key.savedSearchID == s.ID && match.sentAt == nil {
+				delete(m.Store.t.searchMatches, key)
+			}
+		}
+	}
+	return m.Store.record(ctx, "saved_searches", s.ID, audit.ActionUpdate, existing, row)
+}
+
+// Delete permanently removes a saved search and its matches.
+func (m *SavedSearchModel) Delete(ctx context.Context, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.savedSearches[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	m.Store.deleteSavedSearch(id)
+	return m.Store.record(ctx, "saved_searches", id, audit.ActionDelete, existing, nil)
+}
+
+// GetByID retrieves a saved search by id.
+func (m *SavedSearchModel) GetByID(ctx context.Context, id int64) (*savedsearches.SavedSearch, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	s, ok := m.Store.t.savedSearches[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+// GetAll returns a page of the saved searches matching filter.
+func (m *SavedSearchModel) GetAll(ctx context.Context, f *savedsearches.SavedSearchFilter) ([]*savedsearches.SavedSearch, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := []savedsearches.SavedSearch{}
+	for _, s := range m.Store.t.savedSearches {
+		if f.UserID != nil && s.UserID != *f.UserID {
+			continue
+		}
+		rows = append(rows, s)
+	}
+	m.Store.mu.RUnlock()
+
+	page, metadata, err := paginate(rows, f.Default, savedSearchColumns, func(s savedsearches.SavedSearch) int64 { return s.ID })
+	if err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	result := make([]*savedsearches.SavedSearch, len(page))
+	for i := range page {
+		result[i] = &page[i]
+	}
+	return result, metadata, nil
+}
+
+// Unsubscribe turns off the alerts of the saved search with the given unsubscribe token.
+func (m *SavedSearchModel) Unsubscribe(ctx context.Context, token string) (*savedsearches.SavedSearch, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for id, s := range m.Store.t.savedSearches {
+		if s.UnsubscribeToken != token {
+			continue
+		}
+		if s.AlertsEnabled {
+			s.AlertsEnabled = false
+			s.UpdatedAt = time.Now()
+			s.Version++
+			m.Store.t.savedSearches[id] = s
+			changes := audit.Changes{"alerts_enabled": audit.Field(true, false)}
+			m.Store.recordChanges(ctx, "saved_searches", id, audit.ActionUpdate, changes)
+		}
+		return &s, nil
+	}
+	return nil, errors.ErrRecordNotFound
+}
+
+// GetAlerting returns every saved search with alerts turned on whose owner has not been deleted.
+func (m *SavedSearchModel) GetAlerting(ctx context.Context) ([]*savedsearches.SavedSearch, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return m.Store.listSavedSearches(func(s savedsearches.SavedSearch) bool {
+		owner, ok := m.Store.t.users[s.UserID]
+		return s.AlertsEnabled && ok && !owner.Deletion.IsDeleted()
+	}), nil
+}
+
+// RecordMatches records the listings a saved search matched and moves its checked time on.
+// A listing is only ever matched once per search.
+func (m *SavedSearchModel) RecordMatches(ctx context.Context, id int64, listingIDs []int64, checkedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	s, ok := m.Store.t.savedSearches[id]
+	if !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	for _, listingID := range listingIDs {
+		if _, ok := m.Store.t.listings[listingID]; !ok {
+			return errors.ErrForeignKeyViolation
+		}
+	}
+	for _, listingID := range listingIDs {
+		key := searchMatchKey{savedSearchID: id, listingID: listingID}
+		if _, ok := m.Store.t.searchMatches[key]; !ok {
+			m.Store.t.searchMatches[key] = searchMatch{matchedAt: checkedAt}
+		}
+	}
+	s.CheckedAt = checkedAt
+	m.Store.t.savedSearches[id] = s
+	return nil
+}
+
+// GetDue returns the saved searches with alerts turned on that have unsent matches and whose
+// frequency allows a digest at now.
+func (m *SavedSearchModel) GetDue(ctx context.Context, now time.Time) ([]*savedsearches.SavedSearch, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	pending := make(map[int64]bool)
+	for key, match := range m.Store.t.searchMatches {
+		if match.sentAt == nil {
+			pending[key.savedSearchID] = true
+		}
+	}
+	return m.Store.listSavedSearches(func(s savedsearches.SavedSearch) bool {
+		last := s.CreatedAt
+		if s.SentAt != nil {
+			last = *s.SentAt
+		}
+		return s.AlertsEnabled && pending[s.ID] && !last.Add(s.Frequency.Interval()).After(now)
+	}), nil
+}
+
+// GetPending returns the listings a saved search has matched that have not been sent yet, oldest
+// match first.
+func (m *SavedSearchModel) GetPending(ctx context.Context, id int64) ([]int64, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	keys := []searchMatchKey{}
+	for key, match := range m.Store.t.searchMatches {
+		if key.savedSearchID == id && match.sentAt == nil {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b searchMatchKey) int {
+		at, bt := m.Store.t.searchMatches[a].matchedAt, m.Store.t.searchMatches[b].matchedAt
+		return cmp.Or(at.Compare(bt), cmp.Compare(a.listingID, b.listingID))
+	})
+
+	ids := []int64{}
+	for _, key := range keys {
+		ids = append(ids, key.listingID)
+	}
+	return ids, nil
+}
+
+// MarkSent claims the given matches of a saved search that are still unsent for a digest going out
+// at sentAt, and returns the listings it claimed.
+func (m *SavedSearchModel) MarkSent(ctx context.Context, id int64, listingIDs []int64, sentAt time.Time) ([]int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	claimed := []int64{}
+	for _, listingID := range listingIDs {
+		key := searchMatchKey{savedSearchID: id, listingID: listingID}
+		if match, ok := m.Store.t.searchMatches[key]; ok && match.sentAt == nil {
+			match.sentAt = &sentAt
+			m.Store.t.searchMatches[key] = match
+			claimed = append(claimed, listingID)
+		}
+	}
+	if s, ok := m.Store.t.savedSearches[id]; ok && len(claimed) > 0 {
+		s.SentAt = &sentAt
+		m.Store.t.savedSearches[id] = s
+	}
+	return claimed, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// listSavedSearches returns the saved searches that pass keep, ordered by id. The caller must hold
+// the lock.
+func (s *Store) listSavedSearches(keep func(savedsearches.SavedSearch) bool) []*savedsearches.SavedSearch {
+	result := []*savedsearches.SavedSearch{}
+	for _, ss := range s.t.savedSearches {
+		if keep(ss) {
+			result = append(result, &ss)
+		}
+	}
+	slices.SortFunc(result, func(a, b *savedsearches.SavedSearch) int { return cmp.Compare(a.ID, b.ID) })
+	return result
+}
+
+// deleteSavedSearch removes a saved search and, like ON DELETE CASCADE, its matches.
+// The caller must hold the write lock.
+func (s *Store) deleteSavedSearch(id int64) {
+	delete(s.t.savedSearches, id)
+	for key := range s.t.searchMatches {
+		if key.savedSearchID == id {
+			delete(s.t.searchMatches, key)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// File: internal/data/memory/search.go
+package memory
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// document is the text a full-text search looks through, in the order of its weights in the
+// search_document column: the title, the breeds, the places and the description.
+type document [4]string
+
+// weights are the ts_rank_cd default weights of the document's parts.
+var weights = [4]float64{1.0, 0.4, 0.2, 0.1}
+
+// suffixes are the plural endings a crude stemmer drops from English and Spanish words.
+var suffixes = []string{"es", "s"}
+
+/****************************************************************************************
+ *										Search											*
+ ***************************************************************************************/
+
+// searchDocument approximates a full-text search of the search package: every word of q must be
+// in the document, give or take a plural ending or, for longer words, a typo. It returns the rank
+// of the document, with a weight for each part a word was found in, or false if it does not match.
+func searchDocument(doc document, q string) (float64, bool) {
+	terms := strings.FieldsFunc(strings.ToLower(q), isSeparator)
+	if len(terms) == 0 {
+		return 0, false
+	}
+
+	rank := 0.0
+	for _, term := range terms {
+		found := false
+		for i, part := range doc {
+			if containsWord(part, term) {
+				rank += weights[i]
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return rank, true
+}
+
+// snippet escapes text as HTML and marks the words of it that match a word of q, as search.Snippet
+// does.
+func snippet(text, q string) string {
+	terms := strings.FieldsFunc(strings.ToLower(q), isSeparator)
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = html.EscapeString(word)
+		for _, term := range terms {
+			if similarWord(strings.ToLower(strings.TrimFunc(word, isSeparator)), term) {
+				words[i] = "<mark>" + words[i] + "</mark>"
+				break
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// containsWord reports whether any word of text is similar to term.
+func containsWord(text, term string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		if similarWord(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// similarWord reports whether two lower case words have the same stem, or are within one edit of
+// each other when both are at least five letters long.
+func similarWord(a, b string) bool {
+	if stem(a) == stem(b) {
+		return true
+	}
+	return utf8.RuneCountInString(a) >= 5 && utf8.RuneCountInString(b) >= 5 && editDistance(a, b) <= 1
+}
+
+// stem drops a plural ending from a word.
+func stem(word string) string {
+	for _, suffix := range suffixes {
+		if trimmed, ok := strings.CutSuffix(word, suffix); ok && utf8.RuneCountInString(trimmed) >= 3 {
+			return trimmed
+		}
+	}
+	return word
+}
+
+// editDistance returns the Levenshtein distance between two words.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,570 @@
+// File: internal/data/memory/store.go
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/seed"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Store holds every table of the in-memory repositories. The repositories share one store so
+// that relationships behave as they do in Postgres: foreign keys are checked, deletes cascade
+// and listings follow the region of their area.
+//
+// Rows are stored by value and copied on the way in and out, so callers can never change a
+// stored row except through a repository.
+type Store struct {
+	mu    sync.RWMutex
+	txMu  sync.Mutex
+	locks sync.Map // advisory locks, a *sync.Mutex by key
+	t     tables
+}
+
+// tables is the data held by a Store.
+type tables struct {
+	users              map[int64]users.User
+	tokens             []users.Token
+	permissions        map[int64]string
+	userPermissions    map[int64]map[int64]bool
+	breeds             map[int]cattle.Breed
+	cattle             map[int]cattle.Cattle
+	regions            map[int]locations.Region
+	areas              map[int]locations.Area
+	listings           map[int64]listings.Listing
+	listingPrices      map[listingPriceKey]listings.ListingPrice
+	savedSearches      map[int64]savedsearches.SavedSearch
+	searchMatches      map[searchMatchKey]searchMatch
+	watches            map[int64]watchlists.Watch
+	watchNotifications map[int64]watchlists.Notification
+	jobs               map[int64]jobs.Job
+	emails             map[int64]outbox.Email
+	auditLog           []audit.Entry
+	sequences          map[string]int64
+}
+
+// listingPriceKey is the primary key of a listing price.
+type listingPriceKey struct {
+	listingID   int64
+	cattleClass listings.CattleClass
+}
+
+// NewStore returns an empty store holding only the permission codes the seed command loads.
+func NewStore() *Store {
+	s := &Store{t: tables{
+		users:              make(map[int64]users.User),
+		permissions:        make(map[int64]string),
+		userPermissions:    make(map[int64]map[int64]bool),
+		breeds:             make(map[int]cattle.Breed),
+		cattle:             make(map[int]cattle.Cattle),
+		regions:            make(map[int]locations.Region),
+		areas:              make(map[int]locations.Area),
+		listings:           make(map[int64]listings.Listing),
+		listingPrices:      make(map[listingPriceKey]listings.ListingPrice),
+		savedSearches:      make(map[int64]savedsearches.SavedSearch),
+		searchMatches:      make(map[searchMatchKey]searchMatch),
+		watches:            make(map[int64]watchlists.Watch),
+		watchNotifications: make(map[int64]watchlists.Notification),
+		jobs:               make(map[int64]jobs.Job),
+		emails:             make(map[int64]outbox.Email),
+		sequences:          make(map[string]int64),
+	}}
+	for _, code := range seed.Permissions {
+		s.t.permissions[s.nextID("permissions")] = code
+	}
+	return s
+}
+
+/****************************************************************************************
+ *										Transactions									*
+ ***************************************************************************************/
+
+// TryLock runs fn while holding the lock key, and reports whether it ran. When the lock is already
+// held fn is skipped, like database.TryLock.
+func (s *Store) TryLock(key int64, fn func() error) (bool, error) {
+	lock, _ := s.locks.LoadOrStore(key, new(sync.Mutex))
+	if !lock.(*sync.Mutex).TryLock() {
+		return false, nil
+	}
+	defer lock.(*sync.Mutex).Unlock()
+
+	return true, fn()
+}
+
+// RunInTx runs fn with transactions serialised one at a time. If fn returns an error or panics,
+// the store is put back exactly as it was before fn ran. Writes made outside a transaction while
+// one is running are lost if it rolls back, so callers should not mix the two concurrently.
+func (s *Store) RunInTx(fn func() error) (err error) {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.RLock()
+	snapshot := s.t.clone()
+	s.mu.RUnlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			s.restore(snapshot)
+			panic(p)
+		}
+		if err != nil {
+			s.restore(snapshot)
+		}
+	}()
+	return fn()
+}
+
+// restore replaces the store's tables with a snapshot taken by clone.
+func (s *Store) restore(snapshot tables) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.t = snapshot
+}
+
+// clone copies every table. Stored rows are never changed in place, so copying the maps is enough.
+func (t tables) clone() tables {
+	userPermissions := make(map[int64]map[int64]bool, len(t.userPermissions))
+	for userID, granted := range t.userPermissions {
+		userPermissions[userID] = cloneMap(granted)
+	}
+	return tables{
+		users:              cloneMap(t.users),
+		tokens:             slices.Clone(t.tokens),
+		permissions:        cloneMap(t.permissions),
+		userPermissions:    userPermissions,
+		breeds:             cloneMap(t.breeds),
+		cattle:             cloneMap(t.cattle),
+		regions:            cloneMap(t.regions),
+		areas:              cloneMap(t.areas),
+		listings:           cloneMap(t.listings),
+		listingPrices:      cloneMap(t.listingPrices),
+		savedSearches:      cloneMap(t.savedSearches),
+		searchMatches:      cloneMap(t.searchMatches),
+		watches:            cloneMap(t.watches),
+		watchNotifications: cloneMap(t.watchNotifications),
+		jobs:               cloneMap(t.jobs),
+		emails:             cloneMap(t.emails),
+		auditLog:           slices.Clone(t.auditLog),
+		sequences:          cloneMap(t.sequences),
+	}
+}
+
+/****************************************************************************************
+ *										Soft Delete										*
+ ***************************************************************************************/
+
+// rowFields returns the deletion and version of a stored row, so the soft delete helpers can
+// work on every table alike.
+type rowFields[T any] func(row *T) (*database.Deletion, *int)
+
+// softDelete marks a row as deleted by deletedBy, or by nobody when it is zero, and bumps its
+// version, as database.SoftDelete does. The caller must hold the write lock.
+func softDelete[K comparable, T any](rows map[K]T, id K, deletedBy int64, fields rowFields[T]) error {
+	row, ok := rows[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	deletion, version := fields(&row)
+	if deletion.IsDeleted() {
+		return errors.ErrAlreadyDeleted
+	}
+
+	now := time.Now()
+	*deletion = database.Deletion{DeletedAt: &now}
+	if deletedBy != 0 {
+		deletion.DeletedBy = &deletedBy
+	}
+	*version++
+	rows[id] = row
+	return nil
+}
+
+// restoreRow clears the deletion of a row and bumps its version, as database.Restore does.
+// The caller must hold the write lock.
+func restoreRow[K comparable, T any](rows map[K]T, id K, fields rowFields[T]) error {
+	row, ok := rows[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	deletion, version := fields(&row)
+	if !deletion.IsDeleted() {
+		return errors.ErrNotDeleted
+	}
+
+	*deletion = database.Deletion{}
+	*version++
+	rows[id] = row
+	return nil
+}
+
+// purgeable returns the ids of the rows soft deleted before the cutoff. The caller must hold the lock.
+func purgeable[K comparable, T any](rows map[K]T, before time.Time, fields rowFields[T]) []K {
+	var ids []K
+	for id, row := range rows {
+		if deletion, _ := fields(&row); deletion.IsDeleted() && deletion.DeletedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// clearDeletedBy forgets userID as the deleter of any row, like deleted_by's ON DELETE SET NULL.
+// The caller must hold the write lock.
+func clearDeletedBy[K comparable, T any](rows map[K]T, userID int64, fields rowFields[T]) {
+	for id, row := range rows {
+		if deletion, _ := fields(&row); deletion.DeletedBy != nil && *deletion.DeletedBy == userID {
+			deletion.DeletedBy = nil
+			rows[id] = row
+		}
+	}
+}
+
+// visible reports whether a row passes the default exclusion of soft deleted rows.
+func visible(d database.Deletion, withDeleted bool) bool {
+	return withDeleted || !d.IsDeleted()
+}
+
+/****************************************************************************************
+ *										Audit											*
+ ***************************************************************************************/
+
+// record appends the audit entry for a write, diffing before against after as audit.Record does.
+// An update that changed nothing worth recording leaves no entry. The caller must hold the write lock.
+func (s *Store) record(ctx context.Context, entity string, id int64, action audit.Action, before, after any) error {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	if action == audit.ActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	s.recordChanges(ctx, entity, id, action, changes)
+	return nil
+}
+
+// recordChanges appends an audit entry with changes given by the caller, as audit.RecordChanges
+// does. The caller must hold the write lock.
+func (s *Store) recordChanges(ctx context.Context, entity string, id int64, action audit.Action, changes audit.Changes) {
+	entry := audit.NewEntry(ctx, entity, id, action, changes)
+	entry.ID = s.nextID("audit_log")
+	entry.CreatedAt = time.Now()
+	s.t.auditLog = append(s.t.auditLog, *entry)
+}
+
+// recordAll appends an audit entry without changes for each of ids, as audit.RecordAll does.
+// The caller must hold the write lock.
+func recordAll[K int | int64](s *Store, ctx context.Context, entity string, ids []K, action audit.Action) {
+	for _, id := range ids {
+		s.recordChanges(ctx, entity, int64(id), action, nil)
+	}
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// nextID returns the next value of a table's id sequence. The caller must hold the write lock.
+func (s *Store) nextID(table string) int64 {
+	s.t.sequences[table]++
+	return s.t.sequences[table]
+}
+
+// cloneMap returns a shallow copy of m.
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// cloneBool copies a nullable boolean so stored rows never share it with callers.
+func cloneBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	v := *b
+	return &v
+}
+
+// cloneTime copies a nullable time so stored rows never share it with callers.
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
+
+// boolOr copies a nullable boolean, falling back to def like a column default.
+func boolOr(b *bool, def bool) *bool {
+	if b == nil {
+		return &def
+	}
+	return cloneBool(b)
+}
+
+// cloneDeletion copies a deletion so that stored rows never share its fields with callers.
+func cloneDeletion(d database.Deletion) database.Deletion {
+	if d.DeletedAt != nil {
+		at := *d.DeletedAt
+		d.DeletedAt = &at
+	}
+	if d.DeletedBy != nil {
+		by := *d.DeletedBy
+		d.DeletedBy = &by
+	}
+	return d
+}
+
+// timestampLayout has a fixed width so that timestamps sort correctly as strings.
+const timestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// timestamp formats t for the models that keep timestamptz columns as strings.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// containsFold reports whether substr is within s ignoring case, like ILIKE '%' || substr || '%'.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// inRange reports whether v lies within the optional inclusive bounds min and max.
+func inRange[T cmp.Ordered](v T, min, max *T) bool {
+	return (min == nil || v >= *min) && (max == nil || v <= *max)
+}
+
+// inList reports whether v is one of values, or values is empty, like an = ANY() list filter.
+func inList[T comparable](v T, values []T) bool {
+	return len(values) == 0 || slices.Contains(values, v)
+}
+
+// matchesWords reports whether every word of query is a word of text, approximating a
+// plainto_tsquery match against the 'simple' text search configuration. An empty query matches.
+func matchesWords(text, query string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), isSeparator)
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if !slices.Contains(words, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSeparator reports whether r separates words for matchesWords.
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '@' && r != '.' && r != '_' && r != '-'
+}
+
+/****************************************************************************************
+ *										Pagination										*
+ ***************************************************************************************/
+
+// columns maps the sortable column names of a table to the value of that column for a row.
+// Nil values sort like NULL: last when ascending and first when descending.
+type columns[T any] map[string]func(T) any
+
+// paginate sorts rows by the filter's sort columns, breaking ties on id as the SQL queries do,
+// and returns the requested page with its metadata. A page past the end is empty and has no
+// metadata, matching COUNT(*) OVER() returning no rows. With a cursor, the page is the rows
+// after it, or before it, in the sort, as the keyset condition of filters.Filters.SQL selects.
+func paginate[T any](rows []T, f filters.Filters, cols columns[T], id func(T) int64) ([]T, filters.MetaData, error) {
+	keys := f.SortKeys()
+	values := make([]func(T) any, len(keys))
+	for i, key := range keys {
+		value, ok := cols[key.Column]
+		if !ok {
+			return nil, filters.EmptyMetaData, fmt.Errorf("memory: cannot sort by %q", key.Column)
+		}
+		values[i] = value
+	}
+
+	slices.SortStableFunc(rows, func(a, b T) int {
+		for i, key := range keys {
+			c := compare(values[i](a), values[i](b))
+			if key.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return compare(id(a), id(b))
+	})
+
+	var page []T
+	totalRecords := len(rows)
+	if cursor, backward, ok := f.Position(); ok {
+		// position compares a row with the one the cursor marks, in the order of the sort
+		position := func(row T) int {
+			for i, key := range keys {
+				v, c := values[i](row), 0
+				if v == nil || cursor.Values[i] == nil {
+					c = compare(v, nullable(cursor.Values[i]))
+				} else {
+					c = compare(v, parseKey(v, *cursor.Values[i]))
+				}
+				if key.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return compare(id(row), cursor.ID)
+		}
+
+		if backward {
+			// Like the query, a backward page is read nearest the cursor first
+			end := slices.IndexFunc(rows, func(row T) bool { return position(row) >= 0 })
+			if end < 0 {
+				end = len(rows)
+			}
+			page = slices.Clone(rows[max(0, end-f.Limit()):end])
+			slices.Reverse(page)
+		} else {
+			start := slices.IndexFunc(rows, func(row T) bool { return position(row) > 0 })
+			if start < 0 {
+				start = len(rows)
+			}
+			page = rows[start:min(start+f.Limit(), len(rows))]
+		}
+	} else {
+		start := min(f.Offset(), len(rows))
+		page = rows[start:min(start+f.Limit(), len(rows))]
+	}
+	if len(page) == 0 {
+		totalRecords = 0
+	}
+
+	positions := make([]filters.Key, len(page))
+	for i, row := range page {
+		positions[i].ID = id(row)
+		for _, value := range values {
+			positions[i].Values = append(positions[i].Values, sortText(value(row)))
+		}
+	}
+	page, metadata := filters.Paginate(f, page, positions, totalRecords)
+	return page, metadata, nil
+}
+
+// sortText formats a column value as the text of a sort key, with nil for NULL.
+func sortText(v any) *string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		s = fmt.Sprint(v)
+	}
+	return &s
+}
+
+// parseKey parses the text of a sort key back into a value of the same type as sample, a value
+// of the column the key was made from.
+func parseKey(sample any, text string) any {
+	switch sample.(type) {
+	case int:
+		n, _ := strconv.Atoi(text)
+		return n
+	case int64:
+		n, _ := strconv.ParseInt(text, 10, 64)
+		return n
+	case float64:
+		f, _ := strconv.ParseFloat(text, 64)
+		return f
+	case bool:
+		b, _ := strconv.ParseBool(text)
+		return b
+	case time.Time:
+		t, _ := time.Parse(time.RFC3339Nano, text)
+		return t
+	default:
+		return text
+	}
+}
+
+// compare orders two column values of the same type, with nil after everything else.
+func compare(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	switch a := a.(type) {
+	case int:
+		return cmpOrdered(a, b.(int))
+	case int64:
+		return cmpOrdered(a, b.(int64))
+	case float64:
+		return cmpOrdered(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case bool:
+		return cmpOrdered(boolRank(a), boolRank(b.(bool)))
+	default:
+		panic(fmt.Sprintf("memory: cannot compare %T", a))
+	}
+}
+
+// cmpOrdered compares two ordered values.
+func cmpOrdered[T int | int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// boolRank orders false before true, as Postgres does.
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// nullable turns a pointer column into a sortable value, with nil for NULL.
+func nullable[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
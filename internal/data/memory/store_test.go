@@ -0,0 +1,141 @@
+// File: internal/data/memory/store_test.go
+package memory_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/memory"
+)
+
+// The repositories are tested through the model suites of each data package, which run against
+// the store when DB_DSN_TEST is not set. These tests cover what only the store itself does.
+
+func TestRunInTxRestoresSnapshot(t *testing.T) {
+	store := memory.NewStore()
+	breeds := &memory.BreedModel{Store: store}
+	ctx := t.Context()
+
+	kept := &cattle.Breed{Name: "Brahman"}
+	if err := breeds.Insert(ctx, kept); err != nil {
+		t.Fatal(err)
+	}
+
+	rollback := errors.New("rollback")
+	var dropped cattle.Breed
+	err := store.RunInTx(func() error {
+		dropped = cattle.Breed{Name: "Nelore"}
+		if err := breeds.Insert(ctx, &dropped); err != nil {
+			return err
+		}
+		kept.Name = "Renamed"
+		if err := breeds.Update(ctx, kept); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("RunInTx error = %v, want the rollback", err)
+	}
+
+	if _, err := breeds.GetByID(ctx, dropped.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of a rolled back insert error = %v, want ErrRecordNotFound", err)
+	}
+	got, err := breeds.GetByID(ctx, kept.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Brahman" || got.Version != 1 {
+		t.Errorf("breed after the rollback = %+v, want it as it was", got)
+	}
+}
+
+func TestRunInTxSerializes(t *testing.T) {
+	store := memory.NewStore()
+	breeds := &memory.BreedModel{Store: store}
+	ctx := t.Context()
+
+	b := &cattle.Breed{Name: "Brahman"}
+	if err := breeds.Insert(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each transaction reads the version and writes it back; run one at a time, none conflicts.
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for range n {
+		wg.Go(func() {
+			errs <- store.RunInTx(func() error {
+				got, err := breeds.GetByID(ctx, b.ID)
+				if err != nil {
+					return err
+				}
+				return breeds.Update(ctx, got)
+			})
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("RunInTx error = %v, want transactions run one at a time", err)
+		}
+	}
+
+	got, err := breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != n+1 {
+		t.Errorf("version = %d, want %d", got.Version, n+1)
+	}
+}
+
+func TestStoreCopiesRows(t *testing.T) {
+	store := memory.NewStore()
+	breeds := &memory.BreedModel{Store: store}
+	ctx := t.Context()
+
+	active := true
+	b := &cattle.Breed{Name: "Brahman", IsActive: &active}
+	if err := breeds.Insert(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	active = false
+
+	got, err := breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*got.IsActive = false
+	got.Name = "Changed"
+
+	again, err := breeds.GetByID(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*again.IsActive || again.Name != "Brahman" {
+		t.Errorf("stored breed = %+v, want it untouched by callers", again)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	store := memory.NewStore()
+
+	ran, err := store.TryLock(1, func() error {
+		if inner, _ := store.TryLock(1, func() error { return nil }); inner {
+			t.Error("TryLock ran fn while the lock was held")
+		}
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("TryLock = %v, %v, want it run", ran, err)
+	}
+	if ran, _ := store.TryLock(1, func() error { return nil }); !ran {
+		t.Error("TryLock after the lock was released skipped fn")
+	}
+}
@@ -0,0 +1,428 @@
+// File: internal/data/memory/users.go
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// UserModel is the in-memory users.UserRepository.
+type UserModel struct {
+	Store *Store
+}
+
+// TokenModel is the in-memory users.TokenRepository.
+type TokenModel struct {
+	Store *Store
+}
+
+// PermissionModel is the in-memory users.PermissionRepository.
+type PermissionModel struct {
+	Store *Store
+}
+
+var (
+	_ users.UserRepository       = (*UserModel)(nil)
+	_ users.TokenRepository      = (*TokenModel)(nil)
+	_ users.PermissionRepository = (*PermissionModel)(nil)
+)
+
+// userColumns are the columns users can be sorted by.
+var userColumns = columns[users.User]{
+	"id":           func(u users.User) any { return u.ID },
+	"farmer_id":    func(u users.User) any { return u.FarmerID },
+	"email":        func(u users.User) any { return u.Email },
+	"phone_number": func(u users.User) any { return u.PhoneNumber },
+	"first_name":   func(u users.User) any { return u.FirstName },
+	"last_name":    func(u users.User) any { return u.LastName },
+	"version":      func(u users.User) any { return u.Version },
+	"created_at":   func(u users.User) any { return u.CreatedAt },
+	"updated_at":   func(u users.User) any { return u.UpdatedAt },
+}
+
+// userFields gives the soft delete helpers access to a user's deletion and version.
+func userFields(u *users.User) (*database.Deletion, *int) { return &u.Deletion, &u.Version }
+
+/****************************************************************************************
+ *										Users											*
+ ***************************************************************************************/
+
+// Insert adds a new user.
+func (m *UserModel) Insert(ctx context.Context, user *users.User) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := m.Store.checkUserUnique(user); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.ID = m.Store.nextID("users")
+	user.CreatedAt, user.UpdatedAt = now, now
+	user.Version = 1
+	user.Deletion = database.Deletion{}
+	user.IsDeleted = boolOr(nil, false)
+
+	row := userRow(user)
+	m.Store.t.users[user.ID] = row
+	return m.Store.record(ctx, "users", user.ID, audit.ActionInsert, nil, cloneUser(row))
+}
+
+// Update replaces a user, provided its version has not moved on since it was read.
+func (m *UserModel) Update(ctx context.Context, user *users.User) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.users[user.ID]
+	if !ok || existing.Version != user.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+	if err := m.Store.checkUserUnique(user); err != nil {
+		return err
+	}
+
+	user.UpdatedAt = time.Now()
+	user.Version++
+
+	row := userRow(user)
+	row.Deletion = existing.Deletion
+	row.CreatedAt = existing.CreatedAt
+	m.Store.t.users[user.ID] = row
+	return m.Store.record(ctx, "users", user.ID, audit.ActionUpdate, cloneUser(existing), cloneUser(row))
+}
+
+// UpdatePassword replaces a user's password hash, provided its version has not moved on since it was read.
+func (m *UserModel) UpdatePassword(ctx context.Context, user *users.User) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.users[user.ID]
+	if !ok || existing.Version != user.Version || existing.Deletion.IsDeleted() {
+		return errors.ErrEditConflict
+	}
+
+	user.UpdatedAt = time.Now()
+	user.Version++
+
+	existing.Password = user.Password.Stored()
+	existing.UpdatedAt = user.UpdatedAt
+	existing.Version = user.Version
+	m.Store.t.users[user.ID] = existing
+
+	// Record that the password changed, but never the hash
+	m.Store.recordChanges(ctx, "users", user.ID, audit.ActionUpdate, audit.Changes{"password": audit.Change{}})
+	return nil
+}
+
+// SoftDelete marks a user as deleted by deletedBy.
+func (m *UserModel) SoftDelete(ctx context.Context, userID int64, deletedBy int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := softDelete(m.Store.t.users, userID, deletedBy, userFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "users", []int64{userID}, audit.ActionDelete)
+	return nil
+}
+
+// Restore brings a soft deleted user back.
+func (m *UserModel) Restore(ctx context.Context, userID int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := restoreRow(m.Store.t.users, userID, userFields); err != nil {
+		return err
+	}
+	recordAll(m.Store, ctx, "users", []int64{userID}, audit.ActionRestore)
+	return nil
+}
+
+// Purge permanently removes the users soft deleted before the cutoff, together with everything
+// that cascades from them.
+func (m *UserModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := purgeable(m.Store.t.users, before, userFields)
+	for _, id := range ids {
+		m.Store.deleteUser(id)
+	}
+	recordAll(m.Store, ctx, "users", ids, audit.ActionPurge)
+	return int64(len(ids)), nil
+}
+
+// DeleteHard permanently removes a user together with everything that cascades from it.
+func (m *UserModel) DeleteHard(ctx context.Context, userID int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.users[userID]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	m.Store.deleteUser(userID)
+	return m.Store.record(ctx, "users", userID, audit.ActionDelete, cloneUser(existing), nil)
+}
+
+// GetByID retrieves a user by id.
+func (m *UserModel) GetByID(ctx context.Context, userID int64) (*users.User, error) {
+	return m.find(func(u users.User) bool { return u.ID == userID })
+}
+
+// GetByEmail retrieves a user by email address.
+func (m *UserModel) GetByEmail(ctx context.Context, email string) (*users.User, error) {
+	return m.find(func(u users.User) bool { return u.Email == email })
+}
+
+// GetByFarmerID retrieves a user by farmer id.
+func (m *UserModel) GetByFarmerID(ctx context.Context, farmerID string) (*users.User, error) {
+	return m.find(func(u users.User) bool { return u.FarmerID == farmerID })
+}
+
+// GetAll retrieves a page of the users matching the filter.
+func (m *UserModel) GetAll(ctx context.Context, f *users.UserFilters) ([]*users.User, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := []users.User{}
+	for _, u := range m.Store.t.users {
+		switch {
+		case !matchesWords(u.FarmerID, f.FarmerID),
+			!matchesWords(u.FirstName+" "+u.LastName, f.Name),
+			!matchesWords(u.Email, f.Email),
+			!matchesWords(u.PhoneNumber, f.PhoneNumber),
+			f.IsDeleted != nil && u.Deletion.IsDeleted() != *f.IsDeleted,
+			f.IsDeleted == nil && !visible(u.Deletion, f.WithDeleted),
+			f.IsActivated != nil && *u.IsActivated != *f.IsActivated,
+			f.IsVerified != nil && *u.IsVerified != *f.IsVerified:
+			continue
+		}
+		rows = append(rows, cloneUser(u))
+	}
+	m.Store.mu.RUnlock()
+
+	page, metadata, err := paginate(rows, f.Filters, userColumns, func(u users.User) int64 { return u.ID })
+	if err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	result := make([]*users.User, len(page))
+	for i := range page {
+		result[i] = &page[i]
+	}
+	return result, metadata, nil
+}
+
+// find returns a copy of the first user matching match, or ErrRecordNotFound.
+func (m *UserModel) find(match func(users.User) bool) (*users.User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	for _, u := range m.Store.t.users {
+		if match(u) {
+			u = cloneUser(u)
+			return &u, nil
+		}
+	}
+	return nil, errors.ErrRecordNotFound
+}
+
+/****************************************************************************************
+ *										Tokens											*
+ ***************************************************************************************/
+
+// GetUserToken retrieves the live user owning an unexpired token of the given scope.
+func (m *TokenModel) GetUserToken(ctx context.Context, tokenScope, tokenPlaintext string) (*users.User, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	now := time.Now()
+	for _, t := range m.Store.t.tokens {
+		if t.Scope != tokenScope || !bytes.Equal(t.Hash, hash[:]) || !t.Expiry.After(now) {
+			continue
+		}
+		if u, ok := m.Store.t.users[t.UserID]; ok && !u.Deletion.IsDeleted() {
+			u = cloneUser(u)
+			return &u, nil
+		}
+	}
+	return nil, errors.ErrRecordNotFound
+}
+
+// DeleteAllForUser removes all of a user's tokens of the given scope.
+func (m *TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	m.Store.t.tokens = slices.DeleteFunc(m.Store.t.tokens, func(t users.Token) bool {
+		return t.Scope == scope && t.UserID == userID
+	})
+	return nil
+}
+
+// Insert stores a token. Like the tokens table, only its hash is kept.
+func (m *TokenModel) Insert(ctx context.Context, token *users.Token) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.t.users[token.UserID]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	m.Store.t.tokens = append(m.Store.t.tokens, users.Token{
+		Hash:   slices.Clone(token.Hash),
+		UserID: token.UserID,
+		Expiry: token.Expiry,
+		Scope:  token.Scope,
+	})
+	return nil
+}
+
+// New creates and stores a new token for a user.
+func (m *TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*users.Token, error) {
+	token, err := users.GenerateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+/****************************************************************************************
+ *										Permissions										*
+ ***************************************************************************************/
+
+// GetAllForUser retrieves the permission codes granted to a user.
+func (m *PermissionModel) GetAllForUser(ctx context.Context, userID int64) (users.Permissions, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	ids := make([]int64, 0, len(m.Store.t.userPermissions[userID]))
+	for id := range m.Store.t.userPermissions[userID] {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var permissions users.Permissions
+	for _, id := range ids {
+		permissions = append(permissions, m.Store.t.permissions[id])
+	}
+	return permissions, nil
+}
+
+// AssignToUser grants permission codes to a user. Codes that are already granted, or that do not
+// exist, are ignored.
+func (m *PermissionModel) AssignToUser(ctx context.Context, userID int64, permissions ...string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.t.users[userID]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	granted := m.Store.t.userPermissions[userID]
+	if granted == nil {
+		granted = make(map[int64]bool)
+		m.Store.t.userPermissions[userID] = granted
+	}
+	var added []string
+	for id, code := range m.Store.t.permissions {
+		if slices.Contains(permissions, code) && !granted[id] {
+			granted[id] = true
+			added = append(added, code)
+		}
+	}
+
+	// Permissions the user already held were not granted again
+	if len(added) > 0 {
+		slices.Sort(added)
+		m.Store.recordChanges(ctx, "users", userID, audit.ActionUpdate, audit.Changes{"permissions": audit.Field(nil, added)})
+	}
+	return nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// cloneUser copies a user so that it shares no flags with the stored row. Like the generated
+// is_deleted column, IsDeleted follows the user's deletion.
+func cloneUser(u users.User) users.User {
+	u.IsActivated = cloneBool(u.IsActivated)
+	u.IsDeleted = boolOr(nil, u.Deletion.IsDeleted())
+	u.IsVerified = cloneBool(u.IsVerified)
+	u.Deletion = cloneDeletion(u.Deletion)
+	return u
+}
+
+// userRow copies a user for storage, defaulting unset flags to false like the table does. Only the
+// password hash is kept, as in the password_hash column, and IsDeleted is left to cloneUser.
+func userRow(u *users.User) users.User {
+	row := *u
+	row.Password = u.Password.Stored()
+	row.IsActivated = boolOr(u.IsActivated, false)
+	row.IsDeleted = nil
+	row.IsVerified = boolOr(u.IsVerified, false)
+	return row
+}
+
+// checkUserUnique returns the duplicate value error for the first unique column u clashes on.
+// Empty farmer ids and phone numbers are treated as NULL. The caller must hold the lock.
+func (s *Store) checkUserUnique(u *users.User) error {
+	for _, other := range s.t.users {
+		switch {
+		case other.ID == u.ID:
+		case other.Email == u.Email:
+			return errors.ErrDuplicateValue("email")
+		case u.FarmerID != "" && other.FarmerID == u.FarmerID:
+			return errors.ErrDuplicateValue("farmer_id")
+		case u.PhoneNumber != "" && other.PhoneNumber == u.PhoneNumber:
+			return errors.ErrDuplicateValue("phone_number")
+		}
+	}
+	return nil
+}
+
+// deleteUser removes a user and, like ON DELETE CASCADE, its tokens, permissions, cattle and
+// listings. Rows it deleted forget who deleted them. The caller must hold the write lock.
+func (s *Store) deleteUser(userID int64) {
+	delete(s.t.users, userID)
+	delete(s.t.userPermissions, userID)
+	s.t.tokens = slices.DeleteFunc(s.t.tokens, func(t users.Token) bool { return t.UserID == userID })
+	for id, c := range s.t.cattle {
+		if int64(c.OwnerID) == userID {
+			delete(s.t.cattle, id)
+		}
+	}
+	for id, l := range s.t.listings {
+		if l.UserID == userID {
+			s.deleteListing(id)
+		}
+	}
+	for id, ss := range s.t.savedSearches {
+		if ss.UserID == userID {
+			s.deleteSavedSearch(id)
+		}
+	}
+	s.deleteWatchesOf(0, userID)
+
+	clearDeletedBy(s.t.users, userID, userFields)
+	clearDeletedBy(s.t.cattle, userID, cattleFields)
+	clearDeletedBy(s.t.breeds, userID, breedFields)
+	clearDeletedBy(s.t.regions, userID, regionFields)
+	clearDeletedBy(s.t.areas, userID, areaFields)
+	clearDeletedBy(s.t.listings, userID, listingFields)
+}
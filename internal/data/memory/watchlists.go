@@ -0,0 +1,236 @@
+// File: internal/data/memory/watchlists.go
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// WatchModel is the in-memory watchlists.WatchRepository.
+type WatchModel struct {
+	Store *Store
+}
+
+var _ watchlists.WatchRepository = (*WatchModel)(nil)
+
+// watchColumns are the columns watches can be sorted by.
+var watchColumns = columns[watchlists.Watch]{
+	"id":         func(w watchlists.Watch) any { return w.ID },
+	"created_at": func(w watchlists.Watch) any { return w.CreatedAt },
+}
+
+/****************************************************************************************
+ *										Watches											*
+ ***************************************************************************************/
+
+// Insert adds a new watch. A user can only watch a listing or seller once.
+func (m *WatchModel) Insert(ctx context.Context, w *watchlists.Watch) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.t.users[w.UserID]; !ok {
+		return errors.ErrForeignKeyViolation
+	}
+	if w.ListingID != nil {
+		if _, ok := m.Store.t.listings[*w.ListingID]; !ok {
+			return errors.ErrForeignKeyViolation
+		}
+	}
+	if w.SellerID != nil {
+		if _, ok := m.Store.t.users[*w.SellerID]; !ok {
+			return errors.ErrForeignKeyViolation
+		}
+	}
+	for _, existing := range m.Store.t.watches {
+		if existing.UserID != w.UserID {
+			continue
+		}
+		if w.ListingID != nil && existing.ListingID != nil && *existing.ListingID == *w.ListingID {
+			return errors.ErrDuplicateValue("listing_id")
+		}
+		if w.SellerID != nil && existing.SellerID != nil && *existing.SellerID == *w.SellerID {
+			return errors.ErrDuplicateValue("seller_id")
+		}
+	}
+
+	w.ID = m.Store.nextID("watches")
+	w.CreatedAt = time.Now()
+
+	m.Store.t.watches[w.ID] = cloneWatch(*w)
+	return m.Store.record(ctx, "watches", w.ID, audit.ActionInsert, nil, cloneWatch(*w))
+}
+
+// Delete permanently removes a watch. Notifications already queued for it are still sent.
+func (m *WatchModel) Delete(ctx context.Context, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	existing, ok := m.Store.t.watches[id]
+	if !ok {
+		return errors.ErrRecordNotFound
+	}
+	delete(m.Store.t.watches, id)
+	return m.Store.record(ctx, "watches", id, audit.ActionDelete, cloneWatch(existing), nil)
+}
+
+// GetByID retrieves a watch by id.
+func (m *WatchModel) GetByID(ctx context.Context, id int64) (*watchlists.Watch, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	w, ok := m.Store.t.watches[id]
+	if !ok {
+		return nil, errors.ErrRecordNotFound
+	}
+	w = cloneWatch(w)
+	return &w, nil
+}
+
+// GetAll returns a page of the watches matching filter.
+func (m *WatchModel) GetAll(ctx context.Context, f *watchlists.WatchFilter) ([]*watchlists.Watch, filters.MetaData, error) {
+	m.Store.mu.RLock()
+	rows := []watchlists.Watch{}
+	for _, w := range m.Store.t.watches {
+		switch {
+		case f.UserID != nil && w.UserID != *f.UserID,
+			f.Kind == "listing" && w.ListingID == nil,
+			f.Kind == "seller" && w.SellerID == nil:
+			continue
+		}
+		rows = append(rows, cloneWatch(w))
+	}
+	m.Store.mu.RUnlock()
+
+	page, metadata, err := paginate(rows, f.Default, watchColumns, func(w watchlists.Watch) int64 { return w.ID })
+	if err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	result := make([]*watchlists.Watch, len(page))
+	for i := range page {
+		result[i] = &page[i]
+	}
+	return result, metadata, nil
+}
+
+// CountWatchers returns how many users are watching a listing, directly or through its seller.
+func (m *WatchModel) CountWatchers(ctx context.Context, listingID int64) (int, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return len(m.Store.watchersOf(listingID)), nil
+}
+
+// GetPendingNotifications returns the notifications not emailed yet whose watcher has not been
+// deleted, grouped by watcher and oldest first.
+func (m *WatchModel) GetPendingNotifications(ctx context.Context) ([]*watchlists.Notification, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	result := []*watchlists.Notification{}
+	for _, n := range m.Store.t.watchNotifications {
+		if owner, ok := m.Store.t.users[n.UserID]; n.SentAt == nil && ok && !owner.Deletion.IsDeleted() {
+			result = append(result, &n)
+		}
+	}
+	slices.SortFunc(result, func(a, b *watchlists.Notification) int {
+		return cmp.Or(cmp.Compare(a.UserID, b.UserID), cmp.Compare(a.ID, b.ID))
+	})
+	return result, nil
+}
+
+// MarkNotificationsSent claims the given notifications that are still unsent for an email going
+// out at sentAt, and returns the ones it claimed in id order.
+func (m *WatchModel) MarkNotificationsSent(ctx context.Context, ids []int64, sentAt time.Time) ([]int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	claimed := []int64{}
+	for _, id := range ids {
+		if n, ok := m.Store.t.watchNotifications[id]; ok && n.SentAt == nil {
+			n.SentAt = &sentAt
+			m.Store.t.watchNotifications[id] = n
+			claimed = append(claimed, id)
+		}
+	}
+	slices.Sort(claimed)
+	return claimed, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// watchersOf returns the users watching a listing, directly or through its seller, other than the
+// seller, in id order. The caller must hold the lock.
+func (s *Store) watchersOf(listingID int64) []int64 {
+	l, ok := s.t.listings[listingID]
+	if !ok {
+		return nil
+	}
+	watchers := []int64{}
+	for _, w := range s.t.watches {
+		watching := (w.ListingID != nil && *w.ListingID == listingID) || (w.SellerID != nil && *w.SellerID == l.UserID)
+		if watching && w.UserID != l.UserID && !slices.Contains(watchers, w.UserID) {
+			watchers = append(watchers, w.UserID)
+		}
+	}
+	slices.Sort(watchers)
+	return watchers
+}
+
+// notify queues a notification of event for everyone watching a listing, as watchlists.Notify
+// does. The caller must hold the write lock.
+func (s *Store) notify(listingID int64, event watchlists.Event, detail string) {
+	now := time.Now()
+	for _, userID := range s.watchersOf(listingID) {
+		id := s.nextID("watch_notifications")
+		s.t.watchNotifications[id] = watchlists.Notification{
+			ID:        id,
+			UserID:    userID,
+			ListingID: listingID,
+			Event:     event,
+			Detail:    detail,
+			CreatedAt: now,
+		}
+	}
+}
+
+// deleteWatchesOf removes the watches and notifications that refer to a listing or user, like ON
+// DELETE CASCADE. Either id may be zero. The caller must hold the write lock.
+func (s *Store) deleteWatchesOf(listingID, userID int64) {
+	for id, w := range s.t.watches {
+		if (w.ListingID != nil && *w.ListingID == listingID) || w.UserID == userID || (w.SellerID != nil && *w.SellerID == userID) {
+			delete(s.t.watches, id)
+		}
+	}
+	for id, n := range s.t.watchNotifications {
+		if n.ListingID == listingID || n.UserID == userID {
+			delete(s.t.watchNotifications, id)
+		}
+	}
+}
+
+// cloneWatch copies a watch so that it shares no ids with the stored row.
+func cloneWatch(w watchlists.Watch) watchlists.Watch {
+	if w.ListingID != nil {
+		id := *w.ListingID
+		w.ListingID = &id
+	}
+	if w.SellerID != nil {
+		id := *w.SellerID
+		w.SellerID = &id
+	}
+	return w
+}
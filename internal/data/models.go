@@ -2,38 +2,213 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/media"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/memory"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
 )
 
 /****************************************************************************************
  *										Declarations									*
  ***************************************************************************************/
 
-// Models is a wrapper for all data models.
+// Models is a wrapper for all data models. Everything but Media is held behind its repository
+// interface, so handlers can run against Postgres or the in-memory store alike.
 type Models struct {
-	Cattle      cattle.CattleModel
-	Breeds      cattle.BreedModel
-	Users       users.UserModel
-	Tokens      users.TokenModel
-	Permissions users.PermissionModel
-	Areas       locations.AreaModel
-	Regions     locations.RegionModel
+	Cattle        cattle.CattleRepository
+	Breeds        cattle.BreedRepository
+	Users         users.UserRepository
+	Tokens        users.TokenRepository
+	Permissions   users.PermissionRepository
+	Areas         locations.AreaRepository
+	Regions       locations.RegionRepository
+	Listings      listings.ListingRepository
+	ListingPrices listings.ListingPriceRepository
+	SavedSearches savedsearches.SavedSearchRepository
+	Watches       watchlists.WatchRepository
+	Jobs          jobs.JobRepository
+	Emails        outbox.EmailRepository
+	Audit         audit.AuditRepository
+	Media         media.MediaModel
+
+	inTx    bool
+	runTx   func(ctx context.Context, opts *sql.TxOptions, fn func(tx Models) error) error
+	tryLock func(ctx context.Context, key int64, fn func() error) (bool, error)
+	now     func(ctx context.Context) (time.Time, error)
+}
+
+// NewModels initializes and returns a Models struct. Every model call is bounded by timeout,
+// or database.DefaultTimeout when it is zero, as well as by the caller's context.
+func NewModels(db *sql.DB, timeout time.Duration) Models {
+	m := newModels(db, timeout)
+	m.runTx = func(ctx context.Context, opts *sql.TxOptions, fn func(tx Models) error) error {
+		return database.RunInTx(ctx, db, opts, func(tx *sql.Tx) error {
+			txModels := newModels(tx, timeout)
+			txModels.inTx = true
+			return fn(txModels)
+		})
+	}
+	m.tryLock = func(ctx context.Context, key int64, fn func() error) (bool, error) {
+		return database.TryLock(ctx, db, key, fn)
+	}
+	m.now = func(ctx context.Context) (time.Time, error) {
+		return database.Now(ctx, db)
+	}
+	return m
+}
+
+// NewMemoryModels returns Models backed by a fresh in-memory store, for running handlers
+// without a database. Media has no in-memory implementation and must not be used.
+func NewMemoryModels() Models {
+	store := memory.NewStore()
+	m := newMemoryModels(store)
+	m.runTx = func(ctx context.Context, opts *sql.TxOptions, fn func(tx Models) error) error {
+		return store.RunInTx(func() error {
+			txModels := newMemoryModels(store)
+			txModels.inTx = true
+			return fn(txModels)
+		})
+	}
+	m.tryLock = func(ctx context.Context, key int64, fn func() error) (bool, error) {
+		return store.TryLock(key, fn)
+	}
+	m.now = func(ctx context.Context) (time.Time, error) {
+		return time.Now(), nil
+	}
+	return m
+}
+
+// Exclusive runs fn unless another process, or another goroutine of this one, is already running
+// something under the same lock key, and reports whether it ran. It elects a leader among API
+// instances for jobs that must not run twice at once.
+func (m Models) Exclusive(ctx context.Context, key int64, fn func() error) (bool, error) {
+	return m.tryLock(ctx, key, fn)
+}
+
+// Now returns the current time on the clock that stamps the rows, which is the database's rather
+// than this instance's.
+func (m Models) Now(ctx context.Context) (time.Time, error) {
+	return m.now(ctx)
 }
 
-// NewModels initializes and returns a Models struct.
+// WithTx runs fn as a single unit of work: every model in the Models passed to fn shares one
+// transaction, which is committed when fn returns nil and rolled back when it returns an error or
+// panics. Serialization failures and deadlocks are retried, so fn may run more than once.
+func (m Models) WithTx(ctx context.Context, fn func(tx Models) error) error {
+	return m.WithTxOptions(ctx, nil, fn)
+}
+
+// WithTxOptions is WithTx with explicit transaction options, e.g. serializable isolation.
+// Called on Models that are already inside a transaction, fn simply joins it.
+func (m Models) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx Models) error) error {
+	if m.inTx {
+		return fn(m)
+	}
+	return m.runTx(ctx, opts, fn)
+}
+
+// PurgeResult reports what a purge removed: the number of rows purged from each table, and the
+// storage keys of the media files that belonged to them.
+type PurgeResult struct {
+	Purged      map[string]int64
+	StorageKeys []string
+}
+
+// Purge permanently deletes everything soft deleted before the cutoff as one unit of work.
+// Listings and cattle go first so that the areas, breeds and regions they held on to can follow
+// in the same run. Jobs that succeeded and emails sent before the cutoff go too. The media files
+// are not removed here: the caller should delete StorageKeys once Purge has returned successfully.
+func (m Models) Purge(ctx context.Context, before time.Time) (PurgeResult, error) {
+	var result PurgeResult
+	err := m.WithTx(ctx, func(tx Models) error {
+		result = PurgeResult{Purged: make(map[string]int64)}
+
+		// The in-memory store keeps no media.
+		if tx.Media.DB != nil {
+			keys, err := tx.Media.DeletePurged(ctx, before)
+			if err != nil {
+				return err
+			}
+			result.StorageKeys = keys
+		}
+
+		steps := []struct {
+			table string
+			purge func(context.Context, time.Time) (int64, error)
+		}{
+			{"listings", tx.Listings.Purge},
+			{"cattle", tx.Cattle.Purge},
+			{"users", tx.Users.Purge},
+			{"areas", tx.Areas.Purge},
+			{"breeds", tx.Breeds.Purge},
+			{"regions", tx.Regions.Purge},
+			{"jobs", tx.Jobs.Purge},
+			{"email_outbox", tx.Emails.Purge},
+		}
+		for _, step := range steps {
+			n, err := step.purge(ctx, before)
+			if err != nil {
+				return err
+			}
+			result.Purged[step.table] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return PurgeResult{}, err
+	}
+	return result, nil
+}
+
+// newModels builds the models on top of conn, either the pool or a transaction.
+func newModels(conn database.DBTX, timeout time.Duration) Models {
+	return Models{
+		Cattle:        &cattle.CattleModel{DB: conn, Timeout: timeout},
+		Breeds:        &cattle.BreedModel{DB: conn, Timeout: timeout},
+		Users:         &users.UserModel{DB: conn, Timeout: timeout},
+		Tokens:        &users.TokenModel{DB: conn, Timeout: timeout},
+		Permissions:   &users.PermissionModel{DB: conn, Timeout: timeout},
+		Areas:         &locations.AreaModel{DB: conn, Timeout: timeout},
+		Regions:       &locations.RegionModel{DB: conn, Timeout: timeout},
+		Listings:      &listings.ListingModel{DB: conn, Timeout: timeout},
+		ListingPrices: &listings.ListingPricesModel{DB: conn, Timeout: timeout},
+		SavedSearches: &savedsearches.SavedSearchModel{DB: conn, Timeout: timeout},
+		Watches:       &watchlists.WatchModel{DB: conn, Timeout: timeout},
+		Jobs:          &jobs.JobModel{DB: conn, Timeout: timeout},
+		Emails:        &outbox.EmailModel{DB: conn, Timeout: timeout},
+		Audit:         &audit.AuditModel{DB: conn, Timeout: timeout},
+		Media:         media.MediaModel{DB: conn, Timeout: timeout},
+	}
+}
 
-func NewModels(db *sql.DB) Models {
+// newMemoryModels builds the in-memory repositories on top of store.
+func newMemoryModels(store *memory.Store) Models {
 	return Models{
-		Cattle:      cattle.CattleModel{DB: db},
-		Breeds:      cattle.BreedModel{DB: db},
-		Users:       users.UserModel{DB: db},
-		Tokens:      users.TokenModel{DB: db},
-		Permissions: users.PermissionModel{DB: db},
-		Areas:       locations.AreaModel{DB: db},
-		Regions:     locations.RegionModel{DB: db},
+		Cattle:        &memory.CattleModel{Store: store},
+		Breeds:        &memory.BreedModel{Store: store},
+		Users:         &memory.UserModel{Store: store},
+		Tokens:        &memory.TokenModel{Store: store},
+		Permissions:   &memory.PermissionModel{Store: store},
+		Areas:         &memory.AreaModel{Store: store},
+		Regions:       &memory.RegionModel{Store: store},
+		Listings:      &memory.ListingModel{Store: store},
+		ListingPrices: &memory.ListingPriceModel{Store: store},
+		SavedSearches: &memory.SavedSearchModel{Store: store},
+		Watches:       &memory.WatchModel{Store: store},
+		Jobs:          &memory.JobModel{Store: store},
+		Emails:        &memory.EmailModel{Store: store},
+		Audit:         &memory.AuditModel{Store: store},
 	}
 }
@@ -0,0 +1,162 @@
+// File: internal/data/models_test.go
+package data_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestWithTxRollback(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	var breed *cattle.Breed
+	var job *jobs.Job
+	rollback := errors.New("rollback")
+	err := models.WithTx(ctx, func(tx data.Models) error {
+		breed = testdb.NewFactory(t, tx).Breed()
+
+		var err error
+		if job, err = jobs.New("test", struct{}{}); err != nil {
+			return err
+		}
+		if err := tx.Jobs.Enqueue(ctx, job); err != nil {
+			return err
+		}
+
+		// A nested unit of work joins the transaction instead of committing on its own.
+		return tx.WithTx(ctx, func(tx data.Models) error {
+			breed.Name = "Renamed"
+			return tx.Breeds.Update(ctx, breed)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := models.Breeds.GetByID(ctx, breed.ID); err != nil || got.Name != "Renamed" {
+		t.Fatalf("committed breed = %+v, %v, want it renamed", got, err)
+	}
+
+	var user *users.User
+	err = models.WithTx(ctx, func(tx data.Models) error {
+		if err := tx.Breeds.SoftDelete(ctx, breed.ID, 0); err != nil {
+			return err
+		}
+		if _, err := tx.Jobs.Claim(ctx, []string{"test"}, time.Now()); err != nil {
+			return err
+		}
+		user = testdb.NewFactory(t, tx).User()
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx error = %v, want the rollback", err)
+	}
+
+	got, err := models.Breeds.GetByID(ctx, breed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IsDeleted() || got.Version != breed.Version {
+		t.Errorf("breed after the rollback = %+v, want it as committed", got)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{"test"}, time.Now()); err != nil {
+		t.Errorf("Claim after the rolled back claim error = %v, want the job pending again", err)
+	}
+	if _, err := models.Users.GetByID(ctx, user.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of the rolled back user error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	var breed *cattle.Breed
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the panic to reach the caller", p)
+			}
+		}()
+		models.WithTx(ctx, func(tx data.Models) error {
+			breed = testdb.NewFactory(t, tx).Breed()
+			panic("boom")
+		})
+	}()
+
+	if _, err := models.Breeds.GetByID(ctx, breed.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of a breed inserted before a panic error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestExclusive(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	const key = 42
+	ran, err := models.Exclusive(ctx, key, func() error {
+		inner, err := models.Exclusive(ctx, key, func() error {
+			t.Error("Exclusive ran fn while the lock was held")
+			return nil
+		})
+		if err != nil || inner {
+			t.Errorf("Exclusive under a held lock = %v, %v, want it skipped", inner, err)
+		}
+
+		other, err := models.Exclusive(ctx, key+1, func() error { return nil })
+		if err != nil || !other {
+			t.Errorf("Exclusive of another key = %v, %v, want it run", other, err)
+		}
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("Exclusive = %v, %v, want it run", ran, err)
+	}
+
+	failed := errors.New("failed")
+	if ran, err := models.Exclusive(ctx, key, func() error { return failed }); !ran || !errors.Is(err, failed) {
+		t.Errorf("Exclusive after the lock was released = %v, %v, want it run and its error returned", ran, err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+	f := testdb.NewFactory(t, models)
+
+	kept := f.Breed()
+	purged := f.Breed()
+	if err := models.Breeds.SoftDelete(ctx, purged.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := models.Purge(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := result.Purged["breeds"]; n != 0 {
+		t.Errorf("breeds purged before the cutoff = %d, want none", n)
+	}
+
+	result, err = models.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := result.Purged["breeds"]; n != 1 {
+		t.Errorf("breeds purged = %d, want the soft deleted one", n)
+	}
+	if _, err := models.Breeds.GetByID(ctx, purged.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of a purged breed error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Breeds.GetByID(ctx, kept.ID); err != nil {
+		t.Errorf("GetByID of the kept breed error = %v", err)
+	}
+}
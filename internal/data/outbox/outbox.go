@@ -0,0 +1,345 @@
+// File: internal/data/outbox/outbox.go
+
+// Package outbox is the transactional outbox of outgoing emails. An email is written to the outbox
+// in the same transaction as the change it is about, together with a job to deliver it, so it goes
+// out if and only if that change is committed. The delivery job records every attempt on the email.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/jobs"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Status is where an email is in its delivery.
+type Status string
+
+const (
+	StatusQueued Status = "queued" // waiting for its delivery job
+	StatusSent   Status = "sent"   // taken by the SMTP server
+	StatusFailed Status = "failed" // the last attempt failed; the delivery job may still retry it
+)
+
+// Statuses lists every email status, for filtering the outbox.
+var Statuses = []string{string(StatusQueued), string(StatusSent), string(StatusFailed)}
+
+// JobKind is the kind of the job that delivers an email.
+const JobKind = "deliver_email"
+
+// Delivery is the payload of a deliver_email job.
+type Delivery struct {
+	EmailID int64 `json:"email_id"`
+}
+
+// Email is an outgoing email: a template to render with data and send to one recipient.
+type Email struct {
+	ID        int64           `json:"id"`
+	Recipient string          `json:"recipient"`
+	Template  string          `json:"template"`
+	Data      json.RawMessage `json:"data"`
+	Status    Status          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	SentAt    *time.Time      `json:"sent_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// EmailFilter holds the filters for inspecting the outbox.
+type EmailFilter struct {
+	Status    string
+	Recipient string
+	Default   filters.Filters
+}
+
+// EmailModel stores the outbox in Postgres.
+type EmailModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// EmailRepository is the interface for queueing emails and recording their delivery. Delivery is
+// bookkeeping of the workers, so only resends by an admin are audited.
+type EmailRepository interface {
+	Insert(ctx context.Context, e *Email) error
+	MarkSent(ctx context.Context, id int64, sentAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, reason string) error
+	Resend(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*Email, error)
+	GetAll(ctx context.Context, filter *EmailFilter) ([]*Email, filters.MetaData, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// New builds an email of the template to recipient, with data encoded as JSON.
+func New(recipient, template string, data any) (*Email, error) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: encode %s data: %w", template, err)
+	}
+	return &Email{Recipient: recipient, Template: template, Data: js}, nil
+}
+
+// NewDeliveryJob builds the job that delivers the email with the given id.
+func NewDeliveryJob(id int64) (*jobs.Job, error) {
+	return jobs.New(JobKind, Delivery{EmailID: id})
+}
+
+// ValidateEmailFilter validates the status and paging of an outbox query.
+func ValidateEmailFilter(v *validator.Validator, f *EmailFilter) {
+	filters.ValidateFilters(v, f.Default)
+	v.Check(f.Status == "" || v.IsPermitted(f.Status, Statuses...), "status", "must be queued, sent or failed")
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Insert queues an email and the job that delivers it. Insert on the transaction making the change
+// the email is about, so that it is only sent if that change is committed.
+func (m *EmailModel) Insert(ctx context.Context, e *Email) error {
+	if len(e.Data) == 0 {
+		e.Data = json.RawMessage("{}")
+	}
+
+	query := `
+		INSERT INTO email_outbox (recipient, template, data)
+		VALUES ($1, $2, $3::jsonb)
+		RETURNING id, status, attempts, last_error, created_at, updated_at
+	`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, e.Recipient, e.Template, string(e.Data)).
+		Scan(&e.ID, &e.Status, &e.Attempts, &e.LastError, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return errors.WrapInsertError(err, "EmailOutbox")
+	}
+	if err := m.enqueue(ctx, tx, e.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkSent records a successful delivery attempt.
+func (m *EmailModel) MarkSent(ctx context.Context, id int64, sentAt time.Time) error {
+	query := `
+		UPDATE email_outbox
+		SET status = 'sent', attempts = attempts + 1, last_error = '', sent_at = $2, updated_at = NOW()
+		WHERE id = $1`
+	return m.update(ctx, query, id, sentAt)
+}
+
+// MarkFailed records a failed delivery attempt and why it failed.
+func (m *EmailModel) MarkFailed(ctx context.Context, id int64, reason string) error {
+	query := `
+		UPDATE email_outbox
+		SET status = 'failed', attempts = attempts + 1, last_error = $2, updated_at = NOW()
+		WHERE id = $1`
+	return m.update(ctx, query, id, reason)
+}
+
+// Resend queues an email that was sent, or whose delivery failed, to be delivered again. It returns
+// ErrEmailQueued while a job delivering the email is still pending or running, as a second job
+// could send it twice.
+func (m *EmailModel) Resend(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the email first, so that concurrent resends queue up and each sees the job of the one
+	// before it: the check below is a new statement, reading what was committed in the meantime.
+	var locked int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM email_outbox WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return errors.ErrRecordNotFound
+		default:
+			return errors.WrapUpdateError(err, "EmailOutbox")
+		}
+	}
+
+	var delivering bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM jobs
+			WHERE kind = $1 AND status IN ('pending', 'running')
+			AND payload @> jsonb_build_object('email_id', $2::bigint)
+		)`
+	if err := tx.QueryRowContext(ctx, query, JobKind, id).Scan(&delivering); err != nil {
+		return errors.WrapUpdateError(err, "EmailOutbox")
+	}
+	if delivering {
+		return errors.ErrEmailQueued
+	}
+
+	current := &EmailModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE email_outbox SET status = 'queued', updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return errors.WrapUpdateError(err, "EmailOutbox")
+	}
+	if err := m.enqueue(ctx, tx, id); err != nil {
+		return err
+	}
+
+	after, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "email_outbox", id, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByID retrieves an email by its ID.
+func (m *EmailModel) GetByID(ctx context.Context, id int64) (*Email, error) {
+	query := `
+		SELECT id, recipient, template, data, status, attempts, last_error, sent_at, created_at, updated_at
+		FROM email_outbox
+		WHERE id = $1`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	e, err := scanEmail(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "EmailOutbox")
+		}
+	}
+	return e, nil
+}
+
+// GetAll returns a page of the emails matching filter. The recipient is matched case-insensitively.
+func (m *EmailModel) GetAll(ctx context.Context, filter *EmailFilter) ([]*Email, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 5)
+	query := fmt.Sprintf(`
+		SELECT %s, %s, id, recipient, template, data, status, attempts, last_error, sent_at, created_at, updated_at
+		FROM email_outbox
+		WHERE ($1 = '' OR status::text = $1)
+		AND ($2 = '' OR LOWER(recipient) = LOWER($2))
+		AND %s
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, page.Count, page.SortKey, page.Where, page.OrderBy)
+
+	args := []any{filter.Status, filter.Recipient, filter.Default.Limit(), filter.Default.Offset()}
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "EmailOutbox")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	emails := []*Email{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var e Email
+		var key filters.Key
+		err := rows.Scan(&totalRecords, &key.Values, &e.ID, &e.Recipient, &e.Template, &e.Data, &e.Status, &e.Attempts,
+			&e.LastError, &e.SentAt, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "EmailOutbox")
+		}
+		key.ID = e.ID
+		emails = append(emails, &e)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	emails, metaData := filters.Paginate(filter.Default, emails, keys, totalRecords)
+	return emails, metaData, nil
+}
+
+// Purge permanently removes the emails sent before the cutoff. Failed emails are kept until they
+// are resent.
+func (m *EmailModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM email_outbox WHERE status = 'sent' AND sent_at < $1`, before)
+	if err != nil {
+		return 0, errors.WrapDeleteError(err, "EmailOutbox")
+	}
+	return result.RowsAffected()
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// enqueue queues the job delivering the email with the given id on tx.
+func (m *EmailModel) enqueue(ctx context.Context, tx database.DBTX, id int64) error {
+	job, err := NewDeliveryJob(id)
+	if err != nil {
+		return err
+	}
+	return (&jobs.JobModel{DB: tx, Timeout: m.Timeout}).Enqueue(ctx, job)
+}
+
+// update runs an UPDATE of one email by id, returning ErrRecordNotFound when there is no such email.
+func (m *EmailModel) update(ctx context.Context, query string, id int64, arg any) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, arg)
+	if err != nil {
+		return errors.WrapUpdateError(err, "EmailOutbox")
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.ErrRecordNotFound
+	}
+	return nil
+}
+
+// scanEmail reads an email from a row of all its columns in table order.
+func scanEmail(row *sql.Row) (*Email, error) {
+	var e Email
+	err := row.Scan(&e.ID, &e.Recipient, &e.Template, &e.Data, &e.Status, &e.Attempts, &e.LastError,
+		&e.SentAt, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,234 @@
+// File: internal/data/outbox/outbox_test.go
+package outbox_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/outbox"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestEmailInsert(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	email, err := outbox.New("Ana@example.com", "welcome.tmpl", map[string]any{"firstName": "Ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Emails.Insert(ctx, email); err != nil {
+		t.Fatal(err)
+	}
+	if email.ID == 0 || email.Status != outbox.StatusQueued || email.Attempts != 0 {
+		t.Fatalf("inserted email = %+v, want it queued", email)
+	}
+
+	got, err := models.Emails.GetByID(ctx, email.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(got.Data, &data); err != nil || data["firstName"] != "Ana" {
+		t.Errorf("stored data %s, want the template data", got.Data)
+	}
+
+	job, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now())
+	if err != nil {
+		t.Fatalf("Claim of the delivery job error = %v", err)
+	}
+	var delivery outbox.Delivery
+	if err := json.Unmarshal(job.Payload, &delivery); err != nil || delivery.EmailID != email.ID {
+		t.Errorf("delivery job payload %s, want email %d", job.Payload, email.ID)
+	}
+
+	page, metadata, err := models.Emails.GetAll(ctx, &outbox.EmailFilter{Recipient: "ana@EXAMPLE.com", Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != email.ID || metadata.TotalRecords != 1 {
+		t.Errorf("emails to ana = %+v, want the inserted email", page)
+	}
+}
+
+func TestEmailInsertRolledBack(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	email := &outbox.Email{Recipient: "ana@example.com", Template: "welcome.tmpl"}
+	rollback := errors.New("rollback")
+	err := models.WithTx(ctx, func(tx data.Models) error {
+		if err := tx.Emails.Insert(ctx, email); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx error = %v, want the rollback", err)
+	}
+
+	if _, err := models.Emails.GetByID(ctx, email.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID of a rolled back email error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now()); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Claim after the rollback error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestEmailDeliveryAndResend(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	email := &outbox.Email{Recipient: "ana@example.com", Template: "welcome.tmpl"}
+	if err := models.Emails.Insert(ctx, email); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Emails.Resend(ctx, email.ID); !errors.Is(err, internalErrors.ErrEmailQueued) {
+		t.Errorf("Resend of a queued email error = %v, want ErrEmailQueued", err)
+	}
+
+	// The first attempt fails, and its job is retried later
+	job, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Emails.MarkFailed(ctx, email.ID, "smtp is down"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Emails.GetByID(ctx, email.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != outbox.StatusFailed || got.Attempts != 1 || got.LastError != "smtp is down" || got.SentAt != nil {
+		t.Errorf("failed email = %+v, want one failed attempt", got)
+	}
+	if err := models.Emails.Resend(ctx, email.ID); !errors.Is(err, internalErrors.ErrEmailQueued) {
+		t.Errorf("Resend while its delivery job is running error = %v, want ErrEmailQueued", err)
+	}
+	if err := models.Jobs.Fail(ctx, job, "smtp is down", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Emails.Resend(ctx, email.ID); !errors.Is(err, internalErrors.ErrEmailQueued) {
+		t.Errorf("Resend of a failed email whose job will retry error = %v, want ErrEmailQueued", err)
+	}
+
+	// The retry succeeds
+	job, err = models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentAt := time.Now()
+	if err := models.Emails.MarkSent(ctx, email.ID, sentAt); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Emails.GetByID(ctx, email.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != outbox.StatusSent || got.Attempts != 2 || got.LastError != "" || got.SentAt == nil {
+		t.Errorf("sent email = %+v, want it sent on the second attempt", got)
+	}
+	if err := models.Jobs.Complete(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Emails.Resend(ctx, email.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Emails.GetByID(ctx, email.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != outbox.StatusQueued || got.Attempts != 2 {
+		t.Errorf("resent email = %+v, want it queued again with its attempts kept", got)
+	}
+	if err := models.Emails.Resend(ctx, email.ID); !errors.Is(err, internalErrors.ErrEmailQueued) {
+		t.Errorf("Resend of a resent email error = %v, want ErrEmailQueued", err)
+	}
+	resend, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now())
+	if err != nil {
+		t.Fatalf("Claim of the resend's delivery job error = %v", err)
+	}
+	var delivery outbox.Delivery
+	if err := json.Unmarshal(resend.Payload, &delivery); err != nil || delivery.EmailID != email.ID || resend.ID == job.ID {
+		t.Errorf("resend's delivery job = %+v, want a new job for email %d", resend, email.ID)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now()); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Claim of a second delivery job error = %v, want only the one", err)
+	}
+
+	if err := models.Emails.Resend(ctx, 1<<40); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("Resend of a missing email error = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Emails.MarkSent(ctx, 1<<40, sentAt); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("MarkSent of a missing email error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestEmailResendAfterDeadJob(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	email := &outbox.Email{Recipient: "ana@example.com", Template: "welcome.tmpl"}
+	if err := models.Emails.Insert(ctx, email); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every attempt fails until the job is dead lettered
+	for {
+		job, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now())
+		if errors.Is(err, internalErrors.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Emails.MarkFailed(ctx, email.ID, "mailbox full"); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Jobs.Fail(ctx, job, "mailbox full", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := models.Emails.Resend(ctx, email.ID); err != nil {
+		t.Fatalf("Resend of an email whose delivery job is dead error = %v", err)
+	}
+	if _, err := models.Jobs.Claim(ctx, []string{outbox.JobKind}, time.Now()); err != nil {
+		t.Errorf("Claim of the resend's delivery job error = %v", err)
+	}
+}
+
+func TestEmailPurge(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	sent := &outbox.Email{Recipient: "ana@example.com", Template: "welcome.tmpl"}
+	failed := &outbox.Email{Recipient: "ben@example.com", Template: "welcome.tmpl"}
+	for _, e := range []*outbox.Email{sent, failed} {
+		if err := models.Emails.Insert(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sentAt := time.Now()
+	if err := models.Emails.MarkSent(ctx, sent.ID, sentAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Emails.MarkFailed(ctx, failed.ID, "mailbox full"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := models.Emails.Purge(ctx, sentAt.Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge before the email was sent = %d, %v, want nothing purged", n, err)
+	}
+	if n, err := models.Emails.Purge(ctx, sentAt.Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want the sent email purged", n, err)
+	}
+	if _, err := models.Emails.GetByID(ctx, failed.ID); err != nil {
+		t.Errorf("GetByID of the failed email error = %v, want it kept", err)
+	}
+}
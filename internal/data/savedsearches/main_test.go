@@ -0,0 +1,12 @@
+// File: internal/data/savedsearches/main_test.go
+package savedsearches_test
+
+import (
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestMain(m *testing.M) {
+	testdb.Main(m)
+}
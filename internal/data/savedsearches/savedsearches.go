@@ -0,0 +1,547 @@
+// File: internal/data/savedsearches/savedsearches.go
+
+// Package savedsearches keeps the listing searches users save to be alerted about. A background
+// matcher records the new and updated listings each search matches, and digests of those matches
+// are emailed as often as the user asked.
+package savedsearches
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Frequency is how often the matches of a saved search are emailed.
+type Frequency string
+
+const (
+	FrequencyImmediate Frequency = "immediate" // on the next run of the matcher
+	FrequencyDaily     Frequency = "daily"
+	FrequencyWeekly    Frequency = "weekly"
+)
+
+// Frequencies lists every frequency, for validation.
+var Frequencies = []string{string(FrequencyImmediate), string(FrequencyDaily), string(FrequencyWeekly)}
+
+// Interval returns how long a digest waits after the previous one, or after the search was saved.
+func (f Frequency) Interval() time.Duration {
+	switch f {
+	case FrequencyDaily:
+		return 24 * time.Hour
+	case FrequencyWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// SavedSearch is a listing search a user wants to hear about.
+type SavedSearch struct {
+	ID               int64      `json:"id"`
+	UserID           int64      `json:"user_id"`
+	Name             string     `json:"name"`
+	Query            string     `json:"query"` // the listing search as a query string, e.g. "q=brahman&max_price_per_kg=5"
+	Frequency        Frequency  `json:"frequency"`
+	AlertsEnabled    bool       `json:"alerts_enabled"`
+	UnsubscribeToken string     `json:"-"`
+	CheckedAt        time.Time  `json:"checked_at"` // listings changed since then have yet to be matched
+	SentAt           *time.Time `json:"sent_at"`    // when the last digest went out
+	Version          int        `json:"version"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// SavedSearchFilter holds the filters for listing saved searches.
+type SavedSearchFilter struct {
+	UserID  *int64
+	Default filters.Filters
+}
+
+// SavedSearchModel stores saved searches and their matches in Postgres.
+type SavedSearchModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// SavedSearchRepository is the interface for storing saved searches and running their alerts.
+// Recording matches and digests is bookkeeping of the matcher, so it is left out of the audit trail.
+type SavedSearchRepository interface {
+	Insert(ctx context.Context, s *SavedSearch) error
+	Update(ctx context.Context, s *SavedSearch) error
+	Delete(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*SavedSearch, error)
+	GetAll(ctx context.Context, filter *SavedSearchFilter) ([]*SavedSearch, filters.MetaData, error)
+	Unsubscribe(ctx context.Context, token string) (*SavedSearch, error)
+	GetAlerting(ctx context.Context) ([]*SavedSearch, error)
+	RecordMatches(ctx context.Context, id int64, listingIDs []int64, checkedAt time.Time) error
+	GetDue(ctx context.Context, now time.Time) ([]*SavedSearch, error)
+	GetPending(ctx context.Context, id int64) ([]int64, error)
+	MarkSent(ctx context.Context, id int64, listingIDs []int64, sentAt time.Time) ([]int64, error)
+}
+
+// GenerateUnsubscribeToken returns a random token for the unsubscribe link of a saved search.
+func GenerateUnsubscribeToken() (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// ValidateSavedSearch validates the fields of a SavedSearch. The query itself is checked by the
+// caller, which knows how to read a listing search.
+func ValidateSavedSearch(v *validator.Validator, s *SavedSearch) {
+	v.Check(s.Name != "", "name", "must be provided")
+	v.Check(len(s.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(s.Query) <= 2000, "query", "must not be more than 2000 bytes long")
+	v.Check(v.IsPermitted(string(s.Frequency), Frequencies...), "frequency", "must be one of immediate, daily or weekly")
+}
+
+// ValidateUnsubscribeToken checks the plaintext of an unsubscribe token.
+func ValidateUnsubscribeToken(v *validator.Validator, token string) {
+	v.Check(token != "", "token", "must be provided")
+	v.Check(len(token) == 22, "token", "must be 22 characters long")
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Insert adds a new saved search with a fresh unsubscribe token. Only listings changed from now on
+// are matched against it.
+func (m *SavedSearchModel) Insert(ctx context.Context, s *SavedSearch) error {
+	token, err := GenerateUnsubscribeToken()
+	if err != nil {
+		return err
+	}
+	s.UnsubscribeToken = token
+
+	query := `
+		INSERT INTO saved_searches (user_id, name, query, frequency, alerts_enabled, unsubscribe_token)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, checked_at, version, created_at, updated_at
+	`
+	args := []any{s.UserID, s.Name, s.Query, s.Frequency, s.AlertsEnabled, s.UnsubscribeToken}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&s.ID, &s.CheckedAt, &s.Version, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.IsForeignKeyViolation(err):
+			return errors.ErrForeignKeyViolation
+		default:
+			return errors.WrapInsertError(err, "SavedSearches")
+		}
+	}
+	if err := audit.Record(ctx, tx, "saved_searches", s.ID, audit.ActionInsert, nil, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update modifies a saved search, provided its version has not moved on since it was read.
+// Changing the query drops the matches of the old one that have not been sent yet.
+func (m *SavedSearchModel) Update(ctx context.Context, s *SavedSearch) error {
+	query := `
+		UPDATE saved_searches
+		SET name = $1, query = $2, frequency = $3, alerts_enabled = $4, updated_at = NOW(), version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING updated_at, version
+	`
+	args := []any{s.Name, s.Query, s.Frequency, s.AlertsEnabled, s.ID, s.Version}
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &SavedSearchModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, s.ID)
+	if err != nil {
+		if err == errors.ErrRecordNotFound {
+			return errors.ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&s.UpdatedAt, &s.Version)
+	if err != nil {
+		switch {
+		case errors.IsEditConflict(err):
+			return errors.ErrEditConflict
+		default:
+			return errors.WrapUpdateError(err, "SavedSearches")
+		}
+	}
+
+	if before.Query != s.Query {
+		_, err = tx.ExecContext(ctx, `DELETE FROM saved_search_matches WHERE saved_search_id = $1 AND sent_at IS NULL`, s.ID)
+		if err != nil {
+			return errors.WrapUpdateError(err, "SavedSearches")
+		}
+	}
+
+	after, err := current.GetByID(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "saved_searches", s.ID, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete permanently removes a saved search and its matches.
+func (m *SavedSearchModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &SavedSearchModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM saved_searches WHERE id = $1`, id)
+	if err != nil {
+		return errors.WrapDeleteError(err, "SavedSearches")
+	}
+	if err := audit.Record(ctx, tx, "saved_searches", id, audit.ActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByID retrieves a saved search by its ID.
+func (m *SavedSearchModel) GetByID(ctx context.Context, id int64) (*SavedSearch, error) {
+	query := fmt.Sprintf(`SELECT %s FROM saved_searches WHERE id = $1`, savedSearchColumns)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var s SavedSearch
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(s.scan()...)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "SavedSearches")
+		}
+	}
+	return &s, nil
+}
+
+// GetAll returns a page of the saved searches matching filter.
+func (m *SavedSearchModel) GetAll(ctx context.Context, filter *SavedSearchFilter) ([]*SavedSearch, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 4)
+	query := fmt.Sprintf(`
+		SELECT %s, %s, %s
+		FROM saved_searches
+		WHERE ($1::bigint IS NULL OR user_id = $1)
+		AND %s
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, page.Count, page.SortKey, savedSearchColumns, page.Where, page.OrderBy)
+
+	args := []any{filter.UserID, filter.Default.Limit(), filter.Default.Offset()}
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "SavedSearches")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	searches := []*SavedSearch{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var s SavedSearch
+		var key filters.Key
+		if err := rows.Scan(append([]any{&totalRecords, &key.Values}, s.scan()...)...); err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "SavedSearches")
+		}
+		key.ID = s.ID
+		searches = append(searches, &s)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	searches, metaData := filters.Paginate(filter.Default, searches, keys, totalRecords)
+	return searches, metaData, nil
+}
+
+// Unsubscribe turns off the alerts of the saved search with the given unsubscribe token and returns
+// it. Unsubscribing twice is not an error.
+func (m *SavedSearchModel) Unsubscribe(ctx context.Context, token string) (*SavedSearch, error) {
+	query := fmt.Sprintf(`SELECT %s FROM saved_searches WHERE unsubscribe_token = $1`, savedSearchColumns)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var s SavedSearch
+	err = tx.QueryRowContext(ctx, query, token).Scan(s.scan()...)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "SavedSearches")
+		}
+	}
+	if !s.AlertsEnabled {
+		return &s, nil
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE saved_searches
+		SET alerts_enabled = FALSE, updated_at = NOW(), version = version + 1
+		WHERE id = $1
+		RETURNING updated_at, version`, s.ID).Scan(&s.UpdatedAt, &s.Version)
+	if err != nil {
+		return nil, errors.WrapUpdateError(err, "SavedSearches")
+	}
+	s.AlertsEnabled = false
+
+	changes := audit.Changes{"alerts_enabled": audit.Field(true, false)}
+	if err := audit.RecordChanges(ctx, tx, "saved_searches", s.ID, audit.ActionUpdate, changes); err != nil {
+		return nil, err
+	}
+	return &s, tx.Commit()
+}
+
+// GetAlerting returns every saved search with alerts turned on whose owner has not been deleted,
+// for the matcher to run.
+func (m *SavedSearchModel) GetAlerting(ctx context.Context) ([]*SavedSearch, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM saved_searches
+		WHERE alerts_enabled
+		AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		ORDER BY id`, savedSearchColumns)
+
+	return m.list(ctx, query)
+}
+
+// RecordMatches records the listings a saved search matched among those changed since it was last
+// checked, and moves its checked time on to checkedAt. A listing is only ever matched once per
+// search, so editing it again does not bring it back into a digest.
+func (m *SavedSearchModel) RecordMatches(ctx context.Context, id int64, listingIDs []int64, checkedAt time.Time) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO saved_search_matches (saved_search_id, listing_id, matched_at)
+		SELECT $1, listing_id, $3 FROM unnest($2::bigint[]) AS listing_id
+		ON CONFLICT DO NOTHING`, id, pq.Array(listingIDs), checkedAt)
+	if err != nil {
+		switch {
+		case errors.IsForeignKeyViolation(err):
+			return errors.ErrForeignKeyViolation
+		default:
+			return errors.WrapInsertError(err, "SavedSearchMatches")
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE saved_searches SET checked_at = $2 WHERE id = $1`, id, checkedAt)
+	if err != nil {
+		return errors.WrapUpdateError(err, "SavedSearches")
+	}
+	return tx.Commit()
+}
+
+// GetDue returns the saved searches with alerts turned on that have unsent matches and whose
+// frequency allows a digest at now.
+func (m *SavedSearchModel) GetDue(ctx context.Context, now time.Time) ([]*SavedSearch, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM saved_searches AS s
+		WHERE alerts_enabled
+		AND EXISTS (SELECT 1 FROM saved_search_matches AS ssm WHERE ssm.saved_search_id = s.id AND ssm.sent_at IS NULL)
+		AND COALESCE(sent_at, created_at) <= $1::timestamptz - CASE frequency
+			WHEN 'daily' THEN INTERVAL '1 day'
+			WHEN 'weekly' THEN INTERVAL '7 days'
+			ELSE INTERVAL '0'
+		END
+		ORDER BY id`, savedSearchColumns)
+
+	return m.list(ctx, query, now)
+}
+
+// GetPending returns the listings a saved search has matched that have not been sent yet, oldest
+// match first.
+func (m *SavedSearchModel) GetPending(ctx context.Context, id int64) ([]int64, error) {
+	query := `
+		SELECT listing_id FROM saved_search_matches
+		WHERE saved_search_id = $1 AND sent_at IS NULL
+		ORDER BY matched_at, listing_id`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, errors.WrapGetAllError(err, "SavedSearchMatches")
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var listingID int64
+		if err := rows.Scan(&listingID); err != nil {
+			return nil, errors.WrapGetAllError(err, "SavedSearchMatches")
+		}
+		ids = append(ids, listingID)
+	}
+	return ids, rows.Err()
+}
+
+// MarkSent claims the given matches of a saved search that are still unsent for a digest going out
+// at sentAt, and returns the listings it claimed. Matches already sent, e.g. by a concurrent run,
+// are left alone, and when none is claimed the search's sent time is not moved on either.
+func (m *SavedSearchModel) MarkSent(ctx context.Context, id int64, listingIDs []int64, sentAt time.Time) ([]int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE saved_search_matches SET sent_at = $3
+		WHERE saved_search_id = $1 AND listing_id = ANY($2::bigint[]) AND sent_at IS NULL
+		RETURNING listing_id`, id, pq.Array(listingIDs), sentAt)
+	if err != nil {
+		return nil, errors.WrapUpdateError(err, "SavedSearchMatches")
+	}
+	defer rows.Close()
+
+	claimed := make(map[int64]bool)
+	for rows.Next() {
+		var listingID int64
+		if err := rows.Scan(&listingID); err != nil {
+			return nil, errors.WrapUpdateError(err, "SavedSearchMatches")
+		}
+		claimed[listingID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WrapUpdateError(err, "SavedSearchMatches")
+	}
+	if len(claimed) == 0 {
+		return []int64{}, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE saved_searches SET sent_at = $2 WHERE id = $1`, id, sentAt)
+	if err != nil {
+		return nil, errors.WrapUpdateError(err, "SavedSearches")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Keep the caller's order, which is the order the matches were made in
+	ids := []int64{}
+	for _, listingID := range listingIDs {
+		if claimed[listingID] {
+			ids = append(ids, listingID)
+			delete(claimed, listingID)
+		}
+	}
+	return ids, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// savedSearchColumns are the columns scanned by SavedSearch.scan, in order.
+const savedSearchColumns = `id, user_id, name, query, frequency, alerts_enabled, unsubscribe_token,
+	checked_at, sent_at, version, created_at, updated_at`
+
+// scan returns the destinations for savedSearchColumns.
+func (s *SavedSearch) scan() []any {
+	return []any{
+		&s.ID,
+		&s.UserID,
+		&s.Name,
+		&s.Query,
+		&s.Frequency,
+		&s.AlertsEnabled,
+		&s.UnsubscribeToken,
+		&s.CheckedAt,
+		&s.SentAt,
+		&s.Version,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	}
+}
+
+// list runs a query selecting savedSearchColumns.
+func (m *SavedSearchModel) list(ctx context.Context, query string, args ...any) ([]*SavedSearch, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WrapGetAllError(err, "SavedSearches")
+	}
+	defer rows.Close()
+
+	searches := []*SavedSearch{}
+	for rows.Next() {
+		var s SavedSearch
+		if err := rows.Scan(s.scan()...); err != nil {
+			return nil, errors.WrapGetAllError(err, "SavedSearches")
+		}
+		searches = append(searches, &s)
+	}
+	return searches, rows.Err()
+}
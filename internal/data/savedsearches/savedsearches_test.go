@@ -0,0 +1,185 @@
+// File: internal/data/savedsearches/savedsearches_test.go
+package savedsearches_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestSavedSearchInsertUpdateAndDelete(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	buyer := f.User()
+	s := f.SavedSearch(func(s *savedsearches.SavedSearch) { s.UserID, s.Query = buyer.ID, "q=brahman" })
+	f.SavedSearch()
+
+	if s.ID == 0 || s.Version != 1 || len(s.UnsubscribeToken) != 22 {
+		t.Fatalf("inserted saved search = %+v", s)
+	}
+
+	got, metadata, err := models.SavedSearches.GetAll(ctx, &savedsearches.SavedSearchFilter{UserID: &buyer.ID, Default: testdb.FirstPage("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != s.ID || metadata.TotalRecords != 1 {
+		t.Fatalf("searches of the buyer = %+v, want only %d", got, s.ID)
+	}
+
+	stale := *s
+	s.Name, s.Frequency = "Brahman heifers", savedsearches.FrequencyWeekly
+	if err := models.SavedSearches.Update(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != 2 {
+		t.Errorf("version = %d, want 2", s.Version)
+	}
+	if err := models.SavedSearches.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("stale update: got %v, want ErrEditConflict", err)
+	}
+
+	if err := models.SavedSearches.Delete(ctx, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.SavedSearches.GetByID(ctx, s.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("get after delete: got %v, want ErrRecordNotFound", err)
+	}
+	if err := models.SavedSearches.Delete(ctx, s.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("second delete: got %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestSavedSearchUnsubscribe(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	s := f.SavedSearch()
+	other := f.SavedSearch()
+
+	for range 2 {
+		got, err := models.SavedSearches.Unsubscribe(ctx, s.UnsubscribeToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != s.ID || got.AlertsEnabled || got.Version != 2 {
+			t.Errorf("unsubscribed search = %+v, want %d with alerts off at version 2", got, s.ID)
+		}
+	}
+	if _, err := models.SavedSearches.Unsubscribe(ctx, "not-a-token-at-all-xyz"); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("unknown token: got %v, want ErrRecordNotFound", err)
+	}
+
+	alerting, err := models.SavedSearches.GetAlerting(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerting) != 1 || alerting[0].ID != other.ID {
+		t.Errorf("alerting searches = %+v, want only %d", alerting, other.ID)
+	}
+}
+
+func TestSavedSearchDigests(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	immediate := f.SavedSearch(func(s *savedsearches.SavedSearch) { s.Frequency = savedsearches.FrequencyImmediate })
+	daily := f.SavedSearch()
+	weekly := f.SavedSearch(func(s *savedsearches.SavedSearch) { s.Frequency = savedsearches.FrequencyWeekly })
+	first, second := f.Listing(), f.Listing()
+
+	now := time.Now().Add(time.Minute)
+	for _, s := range []*savedsearches.SavedSearch{immediate, daily, weekly} {
+		if err := models.SavedSearches.RecordMatches(ctx, s.ID, []int64{second.ID, first.ID}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Matching a listing again does not queue it twice
+	if err := models.SavedSearches.RecordMatches(ctx, immediate.ID, []int64{first.ID}, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SavedSearches.RecordMatches(ctx, immediate.ID, []int64{-1}, now); !errors.Is(err, internalErrors.ErrForeignKeyViolation) {
+		t.Errorf("unknown listing: got %v, want ErrForeignKeyViolation", err)
+	}
+
+	s, err := models.SavedSearches.GetByID(ctx, immediate.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(time.Minute); s.CheckedAt.Sub(want).Abs() > time.Millisecond {
+		t.Errorf("checked at = %v, want %v", s.CheckedAt, want)
+	}
+
+	pending, err := models.SavedSearches.GetPending(ctx, immediate.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{first.ID, second.ID}; !slices.Equal(pending, want) {
+		t.Errorf("pending = %v, want %v", pending, want)
+	}
+
+	due := func(at time.Time) []int64 {
+		t.Helper()
+		searches, err := models.SavedSearches.GetDue(ctx, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []int64{}
+		for _, s := range searches {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+
+	if got, want := due(now), []int64{immediate.ID}; !slices.Equal(got, want) {
+		t.Errorf("due now = %v, want %v", got, want)
+	}
+	if got, want := due(now.Add(25*time.Hour)), []int64{immediate.ID, daily.ID}; !slices.Equal(got, want) {
+		t.Errorf("due tomorrow = %v, want %v", got, want)
+	}
+
+	claimed, err := models.SavedSearches.MarkSent(ctx, immediate.ID, pending, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claimed, pending) {
+		t.Errorf("claimed = %v, want %v", claimed, pending)
+	}
+	// A second run marking the same matches claims none of them and leaves the sent time alone
+	claimed, err = models.SavedSearches.MarkSent(ctx, immediate.ID, pending, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("claimed again = %v, want none", claimed)
+	}
+	if s, err := models.SavedSearches.GetByID(ctx, immediate.ID); err != nil {
+		t.Fatal(err)
+	} else if s.SentAt == nil || s.SentAt.Sub(now).Abs() > time.Millisecond {
+		t.Errorf("sent at = %v, want %v", s.SentAt, now)
+	}
+	if got, want := due(now.Add(8*24*time.Hour)), []int64{daily.ID, weekly.ID}; !slices.Equal(got, want) {
+		t.Errorf("due next week = %v, want %v", got, want)
+	}
+
+	// A new query drops the matches of the old one that were not sent
+	daily.Query = "q=angus"
+	if err := models.SavedSearches.Update(ctx, daily); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = models.SavedSearches.GetPending(ctx, daily.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("pending after a new query = %v, want none", pending)
+	}
+}
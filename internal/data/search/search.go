@@ -0,0 +1,61 @@
+// File: internal/data/search/search.go
+
+// Package search builds the SQL of full-text searches over tables that keep a weighted
+// search_document and a plain search_text column, as listings do. Triggers keep those columns up
+// to date, so searches only read them: a row matches when its words, stemmed as English or as
+// Spanish, contain the search, or when its short fields are within a typo of it.
+package search
+
+import (
+	"fmt"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// MaxLength is the longest search accepted, in bytes.
+const MaxLength = 200
+
+// headline configures the snippets of ts_headline: up to two fragments with the matches marked.
+const headline = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// Validate checks the search text sent as key.
+func Validate(v *validator.Validator, key, q string) {
+	v.Check(len(q) <= MaxLength, key, fmt.Sprintf("must not be more than %d bytes long", MaxLength))
+}
+
+/****************************************************************************************
+ *										Queries											*
+ ***************************************************************************************/
+
+// Match returns a condition keeping the rows that match the search in $n, or every row when the
+// search is empty. The trigram operator is pg_trgm's, which lives in the public schema.
+func Match(n int) string {
+	return fmt.Sprintf("($%[1]d = '' OR search_document @@ search_query($%[1]d) OR $%[1]d OPERATOR(public.<%%) search_text)", n)
+}
+
+// Rank returns an expression scoring how well a row matches the search in $n, higher being better:
+// the cover density of its weighted words plus how closely its short fields resemble the search.
+func Rank(n int) string {
+	return fmt.Sprintf("(ts_rank_cd(search_document, search_query($%[1]d)) + public.word_similarity($%[1]d, search_text))", n)
+}
+
+// Snippet returns an expression giving the passages of body that best match the search in $n,
+// as HTML: body is escaped as html.EscapeString does, and only then are the matched words wrapped
+// in <mark> tags, so that markup in what the user wrote comes out as text.
+func Snippet(n int, body string) string {
+	return fmt.Sprintf("ts_headline('english', %s, search_query($%d), '%s')", escapeHTML(body), n, headline)
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// escapeHTML returns an expression escaping the characters of the text expression expr that are
+// special in HTML, the same ones html.EscapeString does. The ampersand must go first.
+func escapeHTML(expr string) string {
+	return fmt.Sprintf(`replace(replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`, expr)
+}
@@ -2,10 +2,12 @@ package users
 
 import (
 	"context"
-	"database/sql"
 	"slices"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
 	"github.com/lib/pq"
 )
@@ -31,7 +33,8 @@ type UserPermission struct {
 type Permissions []string
 
 type PermissionModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
 }
 
 // Helper to check if string is included in the list of permissions.
@@ -56,15 +59,22 @@ func ValidatePermission(v *validator.Validator, permission *Permission) {
  * Permission Database Operations
  ************<************************************************************************************************/
 
+// PermissionRepository is an interface for permission-related database operations
+type PermissionRepository interface {
+	GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+	AssignToUser(ctx context.Context, userID int64, permissions ...string) error
+}
+
 // GetAllForUser retrieves all permissions for a specific user.
-func (m *UserModel) GetAllPermissionsForUser(userID int64) (Permissions, error) {
+func (m *PermissionModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
 	query := `
 		SELECT p.code
 		FROM permissions AS p
-		INNER JOIN user_permissions AS up ON p.id = up.permission_id
+		INNER JOIN users_permissions AS up ON p.id = up.permission_id
 		WHERE up.user_id = $1
+		ORDER BY p.id
 	`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userID)
@@ -90,19 +100,42 @@ func (m *UserModel) GetAllPermissionsForUser(userID int64) (Permissions, error)
 	return permissions, nil
 }
 
-// AssignPermissionsToUser assigns a list of permissions to a user.
-func (m *UserModel) AssignPermissionsToUser(userID int64, permissions ...string) error {
+// AssignToUser assigns a list of permissions to a user.
+func (m *PermissionModel) AssignToUser(ctx context.Context, userID int64, permissions ...string) error {
 	query := `
-		INSERT INTO user_permissions (user_id, permission_id)
-		VALUES ($1, $2)
-		ON CONFLICT (user_id, permission_id) DO NOTHING
+		WITH granted AS (
+			INSERT INTO users_permissions (user_id, permission_id)
+			SELECT $1, p.id FROM permissions AS p WHERE p.code = ANY($2)
+			ON CONFLICT (user_id, permission_id) DO NOTHING
+			RETURNING permission_id
+		)
+		SELECT COALESCE(array_agg(p.code ORDER BY p.code), '{}')
+		FROM granted JOIN permissions AS p ON p.id = granted.permission_id
 	`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(permissions))
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var granted []string
+	err = tx.QueryRowContext(ctx, query, userID, pq.Array(permissions)).Scan(pq.Array(&granted))
 	if err != nil {
+		if internalErrors.IsForeignKeyViolation(err) {
+			return internalErrors.ErrForeignKeyViolation
+		}
 		return err
 	}
-	return nil
+
+	// Permissions the user already held were not granted again
+	if len(granted) > 0 {
+		changes := audit.Changes{"permissions": audit.Field(nil, granted)}
+		if err := audit.RecordChanges(ctx, tx, "users", userID, audit.ActionUpdate, changes); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,48 @@
+// File: internal/data/users/permissions_test.go
+package users_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestPermissions(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	u := f.User()
+	got, err := models.Permissions.GetAllForUser(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("new user has permissions %v", got)
+	}
+
+	// Granting twice is a no-op and unknown codes are ignored.
+	for range 2 {
+		if err := models.Permissions.AssignToUser(ctx, u.ID, "write:cattle", "read:users", "no:such:code"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err = models.Permissions.GetAllForUser(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (users.Permissions{"read:users", "write:cattle"}); !slices.Equal(got, want) {
+		t.Errorf("permissions = %v, want %v", got, want)
+	}
+	if !got.Includes("write:cattle") {
+		t.Error("Includes(write:cattle) = false")
+	}
+
+	if err := models.Permissions.AssignToUser(ctx, 404, "read:users"); !errors.Is(err, internalErrors.ErrForeignKeyViolation) {
+		t.Errorf("AssignToUser for a missing user error = %v, want ErrForeignKeyViolation", err)
+	}
+}
@@ -5,10 +5,10 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
-	"database/sql"
 	"encoding/base64"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
 )
@@ -34,15 +34,16 @@ type Token struct {
 }
 
 type TokenModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
 }
 
 /************************************************************************************************************
  * Token Helpers
  ************************************************************************************************************/
 
-// generateToken generates a new token for a user with a specific scope and expiry duration
-func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
+// GenerateToken generates a new token for a user with a specific scope and expiry duration, without storing it
+func GenerateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
 	// Create a new token with the provided userID, scope, and calculated expiry time
 	token := &Token{
 		UserID: userID,
@@ -74,20 +75,20 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 
 // TokenRepository is an interface for token-related database operations
 type TokenRepository interface {
-	GetUserToken(tokenScope, tokenPlaintext string) (*User, error)
-	DeleteAllForUser(scope string, userID int64) error
-	Insert(token *Token) error
-	New(userID int64, ttl time.Duration, scope string) (*Token, error)
+	GetUserToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+	Insert(ctx context.Context, token *Token) error
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
 }
 
 // GetUserToken Method
-func (m *TokenModel) GetUserToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m *TokenModel) GetUserToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	// Query
 	query := `
-		SELECT u.id, u.farmer_id, u.email, u.phone_number, u.first_name, u.last_name, u.password_hash, u.is_activated, u.is_deleted, u.is_verified, u.version, u.created_at, u.updated_at
+		SELECT u.id, COALESCE(u.farmer_id, ''), u.email, COALESCE(u.phone_number, ''), u.first_name, u.last_name, u.password_hash, u.is_activated, u.is_deleted, u.is_verified, u.version, u.deleted_at, u.deleted_by, u.created_at, u.updated_at
 		FROM users AS u
 		INNER JOIN tokens AS t ON u.id = t.user_id
-		WHERE t.scope = $1 AND t.plaintext = $2 AND t.expiry > $3`
+		WHERE t.scope = $1 AND t.hash = $2 AND t.expires_at > $3 AND u.deleted_at IS NULL`
 
 	// Hash the token plaintext
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
@@ -115,12 +116,14 @@ func (m *TokenModel) GetUserToken(tokenScope, tokenPlaintext string) (*User, err
 		&user.IsDeleted,
 		&user.IsVerified,
 		&user.Version,
+		&user.DeletedAt,
+		&user.DeletedBy,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	}
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 	// Execute Query
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(scan...)
@@ -136,44 +139,47 @@ func (m *TokenModel) GetUserToken(tokenScope, tokenPlaintext string) (*User, err
 }
 
 // DeleteAllForUser removes all tokens for a specific user and scope from the database
-func (m *TokenModel) DeleteAllForUser(scope string, userID int64) error {
+func (m *TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
 	// SQL query to delete tokens for a specific user and scope
 	query := `
 		DELETE FROM tokens
 		WHERE scope = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second) // Create a context with a 3-second timeout
-	defer cancel()                                                          // Ensure the context is cancelled to free resources
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout) // Bound the query by the caller and the model timeout
+	defer cancel()                                      // Ensure the context is cancelled to free resources
 
 	_, err := m.DB.ExecContext(ctx, query, scope, userID) // Execute the delete query
 	return err                                            // Return any error that occurred during execution
 }
 
 // Insert adds a new token to the database
-func (m *TokenModel) Insert(token *Token) error {
+func (m *TokenModel) Insert(ctx context.Context, token *Token) error {
 	// SQL query to insert a new token into the tokens table
 	query := `
-		INSERT INTO tokens (hash, user_id, expiry, scope)
+		INSERT INTO tokens (hash, user_id, expires_at, scope)
 		VALUES ($1, $2, $3, $4)`
 
 	// Prepare the arguments for the query
 	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second) // Create a context with a 3-second timeout
-	defer cancel()                                                          // Ensure the context is cancelled to free resources
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout) // Bound the query by the caller and the model timeout
+	defer cancel()                                      // Ensure the context is cancelled to free resources
 
 	_, err := m.DB.ExecContext(ctx, query, args...) // Execute the insert query
-	return err                                      // Return any error that occurred during execution
+	if internalErrors.IsForeignKeyViolation(err) {
+		return internalErrors.ErrForeignKeyViolation // The user does not exist
+	}
+	return err // Return any error that occurred during execution
 }
 
 // New creates a new token for a user and stores it in the database
-func (m *TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m *TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
 	// Generate a new token
-	token, err := generateToken(userID, ttl, scope)
+	token, err := GenerateToken(userID, ttl, scope)
 	if err != nil {
 		return nil, err // Return error if token generation fails
 	}
 
-	err = m.Insert(token) // Insert the token into the database
-	return token, err     // Return the token and any insertion error
+	err = m.Insert(ctx, token) // Insert the token into the database
+	return token, err          // Return the token and any insertion error
 }
@@ -0,0 +1,55 @@
+// File: internal/data/users/tokens_test.go
+package users_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestTokens(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	u := f.User()
+	token, err := models.Tokens.New(ctx, u.ID, time.Hour, users.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Tokens.GetUserToken(ctx, users.ScopeAuthentication, token.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != u.ID {
+		t.Errorf("token belongs to user %d, want %d", got.ID, u.ID)
+	}
+
+	if _, err := models.Tokens.GetUserToken(ctx, users.ScopeActivation, token.Plaintext); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("wrong scope error = %v, want ErrRecordNotFound", err)
+	}
+
+	expired, err := models.Tokens.New(ctx, u.ID, -time.Minute, users.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Tokens.GetUserToken(ctx, users.ScopeAuthentication, expired.Plaintext); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("expired token error = %v, want ErrRecordNotFound", err)
+	}
+
+	if err := models.Tokens.DeleteAllForUser(ctx, users.ScopeAuthentication, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Tokens.GetUserToken(ctx, users.ScopeAuthentication, token.Plaintext); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("deleted token error = %v, want ErrRecordNotFound", err)
+	}
+
+	if _, err := models.Tokens.New(ctx, 404, time.Hour, users.ScopeActivation); !errors.Is(err, internalErrors.ErrForeignKeyViolation) {
+		t.Errorf("token for a missing user error = %v, want ErrForeignKeyViolation", err)
+	}
+}
@@ -3,11 +3,12 @@ package users
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
 	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
@@ -26,19 +27,20 @@ type Password struct {
 
 // User represents an application user.
 type User struct {
-	ID          int64     `json:"id"`
-	FarmerID    string    `json:"farmer_id"`
-	Email       string    `json:"email"`
-	PhoneNumber string    `json:"phone_number"`
-	FirstName   string    `json:"first_name"`
-	LastName    string    `json:"last_name"`
-	Password    Password  `json:"-"`
-	IsActivated *bool     `json:"is_activated"`
-	IsDeleted   *bool     `json:"is_deleted"`
-	IsVerified  *bool     `json:"is_verified"`
-	Version     int       `json:"version"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64    `json:"id"`
+	FarmerID    string   `json:"farmer_id"`
+	Email       string   `json:"email"`
+	PhoneNumber string   `json:"phone_number"`
+	FirstName   string   `json:"first_name"`
+	LastName    string   `json:"last_name"`
+	Password    Password `json:"-"`
+	IsActivated *bool    `json:"is_activated"`
+	IsDeleted   *bool    `json:"is_deleted"` // read-only, follows DeletedAt
+	IsVerified  *bool    `json:"is_verified"`
+	Version     int      `json:"version"`
+	database.Deletion
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // AnonymousUser is a sentinel anonymous user instance.
@@ -54,11 +56,15 @@ type UserFilters struct {
 	IsDeleted   *bool
 	IsVerified  *bool
 	Filters     filters.Filters
+
+	// WithDeleted includes soft deleted users, which are left out by default unless IsDeleted asks for them.
+	WithDeleted bool
 }
 
 // UserModels struct for database operations
 type UserModel struct {
-	DB *sql.DB
+	DB      database.DBTX
+	Timeout time.Duration
 }
 
 /************************************************************************************************************
@@ -76,6 +82,11 @@ func (p *Password) Set(plaintext string) error {
 	return nil
 }
 
+// Stored returns the password as it reads back from the database: the hash without the plaintext.
+func (p Password) Stored() Password {
+	return Password{hash: p.hash}
+}
+
 // Matches verifies that the supplied plaintext password matches the stored hash.
 func (p *Password) Matches(plaintext string) (bool, error) {
 	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintext))
@@ -136,12 +147,27 @@ func ValidateUser(v *validator.Validator, user *User) {
  * Database Operations
  ************************************************************************************************************/
 
-func (m *UserModel) Insert(user *User) error {
+// UserRepository is an interface for user-related database operations
+type UserRepository interface {
+	Insert(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	UpdatePassword(ctx context.Context, user *User) error
+	SoftDelete(ctx context.Context, userID int64, deletedBy int64) error
+	Restore(ctx context.Context, userID int64) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	DeleteHard(ctx context.Context, userID int64) error
+	GetByID(ctx context.Context, userID int64) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByFarmerID(ctx context.Context, farmerID string) (*User, error)
+	GetAll(ctx context.Context, u *UserFilters) ([]*User, filters.MetaData, error)
+}
+
+func (m *UserModel) Insert(ctx context.Context, user *User) error {
 	// Query
 	query := `
-		INSERT INTO users (farmer_id, email, first_name, last_name, password_hash, is_activated, is_deleted, is_verified, phone_number)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id, created_at, updated_at, version`
+		INSERT INTO users (farmer_id, email, first_name, last_name, password_hash, is_activated, is_verified, phone_number)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, COALESCE($6, FALSE), COALESCE($7, FALSE), NULLIF($8, ''))
+		RETURNING id, is_deleted, created_at, updated_at, version`
 
 	// Arguments for Query
 	args := []any{
@@ -151,16 +177,21 @@ func (m *UserModel) Insert(user *User) error {
 		user.LastName,
 		user.Password.hash,
 		user.IsActivated,
-		user.IsDeleted,
 		user.IsVerified,
 		user.PhoneNumber,
 	}
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.IsDeleted, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 	if err != nil {
 		switch {
 		case internalErrors.IsUniqueViolation(err, "email"):
@@ -173,7 +204,12 @@ func (m *UserModel) Insert(user *User) error {
 			return internalErrors.WrapInsertError(err, "Users")
 		}
 	}
-	return nil
+
+	// Record the insert
+	if err := audit.Record(ctx, tx, "users", user.ID, audit.ActionInsert, nil, user); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 /*
@@ -183,12 +219,14 @@ func (m *UserModel) Insert(user *User) error {
 
 ***************************************************************************************
 */
-func (m *UserModel) Update(user *User) error {
+func (m *UserModel) Update(ctx context.Context, user *User) error {
 	// Query
 	query := `
 		UPDATE users
-		SET farmer_id = $1, email = $2, phone_number = $3, first_name = $4, last_name = $5, password_hash = $6, is_activated = $7, is_deleted = $8, is_verified = $9, updated_at = now(), version = version + 1
-		WHERE id = $10 AND version = $11
+		SET farmer_id = NULLIF($1, ''), email = $2, phone_number = NULLIF($3, ''), first_name = $4, last_name = $5, password_hash = $6,
+			is_activated = COALESCE($7, is_activated), is_verified = COALESCE($8, is_verified),
+			updated_at = now(), version = version + 1
+		WHERE id = $9 AND version = $10 AND deleted_at IS NULL
 		RETURNING updated_at, version  `
 
 	// Arguments for Query
@@ -200,18 +238,33 @@ func (m *UserModel) Update(user *User) error {
 		user.LastName,
 		user.Password.hash,
 		user.IsActivated,
-		user.IsDeleted,
 		user.IsVerified,
 		user.ID,
 		user.Version,
 	}
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Read the user as it was, for the audit trail
+	current := &UserModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, user.ID)
+	if err != nil {
+		if internalErrors.IsRecordNotFound(err) {
+			return internalErrors.ErrEditConflict
+		}
+		return err
+	}
+
 	// Execute Query
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.UpdatedAt, &user.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.UpdatedAt, &user.Version)
 	if err != nil {
 		switch {
 		case internalErrors.IsUniqueViolation(err, "email"):
@@ -226,16 +279,25 @@ func (m *UserModel) Update(user *User) error {
 			return internalErrors.WrapUpdateError(err, "Users")
 		}
 	}
-	return nil
+
+	// Record what changed
+	after, err := current.GetByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, "users", user.ID, audit.ActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Update Password Method
-func (m *UserModel) UpdatePassword(user *User) error {
+func (m *UserModel) UpdatePassword(ctx context.Context, user *User) error {
 	// Query
 	query := `
 		UPDATE users
 		SET password_hash = $1, updated_at = now(), version = version + 1
-		WHERE id = $2 AND version = $3
+		WHERE id = $2 AND version = $3 AND deleted_at IS NULL
 		RETURNING updated_at, version`
 
 	// Arguments for Query
@@ -246,11 +308,17 @@ func (m *UserModel) UpdatePassword(user *User) error {
 	}
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// Execute Query
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.UpdatedAt, &user.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.UpdatedAt, &user.Version)
 	if err != nil {
 		switch {
 		case internalErrors.IsEditConflict(err):
@@ -259,7 +327,13 @@ func (m *UserModel) UpdatePassword(user *User) error {
 			return internalErrors.WrapUpdatePasswordError(err)
 		}
 	}
-	return nil
+
+	// Record that the password changed, but never the hash
+	changes := audit.Changes{"password": audit.Change{}}
+	if err := audit.RecordChanges(ctx, tx, "users", user.ID, audit.ActionUpdate, changes); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 /*
@@ -270,45 +344,60 @@ func (m *UserModel) UpdatePassword(user *User) error {
 ***************************************************************************************
 */
 
-// Soft Delete Method
-func (m *UserModel) DeleteSoft(user *User) error {
-	// Query
-	query := `
-		UPDATE users
-		SET is_deleted = true, updated_at = now(), version = version + 1
-		WHERE id = $1 AND is_deleted = false
-		RETURNING updated_at, version`
+// Soft Delete Method marks the user as deleted by deletedBy. Deleted users can no longer
+// authenticate, but keep their data until they are restored or purged.
+func (m *UserModel) SoftDelete(ctx context.Context, userID int64, deletedBy int64) error {
+	// Get Context
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.SoftDelete(ctx, m.DB, "users", userID, deletedBy)
+}
 
+// Restore Method brings a soft deleted user back.
+func (m *UserModel) Restore(ctx context.Context, userID int64) error {
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	// Execute Query
-	err := m.DB.QueryRowContext(ctx, query, user.ID).Scan(&user.UpdatedAt, &user.Version)
-	if err != nil {
-		switch {
-		case internalErrors.IsEditConflict(err):
-			return internalErrors.ErrAlreadyDeleted
-		default:
-			return internalErrors.WrapDeleteError(err, "Users")
-		}
-	}
-	return nil
+	return audit.Restore(ctx, m.DB, "users", userID)
+}
+
+// Purge Method permanently deletes the users soft deleted before the cutoff, together with
+// everything they own.
+func (m *UserModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	// Get Context
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return audit.Purge(ctx, m.DB, "users", before, "")
 }
 
 // Hard Delete Method
-func (m *UserModel) DeleteHard(userID int64) error {
+func (m *UserModel) DeleteHard(ctx context.Context, userID int64) error {
 	// Query
 	query := `
 		DELETE FROM users
 		WHERE id = $1`
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep the user as it was in the audit trail
+	before, err := (&UserModel{DB: tx, Timeout: m.Timeout}).GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
 	// Execute Query
-	result, err := m.DB.ExecContext(ctx, query, userID)
+	result, err := tx.ExecContext(ctx, query, userID)
 	if err != nil {
 		return internalErrors.WrapDeleteError(err, "Users")
 	}
@@ -319,7 +408,11 @@ func (m *UserModel) DeleteHard(userID int64) error {
 	if rows == 0 {
 		return internalErrors.ErrRecordNotFound
 	}
-	return nil
+
+	if err := audit.Record(ctx, tx, "users", userID, audit.ActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 /*
@@ -331,10 +424,10 @@ func (m *UserModel) DeleteHard(userID int64) error {
 */
 
 // GetByID Method
-func (m *UserModel) GetByID(userID int64) (*User, error) {
+func (m *UserModel) GetByID(ctx context.Context, userID int64) (*User, error) {
 	// Query
 	query := `
-		SELECT id, farmer_id, email, phone_number, first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, created_at, updated_at
+		SELECT id, COALESCE(farmer_id, ''), email, COALESCE(phone_number, ''), first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, deleted_at, deleted_by, created_at, updated_at
 		FROM users
 		WHERE id = $1`
 
@@ -342,7 +435,7 @@ func (m *UserModel) GetByID(userID int64) (*User, error) {
 	var user User
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	scan := []any{
@@ -357,6 +450,8 @@ func (m *UserModel) GetByID(userID int64) (*User, error) {
 		&user.IsDeleted,
 		&user.IsVerified,
 		&user.Version,
+		&user.DeletedAt,
+		&user.DeletedBy,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	}
@@ -375,10 +470,10 @@ func (m *UserModel) GetByID(userID int64) (*User, error) {
 }
 
 // GetByEmail Method
-func (m *UserModel) GetByEmail(email string) (*User, error) {
+func (m *UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	// Query
 	query := `
-		SELECT id, farmer_id, email, phone_number, first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, created_at, updated_at
+		SELECT id, COALESCE(farmer_id, ''), email, COALESCE(phone_number, ''), first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, deleted_at, deleted_by, created_at, updated_at
 		FROM users
 		WHERE email = $1`
 
@@ -386,7 +481,7 @@ func (m *UserModel) GetByEmail(email string) (*User, error) {
 	var user User
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	scan := []any{
@@ -401,6 +496,8 @@ func (m *UserModel) GetByEmail(email string) (*User, error) {
 		&user.IsDeleted,
 		&user.IsVerified,
 		&user.Version,
+		&user.DeletedAt,
+		&user.DeletedBy,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	}
@@ -419,10 +516,10 @@ func (m *UserModel) GetByEmail(email string) (*User, error) {
 }
 
 // GetByFarmerID Method
-func (m *UserModel) GetByFarmerID(farmerID string) (*User, error) {
+func (m *UserModel) GetByFarmerID(ctx context.Context, farmerID string) (*User, error) {
 	// Query
 	query := `
-		SELECT id, farmer_id, email, phone_number, first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, created_at, updated_at
+		SELECT id, COALESCE(farmer_id, ''), email, COALESCE(phone_number, ''), first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, deleted_at, deleted_by, created_at, updated_at
 		FROM users
 		WHERE farmer_id = $1`
 
@@ -430,7 +527,7 @@ func (m *UserModel) GetByFarmerID(farmerID string) (*User, error) {
 	var user User
 
 	// Get Context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	scan := []any{
@@ -445,6 +542,8 @@ func (m *UserModel) GetByFarmerID(farmerID string) (*User, error) {
 		&user.IsDeleted,
 		&user.IsVerified,
 		&user.Version,
+		&user.DeletedAt,
+		&user.DeletedBy,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	}
@@ -463,22 +562,24 @@ func (m *UserModel) GetByFarmerID(farmerID string) (*User, error) {
 }
 
 // GetAll Method
-func (m *UserModel) GetAll(u *UserFilters) ([]*User, filters.MetaData, error) {
+func (m *UserModel) GetAll(ctx context.Context, u *UserFilters) ([]*User, filters.MetaData, error) {
 	// Base Query
+	page := u.Filters.SQL(nil, "id", 11)
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, farmer_id, email, phone_number, first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, created_at, updated_at
+		SELECT %s, %s, id, COALESCE(farmer_id, ''), email, COALESCE(phone_number, ''), first_name, last_name, password_hash, is_activated, is_deleted, is_verified, version, deleted_at, deleted_by, created_at, updated_at
 		FROM users
-		WHER E (to_tsvector('simple', farmer_id) @@ plainto_tsquery('simple', $1) OR $1 = '')
-        AND (to_tsvector('simple', first_name || ' ' || last_name || ' ') @@ plainto_tsquery('simple', $2) OR $2 = '')
-        AND (to_tsvector('simple', email) @@ plainto_tsquery('simple', $3) OR $3 = '')
-		AND (to_tsvector('simple', phone_number) @@ plainto_tsquery('simple', $4) OR $4 = '')
-        AND ($4::boolean IS NULL OR is_deleted = $4)
-        AND ($5::boolean IS NULL OR is_activated = $5)
-        AND ($6::boolean IS NULL OR is_verified = $6)
-		ORDER BY %s %s, id ASC
-		LIMIT $6 OFFSET $7`, u.Filters.SortColumn(), u.Filters.SortDirection())
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		WHERE ($1 = '' OR to_tsvector('simple', COALESCE(farmer_id, '')) @@ plainto_tsquery('simple', $1))
+		AND ($2 = '' OR to_tsvector('simple', first_name || ' ' || last_name) @@ plainto_tsquery('simple', $2))
+		AND ($3 = '' OR to_tsvector('simple', email) @@ plainto_tsquery('simple', $3))
+		AND ($4 = '' OR to_tsvector('simple', COALESCE(phone_number, '')) @@ plainto_tsquery('simple', $4))
+		AND (CASE WHEN $5::boolean IS NULL THEN ($10 OR deleted_at IS NULL) ELSE is_deleted = $5 END)
+		AND ($6::boolean IS NULL OR is_activated = $6)
+		AND ($7::boolean IS NULL OR is_verified = $7)
+		AND %s
+		ORDER BY %s
+		LIMIT $8 OFFSET $9`, page.Count, page.SortKey, page.Where, page.OrderBy)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	args := []any{
@@ -491,7 +592,9 @@ func (m *UserModel) GetAll(u *UserFilters) ([]*User, filters.MetaData, error) {
 		u.IsVerified,
 		u.Filters.Limit(),
 		u.Filters.Offset(),
+		u.WithDeleted,
 	}
+	args = append(args, page.Args...)
 
 	// declare MetaData variable
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -502,10 +605,13 @@ func (m *UserModel) GetAll(u *UserFilters) ([]*User, filters.MetaData, error) {
 
 	totalRecords := 0
 	users := []*User{}
+	keys := []filters.Key{}
 	for rows.Next() {
 		var user User
+		var key filters.Key
 		scan := []any{
 			&totalRecords,
+			&key.Values,
 			&user.ID,
 			&user.FarmerID,
 			&user.Email,
@@ -517,6 +623,8 @@ func (m *UserModel) GetAll(u *UserFilters) ([]*User, filters.MetaData, error) {
 			&user.IsDeleted,
 			&user.IsVerified,
 			&user.Version,
+			&user.DeletedAt,
+			&user.DeletedBy,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		}
@@ -524,11 +632,13 @@ func (m *UserModel) GetAll(u *UserFilters) ([]*User, filters.MetaData, error) {
 		if err != nil {
 			return nil, filters.MetaData{}, internalErrors.WrapGetAllError(err, "Users")
 		}
+		key.ID = user.ID
 		users = append(users, &user)
+		keys = append(keys, key)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, filters.MetaData{}, err // Return any error encountered while iterating over the rows
 	}
-	meta := filters.CalculateMetaData(totalRecords, u.Filters.Page, u.Filters.PageSize)
+	users, meta := filters.Paginate(u.Filters, users, keys, totalRecords)
 	return users, meta, nil
 }
@@ -0,0 +1,331 @@
+// File: internal/data/users/users_test.go
+package users_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestUserInsertAndGet(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	want := f.User()
+	if want.ID == 0 || want.Version != 1 {
+		t.Fatalf("Insert did not return the id and version: %+v", want)
+	}
+
+	lookups := map[string]func() (*users.User, error){
+		"GetByID":       func() (*users.User, error) { return models.Users.GetByID(ctx, want.ID) },
+		"GetByEmail":    func() (*users.User, error) { return models.Users.GetByEmail(ctx, want.Email) },
+		"GetByFarmerID": func() (*users.User, error) { return models.Users.GetByFarmerID(ctx, want.FarmerID) },
+	}
+	for name, lookup := range lookups {
+		got, err := lookup()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.ID != want.ID || got.Email != want.Email || got.FarmerID != want.FarmerID || got.PhoneNumber != want.PhoneNumber {
+			t.Errorf("%s = %+v, want %+v", name, got, want)
+		}
+		if !*got.IsActivated || *got.IsDeleted || *got.IsVerified {
+			t.Errorf("%s flags = %v/%v/%v, want activated only", name, *got.IsActivated, *got.IsDeleted, *got.IsVerified)
+		}
+		if ok, err := got.Password.Matches(testdb.Password); err != nil || !ok {
+			t.Errorf("%s password does not match: %v", name, err)
+		}
+	}
+}
+
+func TestUserInsertOptionalFields(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+
+	// Users without a farmer id or phone number must not clash on the empty value.
+	for range 2 {
+		u := f.User(func(u *users.User) { u.FarmerID, u.PhoneNumber, u.IsActivated = "", "", nil })
+
+		got, err := models.Users.GetByID(t.Context(), u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.FarmerID != "" || got.PhoneNumber != "" || *got.IsActivated {
+			t.Errorf("got %+v, want no farmer id, phone number or activation", got)
+		}
+	}
+}
+
+func TestUserInsertDuplicate(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	existing := f.User()
+
+	tests := map[string]func(u *users.User){
+		"email":        func(u *users.User) { u.Email = existing.Email },
+		"farmer_id":    func(u *users.User) { u.FarmerID = existing.FarmerID },
+		"phone_number": func(u *users.User) { u.PhoneNumber = existing.PhoneNumber },
+	}
+	for column, clash := range tests {
+		t.Run(column, func(t *testing.T) {
+			u := &users.User{
+				FarmerID:    "BZ-NEW",
+				Email:       "new@example.com",
+				PhoneNumber: "+501 600-9999",
+				FirstName:   "New",
+				LastName:    "Farmer",
+				Password:    existing.Password,
+			}
+			clash(u)
+
+			err := models.Users.Insert(t.Context(), u)
+			if err == nil || err.Error() != internalErrors.ErrDuplicateValue(column).Error() {
+				t.Errorf("Insert error = %v, want duplicate %s", err, column)
+			}
+		})
+	}
+}
+
+func TestUserUpdate(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	u := f.User()
+	stale := *u
+
+	u.FirstName = "Renamed"
+	u.IsVerified = testdb.Ptr(true)
+	if err := models.Users.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Version != 2 {
+		t.Errorf("version = %d, want 2", u.Version)
+	}
+
+	got, err := models.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FirstName != "Renamed" || !*got.IsVerified || got.Version != 2 {
+		t.Errorf("got %+v, want the update applied", got)
+	}
+
+	stale.LastName = "Lost"
+	if err := models.Users.Update(ctx, &stale); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("stale Update error = %v, want ErrEditConflict", err)
+	}
+
+	other := f.User()
+	got.Email = other.Email
+	if err := models.Users.Update(ctx, got); err == nil || err.Error() != internalErrors.ErrDuplicateValue("email").Error() {
+		t.Errorf("Update error = %v, want duplicate email", err)
+	}
+}
+
+func TestUserUpdatePassword(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	u := f.User()
+	if err := u.Password.Set("a-new-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.UpdatePassword(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := got.Password.Matches("a-new-password"); !ok {
+		t.Error("new password does not match")
+	}
+	if got.Version != 2 {
+		t.Errorf("version = %d, want 2", got.Version)
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	u := f.User()
+	admin := f.User()
+	token, err := models.Tokens.New(ctx, u.ID, time.Hour, users.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.SoftDelete(ctx, u.ID, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*got.IsDeleted || got.DeletedAt == nil || got.DeletedBy == nil || *got.DeletedBy != admin.ID {
+		t.Errorf("got %+v, want it deleted by %d", got, admin.ID)
+	}
+	if _, err := models.Tokens.GetUserToken(ctx, users.ScopeAuthentication, token.Plaintext); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetUserToken of a deleted user error = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Users.Update(ctx, got); !errors.Is(err, internalErrors.ErrEditConflict) {
+		t.Errorf("Update of a deleted user error = %v, want ErrEditConflict", err)
+	}
+	if err := models.Users.SoftDelete(ctx, u.ID, admin.ID); !errors.Is(err, internalErrors.ErrAlreadyDeleted) {
+		t.Errorf("second SoftDelete error = %v, want ErrAlreadyDeleted", err)
+	}
+
+	if err := models.Users.Restore(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err = models.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.IsDeleted || got.DeletedAt != nil {
+		t.Errorf("got %+v, want it restored", got)
+	}
+	if _, err := models.Tokens.GetUserToken(ctx, users.ScopeAuthentication, token.Plaintext); err != nil {
+		t.Errorf("GetUserToken of a restored user error = %v", err)
+	}
+	if err := models.Users.Restore(ctx, u.ID); !errors.Is(err, internalErrors.ErrNotDeleted) {
+		t.Errorf("second Restore error = %v, want ErrNotDeleted", err)
+	}
+
+	if err := models.Users.DeleteHard(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Users.GetByID(ctx, u.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID error = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Users.DeleteHard(ctx, u.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("second DeleteHard error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestUserPurge(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	admin := f.User()
+	u := f.User()
+	c := f.Cattle(func(c *cattle.Cattle) { c.OwnerID = int(u.ID) })
+	if err := models.Users.SoftDelete(ctx, u.ID, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := models.Users.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge before the deletion = %d, %v, want nothing purged", n, err)
+	}
+
+	// Removing the admin leaves the user they deleted in place, deleted by nobody.
+	if err := models.Users.DeleteHard(ctx, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := models.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DeletedAt == nil || got.DeletedBy != nil {
+		t.Errorf("got %+v, want it still deleted, by nobody", got)
+	}
+
+	if n, err := models.Users.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want 1 purged", n, err)
+	}
+	if _, err := models.Users.GetByID(ctx, u.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Cattle.GetByID(ctx, c.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("cattle of a purged user: GetByID error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestUserGetNotFound(t *testing.T) {
+	models := testdb.Models(t)
+	ctx := t.Context()
+
+	if _, err := models.Users.GetByID(ctx, 404); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByID error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Users.GetByEmail(ctx, "nobody@example.com"); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByEmail error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := models.Users.GetByFarmerID(ctx, "BZ-404"); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("GetByFarmerID error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestUserGetAll(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	ana := f.User(func(u *users.User) { u.FirstName, u.LastName = "Ana", "Chan" })
+	f.User(func(u *users.User) { u.FirstName, u.LastName = "Ben", "Chan"; u.IsVerified = testdb.Ptr(true) })
+	carl := f.User(func(u *users.User) { u.FirstName, u.LastName = "Carl", "Young" })
+	if err := models.Users.SoftDelete(ctx, carl.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter users.UserFilters
+		want   []string
+	}{
+		{"live by id", users.UserFilters{Filters: testdb.FirstPage("id")}, []string{"Ana", "Ben"}},
+		{"all by id", users.UserFilters{WithDeleted: true, Filters: testdb.FirstPage("id")}, []string{"Ana", "Ben", "Carl"}},
+		{"all by id descending", users.UserFilters{WithDeleted: true, Filters: testdb.FirstPage("-id")}, []string{"Carl", "Ben", "Ana"}},
+		{"by first name descending", users.UserFilters{WithDeleted: true, Filters: testdb.FirstPage("-first_name")}, []string{"Carl", "Ben", "Ana"}},
+		{"name", users.UserFilters{Name: "chan", Filters: testdb.FirstPage("id")}, []string{"Ana", "Ben"}},
+		{"email", users.UserFilters{Email: ana.Email, Filters: testdb.FirstPage("id")}, []string{"Ana"}},
+		{"farmer id", users.UserFilters{FarmerID: ana.FarmerID, Filters: testdb.FirstPage("id")}, []string{"Ana"}},
+		{"verified", users.UserFilters{IsVerified: testdb.Ptr(true), Filters: testdb.FirstPage("id")}, []string{"Ben"}},
+		{"not deleted", users.UserFilters{IsDeleted: testdb.Ptr(false), Filters: testdb.FirstPage("id")}, []string{"Ana", "Ben"}},
+		{"deleted", users.UserFilters{IsDeleted: testdb.Ptr(true), Filters: testdb.FirstPage("id")}, []string{"Carl"}},
+		{"no match", users.UserFilters{Name: "nobody", Filters: testdb.FirstPage("id")}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := models.Users.GetAll(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, u := range got {
+				names = append(names, u.FirstName)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("got %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", names, tt.want)
+				}
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("total records = %d, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+
+	paged := users.UserFilters{WithDeleted: true, Filters: testdb.FirstPage("id")}
+	paged.Filters.Page, paged.Filters.PageSize = 2, 2
+	got, metadata, err := models.Users.GetAll(ctx, &paged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != carl.ID || metadata.LastPage != 2 || metadata.TotalRecords != 3 {
+		t.Errorf("page 2 = %d users, metadata %+v; want Carl on the last of 2 pages", len(got), metadata)
+	}
+}
@@ -0,0 +1,323 @@
+// File: internal/data/watchlists/watchlists.go
+
+// Package watchlists keeps the listings and sellers users watch. Changes to a watched listing queue
+// a notification for each of its watchers in the same transaction as the change, and the alerts job
+// emails them.
+package watchlists
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/audit"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/database"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Event is something that happened to a watched listing.
+type Event string
+
+const (
+	EventPriceChanged Event = "price_changed"
+	EventSold         Event = "sold"
+	EventExpiring     Event = "expiring"
+)
+
+// Kinds lists what can be watched, for filtering a watchlist.
+var Kinds = []string{"listing", "seller"}
+
+// Watch is a listing, or a seller and so every listing of theirs, that a user is watching.
+type Watch struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	ListingID *int64    `json:"listing_id"`
+	SellerID  *int64    `json:"seller_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Notification tells a watcher about an event of a watched listing.
+type Notification struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	ListingID int64      `json:"listing_id"`
+	Event     Event      `json:"event"`
+	Detail    string     `json:"detail"` // e.g. "cow from 4 to 3 per kg"
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at"`
+}
+
+// WatchFilter holds the filters for listing watches.
+type WatchFilter struct {
+	UserID  *int64
+	Kind    string // "listing" or "seller", or empty for both
+	Default filters.Filters
+}
+
+// WatchModel stores watches and their notifications in Postgres.
+type WatchModel struct {
+	DB      database.DBTX
+	Timeout time.Duration
+}
+
+// WatchRepository is the interface for storing watches and sending their notifications.
+// Notifications are bookkeeping of the alerts job, so they are left out of the audit trail.
+type WatchRepository interface {
+	Insert(ctx context.Context, w *Watch) error
+	Delete(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*Watch, error)
+	GetAll(ctx context.Context, filter *WatchFilter) ([]*Watch, filters.MetaData, error)
+	CountWatchers(ctx context.Context, listingID int64) (int, error)
+	GetPendingNotifications(ctx context.Context) ([]*Notification, error)
+	MarkNotificationsSent(ctx context.Context, ids []int64, sentAt time.Time) ([]int64, error)
+}
+
+// ValidateWatch checks that a watch is of exactly one listing or seller.
+func ValidateWatch(v *validator.Validator, w *Watch) {
+	v.Check(w.ListingID != nil || w.SellerID != nil, "listing_id", "must be provided unless seller_id is")
+	v.Check(w.ListingID == nil || w.SellerID == nil, "seller_id", "must not be provided together with listing_id")
+	v.Check(w.ListingID == nil || *w.ListingID > 0, "listing_id", "must be greater than zero")
+	v.Check(w.SellerID == nil || *w.SellerID > 0, "seller_id", "must be greater than zero")
+}
+
+// ValidateWatchFilter validates the kind and paging of a watchlist query.
+func ValidateWatchFilter(v *validator.Validator, f *WatchFilter) {
+	filters.ValidateFilters(v, f.Default)
+	v.Check(f.Kind == "" || v.IsPermitted(f.Kind, Kinds...), "kind", "must be listing or seller")
+}
+
+// Notify queues a notification of event for everyone watching a listing, directly or through its
+// seller, other than the seller. Run it on the transaction making the change, so that watchers are
+// only told about changes that were committed.
+func Notify(ctx context.Context, tx database.DBTX, listingID int64, event Event, detail string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO watch_notifications (user_id, listing_id, event, detail)
+		SELECT DISTINCT w.user_id, l.id, $2::watch_event_enum, $3::text
+		FROM watches AS w
+		JOIN listings AS l ON w.listing_id = l.id OR w.seller_id = l.user_id
+		WHERE l.id = $1 AND w.user_id <> l.user_id`, listingID, event, detail)
+	if err != nil {
+		return errors.WrapInsertError(err, "WatchNotifications")
+	}
+	return nil
+}
+
+/****************************************************************************************
+ *										Methods											*
+ ***************************************************************************************/
+
+// Insert adds a new watch. A user can only watch a listing or seller once.
+func (m *WatchModel) Insert(ctx context.Context, w *Watch) error {
+	query := `
+		INSERT INTO watches (user_id, listing_id, seller_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, w.UserID, w.ListingID, w.SellerID).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.IsForeignKeyViolation(err):
+			return errors.ErrForeignKeyViolation
+		case errors.IsUniqueViolation(err, "user_id, listing_id"):
+			return errors.ErrDuplicateValue("listing_id")
+		case errors.IsUniqueViolation(err, "user_id, seller_id"):
+			return errors.ErrDuplicateValue("seller_id")
+		default:
+			return errors.WrapInsertError(err, "Watches")
+		}
+	}
+	if err := audit.Record(ctx, tx, "watches", w.ID, audit.ActionInsert, nil, w); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete permanently removes a watch. Notifications already queued for it are still sent.
+func (m *WatchModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := database.Begin(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := &WatchModel{DB: tx, Timeout: m.Timeout}
+	before, err := current.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM watches WHERE id = $1`, id)
+	if err != nil {
+		return errors.WrapDeleteError(err, "Watches")
+	}
+	if err := audit.Record(ctx, tx, "watches", id, audit.ActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByID retrieves a watch by its ID.
+func (m *WatchModel) GetByID(ctx context.Context, id int64) (*Watch, error) {
+	query := `SELECT id, user_id, listing_id, seller_id, created_at FROM watches WHERE id = $1`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var w Watch
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&w.ID, &w.UserID, &w.ListingID, &w.SellerID, &w.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.ErrNoRows(err):
+			return nil, errors.ErrRecordNotFound
+		default:
+			return nil, errors.WrapGetError(err, "Watches")
+		}
+	}
+	return &w, nil
+}
+
+// GetAll returns a page of the watches matching filter.
+func (m *WatchModel) GetAll(ctx context.Context, filter *WatchFilter) ([]*Watch, filters.MetaData, error) {
+	page := filter.Default.SQL(nil, "id", 5)
+	query := fmt.Sprintf(`
+		SELECT %s, %s, id, user_id, listing_id, seller_id, created_at
+		FROM watches
+		WHERE ($1::bigint IS NULL OR user_id = $1)
+		AND ($2 = '' OR ($2 = 'listing' AND listing_id IS NOT NULL) OR ($2 = 'seller' AND seller_id IS NOT NULL))
+		AND %s
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, page.Count, page.SortKey, page.Where, page.OrderBy)
+
+	args := []any{filter.UserID, filter.Kind, filter.Default.Limit(), filter.Default.Offset()}
+	args = append(args, page.Args...)
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Watches")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	watches := []*Watch{}
+	keys := []filters.Key{}
+	for rows.Next() {
+		var w Watch
+		var key filters.Key
+		err := rows.Scan(&totalRecords, &key.Values, &w.ID, &w.UserID, &w.ListingID, &w.SellerID, &w.CreatedAt)
+		if err != nil {
+			return nil, filters.EmptyMetaData, errors.WrapGetAllError(err, "Watches")
+		}
+		key.ID = w.ID
+		watches = append(watches, &w)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, filters.EmptyMetaData, err
+	}
+
+	watches, metaData := filters.Paginate(filter.Default, watches, keys, totalRecords)
+	return watches, metaData, nil
+}
+
+// CountWatchers returns how many users are watching a listing, directly or through its seller.
+// Someone watching both is counted once, and the seller is never counted.
+func (m *WatchModel) CountWatchers(ctx context.Context, listingID int64) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT w.user_id)
+		FROM watches AS w
+		JOIN listings AS l ON w.listing_id = l.id OR w.seller_id = l.user_id
+		WHERE l.id = $1 AND w.user_id <> l.user_id`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var count int
+	if err := m.DB.QueryRowContext(ctx, query, listingID).Scan(&count); err != nil {
+		return 0, errors.WrapGetError(err, "Watches")
+	}
+	return count, nil
+}
+
+// GetPendingNotifications returns the notifications not emailed yet whose watcher has not been
+// deleted, grouped by watcher and oldest first.
+func (m *WatchModel) GetPendingNotifications(ctx context.Context) ([]*Notification, error) {
+	query := `
+		SELECT id, user_id, listing_id, event, detail, created_at, sent_at
+		FROM watch_notifications
+		WHERE sent_at IS NULL
+		AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		ORDER BY user_id, id`
+
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.WrapGetAllError(err, "WatchNotifications")
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ListingID, &n.Event, &n.Detail, &n.CreatedAt, &n.SentAt); err != nil {
+			return nil, errors.WrapGetAllError(err, "WatchNotifications")
+		}
+		notifications = append(notifications, &n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationsSent claims the given notifications that are still unsent for an email going
+// out at sentAt, and returns the ones it claimed in id order. Notifications already sent, e.g. by a
+// concurrent run, are left alone.
+func (m *WatchModel) MarkNotificationsSent(ctx context.Context, ids []int64, sentAt time.Time) ([]int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, `
+		UPDATE watch_notifications SET sent_at = $2
+		WHERE id = ANY($1::bigint[]) AND sent_at IS NULL
+		RETURNING id`, pq.Array(ids), sentAt)
+	if err != nil {
+		return nil, errors.WrapUpdateError(err, "WatchNotifications")
+	}
+	defer rows.Close()
+
+	claimed := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.WrapUpdateError(err, "WatchNotifications")
+		}
+		claimed = append(claimed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WrapUpdateError(err, "WatchNotifications")
+	}
+	slices.Sort(claimed)
+	return claimed, nil
+}
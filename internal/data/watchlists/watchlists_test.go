@@ -0,0 +1,175 @@
+// File: internal/data/watchlists/watchlists_test.go
+package watchlists_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/testdb"
+)
+
+func TestWatchInsertAndDelete(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	buyer := f.User()
+	seller := f.User()
+	listing := f.Listing()
+	byListing := f.Watch(func(w *watchlists.Watch) { w.UserID, w.ListingID = buyer.ID, &listing.ID })
+	bySeller := f.Watch(func(w *watchlists.Watch) { w.UserID, w.SellerID = buyer.ID, &seller.ID })
+	f.Watch()
+
+	if byListing.ID == 0 || byListing.CreatedAt.IsZero() {
+		t.Fatalf("inserted watch = %+v", byListing)
+	}
+
+	again := &watchlists.Watch{UserID: buyer.ID, ListingID: &listing.ID}
+	if err := models.Watches.Insert(ctx, again); err == nil || err.Error() != internalErrors.ErrDuplicateValue("listing_id").Error() {
+		t.Errorf("second watch of the listing: got %v, want a duplicate listing_id", err)
+	}
+	again = &watchlists.Watch{UserID: buyer.ID, SellerID: &seller.ID}
+	if err := models.Watches.Insert(ctx, again); err == nil || err.Error() != internalErrors.ErrDuplicateValue("seller_id").Error() {
+		t.Errorf("second watch of the seller: got %v, want a duplicate seller_id", err)
+	}
+	missing := &watchlists.Watch{UserID: buyer.ID, ListingID: testdb.Ptr(int64(1 << 40))}
+	if err := models.Watches.Insert(ctx, missing); !errors.Is(err, internalErrors.ErrForeignKeyViolation) {
+		t.Errorf("watch of a missing listing: got %v, want ErrForeignKeyViolation", err)
+	}
+
+	tests := []struct {
+		kind string
+		want []int64
+	}{
+		{"", []int64{byListing.ID, bySeller.ID}},
+		{"listing", []int64{byListing.ID}},
+		{"seller", []int64{bySeller.ID}},
+	}
+	for _, tt := range tests {
+		got, metadata, err := models.Watches.GetAll(ctx, &watchlists.WatchFilter{UserID: &buyer.ID, Kind: tt.kind, Default: testdb.FirstPage("id")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []int64{}
+		for _, w := range got {
+			ids = append(ids, w.ID)
+		}
+		if len(ids) != len(tt.want) || ids[0] != tt.want[0] || metadata.TotalRecords != len(tt.want) {
+			t.Errorf("watchlist of kind %q = %v, want %v", tt.kind, ids, tt.want)
+		}
+	}
+
+	if err := models.Watches.Delete(ctx, byListing.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Watches.GetByID(ctx, byListing.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("get after delete: got %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Watches.Delete(ctx, byListing.ID); !errors.Is(err, internalErrors.ErrRecordNotFound) {
+		t.Errorf("second delete: got %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestWatchNotifications(t *testing.T) {
+	models := testdb.Models(t)
+	f := testdb.NewFactory(t, models)
+	ctx := t.Context()
+
+	seller := f.User()
+	listing := f.Listing(func(l *listings.Listing) { l.UserID = seller.ID })
+	price := f.ListingPrice(func(lp *listings.ListingPrice) { lp.ListingID, lp.PricePerKg = listing.ID, 4 })
+
+	// One watcher of the listing, one of the seller and one of both; the seller's own watch and a
+	// watch of another listing bring no one else in
+	a := f.Watch(func(w *watchlists.Watch) { w.ListingID = &listing.ID })
+	b := f.Watch(func(w *watchlists.Watch) { w.SellerID = &seller.ID })
+	c := f.Watch(func(w *watchlists.Watch) { w.ListingID = &listing.ID })
+	f.Watch(func(w *watchlists.Watch) { w.UserID, w.SellerID = c.UserID, &seller.ID })
+	f.Watch(func(w *watchlists.Watch) { w.UserID, w.ListingID = seller.ID, &listing.ID })
+	f.Watch()
+
+	count, err := models.Watches.CountWatchers(ctx, listing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("watchers = %d, want 3", count)
+	}
+
+	// A change of quantity alone is not worth telling anyone about
+	price.Quantity = 8
+	if err := models.ListingPrices.Update(ctx, price); err != nil {
+		t.Fatal(err)
+	}
+	price.PricePerKg = 3
+	if err := models.ListingPrices.Update(ctx, price); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Listings.MarkSold(ctx, listing.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Listings.MarkSold(ctx, listing.ID); !errors.Is(err, internalErrors.ErrAlreadySold) {
+		t.Errorf("second sale: got %v, want ErrAlreadySold", err)
+	}
+
+	sold, err := models.Listings.GetByID(ctx, listing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sold.SoldAt == nil || sold.IsActive == nil || *sold.IsActive || sold.Version != listing.Version+1 {
+		t.Errorf("sold listing = %+v, want sold, inactive and at the next version", sold)
+	}
+
+	pending, err := models.Watches.GetPendingNotifications(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type note struct {
+		userID int64
+		event  watchlists.Event
+		detail string
+	}
+	want := []note{
+		{a.UserID, watchlists.EventPriceChanged, "cow from 4 to 3 per kg"}, {a.UserID, watchlists.EventSold, ""},
+		{b.UserID, watchlists.EventPriceChanged, "cow from 4 to 3 per kg"}, {b.UserID, watchlists.EventSold, ""},
+		{c.UserID, watchlists.EventPriceChanged, "cow from 4 to 3 per kg"}, {c.UserID, watchlists.EventSold, ""},
+	}
+	if len(pending) != len(want) {
+		t.Fatalf("got %d pending notifications, want %d", len(pending), len(want))
+	}
+	ids := []int64{}
+	for i, n := range pending {
+		if got := (note{n.UserID, n.Event, n.Detail}); got != want[i] || n.ListingID != listing.ID {
+			t.Errorf("notification %d = %+v, want %+v", i, got, want[i])
+		}
+		ids = append(ids, n.ID)
+	}
+
+	claimed, err := models.Watches.MarkNotificationsSent(ctx, ids[:2], time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claimed, ids[:2]) {
+		t.Errorf("claimed = %v, want %v", claimed, ids[:2])
+	}
+	// Claiming them again, as a concurrent run would, gets none of the ones already sent
+	claimed, err = models.Watches.MarkNotificationsSent(ctx, ids[:3], time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claimed, ids[2:3]) {
+		t.Errorf("claimed again = %v, want %v", claimed, ids[2:3])
+	}
+	pending, err = models.Watches.GetPendingNotifications(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 || pending[0].UserID != b.UserID {
+		t.Errorf("pending after sending the first watcher's and one more = %+v, want the other 3", pending)
+	}
+}
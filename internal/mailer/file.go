@@ -0,0 +1,53 @@
+// File: internal/mailer/file.go
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileTransport writes every email to a directory as an .eml file instead of sending it, for
+// development without an SMTP server. The files open in any mail client.
+type FileTransport struct {
+	dir string
+}
+
+// NewFileTransport creates the directory if needed and returns a FileTransport for it.
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if dir == "" {
+		return nil, errors.New("mail directory must be provided")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+// Send writes msg to a temporary file and renames it into place, so readers never see partial
+// files. The files are named after the time they were written, so they sort oldest first.
+func (t *FileTransport) Send(msg *Message) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := filepath.Join(t.dir, time.Now().UTC().Format("20060102T150405.000000000Z")+"-"+hex.EncodeToString(suffix)+".eml")
+
+	tmp, err := os.CreateTemp(t.dir, ".eml-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	_, err = msg.mime().WriteTo(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -5,9 +5,7 @@ import (
 	"bytes"
 	"embed"
 	"html/template"
-	"time"
-
-	"github.com/go-mail/mail"
+	texttemplate "text/template"
 )
 
 //go:embed "templates/*"
@@ -15,62 +13,57 @@ var templateFS embed.FS // embed the templates directory
 
 // Mailer struct to hold mailer configuration and state
 type Mailer struct {
-	dialer *mail.Dialer // SMTP dialer for sending emails
-	sender string       // sender email address
+	transport Transport // delivers the rendered emails
+	sender    string    // sender email address
 }
 
-// New creates a new Mailer instance with the provided SMTP configuration
-func New(host string, port int, username, password, sender string) *Mailer {
-	dialer := mail.NewDialer(host, port, username, password) // create a new SMTP dialer
-	dialer.Timeout = 5 * time.Second
-
+// New creates a new Mailer instance that renders emails from sender and hands them to transport
+func New(transport Transport, sender string) *Mailer {
 	// return a pointer to a new Mailer instance
 	return &Mailer{
-		dialer: dialer,
-		sender: sender,
+		transport: transport,
+		sender:    sender,
 	}
 }
 
 // Send sends an email using the Mailer instance
 func (m *Mailer) Send(to, templateFile string, data any) error {
-	tmpl, err := template.ParseFS(templateFS, "templates/"+templateFile) // parse the email template
+	// The subject and plain body are not HTML, so they are rendered without HTML escaping
+	textTmpl, err := texttemplate.ParseFS(templateFS, "templates/"+templateFile) // parse the email template as text
+	if err != nil {
+		return err // return error if template parsing fails
+	}
+	htmlTmpl, err := template.ParseFS(templateFS, "templates/"+templateFile) // parse the email template as HTML
 	if err != nil {
 		return err // return error if template parsing fails
 	}
 
-	subject := new(bytes.Buffer)                         // buffer to hold the email subject
-	err = tmpl.ExecuteTemplate(subject, "subject", data) // execute the subject template
+	subject := new(bytes.Buffer)                             // buffer to hold the email subject
+	err = textTmpl.ExecuteTemplate(subject, "subject", data) // execute the subject template
 	if err != nil {
 		return err // return error if subject template execution fails
 	}
 
-	plainBody := new(bytes.Buffer)                           // buffer to hold the plain text body
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data) // execute the plain body template
+	plainBody := new(bytes.Buffer)                               // buffer to hold the plain text body
+	err = textTmpl.ExecuteTemplate(plainBody, "plainBody", data) // execute the plain body template
 	if err != nil {
 		return err // return error if plain body template execution fails
 	}
 
-	htmlBody := new(bytes.Buffer)                          // buffer to hold the HTML body
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data) // execute the HTML body template
+	htmlBody := new(bytes.Buffer)                              // buffer to hold the HTML body
+	err = htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data) // execute the HTML body template
 	if err != nil {
 		return err // return error if HTML body template execution fails
 	}
 
-	msg := mail.NewMessage()                           // create a new email message
-	msg.SetHeader("From", m.sender)                    // set the sender header
-	msg.SetHeader("To", to)                            // set the recipient header
-	msg.SetHeader("Subject", subject.String())         // set the subject header
-	msg.SetBody("text/plain", plainBody.String())      // set the plain text body
-	msg.AddAlternative("text/html", htmlBody.String()) // add the HTML body as an alternative
-
-	// Sends the email 3 times before returning an error
-	for i := 0; i < 3; i++ {
-		err = m.dialer.DialAndSend(msg) // attempt to send the email
-		if err == nil {
-			break // break the loop if sending is successful
-		}
-		time.Sleep(500 * time.Millisecond) // wait before retrying
+	msg := &Message{
+		From:      m.sender,           // the sender
+		To:        to,                 // the recipient
+		Subject:   subject.String(),   // the rendered subject
+		PlainBody: plainBody.String(), // the plain text body
+		HTMLBody:  htmlBody.String(),  // the HTML body, sent as an alternative
 	}
 
-	return err // return any error encountered during sending
+	// Sends the email once; the job queue retries failed emails with backoff
+	return m.transport.Send(msg)
 }
@@ -0,0 +1,123 @@
+// File: internal/mailer/mailer_test.go
+package mailer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/mailer"
+)
+
+// reminder is the data of a listing expiry reminder.
+var reminder = map[string]any{
+	"firstName":  "Ana",
+	"title":      "Brahman & Nelore steers",
+	"expiresAt":  "2 Jan 2026 15:04 UTC",
+	"listingURL": "http://web/listings/7",
+	"renewURL":   "http://web/listings/7/renew",
+}
+
+func TestMailerSend(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	m := mailer.New(transport, "Cash Cow <no-reply@cashcow.bz>")
+
+	if err := m.Send("ana@example.com", "listing_expiry_reminder.tmpl", reminder); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := transport.To("ANA@example.com")
+	if len(sent) != 1 {
+		t.Fatalf("emails to ana = %d, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.From != "Cash Cow <no-reply@cashcow.bz>" || msg.To != "ana@example.com" {
+		t.Errorf("email from %q to %q, want the sender to ana", msg.From, msg.To)
+	}
+	if want := `Your listing "Brahman & Nelore steers" expires soon`; msg.Subject != want {
+		t.Errorf("subject = %q, want %q", msg.Subject, want)
+	}
+	if !strings.Contains(msg.PlainBody, "Hi Ana,") || !strings.Contains(msg.PlainBody, "http://web/listings/7/renew") {
+		t.Errorf("plain body = %q, want the greeting and renew link", msg.PlainBody)
+	}
+	if !strings.Contains(msg.HTMLBody, "Brahman &amp; Nelore steers") {
+		t.Errorf("HTML body = %q, want the title escaped", msg.HTMLBody)
+	}
+
+	if err := m.Send("ben@example.com", "missing.tmpl", nil); err == nil {
+		t.Error("Send of a missing template succeeded, want an error")
+	}
+	if last, ok := transport.Last(); !ok || last.To != "ana@example.com" {
+		t.Errorf("last email = %+v, %v, want the one to ana", last, ok)
+	}
+}
+
+func TestMemoryTransportFail(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	m := mailer.New(transport, "no-reply@cashcow.bz")
+
+	down := errors.New("smtp is down")
+	transport.Fail(down)
+	if err := m.Send("ana@example.com", "listing_expiry_reminder.tmpl", reminder); !errors.Is(err, down) {
+		t.Errorf("Send error = %v, want the transport's error", err)
+	}
+	if n := len(transport.Messages()); n != 0 {
+		t.Errorf("emails kept = %d, want none while failing", n)
+	}
+
+	transport.Fail(nil)
+	if err := m.Send("ana@example.com", "listing_expiry_reminder.tmpl", reminder); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(transport.Messages()); n != 1 {
+		t.Errorf("emails kept = %d, want 1 once recovered", n)
+	}
+	transport.Reset()
+	if _, ok := transport.Last(); ok {
+		t.Error("Last after Reset found an email, want none")
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	transport, err := mailer.NewTransport(mailer.Config{Transport: mailer.TransportFile, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mailer.New(transport, "no-reply@cashcow.bz")
+
+	if err := m.Send("ana@example.com", "listing_expiry_reminder.tmpl", reminder); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("files written = %v, want one .eml file", files)
+	}
+	eml, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: ana@example.com", "From: no-reply@cashcow.bz", "Subject: ", "text/plain", "text/html"} {
+		if !strings.Contains(string(eml), want) {
+			t.Errorf(".eml file lacks %q:\n%s", want, eml)
+		}
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	if _, err := mailer.NewTransport(mailer.Config{Transport: "pigeon"}); !errors.Is(err, mailer.ErrUnknownTransport) {
+		t.Errorf("NewTransport of an unknown transport error = %v, want ErrUnknownTransport", err)
+	}
+	if _, err := mailer.NewTransport(mailer.Config{Transport: mailer.TransportFile}); err == nil {
+		t.Error("NewTransport of the file transport without a directory succeeded, want an error")
+	}
+	if _, err := mailer.NewTransport(mailer.Config{Transport: mailer.TransportSMTP, Host: "localhost", Port: 25}); err != nil {
+		t.Errorf("NewTransport of the SMTP transport error = %v", err)
+	}
+}
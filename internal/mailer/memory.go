@@ -0,0 +1,81 @@
+// File: internal/mailer/memory.go
+package mailer
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemoryTransport keeps every email it is given instead of sending it, so that tests can check
+// who was emailed and what the rendered subject and bodies said. It is safe for concurrent use.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMemoryTransport returns an empty MemoryTransport.
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+// Send keeps a copy of msg, or returns the error set by Fail without keeping it.
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return t.err
+	}
+	t.messages = append(t.messages, *msg)
+	return nil
+}
+
+// Fail makes every following Send return err, as an SMTP server that is down would. A nil err
+// makes Send succeed again.
+func (t *MemoryTransport) Fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+}
+
+// Messages returns every email sent so far, oldest first.
+func (t *MemoryTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.messages)
+}
+
+// To returns the emails sent to the recipient so far, oldest first. Addresses are compared
+// case-insensitively.
+func (t *MemoryTransport) To(recipient string) []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sent []Message
+	for _, msg := range t.messages {
+		if strings.EqualFold(msg.To, recipient) {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+// Last returns the email sent most recently, and false if none has been.
+func (t *MemoryTransport) Last() (Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.messages) == 0 {
+		return Message{}, false
+	}
+	return t.messages[len(t.messages)-1], true
+}
+
+// Reset forgets the emails sent so far.
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
@@ -0,0 +1,25 @@
+// File: internal/mailer/smtp.go
+package mailer
+
+import (
+	"time"
+
+	"github.com/go-mail/mail"
+)
+
+// SMTPTransport delivers emails through an SMTP server.
+type SMTPTransport struct {
+	dialer *mail.Dialer
+}
+
+// NewSMTPTransport returns an SMTPTransport for the server at host and port.
+func NewSMTPTransport(host string, port int, username, password string) *SMTPTransport {
+	dialer := mail.NewDialer(host, port, username, password)
+	dialer.Timeout = 5 * time.Second
+	return &SMTPTransport{dialer: dialer}
+}
+
+// Send dials the server and hands it msg.
+func (t *SMTPTransport) Send(msg *Message) error {
+	return t.dialer.DialAndSend(msg.mime())
+}
@@ -0,0 +1,75 @@
+// File: internal/mailer/transport.go
+package mailer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-mail/mail"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Transport names accepted by NewTransport.
+const (
+	TransportSMTP = "smtp"
+	TransportFile = "file"
+)
+
+// ErrUnknownTransport is returned by NewTransport for a transport it does not know.
+var ErrUnknownTransport = errors.New("unknown mail transport")
+
+// Message is a rendered email, ready to be delivered.
+type Message struct {
+	From      string
+	To        string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// Transport is implemented by every backend that can deliver emails.
+type Transport interface {
+	// Send delivers msg, or returns why it could not.
+	Send(msg *Message) error
+}
+
+// Config holds the settings needed to build a Transport and the Mailer around it.
+type Config struct {
+	Transport string // smtp or file
+	Host      string // SMTP host
+	Port      int    // SMTP port
+	Username  string
+	Password  string
+	Sender    string // the From address of every email
+	Dir       string // directory the file transport writes .eml files to
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// NewTransport returns the Transport selected by cfg.Transport.
+func NewTransport(cfg Config) (Transport, error) {
+	switch cfg.Transport {
+	case TransportSMTP:
+		return NewSMTPTransport(cfg.Host, cfg.Port, cfg.Username, cfg.Password), nil
+	case TransportFile:
+		return NewFileTransport(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTransport, cfg.Transport)
+	}
+}
+
+// mime builds the MIME message of msg, with the HTML body as an alternative to the plain one.
+func (msg *Message) mime() *mail.Message {
+	m := mail.NewMessage()
+	m.SetHeader("From", msg.From)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	m.SetBody("text/plain", msg.PlainBody)
+	m.AddAlternative("text/html", msg.HTMLBody)
+	return m
+}
@@ -0,0 +1,294 @@
+// File: internal/migrate/migrate.go
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// lockKey is the pg_advisory_lock key held while migrating, so replicas started together take turns.
+const lockKey int64 = 0x636173685f636f77 // "cash_cow"
+
+// Migration errors.
+var (
+	ErrDirty           = errors.New("database is dirty: a previous migration failed part way; fix the schema by hand, then run \"migrate force VERSION\"")
+	ErrUnknownVersion  = errors.New("database version has no matching migration file")
+	ErrNoDownMigration = errors.New("migration has no down file")
+)
+
+// filenameRX matches golang-migrate style file names, e.g. 000001_create_users_table.up.sql.
+var filenameRX = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is one numbered pair of SQL scripts.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes where the database stands relative to the embedded migrations.
+type Status struct {
+	Version int64       `json:"version"` // 0 when nothing has been applied
+	Dirty   bool        `json:"dirty"`
+	Pending []Migration `json:"-"`
+}
+
+// Migrator applies migrations, recording progress in the schema_migrations table used by the
+// migrate CLI so the two can be used interchangeably.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	Logger     *slog.Logger
+}
+
+// New loads the migrations in fsys and returns a Migrator for db.
+func New(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations, Logger: logger}, nil
+}
+
+// Load reads the *.up.sql and *.down.sql files at the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := filenameRX.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+/****************************************************************************************
+ *										Commands										*
+ ***************************************************************************************/
+
+// Up applies every pending migration and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		status, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return ErrDirty
+		}
+		for _, migration := range status.Pending {
+			if err := m.apply(ctx, conn, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			m.Logger.Info("applied migration", "version", migration.Version, "name", migration.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		status, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return ErrDirty
+		}
+
+		current := m.indexOf(status.Version)
+		if status.Version != 0 && current < 0 {
+			return ErrUnknownVersion
+		}
+		for ; reverted < steps && current >= 0; current-- {
+			migration := m.Migrations[current]
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrNoDownMigration)
+			}
+			previous := int64(0)
+			if current > 0 {
+				previous = m.Migrations[current-1].Version
+			}
+			if err := m.apply(ctx, conn, migration.Down, previous); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			m.Logger.Info("reverted migration", "version", migration.Version, "name", migration.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status reports the current version, whether it is dirty and which migrations are pending.
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	defer conn.Close()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return Status{}, err
+	}
+	return m.status(ctx, conn)
+}
+
+// Force records version as applied and clean without running anything. Use it after repairing a
+// dirty database by hand; version 0 means no migrations are applied.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.indexOf(version) < 0 {
+		return ErrUnknownVersion
+	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := setVersion(ctx, tx, version); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// locked runs fn on a dedicated connection holding the migration advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// apply runs one migration script and records the new version in the same transaction, so a
+// failed script rolls back cleanly instead of leaving the database dirty.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, newVersion int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := setVersion(ctx, tx, newVersion); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// status reads schema_migrations and works out which migrations are pending.
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) (Status, error) {
+	var s Status
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&s.Version, &s.Dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Status{}, err
+	}
+
+	for _, migration := range m.Migrations {
+		if migration.Version > s.Version {
+			s.Pending = append(s.Pending, migration)
+		}
+	}
+	return s, nil
+}
+
+// indexOf returns the position of version in m.Migrations, or -1.
+func (m *Migrator) indexOf(version int64) int {
+	for i, migration := range m.Migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// ensureTable creates schema_migrations in the layout the migrate CLI uses.
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`)
+	return err
+}
+
+// setVersion replaces the single schema_migrations row with a clean version; version 0 leaves the table empty.
+func setVersion(ctx context.Context, tx *sql.Tx, version int64) error {
+	if _, err := tx.ExecContext(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version)
+	return err
+}
@@ -0,0 +1,133 @@
+// File: internal/seed/data.go
+package seed
+
+import "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Region is a district to seed, keyed by its code.
+type Region struct {
+	Code string
+	Name string
+}
+
+// Area is a town or village to seed, keyed by its region code and name.
+type Area struct {
+	RegionCode string
+	Name       string
+	AreaType   locations.AreaType
+	Latitude   float64
+	Longitude  float64
+}
+
+// Breed is a breed to seed, keyed by its name.
+type Breed struct {
+	Name        string
+	Description string
+}
+
+/****************************************************************************************
+ *										Reference Data									*
+ ***************************************************************************************/
+
+// Regions are the six districts of Belize.
+var Regions = []Region{
+	{Code: "BZ", Name: "Belize"},
+	{Code: "CY", Name: "Cayo"},
+	{Code: "CZL", Name: "Corozal"},
+	{Code: "OW", Name: "Orange Walk"},
+	{Code: "SC", Name: "Stann Creek"},
+	{Code: "TOL", Name: "Toledo"},
+}
+
+// Areas are the main cattle-trading towns and villages of each district. Coordinates are the
+// approximate centre of each settlement, close enough for proximity search and map pins.
+var Areas = []Area{
+	// Belize District
+	{RegionCode: "BZ", Name: "Belize City", AreaType: locations.AreaTypeCity, Latitude: 17.5046, Longitude: -88.1962},
+	{RegionCode: "BZ", Name: "San Pedro", AreaType: locations.AreaTypeTown, Latitude: 17.9214, Longitude: -87.9611},
+	{RegionCode: "BZ", Name: "Ladyville", AreaType: locations.AreaTypeVillage, Latitude: 17.5550, Longitude: -88.2910},
+	{RegionCode: "BZ", Name: "Burrell Boom", AreaType: locations.AreaTypeVillage, Latitude: 17.5667, Longitude: -88.4000},
+	{RegionCode: "BZ", Name: "Hattieville", AreaType: locations.AreaTypeVillage, Latitude: 17.4500, Longitude: -88.3833},
+	{RegionCode: "BZ", Name: "Crooked Tree", AreaType: locations.AreaTypeVillage, Latitude: 17.7667, Longitude: -88.5333},
+	{RegionCode: "BZ", Name: "Sand Hill", AreaType: locations.AreaTypeVillage, Latitude: 17.6200, Longitude: -88.3500},
+
+	// Cayo District
+	{RegionCode: "CY", Name: "Belmopan", AreaType: locations.AreaTypeCity, Latitude: 17.2510, Longitude: -88.7590},
+	{RegionCode: "CY", Name: "San Ignacio", AreaType: locations.AreaTypeTown, Latitude: 17.1561, Longitude: -89.0714},
+	{RegionCode: "CY", Name: "Santa Elena", AreaType: locations.AreaTypeTown, Latitude: 17.1578, Longitude: -89.0617},
+	{RegionCode: "CY", Name: "Benque Viejo del Carmen", AreaType: locations.AreaTypeTown, Latitude: 17.0747, Longitude: -89.1394},
+	{RegionCode: "CY", Name: "Spanish Lookout", AreaType: locations.AreaTypeVillage, Latitude: 17.2300, Longitude: -88.9700},
+	{RegionCode: "CY", Name: "Georgeville", AreaType: locations.AreaTypeVillage, Latitude: 17.2000, Longitude: -88.9833},
+	{RegionCode: "CY", Name: "Valley of Peace", AreaType: locations.AreaTypeVillage, Latitude: 17.3300, Longitude: -88.8300},
+	{RegionCode: "CY", Name: "Teakettle", AreaType: locations.AreaTypeVillage, Latitude: 17.2200, Longitude: -88.8500},
+
+	// Corozal District
+	{RegionCode: "CZL", Name: "Corozal Town", AreaType: locations.AreaTypeTown, Latitude: 18.3936, Longitude: -88.3883},
+	{RegionCode: "CZL", Name: "Sarteneja", AreaType: locations.AreaTypeVillage, Latitude: 18.3556, Longitude: -88.1453},
+	{RegionCode: "CZL", Name: "Chunox", AreaType: locations.AreaTypeVillage, Latitude: 18.3000, Longitude: -88.3500},
+	{RegionCode: "CZL", Name: "Libertad", AreaType: locations.AreaTypeVillage, Latitude: 18.2800, Longitude: -88.4400},
+	{RegionCode: "CZL", Name: "Consejo", AreaType: locations.AreaTypeVillage, Latitude: 18.4500, Longitude: -88.3200},
+
+	// Orange Walk District
+	{RegionCode: "OW", Name: "Orange Walk Town", AreaType: locations.AreaTypeTown, Latitude: 18.0812, Longitude: -88.5633},
+	{RegionCode: "OW", Name: "Shipyard", AreaType: locations.AreaTypeVillage, Latitude: 17.9000, Longitude: -88.6100},
+	{RegionCode: "OW", Name: "Blue Creek", AreaType: locations.AreaTypeVillage, Latitude: 17.8900, Longitude: -88.9000},
+	{RegionCode: "OW", Name: "Guinea Grass", AreaType: locations.AreaTypeVillage, Latitude: 17.9600, Longitude: -88.5800},
+	{RegionCode: "OW", Name: "August Pine Ridge", AreaType: locations.AreaTypeVillage, Latitude: 17.8400, Longitude: -88.7300},
+	{RegionCode: "OW", Name: "Yo Creek", AreaType: locations.AreaTypeVillage, Latitude: 18.0800, Longitude: -88.6200},
+
+	// Stann Creek District
+	{RegionCode: "SC", Name: "Dangriga", AreaType: locations.AreaTypeTown, Latitude: 16.9697, Longitude: -88.2313},
+	{RegionCode: "SC", Name: "Hopkins", AreaType: locations.AreaTypeVillage, Latitude: 16.8593, Longitude: -88.2798},
+	{RegionCode: "SC", Name: "Placencia", AreaType: locations.AreaTypeVillage, Latitude: 16.5131, Longitude: -88.3662},
+	{RegionCode: "SC", Name: "Independence", AreaType: locations.AreaTypeVillage, Latitude: 16.5333, Longitude: -88.4167},
+	{RegionCode: "SC", Name: "Pomona", AreaType: locations.AreaTypeVillage, Latitude: 17.0000, Longitude: -88.3700},
+
+	// Toledo District
+	{RegionCode: "TOL", Name: "Punta Gorda", AreaType: locations.AreaTypeTown, Latitude: 16.0980, Longitude: -88.8100},
+	{RegionCode: "TOL", Name: "Big Falls", AreaType: locations.AreaTypeVillage, Latitude: 16.2600, Longitude: -88.8860},
+	{RegionCode: "TOL", Name: "San Antonio", AreaType: locations.AreaTypeVillage, Latitude: 16.2350, Longitude: -89.0240},
+	{RegionCode: "TOL", Name: "Barranco", AreaType: locations.AreaTypeVillage, Latitude: 16.0000, Longitude: -88.9200},
+}
+
+// Breeds is the standard breed catalogue, covering the zebu, composite, European beef and dairy
+// breeds common in Central American herds.
+var Breeds = []Breed{
+	{Name: "Brahman", Description: "Heat and tick tolerant zebu beef breed, the backbone of most Belizean herds."},
+	{Name: "Nelore", Description: "White zebu beef breed from Brazil, hardy on poor pasture."},
+	{Name: "Gyr", Description: "Zebu breed kept for milk, often crossed to produce Girolando."},
+	{Name: "Guzerat", Description: "Large dual-purpose zebu breed."},
+	{Name: "Indo-Brazil", Description: "Zebu beef breed developed in Brazil from Gyr, Guzerat and Nelore."},
+	{Name: "Brangus", Description: "Composite of Brahman and Angus combining hardiness with carcass quality."},
+	{Name: "Beefmaster", Description: "Composite of Brahman, Hereford and Shorthorn selected for fertility and growth."},
+	{Name: "Santa Gertrudis", Description: "Red composite of Brahman and Shorthorn bred for the tropics."},
+	{Name: "Senepol", Description: "Heat tolerant, naturally polled red beef breed from the Caribbean."},
+	{Name: "Angus", Description: "Polled British beef breed known for marbling."},
+	{Name: "Hereford", Description: "British beef breed with a white face, valued for docility."},
+	{Name: "Red Poll", Description: "Polled dual-purpose British breed."},
+	{Name: "Simmental", Description: "Large dual-purpose European breed with fast growth."},
+	{Name: "Charolais", Description: "Heavily muscled white French beef breed, popular as a terminal sire."},
+	{Name: "Holstein", Description: "High yielding black and white dairy breed."},
+	{Name: "Jersey", Description: "Small dairy breed producing milk high in butterfat."},
+	{Name: "Brown Swiss", Description: "Hardy dairy breed suited to crossing with zebu cattle."},
+	{Name: "Girolando", Description: "Gyr and Holstein composite, the leading tropical dairy cross."},
+}
+
+// Permissions are the permission codes checked by the API.
+var Permissions = []string{
+	"read:users",
+	"write:users",
+	"write:cattle",
+	"write:listings",
+	"write:breeds",
+	"write:areas",
+	"write:regions",
+	"read:audit",
+	"read:jobs",
+	"write:jobs",
+	"read:emails",
+	"write:emails",
+}
@@ -0,0 +1,155 @@
+// File: internal/seed/seed.go
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Result counts what an upsert did. Rows that already matched the reference data are left
+// untouched, so rerunning the seed reports them as unchanged.
+type Result struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+}
+
+// upserter is one step of the seed, run inside the shared transaction.
+type upserter struct {
+	name string
+	fn   func(ctx context.Context, tx *sql.Tx) (Result, error)
+}
+
+/****************************************************************************************
+ *										Seeding											*
+ ***************************************************************************************/
+
+// Run upserts all reference data in a single transaction. It is idempotent and safe to run on every deploy:
+// existing rows are matched on their natural keys and only changed when the reference data differs.
+func Run(ctx context.Context, db *sql.DB, logger *slog.Logger) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	steps := []upserter{
+		{name: "permissions", fn: upsertPermissions},
+		{name: "regions", fn: upsertRegions},
+		{name: "areas", fn: upsertAreas},
+		{name: "breeds", fn: upsertBreeds},
+	}
+	for _, step := range steps {
+		result, err := step.fn(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("seed %s: %w", step.name, err)
+		}
+		logger.Info("seeded "+step.name, "inserted", result.Inserted, "updated", result.Updated, "unchanged", result.Unchanged)
+	}
+
+	return tx.Commit()
+}
+
+// upsertPermissions inserts any missing permission codes.
+func upsertPermissions(ctx context.Context, tx *sql.Tx) (Result, error) {
+	query := `
+		INSERT INTO permissions (code)
+		VALUES ($1)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING TRUE
+	`
+	var result Result
+	for _, code := range Permissions {
+		if err := result.record(tx.QueryRowContext(ctx, query, code)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// upsertRegions inserts the districts, matching existing rows on their code.
+func upsertRegions(ctx context.Context, tx *sql.Tx) (Result, error) {
+	query := `
+		INSERT INTO regions (code, name)
+		VALUES ($1, $2)
+		ON CONFLICT (code) DO UPDATE
+		SET name = EXCLUDED.name
+		WHERE regions.name IS DISTINCT FROM EXCLUDED.name
+		RETURNING (xmax = 0)
+	`
+	var result Result
+	for _, r := range Regions {
+		if err := result.record(tx.QueryRowContext(ctx, query, r.Code, r.Name)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// upsertAreas inserts the towns and villages, matching existing rows on their region and name.
+func upsertAreas(ctx context.Context, tx *sql.Tx) (Result, error) {
+	query := `
+		INSERT INTO areas (region_id, name, area_type, latitude, longitude)
+		SELECT r.id, $2, $3::area_type_enum, $4, $5
+		FROM regions AS r
+		WHERE r.code = $1
+		ON CONFLICT (region_id, name) DO UPDATE
+		SET area_type = EXCLUDED.area_type, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, updated_at = NOW()
+		WHERE (areas.area_type, areas.latitude, areas.longitude) IS DISTINCT FROM (EXCLUDED.area_type, EXCLUDED.latitude, EXCLUDED.longitude)
+		RETURNING (xmax = 0)
+	`
+	var result Result
+	for _, a := range Areas {
+		if err := result.record(tx.QueryRowContext(ctx, query, a.RegionCode, a.Name, a.AreaType, a.Latitude, a.Longitude)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// upsertBreeds inserts the breed catalogue, matching existing rows on their name.
+func upsertBreeds(ctx context.Context, tx *sql.Tx) (Result, error) {
+	query := `
+		INSERT INTO breeds (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE
+		SET description = EXCLUDED.description, updated_at = NOW()
+		WHERE breeds.description IS DISTINCT FROM EXCLUDED.description
+		RETURNING (xmax = 0)
+	`
+	var result Result
+	for _, b := range Breeds {
+		if err := result.record(tx.QueryRowContext(ctx, query, b.Name, b.Description)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// record tallies one upsert. The statements return whether the row was freshly inserted
+// (xmax = 0), and no row at all when the conflict left the existing row untouched.
+func (r *Result) record(row *sql.Row) error {
+	var inserted bool
+	err := row.Scan(&inserted)
+	switch {
+	case err == sql.ErrNoRows:
+		r.Unchanged++
+	case err != nil:
+		return err
+	case inserted:
+		r.Inserted++
+	default:
+		r.Updated++
+	}
+	return nil
+}
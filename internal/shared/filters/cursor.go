@@ -0,0 +1,289 @@
+// File: internal/shared/filters/cursor.go
+
+package filters
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Key is the position of a row in a sorted result: its sort columns as text, nil for NULL, and its
+// id. Queries select the sort columns as text so that cursors work for every column type alike.
+type Key struct {
+	Values Values
+	ID     int64
+}
+
+// Values holds the sort columns of a row as text. It scans the JSON array selected by
+// Query.SortKey.
+type Values []*string
+
+// Scan implements the sql.Scanner interface.
+func (v *Values) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, v)
+	case string:
+		return json.Unmarshal([]byte(src), v)
+	default:
+		return fmt.Errorf("filters: cannot scan %T into Values", src)
+	}
+}
+
+// cursor is the signed content of a cursor string. It marks a row and which side of it the page
+// lies on, and remembers the sort it was made for, as it means nothing under another.
+type cursor struct {
+	Sort     string `json:"s"`
+	Values   Values `json:"v"`
+	ID       int64  `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Query holds the SQL fragments that page a query by a filter's sort, to be spliced into it.
+// Without a cursor they give the usual OFFSET paging with a total count; with one they give
+// keyset paging, which skips the count and stays fast however deep the page.
+type Query struct {
+	Count   string // the total record count column
+	SortKey string // the sort columns as a JSON array of text, to be scanned into a Key
+	Where   string // the condition keeping the rows past the cursor, TRUE without one
+	OrderBy string // the ORDER BY list
+	Args    []any  // the arguments of Where
+}
+
+var (
+	// ErrInvalidCursor is returned for a cursor that was tampered with or is not a cursor at all.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	cursorMu  sync.RWMutex
+	cursorKey = randomKey()
+)
+
+/****************************************************************************************
+ *										Signing											*
+ ***************************************************************************************/
+
+// SetCursorKey sets the secret cursors are signed with. Until it is called a random key is used,
+// so cursors only stay valid for the life of the process and across a single instance; that is
+// only good enough for development and tests, and the API refuses to start anywhere else without
+// a secret.
+func SetCursorKey(key []byte) {
+	cursorMu.Lock()
+	defer cursorMu.Unlock()
+	cursorKey = slices.Clone(key)
+}
+
+// randomKey returns a fresh signing key.
+func randomKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// sign returns the signature of payload.
+func sign(payload []byte) []byte {
+	cursorMu.RLock()
+	defer cursorMu.RUnlock()
+
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encode turns a cursor into the opaque string handed to clients.
+func (c cursor) encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(payload))
+}
+
+// decodeCursor checks the signature of a cursor string and returns its content.
+func decodeCursor(s string) (cursor, error) {
+	encoded, signature, ok := strings.Cut(s, ".")
+	if !ok {
+		return cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(payload)) {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// cursor returns the decoded cursor of the filter, if it has a valid one for its sort.
+func (f Filters) cursor() (cursor, bool) {
+	if f.Cursor == "" {
+		return cursor{}, false
+	}
+	c, err := decodeCursor(f.Cursor)
+	return c, err == nil && c.Sort == f.Sort
+}
+
+/****************************************************************************************
+ *										Queries											*
+ ***************************************************************************************/
+
+// IsCursor reports whether the filter pages by cursor rather than by page number.
+func (f Filters) IsCursor() bool {
+	_, ok := f.cursor()
+	return ok
+}
+
+// Position returns the row the filter's cursor marks and whether the page lies before it rather
+// than after it, for repositories that page without SQL. ok is false without a cursor.
+func (f Filters) Position() (key Key, backward bool, ok bool) {
+	c, ok := f.cursor()
+	return Key{Values: c.Values, ID: c.ID}, c.Backward, ok
+}
+
+// SQL returns the fragments that page a query by the filter's sort, with id as the tie breaker.
+// column maps a sort column name to its SQL expression; when nil the names are used as they are.
+// The arguments of Where are numbered from $n.
+//
+// Rows are always ordered by id ascending within equal sort values, and NULLs come last when
+// ascending and first when descending, as Postgres orders them by default.
+func (f Filters) SQL(column func(name string) string, id string, n int) Query {
+	if column == nil {
+		column = func(name string) string { return name }
+	}
+	keys := f.SortKeys()
+
+	var texts, order []string
+	for _, key := range keys {
+		texts = append(texts, "("+column(key.Column)+")::text")
+		order = append(order, column(key.Column)+" "+key.Direction())
+	}
+	q := Query{
+		Count:   "COUNT(*) OVER()",
+		SortKey: "json_build_array(" + strings.Join(texts, ", ") + ")",
+		Where:   "TRUE",
+		OrderBy: strings.Join(append(order, id+" ASC"), ", "),
+	}
+
+	c, ok := f.cursor()
+	if !ok {
+		return q
+	}
+	q.Count = "0"
+
+	// When paging backwards the query runs in reverse, so that the rows nearest the cursor come first
+	idOp := ">"
+	if c.Backward {
+		idOp = "<"
+		order = order[:0]
+		for _, key := range keys {
+			order = append(order, column(key.Column)+" "+direction(!key.Desc))
+		}
+		q.OrderBy = strings.Join(append(order, id+" DESC"), ", ")
+	}
+
+	// A row is past the cursor if it is beyond it in the first column that differs, or in the id
+	// when every column is the same: (a beyond) OR (a same AND b beyond) OR ... OR (all same AND id)
+	var same, conditions []string
+	for i, key := range keys {
+		expr, value := column(key.Column), c.Values[i]
+		desc := key.Desc != c.Backward
+
+		var beyond, equal string
+		if value == nil {
+			// NULLs sort last when ascending, so nothing but other NULLs follow them, and first
+			// when descending, so every value follows them
+			beyond = "FALSE"
+			if desc {
+				beyond = expr + " IS NOT NULL"
+			}
+			equal = expr + " IS NULL"
+		} else {
+			beyond = fmt.Sprintf("%s > $%d OR %s IS NULL", expr, n, expr)
+			if desc {
+				beyond = fmt.Sprintf("%s < $%d", expr, n)
+			}
+			equal = fmt.Sprintf("%s = $%d", expr, n)
+			q.Args = append(q.Args, *value)
+			n++
+		}
+
+		conditions = append(conditions, "("+strings.Join(append(slices.Clone(same), "("+beyond+")"), " AND ")+")")
+		same = append(same, equal)
+	}
+	conditions = append(conditions, "("+strings.Join(append(same, fmt.Sprintf("%s %s $%d::bigint", id, idOp, n)), " AND ")+")")
+	q.Args = append(q.Args, c.ID)
+
+	q.Where = "(" + strings.Join(conditions, " OR ") + ")"
+	return q
+}
+
+// direction returns the SQL keyword for a sort direction.
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+/****************************************************************************************
+ *										Results											*
+ ***************************************************************************************/
+
+// Paginate finishes a page read with Limit: it trims the extra row fetched to see whether more
+// follow, puts backward pages the right way round and works out the metadata. keys holds the Key
+// of each row, in the same order.
+func Paginate[T any](f Filters, rows []T, keys []Key, totalRecords int) ([]T, MetaData) {
+	c, ok := f.cursor()
+	if !ok {
+		metaData := CalculateMetaData(totalRecords, f.Page, f.PageSize)
+		if len(rows) > 0 && f.Page < metaData.LastPage {
+			metaData.NextCursor = f.newCursor(keys[len(keys)-1], false)
+		}
+		if len(rows) > 0 && f.Page > 1 {
+			metaData.PrevCursor = f.newCursor(keys[0], true)
+		}
+		return rows, metaData
+	}
+
+	more := len(rows) > f.PageSize
+	if more {
+		rows, keys = rows[:f.PageSize], keys[:f.PageSize]
+	}
+	if len(rows) == 0 {
+		return rows, EmptyMetaData
+	}
+	if c.Backward {
+		slices.Reverse(rows)
+		slices.Reverse(keys)
+	}
+
+	// The cursor itself marks a row on the near side, so the way back is always open
+	metaData := MetaData{PageSize: f.PageSize}
+	if more || c.Backward {
+		metaData.NextCursor = f.newCursor(keys[len(keys)-1], false)
+	}
+	if more || !c.Backward {
+		metaData.PrevCursor = f.newCursor(keys[0], true)
+	}
+	return rows, metaData
+}
+
+// newCursor returns the cursor for the page after, or before, the row at key.
+func (f Filters) newCursor(key Key, backward bool) string {
+	return cursor{Sort: f.Sort, Values: key.Values, ID: key.ID, Backward: backward}.encode()
+}
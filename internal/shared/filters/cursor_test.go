@@ -0,0 +1,275 @@
+// File: internal/shared/filters/cursor_test.go
+package filters_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// row is a row of a result sorted by name, then id.
+type row struct {
+	ID   int64
+	Name string
+}
+
+// keysOf returns the Key of each row.
+func keysOf(rows []row) []filters.Key {
+	keys := make([]filters.Key, len(rows))
+	for i, r := range rows {
+		keys[i] = filters.Key{Values: filters.Values{&r.Name}, ID: r.ID}
+	}
+	return keys
+}
+
+// rows returns the rows with ids from to to, named after their id.
+func rows(from, to int64) []row {
+	var result []row
+	for id := from; id <= to; id++ {
+		result = append(result, row{ID: id, Name: "cow " + string(rune('a'+id))})
+	}
+	return result
+}
+
+// byName is the first page of two rows sorted by name.
+var byName = filters.Filters{Page: 1, PageSize: 2, Sort: "name", SortSafelist: []string{"name", "-name", "id"}}
+
+// cursors returns the next and previous cursors of the second page of byName, holding rows 3 and 4.
+func cursors(t *testing.T) (next, prev string) {
+	t.Helper()
+	f := byName
+	f.Page = 2
+	page := rows(3, 4)
+	_, metaData := filters.Paginate(f, page, keysOf(page), 6)
+	if metaData.NextCursor == "" || metaData.PrevCursor == "" {
+		t.Fatalf("metadata of a middle page = %+v, want both cursors", metaData)
+	}
+	return metaData.NextCursor, metaData.PrevCursor
+}
+
+// cursorErrors returns the errors ValidateFilters reports for the filter's cursor.
+func cursorErrors(f filters.Filters) string {
+	v := validator.New()
+	filters.ValidateFilters(v, f)
+	return v.Errors["cursor"]
+}
+
+func TestCursorSigning(t *testing.T) {
+	filters.SetCursorKey([]byte("first secret"))
+	next, _ := cursors(t)
+
+	f := byName
+	f.Cursor = next
+	if msg := cursorErrors(f); msg != "" {
+		t.Fatalf("a cursor of ours was rejected: %s", msg)
+	}
+	key, backward, ok := f.Position()
+	if !ok || backward || key.ID != 4 || len(key.Values) != 1 || *key.Values[0] != rows(4, 4)[0].Name {
+		t.Errorf("Position = %+v, %v, %v, want forward past row 4", key, backward, ok)
+	}
+
+	payload, signature, _ := strings.Cut(next, ".")
+	flip := func(s string) string {
+		b := []byte(s)
+		if b[len(b)/2] == 'A' {
+			b[len(b)/2] = 'B'
+		} else {
+			b[len(b)/2] = 'A'
+		}
+		return string(b)
+	}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"tampered payload", flip(payload) + "." + signature},
+		{"tampered signature", payload + "." + flip(signature)},
+		{"unsigned", payload},
+		{"not base64", "!!!." + signature},
+		{"garbage", "cursor"},
+	}
+	for _, tt := range tests {
+		f := byName
+		f.Cursor = tt.cursor
+		if msg := cursorErrors(f); msg != "must be a cursor returned by a previous page" {
+			t.Errorf("%s: cursor errors = %q, want it rejected", tt.name, msg)
+		}
+		if f.IsCursor() {
+			t.Errorf("%s: IsCursor = true, want the cursor ignored", tt.name)
+		}
+	}
+
+	filters.SetCursorKey([]byte("second secret"))
+	if msg := cursorErrors(f); msg == "" {
+		t.Error("a cursor signed with the old secret was accepted")
+	}
+}
+
+func TestCursorOtherSort(t *testing.T) {
+	next, _ := cursors(t)
+
+	f := byName
+	f.Sort, f.Cursor = "-name", next
+	if msg := cursorErrors(f); msg != "was made for a different sort" {
+		t.Errorf("cursor errors = %q, want it rejected for its sort", msg)
+	}
+	if f.IsCursor() {
+		t.Error("IsCursor = true for a cursor of another sort")
+	}
+	if f.Limit() != f.PageSize || f.Offset() != 0 {
+		t.Errorf("Limit, Offset = %d, %d, want the first page", f.Limit(), f.Offset())
+	}
+}
+
+func TestSQL(t *testing.T) {
+	next, prev := cursors(t)
+	name := rows(3, 3)[0].Name
+
+	tests := []struct {
+		name    string
+		sort    string
+		cursor  string
+		count   string
+		where   string
+		orderBy string
+		args    []any
+	}{
+		{
+			name:    "offset",
+			sort:    "name",
+			count:   "COUNT(*) OVER()",
+			where:   "TRUE",
+			orderBy: "n.name ASC, n.id ASC",
+		},
+		{
+			name:    "offset, several columns",
+			sort:    "-name,id",
+			count:   "COUNT(*) OVER()",
+			where:   "TRUE",
+			orderBy: "n.name DESC, n.id ASC, n.id ASC",
+		},
+		{
+			name:    "forward",
+			sort:    "name",
+			cursor:  next,
+			count:   "0",
+			where:   "(((n.name > $3 OR n.name IS NULL)) OR (n.name = $3 AND n.id > $4::bigint))",
+			orderBy: "n.name ASC, n.id ASC",
+			args:    []any{rows(4, 4)[0].Name, int64(4)},
+		},
+		{
+			name:    "backward",
+			sort:    "name",
+			cursor:  prev,
+			count:   "0",
+			where:   "(((n.name < $3)) OR (n.name = $3 AND n.id < $4::bigint))",
+			orderBy: "n.name DESC, n.id DESC",
+			args:    []any{name, int64(3)},
+		},
+	}
+	for _, tt := range tests {
+		f := byName
+		f.Sort, f.Cursor = tt.sort, tt.cursor
+		q := f.SQL(func(column string) string { return "n." + column }, "n.id", 3)
+
+		if q.Count != tt.count || q.Where != tt.where || q.OrderBy != tt.orderBy || !slices.Equal(q.Args, tt.args) {
+			t.Errorf("%s: SQL = %+v, want count %q, where %q, order by %q and args %v", tt.name, q, tt.count, tt.where, tt.orderBy, tt.args)
+		}
+	}
+}
+
+func TestSQLNullCursor(t *testing.T) {
+	f := filters.Filters{Page: 2, PageSize: 1, Sort: "-name", SortSafelist: []string{"-name"}}
+	_, metaData := filters.Paginate(f, []row{{ID: 7}}, []filters.Key{{Values: filters.Values{nil}, ID: 7}}, 3)
+
+	f.Cursor = metaData.NextCursor
+	q := f.SQL(nil, "id", 1)
+	if want := "(((name IS NOT NULL)) OR (name IS NULL AND id > $1::bigint))"; q.Where != want {
+		t.Errorf("where past a NULL, descending = %q, want %q", q.Where, want)
+	}
+
+	f.Cursor = metaData.PrevCursor
+	q = f.SQL(nil, "id", 1)
+	if want := "(((FALSE)) OR (name IS NULL AND id < $1::bigint))"; q.Where != want {
+		t.Errorf("where before a NULL, descending = %q, want %q", q.Where, want)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	next, prev := cursors(t)
+
+	tests := []struct {
+		name     string
+		page     int
+		cursor   string
+		rows     []row // as the query returned them
+		total    int
+		want     []int64
+		wantNext bool
+		wantPrev bool
+	}{
+		{name: "first page", page: 1, rows: rows(1, 2), total: 6, want: []int64{1, 2}, wantNext: true},
+		{name: "last page", page: 3, rows: rows(5, 6), total: 6, want: []int64{5, 6}, wantPrev: true},
+		{name: "only page", page: 1, rows: rows(1, 2), total: 2, want: []int64{1, 2}},
+		{name: "empty", page: 1, total: 0},
+		{name: "forward with more", cursor: next, rows: rows(5, 7), want: []int64{5, 6}, wantNext: true, wantPrev: true},
+		{name: "forward to the end", cursor: next, rows: rows(5, 6), want: []int64{5, 6}, wantPrev: true},
+		{name: "forward past the end", cursor: next},
+		// Backward queries run in reverse, nearest the cursor first.
+		{name: "backward with more", cursor: prev, rows: []row{rows(2, 2)[0], rows(1, 1)[0], rows(0, 0)[0]}, want: []int64{1, 2}, wantNext: true, wantPrev: true},
+		{name: "backward to the start", cursor: prev, rows: []row{rows(2, 2)[0], rows(1, 1)[0]}, want: []int64{1, 2}, wantNext: true},
+	}
+	for _, tt := range tests {
+		f := byName
+		f.Page, f.Cursor = max(tt.page, 1), tt.cursor
+
+		page, metaData := filters.Paginate(f, slices.Clone(tt.rows), keysOf(tt.rows), tt.total)
+		var ids []int64
+		for _, r := range page {
+			ids = append(ids, r.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%s: rows = %v, want %v", tt.name, ids, tt.want)
+		}
+		if (metaData.NextCursor != "") != tt.wantNext || (metaData.PrevCursor != "") != tt.wantPrev {
+			t.Errorf("%s: next %q, prev %q, want next %v and prev %v", tt.name, metaData.NextCursor, metaData.PrevCursor, tt.wantNext, tt.wantPrev)
+		}
+		if tt.cursor != "" && metaData.TotalRecords != 0 {
+			t.Errorf("%s: total records = %d, want them not counted", tt.name, metaData.TotalRecords)
+		}
+	}
+}
+
+func TestPaginateRoundTrip(t *testing.T) {
+	// Follow the next cursor from the first page, then the previous cursor back again.
+	all := rows(1, 5)
+	f := byName
+	page, metaData := filters.Paginate(f, all[:2], keysOf(all[:2]), len(all))
+	if !slices.Equal(page, all[:2]) {
+		t.Fatalf("first page = %v", page)
+	}
+
+	f.Cursor = metaData.NextCursor
+	key, _, _ := f.Position()
+	if key.ID != 2 {
+		t.Fatalf("next cursor marks row %d, want 2", key.ID)
+	}
+	page, metaData = filters.Paginate(f, all[2:5], keysOf(all[2:5]), 0)
+	if !slices.Equal(page, all[2:4]) {
+		t.Fatalf("second page = %v, want rows 3 and 4", page)
+	}
+
+	f.Cursor = metaData.PrevCursor
+	key, backward, _ := f.Position()
+	if key.ID != 3 || !backward {
+		t.Fatalf("previous cursor marks row %d, backward %v, want before row 3", key.ID, backward)
+	}
+	reversed := []row{all[1], all[0]}
+	page, metaData = filters.Paginate(f, reversed, keysOf(reversed), 0)
+	if !slices.Equal(page, all[:2]) || metaData.PrevCursor != "" || metaData.NextCursor == "" {
+		t.Errorf("back to the first page = %v, %+v, want rows 1 and 2 with only a next cursor", page, metaData)
+	}
+}
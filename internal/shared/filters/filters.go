@@ -3,6 +3,7 @@
 package filters
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
@@ -12,17 +13,20 @@ import (
 type Filters struct {
 	Page         int      // Current page number
 	PageSize     int      // Number of records per page
-	Sort         string   // Sort parameter
+	Sort         string   // Sort parameter, one or more comma-separated sort values
 	SortSafelist []string // List of permitted sort values
+	Cursor       string   // Cursor of a previous page; when set, Page is ignored
 }
 
 // MetaData holds pagination metadata.
 type MetaData struct {
-	CurrentPage  int `json:"current_page,omitempty"`  // Current page number
-	PageSize     int `json:"page_size,omitempty"`     // Number of records per page
-	FirstPage    int `json:"first_page,omitempty"`    // First page number
-	LastPage     int `json:"last_page,omitempty"`     // Last page number
-	TotalRecords int `json:"total_records,omitempty"` // Total number of records
+	CurrentPage  int    `json:"current_page,omitempty"`  // Current page number
+	PageSize     int    `json:"page_size,omitempty"`     // Number of records per page
+	FirstPage    int    `json:"first_page,omitempty"`    // First page number
+	LastPage     int    `json:"last_page,omitempty"`     // Last page number
+	TotalRecords int    `json:"total_records,omitempty"` // Total number of records, not counted when paging by cursor
+	NextCursor   string `json:"next_cursor,omitempty"`   // Cursor of the next page, if there is one
+	PrevCursor   string `json:"prev_cursor,omitempty"`   // Cursor of the previous page, if there is one
 }
 
 // for empty MetaData
@@ -30,39 +34,71 @@ var EmptyMetaData = MetaData{}
 
 // ValidateFilters checks the validity of the filter parameters.
 func ValidateFilters(v *validator.Validator, f Filters) {
-	v.Check(f.Page > 0, "page", "must be greater than zero")                        // Page must be greater than 0
-	v.Check(f.Page <= 500, "page", "must be a maximum of 500")                      // Page must be at most 500
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")               // PageSize must be greater than 0
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")             // PageSize must be at most 100
-	v.Check(v.IsPermitted(f.Sort, f.SortSafelist...), "sort", "invalid sort value") // Sort must be in the safelist
+	v.Check(f.Page > 0, "page", "must be greater than zero")            // Page must be greater than 0
+	v.Check(f.Page <= 500, "page", "must be a maximum of 500")          // Page must be at most 500
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")   // PageSize must be greater than 0
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100") // PageSize must be at most 100
+
+	// Every sort value must be in the safelist, and sort by a different column
+	seen := make(map[string]bool)
+	for _, value := range strings.Split(f.Sort, ",") {
+		value = strings.TrimSpace(value)
+		column := strings.TrimPrefix(value, "-")
+		v.Check(v.IsPermitted(value, f.SortSafelist...), "sort", "invalid sort value")
+		v.Check(!seen[column], "sort", "must not sort by the same column twice")
+		seen[column] = true
+	}
+
+	// A cursor must be one we signed, for the same sort
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		v.Check(err == nil, "cursor", "must be a cursor returned by a previous page")
+		v.Check(err != nil || c.Sort == f.Sort, "cursor", "was made for a different sort")
+	}
 }
 
-// limit returns the limit for SQL queries based on the PageSize.
+// limit returns the limit for SQL queries based on the PageSize. Paging by cursor reads one row
+// more, to see whether another page follows.
 func (f Filters) Limit() int {
+	if f.IsCursor() {
+		return f.PageSize + 1
+	}
 	return f.PageSize
 }
 
-// offset returns the offset for SQL queries based on the Page and PageSize.
+// offset returns the offset for SQL queries based on the Page and PageSize, or 0 when paging by cursor.
 func (f Filters) Offset() int {
+	if f.IsCursor() {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
 
-// sortColumn returns the column name to sort by, trimsming any leading '-' for descending order.
-func (f Filters) SortColumn() string {
-	for _, safeValue := range f.SortSafelist {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-") // Remove leading '-' if present
-		}
-	}
-	panic("unsafe sort parameter: " + f.Sort) // Panic if the sort parameter is not in the safelist
+// SortKey is one column of a sort, in the order the client gave them.
+type SortKey struct {
+	Column string // Column name, without the leading '-'
+	Desc   bool   // Whether the column sorts in descending order
 }
 
-// sortDirection returns the sort direction (asc/desc) based on the Sort field.
-func (f Filters) SortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC" // Descending order if Sort starts with '-'
+// Direction returns the SQL keyword for the key's sort direction.
+func (k SortKey) Direction() string {
+	return direction(k.Desc)
+}
+
+// SortKeys returns the columns to sort by, trimming any leading '-' for descending order.
+func (f Filters) SortKeys() []SortKey {
+	var keys []SortKey
+	for _, value := range strings.Split(f.Sort, ",") {
+		value = strings.TrimSpace(value)
+		if !slices.Contains(f.SortSafelist, value) {
+			panic("unsafe sort parameter: " + f.Sort) // Panic if the sort parameter is not in the safelist
+		}
+		keys = append(keys, SortKey{
+			Column: strings.TrimPrefix(value, "-"), // Remove leading '-' if present
+			Desc:   strings.HasPrefix(value, "-"),  // Descending order if it starts with '-'
+		})
 	}
-	return "ASC" // Ascending order otherwise
+	return keys
 }
 
 // calculateMetadata computes pagination metadata based on total records.
@@ -0,0 +1,88 @@
+// File: internal/shared/filters/filters_test.go
+package filters_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/validator"
+)
+
+// safelist permits sorting listings by price, title and id, either way.
+var safelist = []string{"price", "-price", "title", "-title", "id", "-id"}
+
+func TestValidateFiltersSort(t *testing.T) {
+	tests := []struct {
+		sort  string
+		error string
+	}{
+		{"price", ""},
+		{"-price,title", ""},
+		{"-price, title , -id", ""},
+		{"price,-price", "must not sort by the same column twice"},
+		{"title,price,title", "must not sort by the same column twice"},
+		{"price,,title", "invalid sort value"},
+		{"price,", "invalid sort value"},
+		{",price", "invalid sort value"},
+		{"", "invalid sort value"},
+		{"colour", "invalid sort value"},
+		{"--price", "invalid sort value"},
+	}
+	for _, tt := range tests {
+		v := validator.New()
+		filters.ValidateFilters(v, filters.Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: safelist})
+		if got := v.Errors["sort"]; got != tt.error {
+			t.Errorf("ValidateFilters(sort=%q) error = %q, want %q", tt.sort, got, tt.error)
+		}
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []filters.SortKey
+	}{
+		{"price", []filters.SortKey{{Column: "price"}}},
+		{"-price", []filters.SortKey{{Column: "price", Desc: true}}},
+		{"-price,title", []filters.SortKey{{Column: "price", Desc: true}, {Column: "title"}}},
+		{"title, -price ,id", []filters.SortKey{{Column: "title"}, {Column: "price", Desc: true}, {Column: "id"}}},
+	}
+	for _, tt := range tests {
+		f := filters.Filters{Sort: tt.sort, SortSafelist: safelist}
+		if got := f.SortKeys(); !slices.Equal(got, tt.want) {
+			t.Errorf("SortKeys(%q) = %+v, want %+v", tt.sort, got, tt.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SortKeys of a sort outside the safelist did not panic")
+		}
+	}()
+	filters.Filters{Sort: "price,,title", SortSafelist: safelist}.SortKeys()
+}
+
+func TestValidateFiltersPaging(t *testing.T) {
+	tests := []struct {
+		page, pageSize int
+		field          string
+	}{
+		{1, 1, ""},
+		{500, 100, ""},
+		{0, 20, "page"},
+		{501, 20, "page"},
+		{1, 0, "page_size"},
+		{1, 101, "page_size"},
+	}
+	for _, tt := range tests {
+		v := validator.New()
+		filters.ValidateFilters(v, filters.Filters{Page: tt.page, PageSize: tt.pageSize, Sort: "id", SortSafelist: safelist})
+		if tt.field == "" && !v.Valid() {
+			t.Errorf("page %d of %d: errors = %v, want none", tt.page, tt.pageSize, v.Errors)
+		}
+		if _, ok := v.Errors[tt.field]; tt.field != "" && !ok {
+			t.Errorf("page %d of %d: errors = %v, want one for %s", tt.page, tt.pageSize, v.Errors, tt.field)
+		}
+	}
+}
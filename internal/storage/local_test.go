@@ -0,0 +1,124 @@
+// File: internal/storage/local_test.go
+package storage_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/storage"
+)
+
+func TestLocalStore(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "media")
+	store, err := storage.New(storage.Config{Driver: storage.DriverLocal, LocalRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := t.Context()
+
+	const key = "cattle/7/photo.jpg"
+	if err := store.Put(ctx, key, strings.NewReader("moo"), 3, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, store, key); got != "moo" {
+		t.Errorf("Get = %q, want %q", got, "moo")
+	}
+	if err := store.Put(ctx, key, strings.NewReader("mooo"), 4, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, store, key); got != "mooo" {
+		t.Errorf("Get after overwrite = %q, want %q", got, "mooo")
+	}
+
+	if err := store.Put(ctx, "cattle/7/short.jpg", strings.NewReader("mo"), 3, "image/jpeg"); err == nil {
+		t.Error("Put of fewer bytes than its size succeeded, want an error")
+	}
+	if err := store.Put(ctx, "cattle/7/long.jpg", strings.NewReader("mooo"), 3, "image/jpeg"); err == nil {
+		t.Error("Put of more bytes than its size succeeded, want an error")
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "cattle", "7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "photo.jpg" {
+		t.Errorf("files left behind = %v, want only photo.jpg", entries)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("Get of a deleted object error = %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete(ctx, key); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("Delete of a deleted object error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestLocalStoreInvalidKeys(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := t.Context()
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "cattle/../../secret", "cattle/./photo.jpg", "cattle//photo.jpg", "cattle/"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "image/jpeg"); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Get(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Delete(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := storage.New(storage.Config{Driver: "floppy"}); !errors.Is(err, storage.ErrUnknownDriver) {
+		t.Errorf("New of an unknown driver error = %v, want ErrUnknownDriver", err)
+	}
+	if _, err := storage.New(storage.Config{Driver: storage.DriverLocal}); err == nil {
+		t.Error("New of the local driver without a root succeeded, want an error")
+	}
+	if _, err := storage.New(storage.Config{Driver: storage.DriverS3, Endpoint: "http://localhost:9000"}); err == nil {
+		t.Error("New of the S3 driver without a bucket succeeded, want an error")
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	a, err := storage.NewKey("cattle/7", ".jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := storage.NewKey("cattle/7", ".jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("NewKey returned %q twice, want random keys", a)
+	}
+	if !strings.HasPrefix(a, "cattle/7/") || !strings.HasSuffix(a, ".jpg") {
+		t.Errorf("NewKey = %q, want a .jpg key below cattle/7/", a)
+	}
+}
+
+// readObject returns the contents of the object stored under key.
+func readObject(t *testing.T, store storage.BlobStore, key string) string {
+	t.Helper()
+	r, err := store.Get(t.Context(), key)
+	if err != nil {
+		t.Fatalf("Get(%q) error = %v", key, err)
+	}
+	defer r.Close()
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
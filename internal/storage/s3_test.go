@@ -0,0 +1,124 @@
+// File: internal/storage/s3_test.go
+package storage_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/storage"
+)
+
+// authorization matches the SigV4 Authorization header the store must send for the fake's credentials.
+var authorization = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKID/\d{8}/eu-west-1/s3/aws4_request, ` +
+	`SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`)
+
+// fakeS3 is an S3 endpoint holding objects in memory. It checks that every request is signed and
+// that the signed payload hash matches the body.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string // path -> body
+	types   map[string]string // path -> content type
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) || r.Header.Get("X-Amz-Date") == "" ||
+		!authorization.MatchString(r.Header.Get("Authorization")) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = string(body)
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		obj, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, obj)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string]string{}, types: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := storage.NewS3Store(srv.URL, "eu-west-1", "cash-cow", "AKID", "secret", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := t.Context()
+
+	const key = "listings/3/brahman bull.jpg"
+	if err := store.Put(ctx, key, strings.NewReader("moo"), 3, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	const path = "/cash-cow/listings/3/brahman bull.jpg"
+	if fake.objects[path] != "moo" || fake.types[path] != "image/jpeg" {
+		t.Errorf("stored objects = %v, want the JPEG under the bucket path", fake.objects)
+	}
+	if got := readObject(t, store, key); got != "moo" {
+		t.Errorf("Get = %q, want %q", got, "moo")
+	}
+
+	if err := store.Put(ctx, "listings/3/short.jpg", strings.NewReader("mo"), 3, "image/jpeg"); err == nil {
+		t.Error("Put of fewer bytes than its size succeeded, want an error")
+	}
+	if _, err := store.Get(ctx, "../secret"); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Errorf("Get of an escaping key error = %v, want ErrInvalidKey", err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("Get of a deleted object error = %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object error = %v, want nil as S3 reports", err)
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	fake := &fakeS3{objects: map[string]string{}, types: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	// The fake only accepts the AKID credentials of the eu-west-1 region.
+	store, err := storage.NewS3Store(srv.URL, "", "cash-cow", "AKID", "secret", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put(t.Context(), "listings/3/photo.jpg", strings.NewReader("moo"), 3, "image/jpeg")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Put with a rejected signature error = %v, want the 403 and S3 error code", err)
+	}
+
+	if _, err := storage.NewS3Store("", "eu-west-1", "cash-cow", "AKID", "secret", true); err == nil {
+		t.Error("NewS3Store without an endpoint succeeded, want an error")
+	}
+}
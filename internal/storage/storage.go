@@ -0,0 +1,90 @@
+// File: internal/storage/storage.go
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Driver names accepted by New.
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Predefined storage errors
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrInvalidKey     = errors.New("invalid object key")
+	ErrUnknownDriver  = errors.New("unknown storage driver")
+)
+
+// BlobStore is implemented by every backend that can hold uploaded media files.
+type BlobStore interface {
+	// Put stores size bytes read from r under key.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config holds the settings needed to build a BlobStore.
+type Config struct {
+	Driver    string // local or s3
+	LocalRoot string // directory used by the local driver
+	Endpoint  string // S3 endpoint, e.g. http://localhost:9000 for MinIO
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // address the bucket as a path segment instead of a subdomain
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// New returns the BlobStore selected by cfg.Driver.
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Driver {
+	case DriverLocal:
+		return NewLocalStore(cfg.LocalRoot)
+	case DriverS3:
+		return NewS3Store(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey, cfg.PathStyle)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+// NewKey generates a random object key below prefix ending in ext (e.g. ".jpg").
+func NewKey(prefix, ext string) (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return path.Join(prefix, hex.EncodeToString(randomBytes)+ext), nil
+}
+
+// validateKey rejects keys that could escape the store root or are otherwise unusable.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
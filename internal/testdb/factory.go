@@ -0,0 +1,306 @@
+// File: internal/testdb/factory.go
+package testdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/cattle"
+	internalErrors "github.com/Pedro-J-Kukul/cash-cow-api/internal/data/errors"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/listings"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/locations"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/savedsearches"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/users"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data/watchlists"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/shared/filters"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// Password is the plaintext password of every user the factory creates.
+const Password = "pa55word-for-tests"
+
+// Factory creates valid rows with unique defaults, so a test only spells out the fields it is
+// about. Each method applies its defaults, then the options, then creates any row the result
+// still refers to by a zero id. Any error fails the test.
+//
+// It works with any data.Models, including the in-memory ones.
+type Factory struct {
+	t      testing.TB
+	models data.Models
+	seq    int
+}
+
+// password is hashed once per test binary; bcrypt is deliberately slow.
+var password struct {
+	once sync.Once
+	hash users.Password
+	err  error
+}
+
+// NewFactory returns a factory that writes through models.
+func NewFactory(t testing.TB, models data.Models) *Factory {
+	return &Factory{t: t, models: models}
+}
+
+// Ptr returns a pointer to v, for the nullable fields of models and filters.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// FirstPage returns the filters for the first page of 20 rows, ordered by sort, which may list
+// several columns.
+func FirstPage(sort string) filters.Filters {
+	return filters.Filters{Page: 1, PageSize: 20, Sort: sort, SortSafelist: strings.Split(sort, ",")}
+}
+
+/****************************************************************************************
+ *										Factories										*
+ ***************************************************************************************/
+
+// User creates an activated user whose password is Password.
+func (f *Factory) User(opts ...func(*users.User)) *users.User {
+	f.t.Helper()
+	n := f.next()
+
+	u := &users.User{
+		FarmerID:    fmt.Sprintf("BZ-%05d", n),
+		Email:       fmt.Sprintf("farmer%d@example.com", n),
+		PhoneNumber: fmt.Sprintf("+501 600-%04d", n),
+		FirstName:   "Test",
+		LastName:    fmt.Sprintf("Farmer%d", n),
+		Password:    f.password(),
+		IsActivated: Ptr(true),
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	if err := f.models.Users.Insert(f.t.Context(), u); err != nil {
+		f.t.Fatalf("factory: insert user: %v", err)
+	}
+	return u
+}
+
+// Breed creates an active breed.
+func (f *Factory) Breed(opts ...func(*cattle.Breed)) *cattle.Breed {
+	f.t.Helper()
+	n := f.next()
+
+	b := &cattle.Breed{
+		Name:        fmt.Sprintf("Breed %d", n),
+		Description: "Created by the test factory",
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if err := f.models.Breeds.Insert(f.t.Context(), b); err != nil {
+		f.t.Fatalf("factory: insert breed: %v", err)
+	}
+	return b
+}
+
+// Cattle creates a purebred heifer, along with an owner and a breed if none are given.
+func (f *Factory) Cattle(opts ...func(*cattle.Cattle)) *cattle.Cattle {
+	f.t.Helper()
+	n := f.next()
+
+	c := &cattle.Cattle{
+		TagNumber: fmt.Sprintf("TAG-%05d", n),
+		Sex:       cattle.Female,
+		AgeMonths: 18,
+		WeightKg:  320,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.OwnerID == 0 {
+		c.OwnerID = int(f.User().ID)
+	}
+	if c.BreedID == 0 && len(c.Composition) == 0 {
+		c.BreedID = f.Breed().ID
+	}
+
+	if err := f.models.Cattle.Insert(f.t.Context(), c); err != nil {
+		f.t.Fatalf("factory: insert cattle: %v", err)
+	}
+	return c
+}
+
+// Region creates a region without a boundary.
+func (f *Factory) Region(opts ...func(*locations.Region)) *locations.Region {
+	f.t.Helper()
+	n := f.next()
+
+	r := &locations.Region{
+		Name: fmt.Sprintf("Region %d", n),
+		Code: fmt.Sprintf("R%d", n),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if err := f.models.Regions.Insert(f.t.Context(), r); err != nil {
+		f.t.Fatalf("factory: insert region: %v", err)
+	}
+	return r
+}
+
+// Area creates a town without coordinates. Without a region it is placed in the region whose
+// boundary covers its coordinates, or in a new region if none does.
+func (f *Factory) Area(opts ...func(*locations.Area)) *locations.Area {
+	f.t.Helper()
+	n := f.next()
+
+	a := &locations.Area{
+		Name:     fmt.Sprintf("Area %d", n),
+		AreaType: locations.AreaTypeTown,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.RegionID == 0 {
+		a.RegionID = f.regionAt(a.Coordinates)
+	}
+
+	if err := f.models.Areas.Insert(f.t.Context(), a); err != nil {
+		f.t.Fatalf("factory: insert area: %v", err)
+	}
+	return a
+}
+
+// Listing creates an active listing, along with a seller and an area if none are given.
+func (f *Factory) Listing(opts ...func(*listings.Listing)) *listings.Listing {
+	f.t.Helper()
+	n := f.next()
+
+	l := &listings.Listing{
+		Title:       fmt.Sprintf("Listing %d", n),
+		Description: "Created by the test factory",
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.UserID == 0 {
+		l.UserID = f.User().ID
+	}
+	if l.AreaID == 0 {
+		l.AreaID = int64(f.Area().ID)
+	}
+
+	if err := f.models.Listings.Insert(f.t.Context(), l); err != nil {
+		f.t.Fatalf("factory: insert listing: %v", err)
+	}
+	return l
+}
+
+// ListingPrice creates a price for cows in a listing, along with the listing if none is given.
+func (f *Factory) ListingPrice(opts ...func(*listings.ListingPrice)) *listings.ListingPrice {
+	f.t.Helper()
+
+	lp := &listings.ListingPrice{
+		CattleClass: listings.CattleClassCow,
+		PricePerKg:  4,
+		Quantity:    10,
+	}
+	for _, opt := range opts {
+		opt(lp)
+	}
+	if lp.ListingID == 0 {
+		lp.ListingID = f.Listing().ID
+	}
+
+	if err := f.models.ListingPrices.Insert(f.t.Context(), lp); err != nil {
+		f.t.Fatalf("factory: insert listing price: %v", err)
+	}
+	return lp
+}
+
+// SavedSearch creates a daily saved search with alerts on, along with its user if none is given.
+func (f *Factory) SavedSearch(opts ...func(*savedsearches.SavedSearch)) *savedsearches.SavedSearch {
+	f.t.Helper()
+	n := f.next()
+
+	s := &savedsearches.SavedSearch{
+		Name:          fmt.Sprintf("Search %d", n),
+		Frequency:     savedsearches.FrequencyDaily,
+		AlertsEnabled: true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.UserID == 0 {
+		s.UserID = f.User().ID
+	}
+
+	if err := f.models.SavedSearches.Insert(f.t.Context(), s); err != nil {
+		f.t.Fatalf("factory: insert saved search: %v", err)
+	}
+	return s
+}
+
+// Watch creates a watch of a listing, along with the watcher and the listing if neither a listing
+// nor a seller is given.
+func (f *Factory) Watch(opts ...func(*watchlists.Watch)) *watchlists.Watch {
+	f.t.Helper()
+
+	w := &watchlists.Watch{}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.UserID == 0 {
+		w.UserID = f.User().ID
+	}
+	if w.ListingID == nil && w.SellerID == nil {
+		w.ListingID = Ptr(f.Listing().ID)
+	}
+
+	if err := f.models.Watches.Insert(f.t.Context(), w); err != nil {
+		f.t.Fatalf("factory: insert watch: %v", err)
+	}
+	return w
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// next returns a number not yet used by this factory, for building unique values.
+func (f *Factory) next() int {
+	f.seq++
+	return f.seq
+}
+
+// regionAt returns the region whose boundary covers c, creating a region if none does.
+func (f *Factory) regionAt(c locations.Coordinates) int {
+	f.t.Helper()
+
+	if c != (locations.Coordinates{}) {
+		r, err := f.models.Regions.Locate(f.t.Context(), c)
+		switch {
+		case err == nil:
+			return r.ID
+		case !errors.Is(err, internalErrors.ErrRecordNotFound):
+			f.t.Fatalf("factory: locate region: %v", err)
+		}
+	}
+	return f.Region().ID
+}
+
+// password returns Password, hashed.
+func (f *Factory) password() users.Password {
+	f.t.Helper()
+
+	password.once.Do(func() { password.err = password.hash.Set(Password) })
+	if password.err != nil {
+		f.t.Fatalf("factory: hash password: %v", password.err)
+	}
+	return password.hash
+}
@@ -0,0 +1,239 @@
+// File: internal/testdb/testdb.go
+
+// Package testdb runs integration tests against a disposable Postgres schema. Each test binary
+// (one per package) creates its own schema on the server named by DB_DSN_TEST, applies the
+// embedded migrations to it and drops it again when the tests finish, so packages can run in
+// parallel against one database without seeing each other's rows.
+//
+// Without DB_DSN_TEST the model suites run against the in-memory store instead: Models then
+// returns data.NewMemoryModels, so `go test ./...` checks that both implementations behave alike.
+// Tests that call New directly need Postgres itself and are skipped.
+package testdb
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/data"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/migrate"
+	"github.com/Pedro-J-Kukul/cash-cow-api/internal/seed"
+	"github.com/Pedro-J-Kukul/cash-cow-api/migrations"
+	"github.com/lib/pq"
+)
+
+/****************************************************************************************
+ *										Declarations									*
+ ***************************************************************************************/
+
+// EnvDSN names the environment variable holding the test database DSN.
+const EnvDSN = "DB_DSN_TEST"
+
+// setupTimeout bounds creating the schema and migrating it.
+const setupTimeout = time.Minute
+
+// keep lists the tables that survive between tests: the migration version and the permission
+// codes, which are reference data loaded once like the seed command does.
+var keep = []string{"schema_migrations", "permissions"}
+
+// nonIdentRX matches the characters that cannot appear in an unquoted schema name.
+var nonIdentRX = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// state is the schema shared by every test in the binary.
+var state struct {
+	once   sync.Once
+	err    error
+	admin  *sql.DB // connection without the search_path, used to create and drop the schema
+	db     *sql.DB
+	schema string
+	tables []string
+}
+
+/****************************************************************************************
+ *										Harness											*
+ ***************************************************************************************/
+
+// Main runs the package's tests and then drops the schema they used. Call it from TestMain.
+func Main(m *testing.M) {
+	code := m.Run()
+	if err := teardown(); err != nil {
+		fmt.Fprintf(os.Stderr, "testdb: %v\n", err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+// New returns a connection pool whose search_path is the package's migrated schema, with every
+// table except those in keep emptied and its sequences reset. It skips the test when DB_DSN_TEST
+// is not set.
+func New(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(EnvDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set; skipping integration test", EnvDSN)
+	}
+
+	state.once.Do(func() { state.err = setup(dsn) })
+	if state.err != nil {
+		t.Fatalf("testdb: %v", state.err)
+	}
+
+	if err := truncate(t.Context()); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	return state.db
+}
+
+// Models returns data.Models backed by a freshly emptied test schema, or by a fresh in-memory
+// store when DB_DSN_TEST is not set.
+func Models(t testing.TB) data.Models {
+	t.Helper()
+	if os.Getenv(EnvDSN) == "" {
+		return data.NewMemoryModels()
+	}
+	return data.NewModels(New(t), 0)
+}
+
+/****************************************************************************************
+ *										Helpers											*
+ ***************************************************************************************/
+
+// setup creates the schema, opens a pool confined to it and migrates it.
+func setup(dsn string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	if err := admin.PingContext(ctx); err != nil {
+		admin.Close()
+		return fmt.Errorf("connect to %s: %w", EnvDSN, err)
+	}
+	state.admin = admin
+
+	schema, err := schemaName()
+	if err != nil {
+		return err
+	}
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+pq.QuoteIdentifier(schema)); err != nil {
+		return fmt.Errorf("create schema: %w", err)
+	}
+	state.schema = schema
+
+	scoped, err := withSearchPath(dsn, schema)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("postgres", scoped)
+	if err != nil {
+		return err
+	}
+	state.db = db
+
+	migrator, err := migrate.New(db, migrations.FS, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	for _, code := range seed.Permissions {
+		if _, err := db.ExecContext(ctx, `INSERT INTO permissions (code) VALUES ($1) ON CONFLICT (code) DO NOTHING`, code); err != nil {
+			return fmt.Errorf("load permissions: %w", err)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = $1 AND table_type = 'BASE TABLE' AND NOT (table_name = ANY($2))
+		ORDER BY table_name`, schema, pq.Array(keep))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return err
+		}
+		state.tables = append(state.tables, pq.QuoteIdentifier(table))
+	}
+	return rows.Err()
+}
+
+// truncate empties every table outside keep and restarts its id sequence.
+func truncate(ctx context.Context) error {
+	if len(state.tables) == 0 {
+		return nil
+	}
+	_, err := state.db.ExecContext(ctx, `TRUNCATE `+strings.Join(state.tables, ", ")+` RESTART IDENTITY CASCADE`)
+	return err
+}
+
+// teardown drops the schema and closes both pools. It does nothing if New was never called.
+func teardown() error {
+	if state.db != nil {
+		state.db.Close()
+	}
+	if state.admin == nil {
+		return nil
+	}
+	defer state.admin.Close()
+
+	if state.schema == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	_, err := state.admin.ExecContext(ctx, `DROP SCHEMA `+pq.QuoteIdentifier(state.schema)+` CASCADE`)
+	return err
+}
+
+// schemaName builds a schema name from the test binary's package name and a random suffix, so
+// concurrent runs of the same package never collide.
+func schemaName() (string, error) {
+	pkg := strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")
+	pkg = nonIdentRX.ReplaceAllString(strings.ToLower(pkg), "_")
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return "test_" + pkg + "_" + hex.EncodeToString(suffix), nil
+}
+
+// withSearchPath adds search_path to a DSN in either the URL or the key=value form. lib/pq sends
+// parameters it does not recognise to the server as run-time settings.
+func withSearchPath(dsn, schema string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("parse %s: %w", EnvDSN, err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return dsn + " search_path=" + schema, nil
+}
@@ -12,7 +12,7 @@ DROP TABLE IF EXISTS "areas";
 -- Drop Area Types Enumeration
 DO $$
 BEGIN
-    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'area_type_enum') THEN
+    IF to_regtype('area_type_enum') IS NOT NULL THEN
         DROP TYPE area_type_enum;
     END IF;
 END $$;
@@ -5,7 +5,7 @@
 -- Area Types Enumeration
 DO $$
 BEGIN
-    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'area_type_enum') THEN
+    IF to_regtype('area_type_enum') IS NULL THEN
         CREATE TYPE area_type_enum AS ENUM ('city', 'town', 'village');
     END IF;
 END $$;
@@ -11,7 +11,7 @@ DROP TABLE IF EXISTS listing_prices;
 -- Drop Price Types Enumeration
 DO $$
 BEGIN
-    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'cattle_class_enum') THEN
+    IF to_regtype('cattle_class_enum') IS NOT NULL THEN
         DROP TYPE cattle_class_enum;
     END IF;
 END $$;
@@ -5,7 +5,7 @@
 -- Create Enum Type for Age Bracket
 DO $$
 BEGIN
-    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'cattle_class_enum') THEN
+    IF to_regtype('cattle_class_enum') IS NULL THEN
         CREATE TYPE cattle_class_enum AS ENUM (
             'male_calf', -- Young male cattle
             'female_calf', -- Young female cattle
@@ -0,0 +1,25 @@
+-- File: 000012_create_media_table.down.sql
+
+-- This migration script drops the 'media' table and associated enum type if they exist.
+
+-- Drop Indexes
+DROP INDEX IF EXISTS idx_media_listing_cover;
+DROP INDEX IF EXISTS idx_media_cattle_cover;
+DROP INDEX IF EXISTS idx_media_listing_id;
+DROP INDEX IF EXISTS idx_media_cattle_id;
+
+-- Drop Foreign Key Constraints
+ALTER TABLE IF EXISTS "media" DROP CONSTRAINT IF EXISTS fk_media_uploaded_by;
+ALTER TABLE IF EXISTS "media" DROP CONSTRAINT IF EXISTS fk_media_listing_id;
+ALTER TABLE IF EXISTS "media" DROP CONSTRAINT IF EXISTS fk_media_cattle_id;
+
+-- Drop Media Table
+DROP TABLE IF EXISTS "media";
+
+-- Drop Media Kind Enumeration
+DO $$
+BEGIN
+    IF to_regtype('media_kind_enum') IS NOT NULL THEN
+        DROP TYPE media_kind_enum;
+    END IF;
+END $$;
@@ -0,0 +1,59 @@
+-- File: 000012_create_media_table.up.sql
+
+-- This migration script creates the 'media' table which holds photos and documents
+-- (vet certificates, PDFs) attached to cattle or listings. The file contents live in
+-- the blob store, this table only keeps the storage keys and metadata.
+
+-- Media Kind Enumeration
+DO $$
+BEGIN
+    IF to_regtype('media_kind_enum') IS NULL THEN
+        CREATE TYPE media_kind_enum AS ENUM ('image', 'document');
+    END IF;
+END $$;
+
+-- Create Media Table
+CREATE TABLE IF NOT EXISTS "media" (
+    -- Primary Key
+    "id" BIGSERIAL PRIMARY KEY,
+    -- Foreign Keys (exactly one of cattle_id / listing_id is set)
+    "cattle_id" BIGINT,
+    "listing_id" BIGINT,
+    "uploaded_by" BIGINT NOT NULL, -- user who uploaded the file
+    -- Media Info
+    "kind" media_kind_enum NOT NULL,
+    "file_name" TEXT NOT NULL,
+    "content_type" TEXT NOT NULL,
+    "size_bytes" BIGINT NOT NULL,
+    "storage_key" TEXT NOT NULL UNIQUE,
+    "thumbnail_key" TEXT NOT NULL DEFAULT '', -- empty for documents
+    "width" INT NOT NULL DEFAULT 0,
+    "height" INT NOT NULL DEFAULT 0,
+    "caption" TEXT NOT NULL DEFAULT '',
+    -- Ordering
+    "position" INT NOT NULL DEFAULT 0,
+    "is_cover" BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Timestamps
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT media_single_owner CHECK (num_nonnulls("cattle_id", "listing_id") = 1),
+    CONSTRAINT media_cover_is_image CHECK (NOT "is_cover" OR "kind" = 'image')
+);
+
+-- Foreign Key Constraints
+ALTER TABLE "media"
+ADD CONSTRAINT fk_media_cattle_id
+FOREIGN KEY ("cattle_id") REFERENCES "cattle"("id") ON DELETE CASCADE;
+ALTER TABLE "media"
+ADD CONSTRAINT fk_media_listing_id
+FOREIGN KEY ("listing_id") REFERENCES "listings"("id") ON DELETE CASCADE;
+ALTER TABLE "media"
+ADD CONSTRAINT fk_media_uploaded_by
+FOREIGN KEY ("uploaded_by") REFERENCES "users"("id") ON DELETE CASCADE;
+
+-- Indexes for listing attachments in display order
+CREATE INDEX IF NOT EXISTS idx_media_cattle_id ON "media" ("cattle_id", "position") WHERE "cattle_id" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_media_listing_id ON "media" ("listing_id", "position") WHERE "listing_id" IS NOT NULL;
+
+-- Only one cover photo per animal / listing
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_cattle_cover ON "media" ("cattle_id") WHERE "is_cover";
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_listing_cover ON "media" ("listing_id") WHERE "is_cover";
@@ -0,0 +1,17 @@
+-- File: 000013_create_cattle_breed_composition_table.down.sql
+
+-- This migration script drops the 'cattle_breed_composition' table and its total check.
+
+-- Drop Trigger and Function
+DROP TRIGGER IF EXISTS trg_cattle_breed_composition_total ON "cattle_breed_composition";
+DROP FUNCTION IF EXISTS check_cattle_breed_composition();
+
+-- Drop Index
+DROP INDEX IF EXISTS idx_cattle_breed_composition_breed;
+
+-- Drop Foreign Key Constraints
+ALTER TABLE IF EXISTS "cattle_breed_composition" DROP CONSTRAINT IF EXISTS fk_cattle_breed_composition_breed_id;
+ALTER TABLE IF EXISTS "cattle_breed_composition" DROP CONSTRAINT IF EXISTS fk_cattle_breed_composition_cattle_id;
+
+-- Drop Cattle Breed Composition Table
+DROP TABLE IF EXISTS "cattle_breed_composition";
@@ -0,0 +1,58 @@
+-- File: 000013_create_cattle_breed_composition_table.up.sql
+
+-- This migration script creates the 'cattle_breed_composition' table which records the
+-- breed make-up of crossbred animals (e.g. 50% Brahman, 25% Angus, 25% Nelore).
+-- cattle.breed_id is kept as the derived primary breed (largest share) for filtering.
+CREATE TABLE IF NOT EXISTS "cattle_breed_composition" (
+    "cattle_id" BIGINT NOT NULL, -- Foreign Key to cattle table
+    "breed_id" BIGINT NOT NULL,  -- Foreign Key to breeds table
+    "percentage" NUMERIC(5, 2) NOT NULL CHECK ("percentage" > 0 AND "percentage" <= 100),
+    PRIMARY KEY ("cattle_id", "breed_id")
+);
+
+-- Foreign Key Constraints
+ALTER TABLE "cattle_breed_composition"
+ADD CONSTRAINT fk_cattle_breed_composition_cattle_id
+FOREIGN KEY ("cattle_id") REFERENCES "cattle"("id") ON DELETE CASCADE;
+
+ALTER TABLE "cattle_breed_composition"
+ADD CONSTRAINT fk_cattle_breed_composition_breed_id
+FOREIGN KEY ("breed_id") REFERENCES "breeds"("id") ON DELETE RESTRICT;
+
+-- Index for "contains at least N% of breed X" searches
+CREATE INDEX IF NOT EXISTS idx_cattle_breed_composition_breed
+ON "cattle_breed_composition" ("breed_id", "percentage");
+
+-- The shares of an animal must add up to 100%. The check is deferred to commit time so
+-- a composition can be replaced row by row inside one transaction.
+CREATE OR REPLACE FUNCTION check_cattle_breed_composition() RETURNS TRIGGER AS $$
+DECLARE
+    target BIGINT;
+    total NUMERIC;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        target := OLD.cattle_id;
+    ELSE
+        target := NEW.cattle_id;
+    END IF;
+
+    SELECT SUM(percentage) INTO total FROM cattle_breed_composition WHERE cattle_id = target;
+
+    -- No rows left means the animal (or its whole composition) was removed.
+    IF total IS NOT NULL AND total <> 100 THEN
+        RAISE EXCEPTION 'breed composition for cattle % must add up to 100, got %', target, total
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'cattle_breed_composition_total';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trg_cattle_breed_composition_total
+AFTER INSERT OR UPDATE OR DELETE ON "cattle_breed_composition"
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_cattle_breed_composition();
+
+-- Existing animals are purebred in their recorded breed.
+INSERT INTO "cattle_breed_composition" ("cattle_id", "breed_id", "percentage")
+SELECT "id", "breed_id", 100 FROM "cattle"
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+-- File: 000014_create_geo_search_functions.down.sql
+
+-- This migration script drops the proximity search function and coordinate indexes.
+
+-- Drop Indexes
+DROP INDEX IF EXISTS idx_listings_coordinates;
+DROP INDEX IF EXISTS idx_areas_coordinates;
+
+-- Drop Distance Function
+DROP FUNCTION IF EXISTS haversine_km(FLOAT, FLOAT, FLOAT, FLOAT);
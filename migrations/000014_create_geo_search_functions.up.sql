@@ -0,0 +1,18 @@
+-- File: 000014_create_geo_search_functions.up.sql
+
+-- This migration script adds a great-circle distance function used by the proximity
+-- searches on areas and listings, plus indexes that let the bounding-box pre-filter
+-- narrow rows down before distances are computed.
+
+-- Great-circle distance in kilometres between two points (haversine formula).
+CREATE OR REPLACE FUNCTION haversine_km(lat1 FLOAT, lon1 FLOAT, lat2 FLOAT, lon2 FLOAT)
+RETURNS FLOAT AS $$
+    SELECT 2 * 6371.0088 * ASIN(LEAST(1, SQRT(
+        POWER(SIN(RADIANS(lat2 - lat1) / 2), 2) +
+        COS(RADIANS(lat1)) * COS(RADIANS(lat2)) * POWER(SIN(RADIANS(lon2 - lon1) / 2), 2)
+    )))
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+
+-- Coordinate indexes for bounding-box searches
+CREATE INDEX IF NOT EXISTS idx_areas_coordinates ON "areas" ("latitude", "longitude") WHERE "latitude" IS NOT NULL AND "longitude" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_listings_coordinates ON "listings" ("latitude", "longitude") WHERE "latitude" IS NOT NULL AND "longitude" IS NOT NULL;
@@ -0,0 +1,19 @@
+-- File: 000015_add_region_boundaries.down.sql
+
+-- This migration script drops region boundaries and the area/listing region triggers.
+
+-- Drop Triggers
+DROP TRIGGER IF EXISTS trg_areas_cascade_region ON "areas";
+DROP FUNCTION IF EXISTS areas_cascade_region();
+DROP TRIGGER IF EXISTS trg_listings_region_from_area ON "listings";
+DROP FUNCTION IF EXISTS listings_region_from_area();
+
+-- Drop Boundary Columns
+DROP INDEX IF EXISTS idx_regions_bounds;
+ALTER TABLE "regions" DROP CONSTRAINT IF EXISTS chk_regions_boundary_bounds;
+ALTER TABLE "regions"
+DROP COLUMN IF EXISTS "max_longitude",
+DROP COLUMN IF EXISTS "max_latitude",
+DROP COLUMN IF EXISTS "min_longitude",
+DROP COLUMN IF EXISTS "min_latitude",
+DROP COLUMN IF EXISTS "boundary";
@@ -0,0 +1,56 @@
+-- File: 000015_add_region_boundaries.up.sql
+
+-- This migration script stores region boundary polygons and keeps each listing's region in
+-- line with the region of its area.
+
+-- Region Boundaries (GeoJSON MultiPolygon) and their bounding box for point lookups
+ALTER TABLE "regions"
+ADD COLUMN IF NOT EXISTS "boundary" JSONB,
+ADD COLUMN IF NOT EXISTS "min_latitude" FLOAT,
+ADD COLUMN IF NOT EXISTS "min_longitude" FLOAT,
+ADD COLUMN IF NOT EXISTS "max_latitude" FLOAT,
+ADD COLUMN IF NOT EXISTS "max_longitude" FLOAT;
+
+ALTER TABLE "regions"
+ADD CONSTRAINT chk_regions_boundary_bounds
+CHECK (("boundary" IS NULL) = ("min_latitude" IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_regions_bounds ON "regions" ("min_latitude", "max_latitude", "min_longitude", "max_longitude") WHERE "boundary" IS NOT NULL;
+
+-- Repair listings whose region disagrees with their area
+UPDATE "listings" AS l
+SET "region_id" = a."region_id"
+FROM "areas" AS a
+WHERE a."id" = l."area_id" AND l."region_id" <> a."region_id";
+
+-- A listing always sits in the region of its area
+CREATE OR REPLACE FUNCTION listings_region_from_area() RETURNS TRIGGER AS $$
+DECLARE
+    area_region BIGINT;
+BEGIN
+    SELECT "region_id" INTO area_region FROM "areas" WHERE "id" = NEW."area_id";
+    IF area_region IS NOT NULL AND NEW."region_id" IS DISTINCT FROM area_region THEN
+        RAISE EXCEPTION 'listing region % does not match region % of area %', NEW."region_id", area_region, NEW."area_id"
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'listings_region_matches_area';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_listings_region_from_area
+BEFORE INSERT OR UPDATE OF "area_id", "region_id" ON "listings"
+FOR EACH ROW EXECUTE FUNCTION listings_region_from_area();
+
+-- Moving an area to another region moves its listings with it
+CREATE OR REPLACE FUNCTION areas_cascade_region() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE "listings" SET "region_id" = NEW."region_id", "updated_at" = NOW()
+    WHERE "area_id" = NEW."id";
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_areas_cascade_region
+AFTER UPDATE OF "region_id" ON "areas"
+FOR EACH ROW WHEN (OLD."region_id" IS DISTINCT FROM NEW."region_id")
+EXECUTE FUNCTION areas_cascade_region();
@@ -0,0 +1,4 @@
+-- File: 000016_add_areas_region_name_unique.down.sql
+
+-- This migration script drops the unique area name per region constraint.
+ALTER TABLE "areas" DROP CONSTRAINT IF EXISTS uq_areas_region_id_name;
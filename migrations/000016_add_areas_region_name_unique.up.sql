@@ -0,0 +1,6 @@
+-- File: 000016_add_areas_region_name_unique.up.sql
+
+-- This migration script makes area names unique within a region, giving the reference-data
+-- seed a natural key to upsert on.
+ALTER TABLE "areas"
+ADD CONSTRAINT uq_areas_region_id_name UNIQUE ("region_id", "name");
@@ -0,0 +1,19 @@
+-- File: 000017_align_schema_with_models.down.sql
+
+-- This migration script reverts the users and cattle column changes. Postgres cannot drop enum
+-- values, so the extra cattle_class_enum age brackets are left in place.
+
+-- Restore Cattle Weights
+ALTER TABLE "cattle"
+ALTER COLUMN "weight_kg" TYPE FLOAT USING "weight_kg"::FLOAT;
+
+-- Drop User Version
+ALTER TABLE "users" DROP COLUMN IF EXISTS "version";
+
+-- Drop Unique Constraints
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS uq_users_phone_number;
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS uq_users_farmer_id;
+
+-- Restore Numeric Farmer IDs
+ALTER TABLE "users"
+ALTER COLUMN "farmer_id" TYPE INTEGER USING NULLIF(regexp_replace("farmer_id", '\D', '', 'g'), '')::INTEGER;
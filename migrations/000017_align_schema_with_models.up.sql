@@ -0,0 +1,33 @@
+-- File: 000017_align_schema_with_models.up.sql
+
+-- This migration script brings the users, cattle and listing price columns in line with the
+-- models that read and write them.
+
+-- Farmer IDs are BAHA identifiers, not numbers, and identify one user each
+ALTER TABLE "users"
+ALTER COLUMN "farmer_id" TYPE TEXT USING "farmer_id"::TEXT;
+
+ALTER TABLE "users"
+ADD CONSTRAINT uq_users_farmer_id UNIQUE ("farmer_id"),
+ADD CONSTRAINT uq_users_phone_number UNIQUE ("phone_number");
+
+-- Optimistic locking version for users
+ALTER TABLE "users"
+ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+
+-- Cattle weights are recorded in whole kilograms
+ALTER TABLE "cattle"
+ALTER COLUMN "weight_kg" TYPE INTEGER USING round("weight_kg");
+
+-- Age brackets used by the listing prices model
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'calf';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'heifer_calf';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'steer_calf';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'bull_calf';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'weaner';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'heifer_weaner';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'steer_weaner';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'bull_weaner';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'yearling';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'spayed_heifer';
+ALTER TYPE cattle_class_enum ADD VALUE IF NOT EXISTS 'spayed_cow';
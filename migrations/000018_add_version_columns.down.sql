@@ -0,0 +1,9 @@
+-- File: 000018_add_version_columns.down.sql
+
+-- This migration script drops the optimistic locking versions.
+
+ALTER TABLE "listings" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "areas" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "regions" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "breeds" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "cattle" DROP COLUMN IF EXISTS "version";
@@ -0,0 +1,19 @@
+-- File: 000018_add_version_columns.up.sql
+
+-- This migration script adds an optimistic locking version to every table that can be edited
+-- through the API, so concurrent edits are detected instead of silently overwriting each other.
+
+ALTER TABLE "cattle"
+ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "breeds"
+ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "regions"
+ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "areas"
+ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "listings"
+ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
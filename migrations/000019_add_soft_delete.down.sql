@@ -0,0 +1,23 @@
+-- File: 000019_add_soft_delete.down.sql
+
+-- This migration script drops the soft delete columns, turning the users' deletion back into a
+-- plain flag.
+
+DROP INDEX IF EXISTS idx_listings_deleted_at;
+DROP INDEX IF EXISTS idx_areas_deleted_at;
+DROP INDEX IF EXISTS idx_regions_deleted_at;
+DROP INDEX IF EXISTS idx_breeds_deleted_at;
+DROP INDEX IF EXISTS idx_cattle_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_deleted";
+ALTER TABLE "users"
+ADD COLUMN "is_deleted" BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE "users" SET "is_deleted" = "deleted_at" IS NOT NULL;
+
+ALTER TABLE "listings" DROP COLUMN IF EXISTS "deleted_by", DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "areas" DROP COLUMN IF EXISTS "deleted_by", DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "regions" DROP COLUMN IF EXISTS "deleted_by", DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "breeds" DROP COLUMN IF EXISTS "deleted_by", DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "cattle" DROP COLUMN IF EXISTS "deleted_by", DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deleted_by", DROP COLUMN IF EXISTS "deleted_at";
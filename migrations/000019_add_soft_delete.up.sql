@@ -0,0 +1,44 @@
+-- File: 000019_add_soft_delete.up.sql
+
+-- This migration script gives every entity the same soft delete: deleted_at records when a row was
+-- deleted and deleted_by who deleted it. Deleted rows are hidden from listings until they are
+-- restored, or purged once the retention period has passed.
+
+ALTER TABLE "users"
+ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "deleted_by" BIGINT REFERENCES "users"("id") ON DELETE SET NULL;
+
+ALTER TABLE "cattle"
+ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "deleted_by" BIGINT REFERENCES "users"("id") ON DELETE SET NULL;
+
+ALTER TABLE "breeds"
+ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "deleted_by" BIGINT REFERENCES "users"("id") ON DELETE SET NULL;
+
+ALTER TABLE "regions"
+ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "deleted_by" BIGINT REFERENCES "users"("id") ON DELETE SET NULL;
+
+ALTER TABLE "areas"
+ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "deleted_by" BIGINT REFERENCES "users"("id") ON DELETE SET NULL;
+
+ALTER TABLE "listings"
+ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "deleted_by" BIGINT REFERENCES "users"("id") ON DELETE SET NULL;
+
+-- Users deleted through the old flag keep their deletion, and the flag now follows deleted_at
+UPDATE "users" SET "deleted_at" = "updated_at" WHERE "is_deleted";
+
+ALTER TABLE "users" DROP COLUMN "is_deleted";
+ALTER TABLE "users"
+ADD COLUMN "is_deleted" BOOLEAN GENERATED ALWAYS AS ("deleted_at" IS NOT NULL) STORED;
+
+-- The purge job looks rows up by deletion time
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON "users" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_cattle_deleted_at ON "cattle" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_breeds_deleted_at ON "breeds" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_regions_deleted_at ON "regions" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_areas_deleted_at ON "areas" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_listings_deleted_at ON "listings" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
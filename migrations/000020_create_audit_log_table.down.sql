@@ -0,0 +1,20 @@
+-- File: 000020_create_audit_log_table.down.sql
+
+-- This migration script drops the 'audit_log' table and associated enum type if they exist.
+
+-- Drop Indexes
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_request_id;
+DROP INDEX IF EXISTS idx_audit_log_actor_id;
+DROP INDEX IF EXISTS idx_audit_log_entity;
+
+-- Drop Audit Log Table
+DROP TABLE IF EXISTS "audit_log";
+
+-- Drop Audit Action Enumeration
+DO $$
+BEGIN
+    IF to_regtype('audit_action_enum') IS NOT NULL THEN
+        DROP TYPE audit_action_enum;
+    END IF;
+END $$;
@@ -0,0 +1,37 @@
+-- File: 000020_create_audit_log_table.up.sql
+
+-- This migration script creates the 'audit_log' table, which records who changed what: one row
+-- for every insert, update, delete, restore and purge made through the models, with the fields
+-- that changed.
+
+-- Audit Action Enumeration
+DO $$
+BEGIN
+    IF to_regtype('audit_action_enum') IS NULL THEN
+        CREATE TYPE audit_action_enum AS ENUM ('insert', 'update', 'delete', 'restore', 'purge');
+    END IF;
+END $$;
+
+-- Create Audit Log Table
+CREATE TABLE IF NOT EXISTS "audit_log" (
+    -- Primary Key
+    "id" BIGSERIAL PRIMARY KEY,
+    -- Who made the change. Deliberately not a foreign key: the trail must outlive purged users.
+    "actor_id" BIGINT,
+    -- What was changed
+    "entity" TEXT NOT NULL, -- table name, e.g. 'cattle'
+    "entity_id" BIGINT NOT NULL,
+    "action" audit_action_enum NOT NULL,
+    "changes" JSONB, -- {"field": {"old": ..., "new": ...}}
+    -- Where the change came from
+    "request_id" TEXT NOT NULL DEFAULT '',
+    "ip" INET,
+    -- Timestamps
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for the history of a record, of a user and of a request
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON "audit_log" ("entity", "entity_id", "created_at");
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON "audit_log" ("actor_id", "created_at");
+CREATE INDEX IF NOT EXISTS idx_audit_log_request_id ON "audit_log" ("request_id") WHERE "request_id" <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON "audit_log" ("created_at");
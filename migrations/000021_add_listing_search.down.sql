@@ -0,0 +1,27 @@
+-- File: 000021_add_listing_search.down.sql
+
+-- This migration script removes full-text search from listings.
+
+-- Drop Triggers and Functions
+DROP TRIGGER IF EXISTS trg_regions_search ON "regions";
+DROP TRIGGER IF EXISTS trg_areas_search ON "areas";
+DROP TRIGGER IF EXISTS trg_breeds_search ON "breeds";
+DROP TRIGGER IF EXISTS trg_cattle_breed_composition_search ON "cattle_breed_composition";
+DROP TRIGGER IF EXISTS trg_listings_cattle_search ON "listings_cattle";
+DROP FUNCTION IF EXISTS refresh_listing_search();
+DROP TRIGGER IF EXISTS trg_listings_search ON "listings";
+DROP FUNCTION IF EXISTS set_listing_search();
+
+-- Drop Indexes
+DROP INDEX IF EXISTS idx_listings_search_text;
+DROP INDEX IF EXISTS idx_listings_search_document;
+
+-- Drop Search Columns
+ALTER TABLE "listings"
+DROP COLUMN IF EXISTS "search_text",
+DROP COLUMN IF EXISTS "search_document";
+
+DROP FUNCTION IF EXISTS search_vector(TEXT, "char");
+DROP FUNCTION IF EXISTS search_query(TEXT);
+
+-- pg_trgm is shared by the whole database, so it is left installed.
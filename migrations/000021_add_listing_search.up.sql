@@ -0,0 +1,118 @@
+-- File: 000021_add_listing_search.up.sql
+
+-- This migration script adds full-text search to listings. Each listing stores a weighted search
+-- document, stemmed in both English and Spanish: its title (A), the breeds of its cattle (B), its
+-- area and region (C) and its description (D). A plain copy of the short fields backs trigram
+-- matching, so that misspelt searches ("brahmn") still find something. Triggers keep both columns
+-- up to date as the listing and the rows it draws on change.
+
+-- Extensions are shared by the whole database, so pg_trgm is kept in public and its operators are
+-- named with the schema wherever they are used.
+CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public;
+
+-- A search matches its words stemmed as English or as Spanish.
+CREATE OR REPLACE FUNCTION search_query(q TEXT) RETURNS tsquery AS $$
+    SELECT websearch_to_tsquery('english', q) || websearch_to_tsquery('spanish', q)
+$$ LANGUAGE sql STABLE STRICT PARALLEL SAFE;
+
+-- Text is indexed under both dictionaries with the same weight.
+CREATE OR REPLACE FUNCTION search_vector(body TEXT, weight "char") RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', COALESCE(body, '')) || to_tsvector('spanish', COALESCE(body, '')), weight)
+$$ LANGUAGE sql STABLE PARALLEL SAFE;
+
+-- Search Columns
+ALTER TABLE "listings"
+ADD COLUMN IF NOT EXISTS "search_document" TSVECTOR NOT NULL DEFAULT ''::tsvector,
+ADD COLUMN IF NOT EXISTS "search_text" TEXT NOT NULL DEFAULT '';
+
+-- Indexes for word and trigram matches
+CREATE INDEX IF NOT EXISTS idx_listings_search_document ON "listings" USING GIN ("search_document");
+CREATE INDEX IF NOT EXISTS idx_listings_search_text ON "listings" USING GIN ("search_text" public.gin_trgm_ops);
+
+-- Builds a listing's search columns from its own fields and the rows it refers to. Breed and place
+-- names are proper nouns, so they are kept unstemmed.
+CREATE OR REPLACE FUNCTION set_listing_search() RETURNS TRIGGER AS $$
+DECLARE
+    breeds TEXT;
+    places TEXT;
+BEGIN
+    SELECT COALESCE(string_agg(DISTINCT b.name, ' '), '') INTO breeds
+    FROM listings_cattle AS lc
+    JOIN cattle_breed_composition AS cb ON cb.cattle_id = lc.cattle_id
+    JOIN breeds AS b ON b.id = cb.breed_id
+    WHERE lc.listing_id = NEW.id;
+
+    places := concat_ws(' ',
+        (SELECT name FROM areas WHERE id = NEW.area_id),
+        (SELECT name FROM regions WHERE id = NEW.region_id));
+
+    NEW.search_document :=
+        search_vector(NEW.title, 'A') ||
+        setweight(to_tsvector('simple', breeds), 'B') ||
+        setweight(to_tsvector('simple', places), 'C') ||
+        search_vector(NEW.description, 'D');
+    NEW.search_text := lower(concat_ws(' ', NEW.title, breeds, places));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Setting search_document to its default is how the other triggers ask for a rebuild.
+CREATE TRIGGER trg_listings_search
+BEFORE INSERT OR UPDATE OF "title", "description", "area_id", "region_id", "search_document" ON "listings"
+FOR EACH ROW EXECUTE FUNCTION set_listing_search();
+
+-- Rebuilds the search columns of the listings a changed row appears in.
+CREATE OR REPLACE FUNCTION refresh_listing_search() RETURNS TRIGGER AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    CASE TG_TABLE_NAME
+    WHEN 'listings_cattle' THEN
+        UPDATE listings SET search_document = DEFAULT WHERE id = changed.listing_id;
+    WHEN 'cattle_breed_composition' THEN
+        UPDATE listings SET search_document = DEFAULT
+        WHERE id IN (SELECT listing_id FROM listings_cattle WHERE cattle_id = changed.cattle_id);
+    WHEN 'breeds' THEN
+        UPDATE listings SET search_document = DEFAULT
+        WHERE id IN (
+            SELECT lc.listing_id FROM listings_cattle AS lc
+            JOIN cattle_breed_composition AS cb ON cb.cattle_id = lc.cattle_id
+            WHERE cb.breed_id = changed.id
+        );
+    WHEN 'areas' THEN
+        UPDATE listings SET search_document = DEFAULT WHERE area_id = changed.id;
+    WHEN 'regions' THEN
+        UPDATE listings SET search_document = DEFAULT WHERE region_id = changed.id;
+    END CASE;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_listings_cattle_search
+AFTER INSERT OR DELETE ON "listings_cattle"
+FOR EACH ROW EXECUTE FUNCTION refresh_listing_search();
+
+CREATE TRIGGER trg_cattle_breed_composition_search
+AFTER INSERT OR UPDATE OR DELETE ON "cattle_breed_composition"
+FOR EACH ROW EXECUTE FUNCTION refresh_listing_search();
+
+CREATE TRIGGER trg_breeds_search
+AFTER UPDATE OF "name" ON "breeds"
+FOR EACH ROW EXECUTE FUNCTION refresh_listing_search();
+
+CREATE TRIGGER trg_areas_search
+AFTER UPDATE OF "name" ON "areas"
+FOR EACH ROW EXECUTE FUNCTION refresh_listing_search();
+
+CREATE TRIGGER trg_regions_search
+AFTER UPDATE OF "name" ON "regions"
+FOR EACH ROW EXECUTE FUNCTION refresh_listing_search();
+
+-- Index the listings that already exist
+UPDATE "listings" SET "search_document" = DEFAULT;
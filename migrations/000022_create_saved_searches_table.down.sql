@@ -0,0 +1,19 @@
+-- File: 000022_create_saved_searches_table.down.sql
+
+-- This migration script drops the saved search tables and the alert frequency enum type.
+
+-- Drop Saved Search Matches Table
+DROP INDEX IF EXISTS idx_saved_search_matches_pending;
+DROP TABLE IF EXISTS "saved_search_matches";
+
+-- Drop Saved Searches Table
+DROP INDEX IF EXISTS idx_saved_searches_user_id;
+DROP TABLE IF EXISTS "saved_searches";
+
+-- Drop Alert Frequency Enumeration
+DO $$
+BEGIN
+    IF to_regtype('alert_frequency_enum') IS NOT NULL THEN
+        DROP TYPE alert_frequency_enum;
+    END IF;
+END $$;
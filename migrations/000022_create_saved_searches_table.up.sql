@@ -0,0 +1,58 @@
+-- File: 000022_create_saved_searches_table.up.sql
+
+-- This migration script creates the 'saved_searches' table, which holds the listing searches users
+-- save to be alerted about, and the 'saved_search_matches' table, which records the listings each
+-- search has matched and whether they have gone out in a digest yet.
+
+-- Alert Frequency Enumeration
+DO $$
+BEGIN
+    IF to_regtype('alert_frequency_enum') IS NULL THEN
+        CREATE TYPE alert_frequency_enum AS ENUM ('immediate', 'daily', 'weekly');
+    END IF;
+END $$;
+
+-- Create Saved Searches Table
+CREATE TABLE IF NOT EXISTS "saved_searches" (
+    -- Primary Key
+    "id" BIGSERIAL PRIMARY KEY,
+    -- Foreign Key to Users Table
+    "user_id" BIGINT NOT NULL,
+    -- Search Info
+    "name" TEXT NOT NULL,
+    "query" TEXT NOT NULL DEFAULT '', -- the listing search as a query string, e.g. 'q=brahman&max_price_per_kg=5'
+    -- Alert Info
+    "frequency" alert_frequency_enum NOT NULL DEFAULT 'daily',
+    "alerts_enabled" BOOLEAN NOT NULL DEFAULT TRUE,
+    -- Kept in plain text, unlike the tokens table, since every digest has to include it and all it
+    -- can do is turn the alerts of one search off.
+    "unsubscribe_token" TEXT NOT NULL UNIQUE,
+    "checked_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- listings changed since then have yet to be matched
+    "sent_at" TIMESTAMPTZ, -- when the last digest went out
+    -- Versioning
+    "version" INT NOT NULL DEFAULT 1,
+    -- Timestamps
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Foreign Key Constraint
+ALTER TABLE "saved_searches"
+ADD CONSTRAINT fk_saved_searches_user_id
+FOREIGN KEY ("user_id") REFERENCES "users"("id")
+ON DELETE CASCADE;
+
+-- Index for a user's searches
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user_id ON "saved_searches" ("user_id");
+
+-- Create Saved Search Matches Table
+CREATE TABLE IF NOT EXISTS "saved_search_matches" (
+    "saved_search_id" BIGINT NOT NULL REFERENCES "saved_searches"("id") ON DELETE CASCADE,
+    "listing_id" BIGINT NOT NULL REFERENCES "listings"("id") ON DELETE CASCADE,
+    "matched_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "sent_at" TIMESTAMPTZ, -- NULL until the match has been in a digest
+    PRIMARY KEY ("saved_search_id", "listing_id")
+);
+
+-- Index for the matches still waiting for a digest
+CREATE INDEX IF NOT EXISTS idx_saved_search_matches_pending ON "saved_search_matches" ("saved_search_id") WHERE "sent_at" IS NULL;
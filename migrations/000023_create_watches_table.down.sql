@@ -0,0 +1,24 @@
+-- File: 000023_create_watches_table.down.sql
+
+-- This migration script drops the watch tables, the watch event enum type and the sold timestamp
+-- of listings.
+
+-- Drop Watch Notifications Table
+DROP INDEX IF EXISTS idx_watch_notifications_pending;
+DROP TABLE IF EXISTS "watch_notifications";
+
+-- Drop Watches Table
+DROP INDEX IF EXISTS idx_watches_seller_id;
+DROP INDEX IF EXISTS idx_watches_listing_id;
+DROP TABLE IF EXISTS "watches";
+
+-- Drop Watch Event Enumeration
+DO $$
+BEGIN
+    IF to_regtype('watch_event_enum') IS NOT NULL THEN
+        DROP TYPE watch_event_enum;
+    END IF;
+END $$;
+
+-- Drop Sold Timestamp from Listings
+ALTER TABLE "listings" DROP COLUMN IF EXISTS "sold_at";
@@ -0,0 +1,50 @@
+-- File: 000023_create_watches_table.up.sql
+
+-- This migration script lets listings be marked as sold, creates the 'watches' table, which holds
+-- the listings and sellers users keep an eye on, and the 'watch_notifications' table, which queues
+-- what happened to a watched listing until it has been emailed to the watcher.
+
+-- Add Sold Timestamp to Listings
+ALTER TABLE "listings" ADD COLUMN IF NOT EXISTS "sold_at" TIMESTAMPTZ;
+
+-- Watch Event Enumeration
+DO $$
+BEGIN
+    IF to_regtype('watch_event_enum') IS NULL THEN
+        CREATE TYPE watch_event_enum AS ENUM ('price_changed', 'sold');
+    END IF;
+END $$;
+
+-- Create Watches Table
+CREATE TABLE IF NOT EXISTS "watches" (
+    -- Primary Key
+    "id" BIGSERIAL PRIMARY KEY,
+    -- Foreign Keys
+    "user_id" BIGINT NOT NULL REFERENCES "users"("id") ON DELETE CASCADE, -- who is watching
+    "listing_id" BIGINT REFERENCES "listings"("id") ON DELETE CASCADE, -- a watched listing
+    "seller_id" BIGINT REFERENCES "users"("id") ON DELETE CASCADE, -- or a seller, whose every listing is watched
+    -- Timestamps
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- A watch is of exactly one listing or one seller, and only once per user
+    CONSTRAINT chk_watches_target CHECK (num_nonnulls("listing_id", "seller_id") = 1),
+    CONSTRAINT uq_watches_listing UNIQUE ("user_id", "listing_id"),
+    CONSTRAINT uq_watches_seller UNIQUE ("user_id", "seller_id")
+);
+
+-- Indexes for finding the watchers of a listing or seller
+CREATE INDEX IF NOT EXISTS idx_watches_listing_id ON "watches" ("listing_id") WHERE "listing_id" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_watches_seller_id ON "watches" ("seller_id") WHERE "seller_id" IS NOT NULL;
+
+-- Create Watch Notifications Table
+CREATE TABLE IF NOT EXISTS "watch_notifications" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT NOT NULL REFERENCES "users"("id") ON DELETE CASCADE, -- the watcher to tell
+    "listing_id" BIGINT NOT NULL REFERENCES "listings"("id") ON DELETE CASCADE,
+    "event" watch_event_enum NOT NULL,
+    "detail" TEXT NOT NULL DEFAULT '', -- e.g. 'cow from 4 to 3 per kg'
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "sent_at" TIMESTAMPTZ -- NULL until the notification has been emailed
+);
+
+-- Index for the notifications still waiting to be emailed
+CREATE INDEX IF NOT EXISTS idx_watch_notifications_pending ON "watch_notifications" ("user_id") WHERE "sent_at" IS NULL;
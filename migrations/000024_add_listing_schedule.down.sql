@@ -0,0 +1,14 @@
+-- File: 000024_add_listing_schedule.down.sql
+
+-- This migration script drops the schedule of listings. Postgres cannot drop a value from an enum,
+-- so 'expiring' stays in watch_event_enum; its pending notifications are removed instead.
+
+DELETE FROM "watch_notifications" WHERE "event" = 'expiring';
+
+DROP INDEX IF EXISTS idx_listings_expires_at;
+DROP INDEX IF EXISTS idx_listings_publish_at;
+
+ALTER TABLE "listings"
+DROP COLUMN IF EXISTS "expiry_reminded_at",
+DROP COLUMN IF EXISTS "expires_at",
+DROP COLUMN IF EXISTS "publish_at";
@@ -0,0 +1,22 @@
+-- File: 000024_add_listing_schedule.up.sql
+
+-- This migration script gives listings a schedule. A listing with publish_at in the future stays
+-- inactive until the scheduler publishes it, and an active listing is taken off the market by the
+-- scheduler once expires_at has passed, unless the seller renews it. Sellers are reminded a few
+-- days before, and expiry_reminded_at records that the reminder for the current expiry went out.
+
+ALTER TABLE "listings"
+ADD COLUMN IF NOT EXISTS "publish_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "expires_at" TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS "expiry_reminded_at" TIMESTAMPTZ;
+
+-- Existing listings get a full lifetime (listings.Lifetime) from now, so none expire unannounced.
+UPDATE "listings" SET "expires_at" = NOW() + INTERVAL '30 days' WHERE "expires_at" IS NULL;
+ALTER TABLE "listings" ALTER COLUMN "expires_at" SET NOT NULL;
+
+-- Indexes for the scheduler
+CREATE INDEX IF NOT EXISTS idx_listings_publish_at ON "listings" ("publish_at") WHERE "publish_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_listings_expires_at ON "listings" ("expires_at") WHERE "is_active";
+
+-- Watchers are told when a watched listing is about to expire
+ALTER TYPE watch_event_enum ADD VALUE IF NOT EXISTS 'expiring';
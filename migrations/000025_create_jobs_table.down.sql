@@ -0,0 +1,17 @@
+-- File: 000025_create_jobs_table.down.sql
+
+-- This migration script drops the 'jobs' table and the job status enum type.
+
+-- Drop Jobs Table
+DROP INDEX IF EXISTS idx_jobs_status_kind;
+DROP INDEX IF EXISTS idx_jobs_running;
+DROP INDEX IF EXISTS idx_jobs_due;
+DROP TABLE IF EXISTS "jobs";
+
+-- Drop Job Status Enumeration
+DO $$
+BEGIN
+    IF to_regtype('job_status_enum') IS NOT NULL THEN
+        DROP TYPE job_status_enum;
+    END IF;
+END $$;
@@ -0,0 +1,38 @@
+-- File: 000025_create_jobs_table.up.sql
+
+-- This migration script creates the 'jobs' table, a durable queue of background work. Workers claim
+-- due jobs with SELECT ... FOR UPDATE SKIP LOCKED, retry failed ones with backoff, and dead letter
+-- those that run out of attempts.
+
+-- Job Status Enumeration
+DO $$
+BEGIN
+    IF to_regtype('job_status_enum') IS NULL THEN
+        CREATE TYPE job_status_enum AS ENUM ('pending', 'running', 'succeeded', 'dead');
+    END IF;
+END $$;
+
+-- Create Jobs Table
+CREATE TABLE IF NOT EXISTS "jobs" (
+    -- Primary Key
+    "id" BIGSERIAL PRIMARY KEY,
+    -- Job Info
+    "kind" TEXT NOT NULL, -- picks the handler, e.g. 'send_email'
+    "payload" JSONB NOT NULL DEFAULT '{}',
+    -- Progress
+    "status" job_status_enum NOT NULL DEFAULT 'pending',
+    "attempts" INT NOT NULL DEFAULT 0,
+    "max_attempts" INT NOT NULL DEFAULT 8 CHECK ("max_attempts" > 0),
+    "run_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- not claimed before then
+    "last_error" TEXT NOT NULL DEFAULT '',
+    "locked_at" TIMESTAMPTZ, -- when a worker claimed it; a stale lock means the worker died
+    "finished_at" TIMESTAMPTZ, -- when it succeeded or was dead lettered
+    -- Timestamps
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for claiming due jobs, reclaiming those of dead workers and inspecting the queue
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON "jobs" ("run_at", "id") WHERE "status" = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON "jobs" ("locked_at") WHERE "status" = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_status_kind ON "jobs" ("status", "kind");
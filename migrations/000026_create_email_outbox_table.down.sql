@@ -0,0 +1,20 @@
+-- File: 000026_create_email_outbox_table.down.sql
+
+-- This migration script drops the 'email_outbox' table and the email status enum type, along with
+-- the jobs that deliver its emails.
+
+-- Drop Delivery Jobs
+DELETE FROM "jobs" WHERE "kind" = 'deliver_email';
+
+-- Drop Email Outbox Table
+DROP INDEX IF EXISTS idx_email_outbox_recipient;
+DROP INDEX IF EXISTS idx_email_outbox_status;
+DROP TABLE IF EXISTS "email_outbox";
+
+-- Drop Email Status Enumeration
+DO $$
+BEGIN
+    IF to_regtype('email_status_enum') IS NOT NULL THEN
+        DROP TYPE email_status_enum;
+    END IF;
+END $$;
@@ -0,0 +1,51 @@
+-- File: 000026_create_email_outbox_table.up.sql
+
+-- This migration script creates the 'email_outbox' table. Outgoing emails are written to it in the
+-- same transaction as the change they are about, together with a 'deliver_email' job that sends
+-- them, so an email goes out exactly when its change is committed. Emails still queued as
+-- 'send_email' jobs are moved into the outbox.
+
+-- Email Status Enumeration
+DO $$
+BEGIN
+    IF to_regtype('email_status_enum') IS NULL THEN
+        CREATE TYPE email_status_enum AS ENUM ('queued', 'sent', 'failed');
+    END IF;
+END $$;
+
+-- Create Email Outbox Table
+CREATE TABLE IF NOT EXISTS "email_outbox" (
+    -- Primary Key
+    "id" BIGSERIAL PRIMARY KEY,
+    -- Email Info
+    "recipient" TEXT NOT NULL,
+    "template" TEXT NOT NULL, -- e.g. 'saved_search_digest.tmpl'
+    "data" JSONB NOT NULL DEFAULT '{}', -- what the template is rendered with
+    -- Delivery
+    "status" email_status_enum NOT NULL DEFAULT 'queued',
+    "attempts" INT NOT NULL DEFAULT 0,
+    "last_error" TEXT NOT NULL DEFAULT '',
+    "sent_at" TIMESTAMPTZ,
+    -- Timestamps
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for inspecting the outbox
+CREATE INDEX IF NOT EXISTS idx_email_outbox_status ON "email_outbox" ("status");
+CREATE INDEX IF NOT EXISTS idx_email_outbox_recipient ON "email_outbox" (LOWER("recipient"));
+
+-- Move the emails still waiting in the job queue into the outbox
+WITH moved AS (
+    DELETE FROM "jobs"
+    WHERE "kind" = 'send_email' AND "status" IN ('pending', 'running')
+    RETURNING "payload"
+), queued AS (
+    INSERT INTO "email_outbox" ("recipient", "template", "data")
+    SELECT "payload"->>'to', "payload"->>'template', COALESCE("payload"->'data', '{}')
+    FROM moved
+    RETURNING "id"
+)
+INSERT INTO "jobs" ("kind", "payload")
+SELECT 'deliver_email', jsonb_build_object('email_id', "id")
+FROM queued;
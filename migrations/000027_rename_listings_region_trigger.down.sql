@@ -0,0 +1,7 @@
+-- File: 000027_rename_listings_region_trigger.down.sql
+
+-- This migration script restores the old name of the listing region check trigger.
+
+ALTER TRIGGER trg_listings_check_region_matches_area ON "listings" RENAME TO trg_listings_region_from_area;
+
+ALTER FUNCTION listings_check_region_matches_area() RENAME TO listings_region_from_area;
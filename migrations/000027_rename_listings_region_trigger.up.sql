@@ -0,0 +1,9 @@
+-- File: 000027_rename_listings_region_trigger.up.sql
+
+-- This migration script renames the trigger that checks a listing's region against the region of
+-- its area. It only ever rejected a mismatch and never set the region, which its old name,
+-- listings_region_from_area, suggested; the models resolve the region themselves.
+
+ALTER FUNCTION listings_region_from_area() RENAME TO listings_check_region_matches_area;
+
+ALTER TRIGGER trg_listings_region_from_area ON "listings" RENAME TO trg_listings_check_region_matches_area;
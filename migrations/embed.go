@@ -0,0 +1,11 @@
+// File: migrations/embed.go
+
+// Package migrations embeds the SQL migration files so the API binary can apply them itself.
+package migrations
+
+import "embed"
+
+// FS holds every *.up.sql and *.down.sql file in this directory.
+//
+//go:embed *.sql
+var FS embed.FS